@@ -0,0 +1,97 @@
+package main
+
+import (
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+)
+
+// bindDriverOf returns the driver the devices of a selector object are to be bound to, if any
+func bindDriverOf(selector interface{}) string {
+	switch s := selector.(type) {
+	case *types.NetDeviceSelectors:
+		return s.BindDriver
+	case *types.AccelDeviceSelectors:
+		return s.BindDriver
+	default:
+		return ""
+	}
+}
+
+// bindDevices binds the selected devices of a selector object to the given driver and returns them rebuilt
+// with their new driver. Devices that fail to bind are dropped, devices allocated or assigned to containers are
+// left bound to their current driver. No device is bound when the PodResources API is unavailable.
+func (rm *resourceManager) bindDevices(log klog.Logger, dp types.DeviceProvider, rc *types.ResourceConfig,
+	selectorIndex int, driver string, devices []types.HostDevice) []types.HostDevice {
+	log = log.WithValues("selectorIndex", selectorIndex, "bindDriver", driver)
+	keep := make(map[string]bool, len(devices))
+	rebound := false
+	var assigned func(deviceID string) bool
+	for _, dev := range devices {
+		id := dev.GetDeviceID()
+		if dev.GetDriver() == driver {
+			keep[id] = true
+			continue
+		}
+		// the tracker and the checkpoint may not know about the assigned devices yet, hence the PodResources
+		// API is queried once before binding the first device
+		if assigned == nil {
+			var err error
+			if assigned, err = rm.assignedDevices(); err != nil {
+				log.Error(err, "Unable to get the devices assigned to containers, not binding devices")
+				return devices
+			}
+		}
+		if rm.deviceInUse(id) || assigned(id) {
+			log.Info("Not binding device allocated to a container", "deviceID", id, "driver", dev.GetDriver())
+			keep[id] = true
+			continue
+		}
+		if err := utils.BindDriver(id, driver); err != nil {
+			log.Error(err, "Unable to bind device, dropping it from the pool", "deviceID", id, "driver", dev.GetDriver())
+			continue
+		}
+		log.Info("Bound device", "deviceID", id, "previousDriver", dev.GetDriver())
+		keep[id] = true
+		rebound = true
+	}
+	if !rebound {
+		return filterDeviceIDs(devices, keep)
+	}
+	// the devices carry driver specific device specs, hence the rebound ones are built again
+	return filterDeviceIDs(dp.GetDevices(rc, selectorIndex), keep)
+}
+
+// deviceInUse tells whether a device of any configured resource pool is recorded as allocated to a container
+// or known to be assigned to one by the PodResources API
+func (rm *resourceManager) deviceInUse(deviceID string) bool {
+	for _, rc := range rm.configList {
+		resourceName := rm.resourceKey(rc)
+		if rm.tracker != nil {
+			if _, ok := rm.tracker.GetDeviceAssignment(resourceName, deviceID); ok {
+				return true
+			}
+		}
+		if rm.checkpoint == nil {
+			continue
+		}
+		for _, id := range rm.checkpoint.GetDevices(resourceName) {
+			if id == deviceID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterDeviceIDs returns the devices whose ID is in ids
+func filterDeviceIDs(devices []types.HostDevice, ids map[string]bool) []types.HostDevice {
+	filtered := make([]types.HostDevice, 0, len(ids))
+	for _, dev := range devices {
+		if ids[dev.GetDeviceID()] {
+			filtered = append(filtered, dev)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+)
+
+var _ = Describe("Binding devices to drivers", func() {
+	newDevice := func(id, driver string) *mocks.PciNetDevice {
+		dev := &mocks.PciNetDevice{}
+		dev.On("GetDeviceID").Return(id).
+			On("GetDriver").Return(driver)
+		return dev
+	}
+
+	Describe("getting the driver of a selector object", func() {
+		It("should return the bindDriver of net and accelerator selectors", func() {
+			Expect(bindDriverOf(&types.NetDeviceSelectors{
+				GenericPciDeviceSelectors: types.GenericPciDeviceSelectors{BindDriver: "vfio-pci"}})).To(Equal("vfio-pci"))
+			Expect(bindDriverOf(&types.AccelDeviceSelectors{
+				GenericPciDeviceSelectors: types.GenericPciDeviceSelectors{BindDriver: "vfio-pci"}})).To(Equal("vfio-pci"))
+			Expect(bindDriverOf(&types.AuxNetDeviceSelectors{})).To(BeEmpty())
+		})
+	})
+	Describe("binding the selected devices", func() {
+		var (
+			rm           *resourceManager
+			rc           *types.ResourceConfig
+			dp           *mocks.DeviceProvider
+			podResources *mocks.PodResourcesClient
+		)
+		BeforeEach(func() {
+			checkpoint := &mocks.AllocationCheckpoint{}
+			checkpoint.On("GetDevices", "test/pool").Return([]string{"0000:01:10.1"})
+			rc = &types.ResourceConfig{ResourceName: "pool", DeviceType: types.NetDeviceType}
+			podResources = &mocks.PodResourcesClient{}
+			rm = &resourceManager{
+				cliParams:    cliParams{resourcePrefix: "test"},
+				configList:   []*types.ResourceConfig{rc},
+				checkpoint:   checkpoint,
+				podResources: podResources,
+			}
+			dp = &mocks.DeviceProvider{}
+		})
+		It("should keep devices already bound to the driver without binding them", func() {
+			devs := []types.HostDevice{newDevice("0000:01:10.0", "vfio-pci")}
+
+			Expect(rm.bindDevices(klog.Background(), dp, rc, 0, "vfio-pci", devs)).To(Equal(devs))
+			dp.AssertNotCalled(GinkgoT(), "GetDevices")
+			podResources.AssertNotCalled(GinkgoT(), "GetDeviceAssignments", mock.Anything)
+		})
+		It("should bind the devices, keep allocated ones and drop the ones failing to bind", func() {
+			fs := &utils.FakeFilesystem{
+				Dirs: []string{
+					"sys/bus/pci/devices/0000:01:10.2", "sys/bus/pci/devices/0000:01:10.3", "sys/bus/pci/drivers/vfio-pci",
+				},
+				Files: map[string][]byte{
+					"sys/bus/pci/devices/0000:01:10.2/driver_override": nil,
+					"sys/bus/pci/drivers_probe":                        nil,
+				},
+				Symlinks: map[string]string{"sys/bus/pci/devices/0000:01:10.2/driver": "../../../../bus/pci/drivers/vfio-pci"},
+			}
+			defer fs.Use()()
+
+			devs := []types.HostDevice{
+				newDevice("0000:01:10.0", "vfio-pci"),
+				newDevice("0000:01:10.1", "iavf"),
+				newDevice("0000:01:10.2", "iavf"),
+				newDevice("0000:01:10.3", "iavf"),
+			}
+			rebuilt := []types.HostDevice{
+				newDevice("0000:01:10.0", "vfio-pci"),
+				newDevice("0000:01:10.1", "iavf"),
+				newDevice("0000:01:10.2", "vfio-pci"),
+				newDevice("0000:01:10.3", "iavf"),
+			}
+			dp.On("GetDevices", rc, 0).Return(rebuilt)
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{}, nil).Once()
+
+			Expect(rm.bindDevices(klog.Background(), dp, rc, 0, "vfio-pci", devs)).To(Equal(rebuilt[:3]))
+			podResources.AssertExpectations(GinkgoT())
+		})
+		It("should not bind devices the PodResources API reports as assigned to a container", func() {
+			pod := types.DeviceAssignment{Namespace: "default", Pod: "pod", Container: "app"}
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{
+				"test/pool": {"0000:01:10.2": pod},
+			}, nil)
+			devs := []types.HostDevice{newDevice("0000:01:10.2", "iavf")}
+
+			Expect(rm.bindDevices(klog.Background(), dp, rc, 0, "vfio-pci", devs)).To(Equal(devs))
+			dp.AssertNotCalled(GinkgoT(), "GetDevices", mock.Anything, mock.Anything)
+		})
+		It("should not bind any device when the PodResources API is unavailable", func() {
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(nil, fmt.Errorf("no kubelet"))
+			devs := []types.HostDevice{newDevice("0000:01:10.0", "vfio-pci"), newDevice("0000:01:10.2", "iavf")}
+
+			Expect(rm.bindDevices(klog.Background(), dp, rc, 0, "vfio-pci", devs)).To(Equal(devs))
+			dp.AssertNotCalled(GinkgoT(), "GetDevices", mock.Anything, mock.Anything)
+		})
+	})
+})
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/klog/v2"
+	"k8s.io/klog/v2/textlogger"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/resources"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+)
+
+const (
+	explainCommand = "explain"
+	outputTable    = "table"
+	outputJSON     = "json"
+)
+
+// resourceExplanation explains which devices the selectors of a resource pool selected
+type resourceExplanation struct {
+	ResourceName string                `json:"resourceName"`
+	DeviceType   types.DeviceType      `json:"deviceType"`
+	Selectors    []selectorExplanation `json:"selectors"`
+}
+
+// selectorExplanation explains the outcome of a selector object for every device of its device type
+type selectorExplanation struct {
+	SelectorIndex int                 `json:"selectorIndex"`
+	Error         string              `json:"error,omitempty"`
+	Devices       []deviceExplanation `json:"devices"`
+}
+
+// deviceExplanation tells whether a device was selected and otherwise why not
+type deviceExplanation struct {
+	DeviceID  string                   `json:"deviceID"`
+	Selected  bool                     `json:"selected"`
+	DroppedBy *resources.DroppedDevice `json:"droppedBy,omitempty"`
+	ClaimedBy *deviceClaim             `json:"claimedBy,omitempty"`
+}
+
+// deviceClaim identifies the selector object of the resource pool that claimed a device first
+type deviceClaim struct {
+	ResourceName  string `json:"resourceName"`
+	SelectorIndex int    `json:"selectorIndex"`
+}
+
+// runExplain discovers the host devices and prints which devices every selector object of the config selects,
+// without starting any resource server. It returns the exit code of the explain command.
+func runExplain(args []string) int {
+	cp := &cliParams{}
+	var output string
+	var verbosity int
+	fs := flag.NewFlagSet(explainCommand, flag.ContinueOnError)
+	fs.StringVar(&cp.configFile, "config-file", defaultConfig, "JSON device pool config file location")
+	fs.StringVar(&cp.resourcePrefix, "resource-prefix", "intel.com", "resource name prefix used for K8s extended resource")
+	fs.StringVar(&output, "output", outputTable, "Output format, \"table\" or \"json\"")
+	fs.IntVar(&verbosity, "v", 0, "Verbosity of the log messages written to stderr")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if output != outputTable && output != outputJSON {
+		fmt.Fprintf(os.Stderr, "unsupported output format %q, must be %q or %q\n", output, outputTable, outputJSON)
+		return 2
+	}
+
+	klog.SetLogger(textlogger.NewLogger(textlogger.NewConfig(textlogger.Verbosity(verbosity), textlogger.Output(os.Stderr))))
+	defer klog.ClearLogger()
+	trace := resources.NewSelectorTrace()
+	resources.SetDropRecorder(trace.Record)
+	defer resources.SetDropRecorder(nil)
+
+	rm := newResourceManager(cp)
+	if err := rm.readConfig(); err != nil {
+		klog.ErrorS(err, "Error getting resources from file", "configFile", cp.configFile)
+		return 1
+	}
+	if !rm.validConfigs() {
+		klog.ErrorS(nil, "One or more invalid configuration(s) given")
+		return 1
+	}
+	if err := rm.discoverHostDevices(); err != nil {
+		klog.ErrorS(err, "Error discovering host devices")
+		return 1
+	}
+
+	if err := writeExplanations(os.Stdout, output, rm.explain(trace)); err != nil {
+		klog.ErrorS(err, "Error writing explanation")
+		return 1
+	}
+	return 0
+}
+
+// explain selects the devices of every resource config the way initServers does, without binding them or
+// creating vDPA devices, and explains the outcome for every device
+func (rm *resourceManager) explain(trace *resources.SelectorTrace) []resourceExplanation {
+	explanations := make([]resourceExplanation, 0, len(rm.configList))
+	claims := make(map[string]*deviceClaim)
+	rm.observer = func(rc *types.ResourceConfig, index int, devices, filtered, selected []types.HostDevice, err error) {
+		re := &explanations[len(explanations)-1]
+		se := selectorExplanation{SelectorIndex: index, Devices: make([]deviceExplanation, 0, len(devices))}
+		if err != nil {
+			se.Error = err.Error()
+		}
+		dropped := make(map[string]resources.DroppedDevice)
+		for _, d := range trace.Take() {
+			dropped[d.DeviceID] = d
+		}
+		kept := make(map[string]bool, len(filtered))
+		for _, dev := range filtered {
+			kept[dev.GetDeviceID()] = true
+		}
+		isSelected := make(map[string]bool, len(selected))
+		for _, dev := range selected {
+			isSelected[dev.GetDeviceID()] = true
+		}
+		for _, dev := range devices {
+			de := deviceExplanation{DeviceID: dev.GetDeviceID()}
+			switch {
+			case isSelected[de.DeviceID]:
+				de.Selected = true
+				claims[de.DeviceID] = &deviceClaim{ResourceName: re.ResourceName, SelectorIndex: index}
+			case kept[de.DeviceID]:
+				de.ClaimedBy = claims[de.DeviceID]
+			default:
+				if d, ok := dropped[de.DeviceID]; ok {
+					de.DroppedBy = &d
+				}
+			}
+			se.Devices = append(se.Devices, de)
+		}
+		re.Selectors = append(re.Selectors, se)
+	}
+	defer func() { rm.observer = nil }()
+
+	// devices dropped while validating and discovering are not explained
+	trace.Take()
+	deviceAllocated := make(map[string]bool)
+	for _, rc := range rm.configList {
+		explanations = append(explanations, resourceExplanation{
+			ResourceName: rm.resourceKey(rc),
+			DeviceType:   rc.DeviceType,
+			Selectors:    make([]selectorExplanation, 0, len(rc.SelectorObjs)),
+		})
+		// resource configs without device provider are explained without selectors
+		_, _ = rm.getPoolDevices(rc, deviceAllocated)
+	}
+	return explanations
+}
+
+// writeExplanations writes the explanations to w as a table or as JSON
+func writeExplanations(w io.Writer, output string, explanations []resourceExplanation) error {
+	if output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(explanations)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESOURCE\tSELECTOR\tDEVICE\tRESULT")
+	for _, re := range explanations {
+		for _, se := range re.Selectors {
+			if se.Error != "" {
+				fmt.Fprintf(tw, "%s\t%d\t-\terror: %s\n", re.ResourceName, se.SelectorIndex, se.Error)
+			}
+			if len(se.Devices) == 0 {
+				fmt.Fprintf(tw, "%s\t%d\t-\tno %s devices discovered\n", re.ResourceName, se.SelectorIndex, re.DeviceType)
+			}
+			for _, de := range se.Devices {
+				fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", re.ResourceName, se.SelectorIndex, de.DeviceID, de.result())
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// result describes the outcome of the selection of a device in a table cell
+func (de *deviceExplanation) result() string {
+	switch {
+	case de.Selected:
+		return "selected"
+	case de.ClaimedBy != nil:
+		return fmt.Sprintf("already claimed by %s selector %d", de.ClaimedBy.ResourceName, de.ClaimedBy.SelectorIndex)
+	case de.DroppedBy != nil:
+		result := "dropped by " + de.DroppedBy.Selector
+		if len(de.DroppedBy.Values) > 0 {
+			result += fmt.Sprintf(" [%s]", strings.Join(de.DroppedBy.Values, ","))
+		}
+		if de.DroppedBy.DeviceValue != "" {
+			result += fmt.Sprintf(", device has %q", de.DroppedBy.DeviceValue)
+		}
+		return result
+	default:
+		return "dropped"
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/resources"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
+)
+
+var _ = Describe("Explaining selectors", func() {
+	var (
+		rm           *resourceManager
+		explanations []resourceExplanation
+	)
+	BeforeEach(func() {
+		trace := resources.NewSelectorTrace()
+		resources.SetDropRecorder(trace.Record)
+		devA := &mocks.PciNetDevice{}
+		devA.On("GetDeviceID").Return("0000:01:10.0").
+			On("GetDriver").Return("iavf")
+		devB := &mocks.PciNetDevice{}
+		devB.On("GetDeviceID").Return("0000:01:10.1").
+			On("GetDriver").Return("vfio-pci")
+		devs := []types.HostDevice{devA, devB}
+
+		// pool_a only selects iavf devices, pool_b selects all devices
+		dp := &mocks.DeviceProvider{}
+		dp.On("GetDevices", mock.Anything, 0).Return(devs).
+			On("GetFilteredDevices", devs, mock.Anything, 0).Return(
+			func(in []types.HostDevice, rc *types.ResourceConfig, _ int) []types.HostDevice {
+				if rc.ResourceName != "pool_a" {
+					return in
+				}
+				resources.LogDroppedDevices(logr.Discard(), "drivers", []string{"iavf"}, in, in[:1])
+				return in[:1]
+			}, nil)
+
+		rm = &resourceManager{
+			cliParams: cliParams{resourcePrefix: "test"},
+			configList: []*types.ResourceConfig{
+				{ResourceName: "pool_a", DeviceType: types.NetDeviceType, SelectorObjs: []interface{}{nil}},
+				{ResourceName: "pool_b", DeviceType: types.NetDeviceType, SelectorObjs: []interface{}{nil}},
+			},
+			deviceProviders: map[types.DeviceType]types.DeviceProvider{types.NetDeviceType: dp},
+		}
+		explanations = rm.explain(trace)
+	})
+	AfterEach(func() {
+		resources.SetDropRecorder(nil)
+	})
+	It("should explain the outcome for every device", func() {
+		Expect(explanations).To(Equal([]resourceExplanation{{
+			ResourceName: "test/pool_a",
+			DeviceType:   types.NetDeviceType,
+			Selectors: []selectorExplanation{{SelectorIndex: 0, Devices: []deviceExplanation{
+				{DeviceID: "0000:01:10.0", Selected: true},
+				{DeviceID: "0000:01:10.1", DroppedBy: &resources.DroppedDevice{
+					DeviceID: "0000:01:10.1", Selector: "drivers", Values: []string{"iavf"}, DeviceValue: "vfio-pci"}},
+			}}},
+		}, {
+			ResourceName: "test/pool_b",
+			DeviceType:   types.NetDeviceType,
+			Selectors: []selectorExplanation{{SelectorIndex: 0, Devices: []deviceExplanation{
+				{DeviceID: "0000:01:10.0", ClaimedBy: &deviceClaim{ResourceName: "test/pool_a", SelectorIndex: 0}},
+				{DeviceID: "0000:01:10.1", Selected: true},
+			}}},
+		}}))
+	})
+	It("should write the explanations as a table", func() {
+		out := &bytes.Buffer{}
+		Expect(writeExplanations(out, outputTable, explanations)).To(Succeed())
+		Expect(out.String()).To(Equal(
+			"RESOURCE     SELECTOR  DEVICE        RESULT\n" +
+				"test/pool_a  0         0000:01:10.0  selected\n" +
+				"test/pool_a  0         0000:01:10.1  dropped by drivers [iavf], device has \"vfio-pci\"\n" +
+				"test/pool_b  0         0000:01:10.0  already claimed by test/pool_a selector 0\n" +
+				"test/pool_b  0         0000:01:10.1  selected\n"))
+	})
+	It("should write the explanations as JSON", func() {
+		out := &bytes.Buffer{}
+		Expect(writeExplanations(out, outputJSON, explanations)).To(Succeed())
+		var decoded []resourceExplanation
+		Expect(json.Unmarshal(out.Bytes(), &decoded)).To(Succeed())
+		Expect(decoded).To(Equal(explanations))
+	})
+})
@@ -15,80 +15,245 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
+
+	cdiPkg "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/dra"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/logging"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/nri"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 )
 
 const (
-	defaultConfig = "/etc/pcidp/config.json"
+	defaultConfig          = "/etc/pcidp/config.json"
+	debugReadHeaderTimeout = 10 * time.Second
 )
 
 // flagInit parse command line flags
 func flagInit(cp *cliParams) {
+	logging.InitFlags(nil)
+	flag.StringVar(&cp.logFormat, "log-format", logging.FormatText,
+		"Log output format, \"text\" or \"json\"; JSON lines are written to stderr")
 	flag.StringVar(&cp.configFile, "config-file", defaultConfig,
 		"JSON device pool config file location")
 	flag.StringVar(&cp.resourcePrefix, "resource-prefix", "intel.com",
 		"resource name prefix used for K8s extended resource")
 	flag.BoolVar(&cp.useCdi, "use-cdi", false,
 		"Use Container Device Interface to expose devices in containers")
+	flag.StringVar(&cp.cdiSpecDir, "cdi-spec-dir", cdiPkg.DefaultSpecDir,
+		"Directory the CDI specs are written to")
+	flag.StringVar(&cp.cdiSpecVersion, "cdi-spec-version", cdiPkg.DefaultSpecVersion,
+		"CDI specification version of the generated CDI specs")
+	flag.BoolVar(&cp.watchConfig, "watch-config", false,
+		"Reload resource pools when the config file changes")
+	flag.StringVar(&cp.metricsBindAddress, "metrics-bind-address", "",
+		"Address to serve Prometheus metrics on, e.g. \":9808\"; metrics are disabled when empty")
+	flag.DurationVar(&cp.rediscoveryInterval, "rediscovery-interval", 0,
+		"Interval to check for host devices being added or removed, e.g. \"30s\"; rediscovery is disabled when 0")
+	flag.BoolVar(&cp.draMode, "dra", false,
+		"Publish resource pools as Dynamic Resource Allocation ResourceSlices instead of serving device plugins; implies -use-cdi")
+	flag.StringVar(&cp.draDriverName, "dra-driver-name", dra.DefaultDriverName,
+		"Name of the DRA driver used in ResourceSlices and DeviceClasses")
+	flag.StringVar(&cp.nodeName, "node-name", os.Getenv("NODE_NAME"),
+		"Name of the node the ResourceSlices are published for in DRA mode, defaults to the NODE_NAME environment variable")
+	flag.StringVar(&cp.kubeConfig, "kubeconfig", "",
+		"Path to a kubeconfig file used in DRA mode, the in-cluster config is used when empty")
+	flag.StringVar(&cp.debugBindAddress, "debug-bind-address", "",
+		"Address to serve debug endpoints on, e.g. \"127.0.0.1:9809\"; debug endpoints are disabled when empty")
+	flag.BoolVar(&cp.nriMode, "nri", false,
+		"Run an NRI plugin moving the netdevices allocated to containers into the network namespace of their pods")
+	flag.StringVar(&cp.nriSocket, "nri-socket", nri.DefaultSocketPath,
+		"Path of the NRI socket of the container runtime")
+	flag.StringVar(&cp.nriPluginIndex, "nri-plugin-index", nri.DefaultPluginIndex,
+		"Index of the NRI plugin, which orders it among the other NRI plugins of the container runtime")
+	flag.StringVar(&cp.checkpointFile, "checkpoint-file", types.DefaultCheckpointFile,
+		"File recording the allocated devices across restarts; device info files are all removed on restart when empty")
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == explainCommand {
+		os.Exit(runExplain(os.Args[2:]))
+	}
 	cp := &cliParams{}
+
 	flagInit(cp)
 	flag.Parse()
+	defer klog.Flush()
+	if err := logging.Setup(cp.logFormat); err != nil {
+		klog.ErrorS(err, "Invalid log format")
+		return
+	}
+	if cp.draMode {
+		if cp.nodeName == "" {
+			klog.ErrorS(nil, "DRA mode requires the node name, set -node-name or the NODE_NAME environment variable")
+			return
+		}
+		// devices are always handed to containers through CDI in DRA mode
+		cp.useCdi = true
+	}
+	if cp.useCdi {
+		if err := cdiPkg.Configure(cp.cdiSpecDir, cp.cdiSpecVersion); err != nil {
+			klog.ErrorS(err, "Invalid CDI configuration")
+			return
+		}
+	}
 	rm := newResourceManager(cp)
 
-	glog.Infof("resource manager reading configs")
+	klog.InfoS("Resource manager reading configs", "configFile", cp.configFile)
 	if err := rm.readConfig(); err != nil {
-		glog.Errorf("error getting resources from file %v", err)
+		klog.ErrorS(err, "Error getting resources from file", "configFile", cp.configFile)
 		return
 	}
 
 	if len(rm.configList) < 1 {
-		glog.Errorf("no resource configuration; exiting")
+		klog.ErrorS(nil, "No resource configuration; exiting")
 		return // No config found
 	}
 
 	// Validate configs
 	if !rm.validConfigs() {
-		glog.Fatalf("Exiting.. one or more invalid configuration(s) given")
-		return
+		klog.ErrorS(nil, "Exiting.. one or more invalid configuration(s) given")
+		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 	}
-	glog.Infof("Discovering host devices")
+	if cp.metricsBindAddress != "" {
+		srv := metrics.Serve(cp.metricsBindAddress)
+		defer srv.Close() //nolint:errcheck
+	}
+	if cp.debugBindAddress != "" {
+		srv := serveDebug(cp.debugBindAddress, rm)
+		defer srv.Close() //nolint:errcheck
+	}
+
+	if len(rm.pfConfigs) > 0 {
+		klog.InfoS("Configuring SR-IOV PFs", "pfCount", len(rm.pfConfigs))
+		rm.configurePfs(rm.pfConfigs)
+	}
+	if len(rm.sfConfigs) > 0 {
+		klog.InfoS("Configuring subfunctions", "pfCount", len(rm.sfConfigs))
+		rm.configureSfs(rm.sfConfigs)
+	}
+
+	klog.InfoS("Discovering host devices")
 	if err := rm.discoverHostDevices(); err != nil {
-		glog.Errorf("error discovering host devices%v", err)
+		klog.ErrorS(err, "Error discovering host devices")
 		return
 	}
 
-	glog.Infof("Initializing resource servers")
+	if cp.draMode {
+		klog.InfoS("Starting DRA driver", "driverName", cp.draDriverName)
+		if err := rm.initDRADriver(); err != nil {
+			klog.ErrorS(err, "Error starting DRA driver", "driverName", cp.draDriverName)
+			return
+		}
+		handleEvents(rm, cp)
+		return
+	}
+
+	klog.InfoS("Reconciling allocated devices")
+	if err := rm.reconcileAllocations(); err != nil {
+		klog.ErrorS(err, "Unable to reconcile allocated devices")
+	}
+
+	klog.InfoS("Initializing resource servers")
 	if err := rm.initServers(); err != nil {
-		glog.Errorf("error initializing resource servers %v", err)
+		klog.ErrorS(err, "Error initializing resource servers")
 		return
 	}
 
-	glog.Infof("Starting all servers...")
+	klog.InfoS("Starting all servers")
 	if err := rm.startAllServers(); err != nil {
-		glog.Errorf("error starting resource servers %v\n", err)
+		klog.ErrorS(err, "Error starting resource servers")
 		return
 	}
-	glog.Infof("All servers started.")
-	glog.Infof("Listening for term signals")
+	klog.InfoS("All servers started")
+
+	handleEvents(rm, cp)
+}
+
+// handleEvents reloads the config and rediscovers host devices on demand until a termination signal is received
+func handleEvents(rm *resourceManager, cp *cliParams) {
+	reloadCh := make(chan struct{}, 1)
+	rediscoverCh := make(chan struct{}, 1)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if cp.watchConfig {
+		cw, err := newConfigWatcher(cp.configFile)
+		if err != nil {
+			klog.ErrorS(err, "Unable to watch config file", "configFile", cp.configFile)
+		} else {
+			klog.InfoS("Watching config file for changes", "configFile", cp.configFile)
+			go cw.Run(reloadCh, stopCh)
+		}
+	}
+	if !cp.draMode {
+		go rm.tracker.Run(stopCh)
+	}
+	if rm.nriPlugin != nil {
+		klog.InfoS("Starting NRI plugin", "socket", cp.nriSocket)
+		go rm.nriPlugin.Run(stopCh)
+	}
+	if cp.rediscoveryInterval > 0 {
+		klog.InfoS("Watching host devices for changes", "interval", cp.rediscoveryInterval)
+		go newDeviceWatcher(cp.rediscoveryInterval).Run(rediscoverCh, stopCh)
+	}
+
+	klog.InfoS("Listening for term signals")
 	// respond to syscalls for termination
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
-	// Catch termination signals
-	sig := <-sigCh
-	glog.Infof("Received signal \"%v\", shutting down.", sig)
-	if err := rm.stopAllServers(); err != nil {
-		glog.Errorf("stopping servers produced error: %s", err.Error())
+	for {
+		select {
+		case <-rediscoverCh:
+			klog.InfoS("Host devices changed, rediscovering devices")
+			if err := rm.rediscover(); err != nil {
+				klog.ErrorS(err, "Rediscovering devices produced error")
+			}
+			continue
+		case <-reloadCh:
+			klog.InfoS("Config file changed, reloading resource pools", "configFile", cp.configFile)
+		case sig := <-sigCh:
+			if sig != syscall.SIGHUP {
+				// Catch termination signals
+				klog.InfoS("Received signal, shutting down", "signal", sig)
+				if err := rm.stopAllServers(); err != nil {
+					klog.ErrorS(err, "Stopping servers produced error")
+				}
+				return
+			}
+			klog.InfoS("Received signal, reloading resource pools", "signal", sig)
+		}
+		err := rm.reloadConfig()
+		if err != nil {
+			klog.ErrorS(err, "Reloading config produced error")
+		}
+		metrics.ConfigReload(err)
 	}
-	if err := rm.cleanupCDISpecs(); err != nil {
-		glog.Errorf("cleaning up CDI Specs produced error: %s", err.Error())
+}
+
+// serveDebug starts an HTTP listener serving the debug endpoints on bindAddress in the background
+func serveDebug(bindAddress string, rm *resourceManager) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/assignments", rm.tracker)
+	srv := &http.Server{
+		Addr:              bindAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: debugReadHeaderTimeout,
 	}
+	go func() {
+		klog.InfoS("Serving debug endpoints", "address", bindAddress)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.ErrorS(err, "Debug server failed", "address", bindAddress)
+		}
+	}()
+	return srv
 }
@@ -15,28 +15,65 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"time"
 
-	"github.com/golang/glog"
 	"github.com/jaypipes/ghw"
+	"k8s.io/klog/v2"
 
 	cdiPkg "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/checkpoint"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/dra"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/factory"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/infoprovider"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/nri"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/podresources"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/resources"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
 )
 
 const (
-	socketSuffix = "sock"
+	socketSuffix        = "sock"
+	podResourcesTimeout = 10 * time.Second
+	// assignmentInterval is the interval to list the containers devices are assigned to
+	assignmentInterval = 10 * time.Second
 )
 
 // cliParams presents CLI parameters for SR-IOV Network Device Plugin
 type cliParams struct {
-	configFile     string
-	resourcePrefix string
-	useCdi         bool
+	configFile          string
+	resourcePrefix      string
+	useCdi              bool
+	cdiSpecDir          string
+	cdiSpecVersion      string
+	watchConfig         bool
+	metricsBindAddress  string
+	rediscoveryInterval time.Duration
+	draMode             bool
+	draDriverName       string
+	nodeName            string
+	kubeConfig          string
+	checkpointFile      string
+	debugBindAddress    string
+	logFormat           string
+	nriMode             bool
+	nriSocket           string
+	nriPluginIndex      string
+}
+
+// managedServer ties a ResourceServer to the config and devices it was created from
+type managedServer struct {
+	server    types.ResourceServer
+	config    *types.ResourceConfig
+	deviceIDs []string
+	devices   []types.HostDevice
 }
 
 // resourceManager manages resources for SR-IOV Network Device Plugin binaries
@@ -45,128 +82,504 @@ type resourceManager struct {
 	pluginWatchMode bool
 	rFactory        types.ResourceFactory
 	configList      []*types.ResourceConfig
+	pfConfigs       []types.PfConfig
+	sfConfigs       []types.SfConfig
 	resourceServers []types.ResourceServer
+	servers         map[string]*managedServer // resource servers keyed by fully qualified resource name
 	deviceProviders map[types.DeviceType]types.DeviceProvider
 	cdi             cdiPkg.CDI
+	draDriver       *dra.Driver
+	draPools        map[string]*managedServer // DRA resource pools, without server, keyed by fully qualified resource name
+	checkpoint      types.AllocationCheckpoint
+	unassigned      map[string]map[string]bool // checkpointed devices found unassigned by the last runtime prune
+	podResources    types.PodResourcesClient
+	tracker         *podresources.Tracker
+	nriPlugin       *nri.Plugin
+	observer        selectorObserver // set by the explain command only
+	log             klog.Logger
 }
 
+// selectorObserver is called by getPoolDevices with the outcome of every selector object of a resource config:
+// the devices of its device type, those the selectors kept and those of them not claimed by a previous pool.
+// Devices are neither bound nor get vDPA devices created while an observer is set.
+type selectorObserver func(rc *types.ResourceConfig, index int, devices, filtered, selected []types.HostDevice, err error)
+
 // newResourceManager initiates a new instance of resourceManager
 func newResourceManager(cp *cliParams) *resourceManager {
+	log := klog.Background().WithName("manager")
 	pluginWatchMode := utils.DetectPluginWatchMode(types.SockDir)
 	if pluginWatchMode {
-		glog.Infof("Using Kubelet Plugin Registry Mode")
+		log.Info("Using Kubelet Plugin Registry Mode")
 	} else {
-		glog.Infof("Using Deprecated Device Plugin Registry Path")
+		log.Info("Using Deprecated Device Plugin Registry Path")
 	}
 
-	rf := factory.NewResourceFactory(cp.resourcePrefix, socketSuffix, pluginWatchMode, cp.useCdi)
-	dp := make(map[types.DeviceType]types.DeviceProvider)
-	for k := range types.SupportedDevices {
-		dp[k] = rf.GetDeviceProvider(k)
+	var allocationCheckpoint types.AllocationCheckpoint
+	if cp.checkpointFile != "" {
+		var err error
+		allocationCheckpoint, err = checkpoint.New(cp.checkpointFile)
+		if err != nil {
+			log.Info("Unable to load allocation checkpoint, starting with an empty one", "file", cp.checkpointFile, "err", err)
+		}
 	}
 
-	return &resourceManager{
+	rm := &resourceManager{
 		cliParams:       *cp,
 		pluginWatchMode: pluginWatchMode,
-		rFactory:        rf,
-		deviceProviders: dp,
 		cdi:             cdiPkg.New(),
+		checkpoint:      allocationCheckpoint,
+		podResources:    podresources.NewClient(types.PodResourcesSocket),
+		log:             log,
 	}
+	rm.tracker = podresources.NewTracker(rm.podResources, assignmentInterval, rm.setDeviceInfoAssignment,
+		rm.syncAllocations)
+	if cp.nriMode {
+		rm.nriPlugin = nri.NewPlugin(cp.nriSocket, cp.nriPluginIndex)
+	}
+
+	rf := factory.NewResourceFactory(cp.resourcePrefix, socketSuffix, pluginWatchMode, cp.useCdi,
+		allocationCheckpoint, rm.tracker)
+	dp := make(map[types.DeviceType]types.DeviceProvider)
+	for k := range types.SupportedDevices {
+		dp[k] = rf.GetDeviceProvider(k)
+	}
+	rm.rFactory = rf
+	rm.deviceProviders = dp
+	return rm
 }
 
 // readConfig reads and validate configurations from Config file
 func (rm *resourceManager) readConfig() error {
+	configList, resources, err := rm.parseConfig()
+	if err != nil {
+		return err
+	}
+	rm.configList = configList
+	rm.pfConfigs = resources.SriovPfs
+	rm.sfConfigs = resources.Subfunctions
+	return nil
+}
+
+// parseConfig reads the Config file and returns the list of resource configs it contains along with the parsed
+// file, which also holds the PF and SF configs
+func (rm *resourceManager) parseConfig() ([]*types.ResourceConfig, *types.ResourceConfList, error) {
 	resources := &types.ResourceConfList{}
 	rawBytes, err := os.ReadFile(rm.configFile)
 
 	if err != nil {
-		return fmt.Errorf("error reading file %s, %v", rm.configFile, err)
+		return nil, nil, fmt.Errorf("error reading file %s, %v", rm.configFile, err)
 	}
 
-	glog.Infof("raw ResourceList: %s", rawBytes)
+	rm.log.V(4).Info("Raw resource list", "config", string(rawBytes))
 	if err = json.Unmarshal(rawBytes, resources); err != nil {
-		return fmt.Errorf("error unmarshalling raw bytes %v please make sure the config is in json format", err)
+		return nil, nil, fmt.Errorf("error unmarshalling raw bytes %v please make sure the config is in json format", err)
+	}
+	if err = validatePfConfigs(resources.SriovPfs); err != nil {
+		return nil, nil, err
+	}
+	if err = validateSfConfigs(resources.Subfunctions); err != nil {
+		return nil, nil, err
 	}
 
+	configList := make([]*types.ResourceConfig, 0, len(resources.ResourceList))
 	for i := range resources.ResourceList {
 		conf := &resources.ResourceList[i]
 		// Validate deviceType
 		if conf.DeviceType == "" {
 			conf.DeviceType = types.NetDeviceType // Default to NetDeviceType
 		} else if _, ok := types.SupportedDevices[conf.DeviceType]; !ok {
-			return fmt.Errorf("unsupported deviceType:  \"%s\"", conf.DeviceType)
+			return nil, nil, fmt.Errorf("unsupported deviceType:  \"%s\"", conf.DeviceType)
 		}
 		if conf.SelectorObjs, err = rm.rFactory.GetDeviceFilter(conf); err == nil {
-			rm.configList = append(rm.configList, &resources.ResourceList[i])
+			configList = append(configList, &resources.ResourceList[i])
 		} else {
-			glog.Warningf("unable to get SelectorObj from selectors list:'%s' for deviceType: %s error: %s",
-				*conf.Selectors, conf.DeviceType, err)
+			rm.log.Info("Unable to get selector objects, skipping resource", "resourceName", conf.ResourceName,
+				"deviceType", conf.DeviceType, "err", err)
 		}
 	}
-	glog.Infof("unmarshalled ResourceList: %+v", resources.ResourceList)
-	return nil
+	rm.log.V(2).Info("Parsed resource list", "resourceCount", len(configList), "pfCount", len(resources.SriovPfs),
+		"sfPfCount", len(resources.Subfunctions))
+	return configList, resources, nil
 }
 
 func (rm *resourceManager) initServers() error {
-	err := rm.cleanupCDISpecs()
+	err := rm.removeStaleCDISpecs(rm.configList)
 	if err != nil {
-		glog.Errorf("Unable to delete CDI specs: %v", err)
+		rm.log.Error(err, "Unable to delete stale CDI specs")
 		return err
 	}
-	rf := rm.rFactory
-	glog.Infof("number of config: %d\n", len(rm.configList))
+	rm.log.Info("Initializing resource servers", "resourceCount", len(rm.configList))
 	deviceAllocated := make(map[string]bool)
 	for _, rc := range rm.configList {
-		// Create new ResourcePool
-		glog.Infof("Creating new ResourcePool: %s", rc.ResourceName)
-		glog.Infof("DeviceType: %+v", rc.DeviceType)
-		dp, ok := rm.deviceProviders[rc.DeviceType]
-		if !ok {
-			glog.Infof("Unable to get device provider from deviceType: %s", rc.DeviceType)
-			return fmt.Errorf("error getting device provider")
-		}
-
-		filteredDevices := make([]types.HostDevice, 0)
-
-		for index := range rc.SelectorObjs {
-			devices := dp.GetDevices(rc, index)
-			partialFilteredDevices, err := dp.GetFilteredDevices(devices, rc, index)
-			if err != nil {
-				glog.Errorf("initServers(): error getting filtered devices for config %+v: %q", rc, err)
-			}
-			partialFilteredDevices = rm.excludeAllocatedDevices(partialFilteredDevices, deviceAllocated)
-			glog.Infof("initServers(): selector index %d will register %d devices", index, len(partialFilteredDevices))
-			filteredDevices = append(filteredDevices, partialFilteredDevices...)
+		filteredDevices, err := rm.getPoolDevices(rc, deviceAllocated)
+		if err != nil {
+			return err
 		}
 		if len(filteredDevices) < 1 {
-			glog.Infof("no devices in device pool, skipping creating resource server for %s", rc.ResourceName)
+			rm.log.Info("No devices in device pool, skipping creating resource server", "resourceName", rm.resourceKey(rc))
 			continue
 		}
-		rPool, err := rm.rFactory.GetResourcePool(rc, filteredDevices)
+		s, err := rm.newServer(rc, filteredDevices)
 		if err != nil {
-			glog.Errorf("initServers(): error creating ResourcePool with config %+v: %q", rc, err)
 			return err
 		}
-		// Create ResourceServer with this ResourcePool
-		s, err := rf.GetResourceServer(rPool)
+		rm.resourceServers = append(rm.resourceServers, s)
+		rm.trackServer(rc, s, filteredDevices)
+	}
+	if rm.tracker != nil {
+		rm.trackServedDevices()
+	}
+	rm.setNRIDevices(rm.servedDevices())
+	rm.deleteStaleVdpaDevices(rm.servers)
+	return nil
+}
+
+// getPoolDevices runs every selector object of a resource config against the devices of its DeviceProvider and
+// returns the devices that are not already claimed by a previous pool
+func (rm *resourceManager) getPoolDevices(rc *types.ResourceConfig, deviceAllocated map[string]bool) ([]types.HostDevice, error) {
+	log := rm.log.WithValues("resourceName", rm.resourceKey(rc))
+	log.Info("Selecting devices of resource pool", "deviceType", rc.DeviceType)
+	dp, ok := rm.deviceProviders[rc.DeviceType]
+	if !ok {
+		log.Info("Unable to get device provider", "deviceType", rc.DeviceType)
+		return nil, fmt.Errorf("error getting device provider")
+	}
+
+	filteredDevices := make([]types.HostDevice, 0)
+
+	for index := range rc.SelectorObjs {
+		devices := dp.GetDevices(rc, index)
+		partialFilteredDevices, err := dp.GetFilteredDevices(devices, rc, index)
 		if err != nil {
-			glog.Errorf("initServers(): error creating ResourceServer: %v", err)
+			log.Error(err, "Error getting filtered devices", "selectorIndex", index)
+		}
+		selectedDevices := rm.excludeAllocatedDevices(log, partialFilteredDevices, deviceAllocated)
+		if rm.observer != nil {
+			rm.observer(rc, index, devices, partialFilteredDevices, selectedDevices, err)
+			filteredDevices = append(filteredDevices, selectedDevices...)
+			continue
+		}
+		partialFilteredDevices = selectedDevices
+		if driver := bindDriverOf(rc.SelectorObjs[index]); driver != "" {
+			partialFilteredDevices = rm.bindDevices(log, dp, rc, index, driver, partialFilteredDevices)
+		}
+		if vdpaType := createVdpaOf(rc.SelectorObjs[index]); vdpaType != "" {
+			partialFilteredDevices = rm.createVdpaDevices(log, dp, rc, index, vdpaType, partialFilteredDevices)
+		}
+		log.Info("Selected devices", "selectorIndex", index, "deviceCount", len(partialFilteredDevices))
+		filteredDevices = append(filteredDevices, partialFilteredDevices...)
+	}
+	return filteredDevices, nil
+}
+
+// newServer creates a ResourcePool from the given devices and a ResourceServer serving it
+func (rm *resourceManager) newServer(rc *types.ResourceConfig, filteredDevices []types.HostDevice) (types.ResourceServer, error) {
+	log := rm.log.WithValues("resourceName", rm.resourceKey(rc))
+	rPool, err := rm.rFactory.GetResourcePool(rc, filteredDevices)
+	if err != nil {
+		log.Error(err, "Error creating ResourcePool")
+		return nil, err
+	}
+	// Create ResourceServer with this ResourcePool
+	s, err := rm.rFactory.GetResourceServer(rPool)
+	if err != nil {
+		log.Error(err, "Error creating ResourceServer")
+		return nil, err
+	}
+	log.Info("New resource server is created")
+	return s, nil
+}
+
+// trackServer records which config and devices a resource server was created from
+func (rm *resourceManager) trackServer(rc *types.ResourceConfig, s types.ResourceServer, devices []types.HostDevice) {
+	if rm.servers == nil {
+		rm.servers = make(map[string]*managedServer)
+	}
+	rm.servers[rm.resourceKey(rc)] = &managedServer{
+		server:    s,
+		config:    rc,
+		deviceIDs: getDeviceIDs(devices),
+		devices:   devices,
+	}
+}
+
+// reloadConfig re-reads the Config file and reconciles the running resource servers with it
+func (rm *resourceManager) reloadConfig() error {
+	// reset before parsing, so that the patterns of the new config compiled when validating it are kept
+	resources.ResetPatternCache()
+	configList, conf, err := rm.parseConfig()
+	if err != nil {
+		return err
+	}
+	if !rm.validateConfigs(configList) {
+		return fmt.Errorf("invalid configuration, keeping the current resource servers")
+	}
+	pfsChanged := rm.configurePfs(conf.SriovPfs)
+	sfsChanged := rm.configureSfs(conf.Subfunctions)
+	if pfsChanged || sfsChanged {
+		if err := rm.discoverHostDevices(); err != nil {
 			return err
 		}
-		glog.Infof("New resource server is created for %s ResourcePool", rc.ResourceName)
-		rm.resourceServers = append(rm.resourceServers, s)
 	}
+	rm.pfConfigs = conf.SriovPfs
+	rm.sfConfigs = conf.Subfunctions
+	if err := rm.removeStaleCDISpecs(configList); err != nil {
+		rm.log.Error(err, "Unable to delete stale CDI specs")
+	}
+	if rm.draDriver != nil {
+		return rm.syncDRAPools(configList)
+	}
+	rm.syncServers(configList)
 	return nil
 }
 
-func (rm *resourceManager) excludeAllocatedDevices(filteredDevices []types.HostDevice, deviceAllocated map[string]bool) []types.HostDevice {
+// rediscover re-runs host device discovery and updates the running resource servers with the devices found
+func (rm *resourceManager) rediscover() error {
+	if err := rm.discoverHostDevices(); err != nil {
+		return err
+	}
+	if rm.draDriver != nil {
+		return rm.syncDRAPools(rm.configList)
+	}
+	rm.syncServers(rm.configList)
+	return nil
+}
+
+// initDRADriver creates the resource pools and starts a DRA driver publishing them instead of resource servers
+func (rm *resourceManager) initDRADriver() error {
+	if err := rm.removeStaleCDISpecs(rm.configList); err != nil {
+		rm.log.Error(err, "Unable to delete stale CDI specs")
+		return err
+	}
+	client, err := dra.NewKubeClient(rm.kubeConfig)
+	if err != nil {
+		return err
+	}
+	pools, err := rm.getDRAPools(rm.configList)
+	if err != nil {
+		return err
+	}
+	driver := dra.NewDriver(rm.draDriverName, rm.nodeName, rm.resourcePrefix, client)
+	if err := driver.Start(pools); err != nil {
+		return err
+	}
+	rm.draDriver = driver
+	rm.draPools = rm.managedPools(pools)
+	rm.setNRIDevices(poolDevices(pools))
+	rm.deleteStaleVdpaDevices(rm.draPools)
+	return nil
+}
+
+// syncDRAPools rebuilds the resource pools from the given configs and the discovered devices and
+// publishes them through the DRA driver
+func (rm *resourceManager) syncDRAPools(configList []*types.ResourceConfig) error {
+	pools, err := rm.getDRAPools(configList)
+	if err != nil {
+		return err
+	}
+	current := rm.managedPools(pools)
+	rm.deleteCreatedVdpaDevices(rm.draPools, current)
+	rm.configList = configList
+	rm.draPools = current
+	rm.setNRIDevices(poolDevices(pools))
+	return rm.draDriver.UpdatePools(pools)
+}
+
+// managedPools returns the given DRA resource pools as managed servers without server, keyed by fully qualified
+// resource name, so that they are reconciled the way resource servers are
+func (rm *resourceManager) managedPools(pools []types.ResourcePool) map[string]*managedServer {
+	managed := make(map[string]*managedServer, len(pools))
+	for _, pool := range pools {
+		devices := pool.GetDevicePool()
+		deviceIDs := make([]string, 0, len(devices))
+		for id := range devices {
+			deviceIDs = append(deviceIDs, id)
+		}
+		managed[rm.resourceKey(pool.GetConfig())] = &managedServer{config: pool.GetConfig(), deviceIDs: deviceIDs}
+	}
+	return managed
+}
+
+// getDRAPools returns a ResourcePool for every config selecting at least one device
+func (rm *resourceManager) getDRAPools(configList []*types.ResourceConfig) ([]types.ResourcePool, error) {
+	pools := make([]types.ResourcePool, 0, len(configList))
+	deviceAllocated := make(map[string]bool)
+	for _, rc := range configList {
+		filteredDevices, err := rm.getPoolDevices(rc, deviceAllocated)
+		if err != nil {
+			return nil, err
+		}
+		if len(filteredDevices) < 1 {
+			rm.log.Info("No devices in device pool, skipping creating DRA pool", "resourceName", rm.resourceKey(rc))
+			continue
+		}
+		rPool, err := rm.rFactory.GetResourcePool(rc, filteredDevices)
+		if err != nil {
+			rm.log.Error(err, "Error creating ResourcePool", "resourceName", rm.resourceKey(rc))
+			return nil, err
+		}
+		pools = append(pools, rPool)
+	}
+	return pools, nil
+}
+
+// syncServers reconciles the running resource servers with the given configs and the discovered devices.
+// Servers are only replaced when their config changed and get their devices updated in place when only
+// their set of selected devices changed. Servers for removed resources are stopped and servers for new
+// resources are started. All other servers keep running untouched.
+func (rm *resourceManager) syncServers(configList []*types.ResourceConfig) {
+	servers := make(map[string]*managedServer)
+	resourceServers := make([]types.ResourceServer, 0, len(configList))
+	created := make([]types.ResourceServer, 0)
+	deviceAllocated := make(map[string]bool)
+	for _, rc := range configList {
+		key := rm.resourceKey(rc)
+		log := rm.log.WithValues("resourceName", key)
+		filteredDevices, err := rm.getPoolDevices(rc, deviceAllocated)
+		if err != nil {
+			log.Error(err, "Unable to get devices")
+			continue
+		}
+		deviceIDs := getDeviceIDs(filteredDevices)
+		if old, ok := rm.servers[key]; ok && sameConfig(old.config, rc) {
+			if sameDeviceIDs(old.deviceIDs, deviceIDs) {
+				log.Info("Resource is unchanged")
+				servers[key] = old
+			} else {
+				log.Info("Updating devices of resource", "deviceIDs", deviceIDs)
+				rm.warnRemovedAssignedDevices(key, old.deviceIDs, deviceIDs)
+				old.server.UpdateDevices(filteredDevices)
+				servers[key] = &managedServer{server: old.server, config: rc, deviceIDs: deviceIDs, devices: filteredDevices}
+			}
+			resourceServers = append(resourceServers, old.server)
+			continue
+		}
+		if len(filteredDevices) < 1 {
+			log.Info("No devices in device pool, skipping creating resource server")
+			continue
+		}
+		s, err := rm.newServer(rc, filteredDevices)
+		if err != nil {
+			log.Error(err, "Unable to create resource server")
+			continue
+		}
+		log.Info("Resource is added or changed")
+		servers[key] = &managedServer{server: s, config: rc, deviceIDs: deviceIDs, devices: filteredDevices}
+		resourceServers = append(resourceServers, s)
+		created = append(created, s)
+	}
+
+	// Stop removed and replaced servers first as the replacements reuse their socket paths
+	for key, old := range rm.servers {
+		if cur, ok := servers[key]; ok && cur.server == old.server {
+			continue
+		}
+		rm.log.Info("Stopping resource server", "resourceName", key)
+		if err := old.server.Stop(); err != nil {
+			rm.log.Error(err, "Error stopping resource server", "resourceName", key)
+		}
+		if _, ok := servers[key]; !ok {
+			metrics.DeleteResource(key)
+		}
+	}
+
+	for _, s := range created {
+		if err := rm.startServer(s); err != nil {
+			rm.log.Error(err, "Error starting resource server")
+		}
+	}
+
+	rm.deleteCreatedVdpaDevices(rm.servers, servers)
+	rm.configList = configList
+	rm.servers = servers
+	rm.resourceServers = resourceServers
+	if rm.tracker != nil {
+		rm.trackServedDevices()
+	}
+	rm.setNRIDevices(rm.servedDevices())
+}
+
+// warnRemovedAssignedDevices logs the devices removed from a resource while still assigned to a container
+func (rm *resourceManager) warnRemovedAssignedDevices(resourceName string, oldIDs, newIDs []string) {
+	if rm.tracker == nil {
+		return
+	}
+	kept := make(map[string]bool, len(newIDs))
+	for _, id := range newIDs {
+		kept[id] = true
+	}
+	for _, id := range oldIDs {
+		if a, ok := rm.tracker.GetDeviceAssignment(resourceName, id); ok && !kept[id] {
+			rm.log.Info("Device removed from resource is still assigned to a container", "resourceName", resourceName,
+				"deviceID", id, "pod", a.Namespace+"/"+a.Pod, "container", a.Container)
+		}
+	}
+}
+
+// resourceKey returns the fully qualified resource name of a resource config
+func (rm *resourceManager) resourceKey(rc *types.ResourceConfig) string {
+	// resourcePrefix might be overridden for a given resource pool
+	resourcePrefix := rm.cliParams.resourcePrefix
+	if rc.ResourcePrefix != "" {
+		resourcePrefix = rc.ResourcePrefix
+	}
+	return resourcePrefix + "/" + rc.ResourceName
+}
+
+// sameConfig returns true if both resource configs describe the same resource pool
+func sameConfig(a, b *types.ResourceConfig) bool {
+	// SelectorObjs are derived from Selectors; compare the serialized user-facing fields only
+	ac, bc := *a, *b
+	ac.SelectorObjs, bc.SelectorObjs = nil, nil
+	aBytes, errA := json.Marshal(ac)
+	bBytes, errB := json.Marshal(bc)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return bytes.Equal(aBytes, bBytes)
+}
+
+// sameDeviceIDs returns true if both lists contain the same device IDs in the same order
+func sameDeviceIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func getDeviceIDs(devices []types.HostDevice) []string {
+	ids := make([]string, 0, len(devices))
+	for _, dev := range devices {
+		ids = append(ids, dev.GetDeviceID())
+	}
+	return ids
+}
+
+// servedDeviceIDs returns the IDs of the devices served by the given resource servers
+func servedDeviceIDs(servers map[string]*managedServer) map[string]bool {
+	ids := make(map[string]bool)
+	for _, s := range servers {
+		for _, id := range s.deviceIDs {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+func (rm *resourceManager) excludeAllocatedDevices(log klog.Logger, filteredDevices []types.HostDevice,
+	deviceAllocated map[string]bool) []types.HostDevice {
 	filteredDevicesTemp := []types.HostDevice{}
 	for _, dev := range filteredDevices {
 		if !deviceAllocated[dev.GetDeviceID()] {
 			deviceAllocated[dev.GetDeviceID()] = true
 			filteredDevicesTemp = append(filteredDevicesTemp, dev)
 		} else {
-			glog.Warningf("Cannot add device [%s]. Already allocated.", dev.GetDeviceID())
+			log.Info("Cannot add device, already allocated to a previous resource pool", "deviceID", dev.GetDeviceID())
 		}
 	}
 	return filteredDevicesTemp
@@ -174,19 +587,29 @@ func (rm *resourceManager) excludeAllocatedDevices(filteredDevices []types.HostD
 
 func (rm *resourceManager) startAllServers() error {
 	for _, rs := range rm.resourceServers {
-		if err := rs.Start(); err != nil {
+		if err := rm.startServer(rs); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		// start watcher
-		if !rm.pluginWatchMode {
-			go rs.Watch()
-		}
+func (rm *resourceManager) startServer(rs types.ResourceServer) error {
+	if err := rs.Start(); err != nil {
+		return err
+	}
+
+	// start watcher
+	if !rm.pluginWatchMode {
+		go rs.Watch()
 	}
 	return nil
 }
 
 func (rm *resourceManager) stopAllServers() error {
+	if rm.draDriver != nil {
+		rm.draDriver.Stop()
+	}
 	for _, rs := range rm.resourceServers {
 		if err := rs.Stop(); err != nil {
 			return err
@@ -197,35 +620,34 @@ func (rm *resourceManager) stopAllServers() error {
 
 // Validate configurations
 func (rm *resourceManager) validConfigs() bool {
+	return rm.validateConfigs(rm.configList)
+}
+
+// validateConfigs validates a list of resource configurations
+func (rm *resourceManager) validateConfigs(configList []*types.ResourceConfig) bool {
 	resourceNames := make(map[string]string) // resource names placeholder
 
-	for _, conf := range rm.configList {
+	for _, conf := range configList {
 		// check if name contains acceptable characters
 		if !utils.ValidResourceName(conf.ResourceName) {
-			glog.Errorf("resource name \"%s\" contains invalid characters", conf.ResourceName)
+			rm.log.Error(nil, "Resource name contains invalid characters", "resourceName", conf.ResourceName)
 			return false
 		}
 
-		// resourcePrefix might be overridden for a given resource pool
-		resourcePrefix := rm.cliParams.resourcePrefix
-		if conf.ResourcePrefix != "" {
-			resourcePrefix = conf.ResourcePrefix
-		}
-
-		resourceName := resourcePrefix + "/" + conf.ResourceName
+		resourceName := rm.resourceKey(conf)
 
-		glog.Infof("validating resource name \"%s\"", resourceName)
+		rm.log.V(2).Info("Validating resource name", "resourceName", resourceName)
 
 		// ensure that resource name is unique
 		if _, exists := resourceNames[resourceName]; exists {
 			// resource name already exist
-			glog.Errorf("resource name \"%s\" already exists", resourceName)
+			rm.log.Error(nil, "Resource name already exists", "resourceName", resourceName)
 			return false
 		}
 
 		// Check if the DeviceType is valid
 		if _, ok := types.SupportedDevices[conf.DeviceType]; !ok {
-			glog.Errorf("unsupported deviceType:  \"%s\" already exists", conf.DeviceType)
+			rm.log.Error(nil, "Unsupported deviceType", "resourceName", resourceName, "deviceType", conf.DeviceType)
 			return false
 		}
 
@@ -234,12 +656,86 @@ func (rm *resourceManager) validConfigs() bool {
 			return false
 		}
 
+		// Check if the AllocationPolicy is valid
+		if _, err := rm.rFactory.GetAllocator(conf.AllocationPolicy); err != nil {
+			rm.log.Error(err, "Invalid allocation policy", "resourceName", resourceName)
+			return false
+		}
+
+		// Check if the VFIO mode is valid and the host provides the iommufd device VFIO cdevs need
+		switch conf.VfioMode {
+		case "", types.VfioModeGroup, types.VfioModeAuto:
+		case types.VfioModeCdev:
+			if !infoprovider.IommufdDeviceExist() {
+				rm.log.Error(nil, "VFIO cdev mode requires the iommufd device, is the iommufd module loaded?",
+					"resourceName", resourceName, "iommufd", infoprovider.HostIommufd)
+				return false
+			}
+		case types.VfioModeBoth:
+			if !infoprovider.IommufdDeviceExist() {
+				rm.log.Info("iommufd device not found, only the VFIO group devices are usable, is the iommufd module loaded?",
+					"resourceName", resourceName, "iommufd", infoprovider.HostIommufd)
+			}
+		default:
+			rm.log.Error(nil, "Invalid VFIO mode", "resourceName", resourceName, "vfioMode", conf.VfioMode)
+			return false
+		}
+
+		// Check if the CDI hooks are valid
+		if err := cdiPkg.ValidateHooks(conf.CDIHooks); err != nil {
+			rm.log.Error(err, "Invalid CDI hooks", "resourceName", resourceName)
+			return false
+		}
+
+		// Check if the RDMA configuration is valid and matches the host RDMA subsystem
+		if !rm.validRdmaConfig(resourceName, conf.Rdma) {
+			return false
+		}
+
 		resourceNames[resourceName] = resourceName
 	}
 
 	return true
 }
 
+// validRdmaConfig validates the RDMA configuration of a resource. A network namespace mode not matching the mode
+// of the host RDMA subsystem is only reported, unless the configuration is strict.
+func (rm *resourceManager) validRdmaConfig(resourceName string, rc *types.RdmaConfig) bool {
+	if rc == nil {
+		return true
+	}
+	if rc.NetnsMode != "" && rc.NetnsMode != types.RdmaNetnsModeShared && rc.NetnsMode != types.RdmaNetnsModeExclusive {
+		rm.log.Error(nil, "Invalid RDMA netns mode", "resourceName", resourceName, "netnsMode", rc.NetnsMode)
+		return false
+	}
+	for _, charDevice := range rc.CharDevices {
+		if !slices.Contains(types.RdmaOptionalCharDevices, charDevice) {
+			rm.log.Error(nil, "Invalid RDMA char device", "resourceName", resourceName, "charDevice", charDevice,
+				"supported", types.RdmaOptionalCharDevices)
+			return false
+		}
+	}
+	if rc.NetnsMode == "" {
+		return true
+	}
+
+	mode, err := utils.GetNetlinkProvider().GetRdmaNetnsMode()
+	if err != nil {
+		rm.log.Error(err, "Unable to get the RDMA netns mode of the host", "resourceName", resourceName)
+		return !rc.Strict
+	}
+	if mode != rc.NetnsMode {
+		if rc.Strict {
+			rm.log.Error(nil, "RDMA netns mode of the host does not match the resource configuration",
+				"resourceName", resourceName, "hostNetnsMode", mode, "netnsMode", rc.NetnsMode)
+			return false
+		}
+		rm.log.Info("RDMA netns mode of the host does not match the resource configuration",
+			"resourceName", resourceName, "hostNetnsMode", mode, "netnsMode", rc.NetnsMode)
+	}
+	return true
+}
+
 func (rm *resourceManager) discoverHostDevices() error {
 	pci, err := ghw.PCI()
 	if err != nil {
@@ -247,24 +743,190 @@ func (rm *resourceManager) discoverHostDevices() error {
 	}
 
 	if len(pci.Devices) == 0 {
-		glog.Warningf("discoverHostDevices(): no PCI network device found")
+		rm.log.Info("No PCI device found")
 	}
 
 	for k, v := range types.SupportedDevices {
 		if dp, ok := rm.deviceProviders[k]; ok {
+			dp.ResetDevices()
 			if err := dp.AddTargetDevices(pci.Devices, v); err != nil {
-				glog.Errorf("adding supported device identifier '%d' to device provider failed: %s", v, err.Error())
+				rm.log.Error(err, "Adding supported devices to device provider failed", "deviceType", k)
+			}
+			metrics.SetDiscoveredDevices(string(k), len(dp.GetDiscoveredDevices()))
+		}
+	}
+	return nil
+}
+
+// reconcileAllocations replaces the checkpointed allocations with the devices the kubelet reports as assigned
+// to containers and removes the device info files of the devices no longer in use. The checkpoint is left
+// untouched when the PodResources API is unavailable.
+func (rm *resourceManager) reconcileAllocations() error {
+	if rm.checkpoint == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), podResourcesTimeout)
+	defer cancel()
+	assignments, err := rm.podResources.GetDeviceAssignments(ctx)
+	if err != nil {
+		return fmt.Errorf("keeping the allocation checkpoint as it is: %v", err)
+	}
+	return rm.pruneAllocations(assignments, false)
+}
+
+// syncAllocations is called by the device assignment tracker after every successful listing to prune the
+// devices freed at runtime from the checkpoint
+func (rm *resourceManager) syncAllocations(assignments map[string]map[string]types.DeviceAssignment) {
+	if rm.checkpoint == nil {
+		return
+	}
+	if err := rm.pruneAllocations(assignments, true); err != nil {
+		rm.log.Error(err, "Unable to prune the allocation checkpoint")
+	}
+}
+
+// pruneAllocations replaces the checkpointed allocations with the given device assignments and removes the
+// device info files of the devices no longer in use. When deferred is set, a device is only removed once it was
+// found unassigned by the previous call too, as the kubelet reports a device a moment after allocating it. Only
+// the devices of the configured resources can just have been allocated.
+func (rm *resourceManager) pruneAllocations(assignments map[string]map[string]types.DeviceAssignment,
+	deferred bool) error {
+	// only keep the resources of this plugin, including the ones removed from the config but still in use
+	configured := make(map[string]bool)
+	for _, rc := range rm.configList {
+		configured[rm.resourceKey(rc)] = true
+	}
+	inUse := make(map[string][]string)
+	for resourceName, devices := range assignments {
+		if configured[resourceName] || len(rm.checkpoint.GetDevices(resourceName)) > 0 {
+			for id := range devices {
+				inUse[resourceName] = append(inUse[resourceName], id)
+			}
+		}
+	}
+
+	if deferred {
+		unassigned := make(map[string]map[string]bool)
+		for resourceName := range configured {
+			for _, id := range rm.checkpoint.GetDevices(resourceName) {
+				if _, ok := assignments[resourceName][id]; ok || rm.unassigned[resourceName][id] {
+					continue
+				}
+				if unassigned[resourceName] == nil {
+					unassigned[resourceName] = make(map[string]bool)
+				}
+				unassigned[resourceName][id] = true
+				inUse[resourceName] = append(inUse[resourceName], id)
+			}
+		}
+		rm.unassigned = unassigned
+	}
+
+	stale, err := rm.checkpoint.Reconcile(inUse)
+	if err != nil {
+		return err
+	}
+	nadUtils := rm.rFactory.GetNadUtils()
+	for resourceName, deviceIDs := range stale {
+		rm.log.Info("Checkpointed devices are no longer in use", "resourceName", resourceName, "deviceIDs", deviceIDs)
+		for _, id := range deviceIDs {
+			if err := nadUtils.CleanDeviceInfoFile(resourceName, id); err != nil {
+				rm.log.Error(err, "Unable to clean device info file", "resourceName", resourceName, "deviceID", id)
 			}
 		}
 	}
 	return nil
 }
 
-func (rm *resourceManager) cleanupCDISpecs() error {
-	if rm.cliParams.useCdi {
-		if err := rm.cdi.CleanupSpecs(); err != nil {
-			return fmt.Errorf("unable to delete CDI specs: %v", err)
+// assignedDevices queries the PodResources API for the devices assigned to containers, through any device plugin
+// resource or, in DRA mode, through resource claims of the DRA driver, and returns a function telling whether a
+// device is one of them. Unlike deviceInUse, it does not rely on the device assignment tracker or the allocation
+// checkpoint, which are not up to date before the resource servers are started.
+func (rm *resourceManager) assignedDevices() (func(deviceID string) bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), podResourcesTimeout)
+	defer cancel()
+	assignments, err := rm.podResources.GetDeviceAssignments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	assigned := make(map[string]bool)
+	for _, devices := range assignments {
+		for id := range devices {
+			assigned[id] = true
+		}
+	}
+	claimed := make(map[string]bool)
+	if rm.draMode {
+		names, err := rm.podResources.GetClaimDevices(ctx, rm.draDriverName)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			claimed[name] = true
 		}
 	}
+	return func(deviceID string) bool {
+		return assigned[deviceID] || claimed[dra.DeviceName(deviceID)]
+	}, nil
+}
+
+// setDeviceInfoAssignment adds the container a device got assigned to to its device info file
+func (rm *resourceManager) setDeviceInfoAssignment(resourceName, deviceID string, assignment types.DeviceAssignment) {
+	if err := rm.rFactory.GetNadUtils().SetDeviceInfoAssignment(resourceName, deviceID, &assignment); err != nil {
+		rm.log.Error(err, "Unable to add the assignment of a device to its device info file", "resourceName", resourceName,
+			"deviceID", deviceID)
+	}
+}
+
+// trackServedDevices makes the device assignment tracker follow the devices of the running resource servers
+func (rm *resourceManager) trackServedDevices() {
+	pools := make(map[string][]string, len(rm.servers))
+	for key, ms := range rm.servers {
+		pools[key] = ms.deviceIDs
+	}
+	rm.tracker.SetPools(pools)
+}
+
+// removeStaleCDISpecs removes the CDI specs of resource pools which are not in configList.
+// Specs of configured pools are kept, so that containers can still be started with them while the servers restart
+func (rm *resourceManager) removeStaleCDISpecs(configList []*types.ResourceConfig) error {
+	if !rm.cliParams.useCdi {
+		return nil
+	}
+	resourceNames := make(map[string]bool, len(configList))
+	for _, rc := range configList {
+		resourceNames[rm.resourceKey(rc)] = true
+	}
+	if err := rm.cdi.RemoveStaleSpecs(resourceNames); err != nil {
+		return fmt.Errorf("unable to delete stale CDI specs: %v", err)
+	}
 	return nil
 }
+
+// servedDevices returns the devices of the running resource servers
+func (rm *resourceManager) servedDevices() []types.HostDevice {
+	devices := make([]types.HostDevice, 0)
+	for _, ms := range rm.servers {
+		devices = append(devices, ms.devices...)
+	}
+	return devices
+}
+
+// poolDevices returns the devices of the given resource pools
+func poolDevices(pools []types.ResourcePool) []types.HostDevice {
+	devices := make([]types.HostDevice, 0)
+	for _, pool := range pools {
+		for _, dev := range pool.GetDevicePool() {
+			devices = append(devices, dev)
+		}
+	}
+	return devices
+}
+
+// setNRIDevices makes the NRI plugin move the given devices into the network namespace of the pods they are
+// allocated to
+func (rm *resourceManager) setNRIDevices(devices []types.HostDevice) {
+	if rm.nriPlugin != nil {
+		rm.nriPlugin.SetDevices(devices)
+	}
+}
@@ -17,6 +17,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -24,11 +25,14 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	CDImocks "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/cdi/mocks"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/checkpoint"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/factory"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/infoprovider"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/netdevice"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+	utilmocks "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils/mocks"
 )
 
 func TestSriovdp(t *testing.T) {
@@ -299,6 +303,271 @@ var _ = Describe("Resource manager", func() {
 				Expect(rm.validConfigs()).To(BeFalse())
 			})
 		})
+		Context("when vdpaType is configured in an anyOf group", func() {
+			BeforeEach(func() {
+				err := os.MkdirAll("/tmp/sriovdp", 0755)
+				if err != nil {
+					panic(err)
+				}
+				err = os.WriteFile("/tmp/sriovdp/test_config", []byte(`{
+					"resourceList":	[{
+						"resourceName": "wrong_config",
+						"selectors": {
+							"vendors": ["15b3"],
+							"anyOf": [{"pfNames": ["ens1f0"]}, {"vdpaType": "virtio"}]
+						}
+					}]
+				}`), 0644)
+				if err != nil {
+					panic(err)
+				}
+				_ = rm.readConfig()
+			})
+			It("should return false", func() {
+				defer fs.Use()()
+				Expect(rm.validConfigs()).To(BeFalse())
+			})
+		})
+		Context("when not and anyOf groups are configured", func() {
+			BeforeEach(func() {
+				err := os.MkdirAll("/tmp/sriovdp", 0755)
+				if err != nil {
+					panic(err)
+				}
+				err = os.WriteFile("/tmp/sriovdp/test_config", []byte(`{
+					"resourceList":	[{
+						"resourceName": "mlx_vfs",
+						"selectors": {
+							"vendors": ["15b3"],
+							"not": {"pfNames": ["ens1f1"]},
+							"anyOf": [{"drivers": ["mlx5_core"]}, {"not": {"drivers": ["vfio-pci"]}}]
+						}
+					}]
+				}`), 0644)
+				if err != nil {
+					panic(err)
+				}
+				Expect(rm.readConfig()).To(Succeed())
+			})
+			It("should parse the nested groups and return true", func() {
+				defer fs.Use()()
+				nf := rm.configList[0].SelectorObjs[0].(*types.NetDeviceSelectors)
+				Expect(nf.Not.PfNames).To(Equal([]string{"ens1f1"}))
+				Expect(nf.AnyOf).To(HaveLen(2))
+				Expect(nf.AnyOf[1].Not.Drivers).To(Equal([]string{"vfio-pci"}))
+				Expect(rm.validConfigs()).To(BeTrue())
+			})
+		})
+		DescribeTable("when a nested group sets no selector",
+			func(groups string) {
+				err := os.MkdirAll("/tmp/sriovdp", 0755)
+				if err != nil {
+					panic(err)
+				}
+				err = os.WriteFile("/tmp/sriovdp/test_config", []byte(`{
+					"resourceList":	[{
+						"resourceName": "wrong_config",
+						"selectors": {
+							"vendors": ["15b3"],
+							`+groups+`
+						}
+					}]
+				}`), 0644)
+				if err != nil {
+					panic(err)
+				}
+				Expect(rm.readConfig()).To(Succeed())
+				defer fs.Use()()
+				Expect(rm.validConfigs()).To(BeFalse())
+			},
+			Entry("empty not group", `"not": {}`),
+			Entry("empty anyOf group", `"anyOf": [{"drivers": ["mlx5_core"]}, {}]`),
+			Entry("anyOf group with empty selectors", `"anyOf": [{"drivers": []}]`),
+			Entry("empty nested not group", `"allOf": [{"not": {}}]`),
+		)
+		Context("when minLinkSpeed is greater than maxLinkSpeed", func() {
+			BeforeEach(func() {
+				err := os.MkdirAll("/tmp/sriovdp", 0755)
+				if err != nil {
+					panic(err)
+				}
+				err = os.WriteFile("/tmp/sriovdp/test_config", []byte(`{
+					"resourceList":	[{
+						"resourceName": "wrong_config",
+						"selectors": {
+							"vendors": ["8086"],
+							"minLinkSpeed": 100000,
+							"maxLinkSpeed": 25000
+						}
+					}]
+				}`), 0644)
+				if err != nil {
+					panic(err)
+				}
+				_ = rm.readConfig()
+			})
+			It("should return false", func() {
+				defer fs.Use()()
+				Expect(rm.validConfigs()).To(BeFalse())
+			})
+		})
+		Context("when minLinkSpeed is greater than maxLinkSpeed in a nested group", func() {
+			BeforeEach(func() {
+				err := os.MkdirAll("/tmp/sriovdp", 0755)
+				if err != nil {
+					panic(err)
+				}
+				err = os.WriteFile("/tmp/sriovdp/test_config", []byte(`{
+					"resourceList":	[{
+						"resourceName": "wrong_config",
+						"selectors": {
+							"vendors": ["8086"],
+							"anyOf": [{"pfNames": ["ens1f0"]}, {"minLinkSpeed": 100000, "maxLinkSpeed": 25000}]
+						}
+					}]
+				}`), 0644)
+				if err != nil {
+					panic(err)
+				}
+				_ = rm.readConfig()
+			})
+			It("should return false", func() {
+				defer fs.Use()()
+				Expect(rm.validConfigs()).To(BeFalse())
+			})
+		})
+		Context("when bindDriver and drivers are both configured", func() {
+			BeforeEach(func() {
+				err := os.MkdirAll("/tmp/sriovdp", 0755)
+				if err != nil {
+					panic(err)
+				}
+				err = os.WriteFile("/tmp/sriovdp/test_config", []byte(`{
+					"resourceList":	[{
+						"resourceName": "wrong_config",
+						"selectors": {
+							"vendors": ["8086"],
+							"drivers": ["iavf"],
+							"bindDriver": "vfio-pci"
+						}
+					}]
+				}`), 0644)
+				if err != nil {
+					panic(err)
+				}
+				_ = rm.readConfig()
+			})
+			It("should return false", func() {
+				defer fs.Use()()
+				Expect(rm.validConfigs()).To(BeFalse())
+			})
+		})
+		Context("when createVdpa is configured without vdpaType", func() {
+			BeforeEach(func() {
+				err := os.MkdirAll("/tmp/sriovdp", 0755)
+				if err != nil {
+					panic(err)
+				}
+				err = os.WriteFile("/tmp/sriovdp/test_config", []byte(`{
+					"resourceList":	[{
+						"resourceName": "wrong_config",
+						"selectors": {
+							"vendors": ["15b3"],
+							"createVdpa": true
+						}
+					}]
+				}`), 0644)
+				if err != nil {
+					panic(err)
+				}
+				_ = rm.readConfig()
+			})
+			It("should return false", func() {
+				defer fs.Use()()
+				Expect(rm.validConfigs()).To(BeFalse())
+			})
+		})
+		Context("when a CDI hook has an unknown hook name", func() {
+			BeforeEach(func() {
+				err := os.MkdirAll("/tmp/sriovdp", 0755)
+				if err != nil {
+					panic(err)
+				}
+				err = os.WriteFile("/tmp/sriovdp/test_config", []byte(`{
+					"resourceList":	[{
+						"resourceName": "wrong_config",
+						"selectors": {
+							"vendors": ["15b3"]
+						},
+						"cdiHooks": [{"hookName": "prerun", "path": "/usr/bin/hook"}]
+					}]
+				}`), 0644)
+				if err != nil {
+					panic(err)
+				}
+				_ = rm.readConfig()
+			})
+			It("should return false", func() {
+				defer fs.Use()()
+				Expect(rm.validConfigs()).To(BeFalse())
+			})
+		})
+		Context("when vfioMode is unknown", func() {
+			BeforeEach(func() {
+				err := os.MkdirAll("/tmp/sriovdp", 0755)
+				if err != nil {
+					panic(err)
+				}
+				err = os.WriteFile("/tmp/sriovdp/test_config", []byte(`{
+					"resourceList":	[{
+						"resourceName": "wrong_config",
+						"selectors": {
+							"drivers": ["vfio-pci"]
+						},
+						"vfioMode": "iommufd"
+					}]
+				}`), 0644)
+				if err != nil {
+					panic(err)
+				}
+				_ = rm.readConfig()
+			})
+			It("should return false", func() {
+				defer fs.Use()()
+				Expect(rm.validConfigs()).To(BeFalse())
+			})
+		})
+		Context("when vfioMode is cdev and the iommufd device does not exist", func() {
+			var origIommufd string
+			BeforeEach(func() {
+				origIommufd = infoprovider.HostIommufd
+				infoprovider.HostIommufd = "/tmp/sriovdp/nonexistent-iommu"
+				err := os.MkdirAll("/tmp/sriovdp", 0755)
+				if err != nil {
+					panic(err)
+				}
+				err = os.WriteFile("/tmp/sriovdp/test_config", []byte(`{
+					"resourceList":	[{
+						"resourceName": "cdev_config",
+						"selectors": {
+							"drivers": ["vfio-pci"]
+						},
+						"vfioMode": "cdev"
+					}]
+				}`), 0644)
+				if err != nil {
+					panic(err)
+				}
+				_ = rm.readConfig()
+			})
+			AfterEach(func() {
+				infoprovider.HostIommufd = origIommufd
+			})
+			It("should return false", func() {
+				defer fs.Use()()
+				Expect(rm.validConfigs()).To(BeFalse())
+			})
+		})
 		Context("when isRdma and vdpaType are configured in separate selectors", func() {
 			BeforeEach(func() {
 				err := os.MkdirAll("/tmp/sriovdp", 0755)
@@ -433,6 +702,154 @@ var _ = Describe("Resource manager", func() {
 			})
 		})
 	})
+	Describe("reloading config", func() {
+		var (
+			dp       *mocks.DeviceProvider
+			rf       *mocks.ResourceFactory
+			serverA  *mocks.ResourceServer
+			serverB  *mocks.ResourceServer
+			newSrv   *mocks.ResourceServer
+			devA     *mocks.PciDevice
+			devB     *mocks.PciDevice
+			poolDevs map[string][]types.HostDevice
+		)
+		const (
+			configAB = `{"resourceList": [
+				{"resourceName": "pool_a", "selectors": [{"drivers": ["iavf"]}]},
+				{"resourceName": "pool_b", "selectors": [{"drivers": ["vfio-pci"]}]}
+			]}`
+		)
+		writeConfig := func(config string) {
+			err := os.MkdirAll("/tmp/sriovdp", 0755)
+			if err != nil {
+				panic(err)
+			}
+			err = os.WriteFile("/tmp/sriovdp/test_config", []byte(config), 0644)
+			if err != nil {
+				panic(err)
+			}
+		}
+		byName := func(name string) interface{} {
+			return mock.MatchedBy(func(rc *types.ResourceConfig) bool { return rc.ResourceName == name })
+		}
+		BeforeEach(func() {
+			devA = &mocks.PciDevice{}
+			devA.On("GetDeviceID").Return("0000:01:10.0")
+			devB = &mocks.PciDevice{}
+			devB.On("GetDeviceID").Return("0000:01:10.1")
+			poolDevs = map[string][]types.HostDevice{
+				"pool_a": {devA},
+				"pool_b": {devB},
+			}
+
+			dp = &mocks.DeviceProvider{}
+			dp.On("ValidConfig", mock.Anything).Return(true).
+				On("GetDevices", mock.Anything, 0).Return([]types.HostDevice{devA, devB}).
+				On("GetFilteredDevices", mock.Anything, mock.Anything, 0).Return(
+				func(_ []types.HostDevice, rc *types.ResourceConfig, _ int) []types.HostDevice {
+					return poolDevs[rc.ResourceName]
+				}, nil)
+
+			serverA = &mocks.ResourceServer{}
+			serverB = &mocks.ResourceServer{}
+			newSrv = &mocks.ResourceServer{}
+			newSrv.On("Start").Return(nil)
+
+			rf = &mocks.ResourceFactory{}
+			rf.On("GetDeviceFilter", mock.Anything).Return([]interface{}{&types.NetDeviceSelectors{}}, nil).
+				On("GetResourcePool", mock.Anything, mock.Anything).Return(&mocks.ResourcePool{}, nil).
+				On("GetResourceServer", mock.Anything).Return(newSrv, nil).
+				On("GetAllocator", mock.Anything).Return(nil, nil)
+
+			rm = &resourceManager{
+				cliParams: cliParams{
+					configFile:     "/tmp/sriovdp/test_config",
+					resourcePrefix: "test_",
+				},
+				pluginWatchMode: true,
+				rFactory:        rf,
+				deviceProviders: map[types.DeviceType]types.DeviceProvider{
+					types.NetDeviceType: dp,
+				},
+			}
+			writeConfig(configAB)
+			Expect(rm.readConfig()).To(Succeed())
+			rm.resourceServers = []types.ResourceServer{serverA, serverB}
+			rm.trackServer(rm.configList[0], serverA, poolDevs["pool_a"])
+			rm.trackServer(rm.configList[1], serverB, poolDevs["pool_b"])
+		})
+		AfterEach(func() {
+			err := os.RemoveAll("/tmp/sriovdp")
+			if err != nil {
+				panic(err)
+			}
+		})
+		It("should keep all servers when nothing changed", func() {
+			Expect(rm.reloadConfig()).To(Succeed())
+			Expect(rm.resourceServers).To(Equal([]types.ResourceServer{serverA, serverB}))
+			rf.AssertNotCalled(GinkgoT(), "GetResourceServer", mock.Anything)
+		})
+		It("should only replace the server whose config changed", func() {
+			serverB.On("Stop").Return(nil)
+			writeConfig(`{"resourceList": [
+				{"resourceName": "pool_a", "selectors": [{"drivers": ["iavf"]}]},
+				{"resourceName": "pool_b", "selectors": [{"drivers": ["vfio-pci", "igb_uio"]}]}
+			]}`)
+			Expect(rm.reloadConfig()).To(Succeed())
+			Expect(rm.resourceServers).To(Equal([]types.ResourceServer{serverA, newSrv}))
+			serverB.AssertCalled(GinkgoT(), "Stop")
+			serverA.AssertNotCalled(GinkgoT(), "Stop")
+			newSrv.AssertCalled(GinkgoT(), "Start")
+		})
+		It("should update the devices of a server whose devices changed in place", func() {
+			poolDevs["pool_a"] = []types.HostDevice{}
+			poolDevs["pool_b"] = []types.HostDevice{devA, devB}
+			serverA.On("UpdateDevices", mock.Anything).Return()
+			serverB.On("UpdateDevices", mock.Anything).Return()
+			Expect(rm.reloadConfig()).To(Succeed())
+			Expect(rm.resourceServers).To(Equal([]types.ResourceServer{serverA, serverB}))
+			Expect(rm.servers["test_/pool_b"].deviceIDs).To(ConsistOf("0000:01:10.0", "0000:01:10.1"))
+			serverA.AssertCalled(GinkgoT(), "UpdateDevices", []types.HostDevice{})
+			serverB.AssertCalled(GinkgoT(), "UpdateDevices", []types.HostDevice{devA, devB})
+			serverA.AssertNotCalled(GinkgoT(), "Stop")
+			serverB.AssertNotCalled(GinkgoT(), "Stop")
+			rf.AssertNotCalled(GinkgoT(), "GetResourceServer", mock.Anything)
+		})
+		It("should stop the server of a removed resource", func() {
+			serverB.On("Stop").Return(nil)
+			writeConfig(`{"resourceList": [
+				{"resourceName": "pool_a", "selectors": [{"drivers": ["iavf"]}]}
+			]}`)
+			Expect(rm.reloadConfig()).To(Succeed())
+			Expect(rm.resourceServers).To(Equal([]types.ResourceServer{serverA}))
+			Expect(rm.configList).To(HaveLen(1))
+			serverB.AssertCalled(GinkgoT(), "Stop")
+		})
+		It("should start a server for an added resource", func() {
+			poolDevs["pool_c"] = []types.HostDevice{}
+			writeConfig(`{"resourceList": [
+				{"resourceName": "pool_a", "selectors": [{"drivers": ["iavf"]}]},
+				{"resourceName": "pool_b", "selectors": [{"drivers": ["vfio-pci"]}]},
+				{"resourceName": "pool_c", "selectors": [{"drivers": ["mlx5_core"]}]}
+			]}`)
+			devC := &mocks.PciDevice{}
+			devC.On("GetDeviceID").Return("0000:01:10.2")
+			poolDevs["pool_c"] = []types.HostDevice{devC}
+			Expect(rm.reloadConfig()).To(Succeed())
+			Expect(rm.resourceServers).To(Equal([]types.ResourceServer{serverA, serverB, newSrv}))
+			rf.AssertCalled(GinkgoT(), "GetResourcePool", byName("pool_c"), []types.HostDevice{devC})
+			newSrv.AssertCalled(GinkgoT(), "Start")
+		})
+		It("should keep the current servers when the new config is invalid", func() {
+			writeConfig(`{"resourceList": [
+				{"resourceName": "pool_a", "selectors": [{"drivers": ["iavf"]}]},
+				{"resourceName": "pool_a", "selectors": [{"drivers": ["vfio-pci"]}]}
+			]}`)
+			Expect(rm.reloadConfig()).NotTo(Succeed())
+			Expect(rm.resourceServers).To(Equal([]types.ResourceServer{serverA, serverB}))
+			Expect(rm.configList).To(HaveLen(2))
+		})
+	})
 	DescribeTable("discovering devices",
 		func(fs *utils.FakeFilesystem) {
 			defer fs.Use()()
@@ -441,7 +858,7 @@ var _ = Describe("Resource manager", func() {
 				_ = os.Unsetenv("GHW_CHROOT")
 			}()
 
-			rf := factory.NewResourceFactory("fake", "fake", true, false)
+			rf := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 
 			rm := &resourceManager{
 				rFactory: rf,
@@ -558,6 +975,86 @@ var _ = Describe("Resource manager", func() {
 			},
 		),
 	)
+	Describe("reconciling allocations", func() {
+		var (
+			rm           *resourceManager
+			checkpoint   *mocks.AllocationCheckpoint
+			podResources *mocks.PodResourcesClient
+			nadUtils     *mocks.NadUtils
+		)
+		BeforeEach(func() {
+			checkpoint = &mocks.AllocationCheckpoint{}
+			podResources = &mocks.PodResourcesClient{}
+			nadUtils = &mocks.NadUtils{}
+			rf := &mocks.ResourceFactory{}
+			rf.On("GetNadUtils").Return(nadUtils)
+			rm = &resourceManager{
+				cliParams:    cliParams{resourcePrefix: "intel.com"},
+				rFactory:     rf,
+				configList:   []*types.ResourceConfig{{ResourceName: "sriov"}},
+				checkpoint:   checkpoint,
+				podResources: podResources,
+			}
+		})
+		It("should keep the devices in use and clean the device info files of the others", func() {
+			pod := types.DeviceAssignment{Namespace: "default", Pod: "pod", Container: "app"}
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{
+				"intel.com/sriov":   {"0000:3b:02.1": pod},
+				"intel.com/removed": {"0000:5e:02.0": pod},
+				"nvidia.com/gpu":    {"gpu-0": pod},
+			}, nil)
+			checkpoint.On("GetDevices", "intel.com/removed").Return([]string{"0000:5e:02.0"}).
+				On("GetDevices", "nvidia.com/gpu").Return([]string{}).
+				On("Reconcile", map[string][]string{
+					"intel.com/sriov":   {"0000:3b:02.1"},
+					"intel.com/removed": {"0000:5e:02.0"},
+				}).Return(map[string][]string{"intel.com/sriov": {"0000:3b:02.0"}}, nil)
+			nadUtils.On("CleanDeviceInfoFile", "intel.com/sriov", "0000:3b:02.0").Return(nil)
+
+			Expect(rm.reconcileAllocations()).To(Succeed())
+			checkpoint.AssertExpectations(GinkgoT())
+			nadUtils.AssertExpectations(GinkgoT())
+		})
+		It("should keep the checkpoint when the PodResources API is unavailable", func() {
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(nil, fmt.Errorf("no kubelet"))
+
+			Expect(rm.reconcileAllocations()).NotTo(Succeed())
+			checkpoint.AssertNotCalled(GinkgoT(), "Reconcile", mock.Anything)
+		})
+	})
+	Describe("pruning allocations at runtime", func() {
+		var (
+			rm       *resourceManager
+			nadUtils *mocks.NadUtils
+		)
+		BeforeEach(func() {
+			cp, err := checkpoint.New(filepath.Join(GinkgoT().TempDir(), "allocations.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cp.AddDevices("intel.com/sriov", []string{"0000:3b:02.0", "0000:3b:02.1"})).To(Succeed())
+			nadUtils = &mocks.NadUtils{}
+			rf := &mocks.ResourceFactory{}
+			rf.On("GetNadUtils").Return(nadUtils)
+			rm = &resourceManager{
+				cliParams:  cliParams{resourcePrefix: "intel.com"},
+				rFactory:   rf,
+				configList: []*types.ResourceConfig{{ResourceName: "sriov"}},
+				checkpoint: cp,
+			}
+		})
+		It("should remove a freed device once it is found unassigned twice", func() {
+			pod := types.DeviceAssignment{Namespace: "default", Pod: "pod", Container: "app"}
+			nadUtils.On("CleanDeviceInfoFile", "intel.com/sriov", "0000:3b:02.1").Return(nil)
+			assignments := map[string]map[string]types.DeviceAssignment{"intel.com/sriov": {"0000:3b:02.0": pod}}
+
+			rm.syncAllocations(assignments)
+			Expect(rm.checkpoint.GetDevices("intel.com/sriov")).To(ConsistOf("0000:3b:02.0", "0000:3b:02.1"))
+			nadUtils.AssertNotCalled(GinkgoT(), "CleanDeviceInfoFile", mock.Anything, mock.Anything)
+
+			rm.syncAllocations(assignments)
+			Expect(rm.checkpoint.GetDevices("intel.com/sriov")).To(ConsistOf("0000:3b:02.0"))
+			nadUtils.AssertExpectations(GinkgoT())
+		})
+	})
 	Describe("starting all server", func() {
 		Context("when resource servers are starting fine", func() {
 			rs := &mocks.ResourceServer{}
@@ -615,24 +1112,57 @@ var _ = Describe("Resource manager", func() {
 				Expect(err).To(HaveOccurred())
 			})
 		})
-		Context("when resource servers cleans CDI specs ", func() {
-			rs := &mocks.ResourceServer{}
-			rs.On("Stop").Return(nil)
-
-			cp = &cliParams{
-				configFile:     "/tmp/sriovdp/test_config",
-				resourcePrefix: "test_",
-				useCdi:         true,
-			}
-			rm = newResourceManager(cp)
-			cdi := &CDImocks.CDI{}
-			cdi.On("CleanupSpecs").Return(nil)
-			rm.cdi = &CDImocks.CDI{}
-
-			err := rm.stopAllServers()
-			It("shouldn't fail", func() {
-				Expect(err).NotTo(HaveOccurred())
-			})
+	})
+	Describe("removing stale CDI specs", func() {
+		var (
+			rm  *resourceManager
+			cdi *CDImocks.CDI
+		)
+		BeforeEach(func() {
+			rm = newResourceManager(&cliParams{resourcePrefix: "test_", useCdi: true})
+			cdi = &CDImocks.CDI{}
+			rm.cdi = cdi
+		})
+		It("should keep the specs of the configured resource pools", func() {
+			cdi.On("RemoveStaleSpecs", map[string]bool{"test_/pool_a": true, "example.com/pool_b": true}).Return(nil)
+			err := rm.removeStaleCDISpecs([]*types.ResourceConfig{
+				{ResourceName: "pool_a"}, {ResourceName: "pool_b", ResourcePrefix: "example.com"}})
+			Expect(err).NotTo(HaveOccurred())
+			cdi.AssertExpectations(GinkgoT())
+		})
+		It("should fail when the specs can not be removed", func() {
+			cdi.On("RemoveStaleSpecs", map[string]bool{}).Return(fmt.Errorf("failed"))
+			Expect(rm.removeStaleCDISpecs(nil)).To(HaveOccurred())
+		})
+		It("should not touch CDI specs when CDI is disabled", func() {
+			rm.cliParams.useCdi = false
+			Expect(rm.removeStaleCDISpecs(nil)).To(Succeed())
+			cdi.AssertNotCalled(GinkgoT(), "RemoveStaleSpecs", mock.Anything)
 		})
 	})
+	Describe("validating RDMA configuration", func() {
+		var origProvider utils.NetlinkProvider
+		BeforeEach(func() {
+			rm = newResourceManager(&cliParams{resourcePrefix: "test_"})
+			origProvider = utils.GetNetlinkProvider()
+			mockProvider := &utilmocks.NetlinkProvider{}
+			mockProvider.On("GetRdmaNetnsMode").Return(types.RdmaNetnsModeShared, nil)
+			utils.SetNetlinkProviderInst(mockProvider)
+		})
+		AfterEach(func() {
+			utils.SetNetlinkProviderInst(origProvider)
+		})
+		DescribeTable("checking the RDMA configuration",
+			func(rc *types.RdmaConfig, expected bool) {
+				Expect(rm.validRdmaConfig("test_/pool", rc)).To(Equal(expected))
+			},
+			Entry("no RDMA configuration", nil, true),
+			Entry("matching netns mode", &types.RdmaConfig{NetnsMode: "shared", Strict: true}, true),
+			Entry("mismatching netns mode", &types.RdmaConfig{NetnsMode: "exclusive"}, true),
+			Entry("mismatching netns mode in strict mode", &types.RdmaConfig{NetnsMode: "exclusive", Strict: true}, false),
+			Entry("unknown netns mode", &types.RdmaConfig{NetnsMode: "private"}, false),
+			Entry("supported char devices", &types.RdmaConfig{CharDevices: []string{"rdma_cm", "umad"}}, true),
+			Entry("unsupported char device", &types.RdmaConfig{CharDevices: []string{"uverbs"}}, false),
+		)
+	})
 })
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+)
+
+// validatePfConfigs checks that every PF config identifies a single PF, by exactly one of its identifiers, and
+// declares a valid number of VFs
+func validatePfConfigs(pfConfigs []types.PfConfig) error {
+	seen := make(map[string]bool, len(pfConfigs))
+	for i := range pfConfigs {
+		pc := &pfConfigs[i]
+		if err := validatePfIdentifier(&pc.PfIdentifier); err != nil {
+			return fmt.Errorf("sriovPfs[%d]: %v", i, err)
+		}
+		if pc.NumVfs < 0 {
+			return fmt.Errorf("sriovPfs[%d]: numVfs must not be negative, got %d", i, pc.NumVfs)
+		}
+		if seen[pfID(&pc.PfIdentifier)] {
+			return fmt.Errorf("sriovPfs[%d]: PF %s is configured more than once", i, pfID(&pc.PfIdentifier))
+		}
+		seen[pfID(&pc.PfIdentifier)] = true
+	}
+	return nil
+}
+
+// validatePfIdentifier checks that a PF identifier sets exactly one of its identifiers
+func validatePfIdentifier(id *types.PfIdentifier) error {
+	identifiers := 0
+	for _, v := range []string{id.PfName, id.PciAddress, id.AcpiIndex} {
+		if v != "" {
+			identifiers++
+		}
+	}
+	if identifiers != 1 {
+		return fmt.Errorf("exactly one of pfName, pciAddress and acpiIndex must be set")
+	}
+	return nil
+}
+
+// pfID returns the identifier a PF is referred to by
+func pfID(id *types.PfIdentifier) string {
+	switch {
+	case id.PfName != "":
+		return "pfName=" + id.PfName
+	case id.PciAddress != "":
+		return "pciAddress=" + id.PciAddress
+	default:
+		return "acpiIndex=" + id.AcpiIndex
+	}
+}
+
+// getPfAddr returns the PCI address of the PF a PF identifier refers to
+func getPfAddr(id *types.PfIdentifier) (string, error) {
+	switch {
+	case id.PfName != "":
+		return utils.GetPciAddrFromNetName(id.PfName)
+	case id.PciAddress != "":
+		return id.PciAddress, nil
+	default:
+		return utils.GetPciAddrFromAcpiIndex(id.AcpiIndex)
+	}
+}
+
+// configurePfs brings the number of VFs of the configured PFs to the declared state. The VFs of a PF are only
+// changed when none of them is in use by a container, since the kernel removes all VFs before creating new ones,
+// and are left alone when that cannot be determined. Failures are logged per PF. It returns true when the VFs of
+// any PF were changed.
+func (rm *resourceManager) configurePfs(pfConfigs []types.PfConfig) bool {
+	changed := false
+	var assigned func(deviceID string) bool
+	for i := range pfConfigs {
+		pc := &pfConfigs[i]
+		log := rm.log.WithValues("pf", pfID(&pc.PfIdentifier), "numVfs", pc.NumVfs)
+		pfAddr, err := getPfAddr(&pc.PfIdentifier)
+		if err != nil {
+			log.Error(err, "Unable to find PF")
+			continue
+		}
+		log = log.WithValues("pciAddress", pfAddr)
+		current := utils.GetVFconfigured(pfAddr)
+		if current == pc.NumVfs {
+			log.V(2).Info("PF already has the declared number of VFs")
+			continue
+		}
+		if assigned == nil {
+			if assigned, err = rm.assignedDevices(); err != nil {
+				log.Error(err, "Not changing the VFs of PF, unable to tell whether VFs are in use", "currentVfs", current)
+				continue
+			}
+		}
+		inUse, err := rm.vfsInUse(pfAddr, assigned)
+		if err != nil {
+			log.Error(err, "Not changing the VFs of PF, unable to list its VFs", "currentVfs", current)
+			continue
+		}
+		if len(inUse) > 0 {
+			log.Error(nil, "Not changing the VFs of PF, VFs are in use by containers", "currentVfs", current, "inUse", inUse)
+			continue
+		}
+		if err := utils.SetVFconfigured(pfAddr, pc.NumVfs); err != nil {
+			log.Error(err, "Unable to configure VFs of PF", "currentVfs", current)
+			continue
+		}
+		log.Info("Configured VFs of PF", "previousVfs", current)
+		changed = true
+	}
+	return changed
+}
+
+// vfsInUse returns the VFs of a PF that are in use by containers, as known to the resource manager or reported
+// as assigned by the PodResources API
+func (rm *resourceManager) vfsInUse(pfAddr string, assigned func(deviceID string) bool) ([]string, error) {
+	vfs, err := utils.GetVFList(pfAddr)
+	if err != nil {
+		return nil, err
+	}
+	inUse := make([]string, 0)
+	for _, vf := range vfs {
+		if rm.deviceInUse(vf) || assigned(vf) {
+			inUse = append(inUse, vf)
+		}
+	}
+	return inUse, nil
+}
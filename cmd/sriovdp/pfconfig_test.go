@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+)
+
+var _ = Describe("Configuring SR-IOV PFs", func() {
+	DescribeTable("validating PF configs",
+		func(pfConfigs []types.PfConfig, expectedErr string) {
+			err := validatePfConfigs(pfConfigs)
+			if expectedErr == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(expectedErr))
+			}
+		},
+		Entry("valid configs", []types.PfConfig{
+			pfConfig(types.PfIdentifier{PfName: "ens1f0"}, 8), pfConfig(types.PfIdentifier{AcpiIndex: "101"}, 0)}, ""),
+		Entry("no identifier", []types.PfConfig{pfConfig(types.PfIdentifier{}, 8)},
+			"sriovPfs[0]: exactly one of pfName, pciAddress and acpiIndex must be set"),
+		Entry("several identifiers", []types.PfConfig{pfConfig(types.PfIdentifier{PfName: "ens1f0", PciAddress: "0000:01:00.0"}, 8)},
+			"sriovPfs[0]: exactly one of pfName, pciAddress and acpiIndex must be set"),
+		Entry("negative number of VFs", []types.PfConfig{pfConfig(types.PfIdentifier{PciAddress: "0000:01:00.0"}, -1)},
+			"sriovPfs[0]: numVfs must not be negative, got -1"),
+		Entry("duplicate PF", []types.PfConfig{
+			pfConfig(types.PfIdentifier{PfName: "ens1f0"}, 8), pfConfig(types.PfIdentifier{PfName: "ens1f0"}, 4)},
+			"sriovPfs[1]: PF pfName=ens1f0 is configured more than once"),
+	)
+
+	Describe("setting the number of VFs", func() {
+		var (
+			fs           *utils.FakeFilesystem
+			rm           *resourceManager
+			podResources *mocks.PodResourcesClient
+		)
+		BeforeEach(func() {
+			fs = &utils.FakeFilesystem{
+				Dirs: []string{"sys/bus/pci/devices/0000:01:00.0", "sys/bus/pci/devices/0000:01:00.2",
+					"sys/bus/pci/devices/0000:02:00.0"},
+				Files: map[string][]byte{
+					"sys/bus/pci/devices/0000:01:00.0/sriov_totalvfs": []byte("8"),
+					"sys/bus/pci/devices/0000:01:00.0/sriov_numvfs":   []byte("1"),
+					"sys/bus/pci/devices/0000:02:00.0/sriov_totalvfs": []byte("8"),
+					"sys/bus/pci/devices/0000:02:00.0/sriov_numvfs":   []byte("0"),
+				},
+				Symlinks: map[string]string{"sys/bus/pci/devices/0000:01:00.0/virtfn0": "../0000:01:00.2"},
+			}
+			checkpoint := &mocks.AllocationCheckpoint{}
+			checkpoint.On("GetDevices", "test/pool").Return([]string{"0000:01:00.2"})
+			podResources = &mocks.PodResourcesClient{}
+			rm = &resourceManager{
+				cliParams:    cliParams{resourcePrefix: "test", draDriverName: "sriovnetwork.k8snetworkplumbingwg.io"},
+				configList:   []*types.ResourceConfig{{ResourceName: "pool"}},
+				checkpoint:   checkpoint,
+				podResources: podResources,
+				log:          klog.Background(),
+			}
+		})
+		It("should create the declared VFs", func() {
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{}, nil)
+			defer fs.Use()()
+			Expect(rm.configurePfs([]types.PfConfig{pfConfig(types.PfIdentifier{PciAddress: "0000:02:00.0"}, 4)})).To(BeTrue())
+			Expect(utils.GetVFconfigured("0000:02:00.0")).To(Equal(4))
+		})
+		It("should leave PFs that already have the declared VFs alone", func() {
+			defer fs.Use()()
+			Expect(rm.configurePfs([]types.PfConfig{pfConfig(types.PfIdentifier{PciAddress: "0000:01:00.0"}, 1)})).To(BeFalse())
+		})
+		It("should not change the VFs of a PF when some are in use", func() {
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{}, nil)
+			defer fs.Use()()
+			Expect(rm.configurePfs([]types.PfConfig{pfConfig(types.PfIdentifier{PciAddress: "0000:01:00.0"}, 4)})).To(BeFalse())
+			Expect(utils.GetVFconfigured("0000:01:00.0")).To(Equal(1))
+		})
+		It("should not change the VFs of a PF when the PodResources API reports some as assigned", func() {
+			rm.checkpoint = nil
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{
+				"other.com/vfs": {"0000:01:00.2": {Namespace: "default", Pod: "pod-a", Container: "app"}},
+			}, nil)
+			defer fs.Use()()
+			Expect(rm.configurePfs([]types.PfConfig{pfConfig(types.PfIdentifier{PciAddress: "0000:01:00.0"}, 4)})).To(BeFalse())
+			Expect(utils.GetVFconfigured("0000:01:00.0")).To(Equal(1))
+		})
+		It("should not change the VFs of a PF when some are allocated through DRA claims", func() {
+			rm.checkpoint = nil
+			rm.draMode = true
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{}, nil).
+				On("GetClaimDevices", mock.Anything, "sriovnetwork.k8snetworkplumbingwg.io").Return([]string{"0000-01-00-2"}, nil)
+			defer fs.Use()()
+			Expect(rm.configurePfs([]types.PfConfig{pfConfig(types.PfIdentifier{PciAddress: "0000:01:00.0"}, 4)})).To(BeFalse())
+			Expect(utils.GetVFconfigured("0000:01:00.0")).To(Equal(1))
+		})
+		It("should not change the VFs of a PF when the PodResources API is unavailable", func() {
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(nil, fmt.Errorf("connection refused"))
+			defer fs.Use()()
+			Expect(rm.configurePfs([]types.PfConfig{pfConfig(types.PfIdentifier{PciAddress: "0000:02:00.0"}, 4)})).To(BeFalse())
+			Expect(utils.GetVFconfigured("0000:02:00.0")).To(Equal(0))
+		})
+		It("should skip PFs that cannot be found", func() {
+			defer fs.Use()()
+			Expect(rm.configurePfs([]types.PfConfig{pfConfig(types.PfIdentifier{PfName: "ens9f0"}, 4)})).To(BeFalse())
+		})
+	})
+})
+
+func pfConfig(id types.PfIdentifier, numVfs int) types.PfConfig {
+	return types.PfConfig{PfIdentifier: id, NumVfs: numVfs}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+)
+
+const (
+	sfStateActive   = "active"
+	sfStateInactive = "inactive"
+)
+
+// validateSfConfigs checks that every SF config identifies a single PF and declares a valid set of subfunctions
+func validateSfConfigs(sfConfigs []types.SfConfig) error {
+	seen := make(map[string]bool, len(sfConfigs))
+	for i := range sfConfigs {
+		sc := &sfConfigs[i]
+		if err := validatePfIdentifier(&sc.PfIdentifier); err != nil {
+			return fmt.Errorf("subfunctions[%d]: %v", i, err)
+		}
+		if sc.NumSfs < 0 || sc.SfNumStart < 0 {
+			return fmt.Errorf("subfunctions[%d]: numSfs and sfNumStart must not be negative", i)
+		}
+		if len(sc.MacAddresses) > sc.NumSfs {
+			return fmt.Errorf("subfunctions[%d]: %d MAC addresses given for %d SFs", i, len(sc.MacAddresses), sc.NumSfs)
+		}
+		for _, mac := range sc.MacAddresses {
+			if _, err := net.ParseMAC(mac); err != nil {
+				return fmt.Errorf("subfunctions[%d]: %v", i, err)
+			}
+		}
+		if sc.State != "" && sc.State != sfStateActive && sc.State != sfStateInactive {
+			return fmt.Errorf("subfunctions[%d]: state must be %q or %q, got %q", i, sfStateActive, sfStateInactive, sc.State)
+		}
+		if seen[pfID(&sc.PfIdentifier)] {
+			return fmt.Errorf("subfunctions[%d]: PF %s is configured more than once", i, pfID(&sc.PfIdentifier))
+		}
+		seen[pfID(&sc.PfIdentifier)] = true
+	}
+	return nil
+}
+
+// configureSfs creates the declared subfunctions that do not exist yet and sets the MAC address and state of
+// their port functions. Subfunctions are never deleted, and the port function of subfunctions in use by
+// containers, or that cannot be told not to be, is left alone. Failures are logged per SF. It returns true when
+// any subfunction was created or changed.
+func (rm *resourceManager) configureSfs(sfConfigs []types.SfConfig) bool {
+	changed := false
+	var assigned func(deviceID string) bool
+	for i := range sfConfigs {
+		sc := &sfConfigs[i]
+		log := rm.log.WithValues("pf", pfID(&sc.PfIdentifier), "numSfs", sc.NumSfs)
+		pfAddr, err := getPfAddr(&sc.PfIdentifier)
+		if err != nil {
+			log.Error(err, "Unable to find PF")
+			continue
+		}
+		log = log.WithValues("pciAddress", pfAddr)
+		if mode, err := utils.GetPfEswitchMode(pfAddr); err != nil || mode != utils.EswitchModeSwitchdev {
+			log.Error(err, "Subfunctions can only be created on PFs in switchdev mode", "eswitchMode", mode)
+			continue
+		}
+		ports, err := utils.GetSfPorts(pfAddr)
+		if err != nil {
+			log.Error(err, "Unable to get the subfunctions of PF")
+			continue
+		}
+		active := sc.State != sfStateInactive
+		var inUse map[int]string
+		var inUseErr error
+		for j := 0; j < sc.NumSfs; j++ {
+			sfNum := sc.SfNumStart + j
+			var hwAddr net.HardwareAddr
+			if j < len(sc.MacAddresses) {
+				hwAddr, _ = net.ParseMAC(sc.MacAddresses[j])
+			}
+			port, ok := ports[sfNum]
+			switch {
+			case !ok:
+				if port, err = utils.AddSf(pfAddr, sfNum); err != nil {
+					log.Error(err, "Unable to create subfunction", "sfNum", sfNum)
+					continue
+				}
+				log.Info("Created subfunction", "sfNum", sfNum, "portIndex", port.PortIndex)
+				changed = true
+			case utils.IsSfFunctionSet(port, hwAddr, active):
+				continue
+			default:
+				if inUse == nil && inUseErr == nil {
+					if assigned == nil {
+						assigned, inUseErr = rm.assignedDevices()
+					}
+					if inUseErr == nil {
+						inUse, inUseErr = rm.sfsInUse(pfAddr, assigned)
+					}
+				}
+				if inUseErr != nil {
+					log.Error(inUseErr, "Not changing the port function of subfunction, unable to tell whether it is in use",
+						"sfNum", sfNum)
+					continue
+				}
+				if deviceID, ok := inUse[sfNum]; ok {
+					log.Error(nil, "Not changing the port function of subfunction in use by a container", "sfNum", sfNum,
+						"deviceID", deviceID)
+					continue
+				}
+			}
+			set, err := utils.SetSfFunction(pfAddr, port, hwAddr, active)
+			if err != nil {
+				log.Error(err, "Unable to set port function of subfunction", "sfNum", sfNum)
+				continue
+			}
+			if set {
+				log.Info("Set port function of subfunction", "sfNum", sfNum, "mac", hwAddr.String(), "active", active)
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// sfsInUse returns the auxiliary devices of the subfunctions of a PF that are in use by containers, as known to
+// the resource manager or reported as assigned by the PodResources API, keyed by SF number
+func (rm *resourceManager) sfsInUse(pfAddr string, assigned func(deviceID string) bool) (map[int]string, error) {
+	auxDevs, err := utils.GetSriovnetProvider().GetAuxNetDevicesFromPci(pfAddr)
+	if err != nil {
+		return nil, err
+	}
+	inUse := make(map[int]string)
+	for _, auxDev := range auxDevs {
+		if !rm.deviceInUse(auxDev) && !assigned(auxDev) {
+			continue
+		}
+		sfNum, err := utils.GetSriovnetProvider().GetSfIndexByAuxDev(auxDev)
+		if err != nil {
+			return nil, err
+		}
+		inUse[sfNum] = auxDev
+	}
+	return inUse, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	nl "github.com/vishvananda/netlink"
+	nlapi "github.com/vishvananda/netlink/nl"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+	utilmocks "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils/mocks"
+)
+
+var _ = Describe("Configuring subfunctions", func() {
+	pf := types.PfIdentifier{PciAddress: "0000:03:00.0"}
+
+	DescribeTable("validating SF configs",
+		func(sfConfigs []types.SfConfig, expectedErr string) {
+			err := validateSfConfigs(sfConfigs)
+			if expectedErr == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(expectedErr))
+			}
+		},
+		Entry("valid configs", []types.SfConfig{
+			{PfIdentifier: pf, NumSfs: 2, SfNumStart: 88, MacAddresses: []string{"00:00:00:00:88:88"}},
+			{PfIdentifier: types.PfIdentifier{PfName: "ens3f1"}, NumSfs: 1, State: "inactive"}}, ""),
+		Entry("no identifier", []types.SfConfig{{NumSfs: 2}},
+			"subfunctions[0]: exactly one of pfName, pciAddress and acpiIndex must be set"),
+		Entry("negative number of SFs", []types.SfConfig{{PfIdentifier: pf, NumSfs: -1}},
+			"subfunctions[0]: numSfs and sfNumStart must not be negative"),
+		Entry("more MAC addresses than SFs", []types.SfConfig{{PfIdentifier: pf, NumSfs: 1,
+			MacAddresses: []string{"00:00:00:00:88:88", "00:00:00:00:88:89"}}},
+			"subfunctions[0]: 2 MAC addresses given for 1 SFs"),
+		Entry("invalid MAC address", []types.SfConfig{{PfIdentifier: pf, NumSfs: 1, MacAddresses: []string{"88:88"}}},
+			"subfunctions[0]: address 88:88: invalid MAC address"),
+		Entry("invalid state", []types.SfConfig{{PfIdentifier: pf, NumSfs: 1, State: "up"}},
+			`subfunctions[0]: state must be "active" or "inactive", got "up"`),
+		Entry("duplicate PF", []types.SfConfig{{PfIdentifier: pf, NumSfs: 1}, {PfIdentifier: pf, NumSfs: 2}},
+			"subfunctions[1]: PF pciAddress=0000:03:00.0 is configured more than once"),
+	)
+
+	Describe("creating subfunctions", func() {
+		var (
+			fs           *utils.FakeFilesystem
+			rm           *resourceManager
+			mockProvider *utilmocks.NetlinkProvider
+			origProvider utils.NetlinkProvider
+			mockSriovnet *utilmocks.SriovnetProvider
+			origSriovnet utils.SriovnetProvider
+			podResources *mocks.PodResourcesClient
+			sf0          *nl.DevlinkPort
+		)
+		BeforeEach(func() {
+			fs = &utils.FakeFilesystem{Dirs: []string{"sys/bus/pci/devices/0000:03:00.0"}}
+			uplink := &nl.DevlinkPort{PortIndex: 65535, PortFlavour: nlapi.DEVLINK_PORT_FLAVOUR_PHYSICAL}
+			sf0 = &nl.DevlinkPort{PortIndex: 32768, PortFlavour: nlapi.DEVLINK_PORT_FLAVOUR_PCI_SF,
+				NetdeviceName: "en3f0pf0sf0", Fn: &nl.DevlinkPortFn{State: nlapi.DEVLINK_PORT_FN_STATE_ACTIVE}}
+			mockProvider = &utilmocks.NetlinkProvider{}
+			mockProvider.On("GetDevLinkDeviceEswitchAttrs", "0000:03:00.0").
+				Return(&nl.DevlinkDevEswitchAttr{Mode: "switchdev"}, nil)
+			mockProvider.On("GetDevlinkPorts", "pci", "0000:03:00.0").Return([]*nl.DevlinkPort{uplink, sf0}, nil)
+			mockProvider.On("GetDevlinkPortNumbers", "pci", "0000:03:00.0").Return(map[uint32]types.DevlinkPortNumbers{
+				65535: {PortNumber: 0}, 32768: {SfNumber: 0}}, nil)
+			origProvider = utils.GetNetlinkProvider()
+			utils.SetNetlinkProviderInst(mockProvider)
+			mockSriovnet = &utilmocks.SriovnetProvider{}
+			mockSriovnet.On("GetAuxNetDevicesFromPci", "0000:03:00.0").Return([]string{"mlx5_core.sf.2"}, nil)
+			mockSriovnet.On("GetSfIndexByAuxDev", "mlx5_core.sf.2").Return(0, nil)
+			origSriovnet = utils.GetSriovnetProvider()
+			utils.SetSriovnetProviderInst(mockSriovnet)
+			checkpoint := &mocks.AllocationCheckpoint{}
+			checkpoint.On("GetDevices", "test/sfs").Return([]string{})
+			podResources = &mocks.PodResourcesClient{}
+			rm = &resourceManager{
+				cliParams:    cliParams{resourcePrefix: "test"},
+				configList:   []*types.ResourceConfig{{ResourceName: "sfs"}},
+				checkpoint:   checkpoint,
+				podResources: podResources,
+				log:          klog.Background(),
+			}
+		})
+		AfterEach(func() {
+			utils.SetNetlinkProviderInst(origProvider)
+			utils.SetSriovnetProviderInst(origSriovnet)
+		})
+		It("should create and activate the missing subfunctions", func() {
+			defer fs.Use()()
+			mockProvider.On("AddDevlinkSfPort", "0000:03:00.0", uint16(0), uint32(1)).
+				Return(&nl.DevlinkPort{PortIndex: 32769, Fn: &nl.DevlinkPortFn{}}, nil)
+			mockProvider.On("SetDevlinkPortFunction", "pci", "0000:03:00.0", uint32(32769), nl.DevlinkPortFnSetAttrs{
+				FnAttrs: nl.DevlinkPortFn{State: nlapi.DEVLINK_PORT_FN_STATE_ACTIVE}, StateValid: true}).Return(nil)
+
+			Expect(rm.configureSfs([]types.SfConfig{{PfIdentifier: pf, NumSfs: 2}})).To(BeTrue())
+			mockProvider.AssertNumberOfCalls(GinkgoT(), "AddDevlinkSfPort", 1)
+			mockProvider.AssertNumberOfCalls(GinkgoT(), "SetDevlinkPortFunction", 1)
+			podResources.AssertNotCalled(GinkgoT(), "GetDeviceAssignments", mock.Anything)
+		})
+		It("should leave subfunctions that are already as declared alone", func() {
+			defer fs.Use()()
+			Expect(rm.configureSfs([]types.SfConfig{{PfIdentifier: pf, NumSfs: 1}})).To(BeFalse())
+			mockProvider.AssertNotCalled(GinkgoT(), "AddDevlinkSfPort", mock.Anything, mock.Anything, mock.Anything)
+			mockProvider.AssertNotCalled(GinkgoT(), "SetDevlinkPortFunction", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything)
+		})
+		It("should change the port function of subfunctions that are not in use", func() {
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{}, nil)
+			mockProvider.On("SetDevlinkPortFunction", "pci", "0000:03:00.0", uint32(32768), nl.DevlinkPortFnSetAttrs{
+				FnAttrs: nl.DevlinkPortFn{State: nlapi.DEVLINK_PORT_FN_STATE_INACTIVE}, StateValid: true}).Return(nil)
+			defer fs.Use()()
+
+			Expect(rm.configureSfs([]types.SfConfig{{PfIdentifier: pf, NumSfs: 1, State: "inactive"}})).To(BeTrue())
+			mockProvider.AssertNumberOfCalls(GinkgoT(), "SetDevlinkPortFunction", 1)
+		})
+		It("should not change the port function of subfunctions in use", func() {
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{
+				"other.com/sfs": {"mlx5_core.sf.2": {Namespace: "default", Pod: "pod-a", Container: "app"}},
+			}, nil)
+			defer fs.Use()()
+
+			Expect(rm.configureSfs([]types.SfConfig{{PfIdentifier: pf, NumSfs: 1, State: "inactive"}})).To(BeFalse())
+			mockProvider.AssertNotCalled(GinkgoT(), "SetDevlinkPortFunction", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything)
+		})
+		It("should not change the port function of subfunctions when the PodResources API is unavailable", func() {
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(nil, fmt.Errorf("connection refused"))
+			defer fs.Use()()
+
+			Expect(rm.configureSfs([]types.SfConfig{{PfIdentifier: pf, NumSfs: 1, State: "inactive"}})).To(BeFalse())
+			mockProvider.AssertNotCalled(GinkgoT(), "SetDevlinkPortFunction", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything)
+		})
+		It("should not create subfunctions on PFs in legacy mode", func() {
+			defer fs.Use()()
+			mockProvider.ExpectedCalls = nil
+			mockProvider.On("GetDevLinkDeviceEswitchAttrs", "0000:03:00.0").
+				Return(&nl.DevlinkDevEswitchAttr{Mode: "legacy"}, nil)
+
+			Expect(rm.configureSfs([]types.SfConfig{{PfIdentifier: pf, NumSfs: 2}})).To(BeFalse())
+			mockProvider.AssertNotCalled(GinkgoT(), "AddDevlinkSfPort", mock.Anything, mock.Anything, mock.Anything)
+		})
+	})
+})
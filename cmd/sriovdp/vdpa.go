@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+)
+
+// vdpaDevicePrefix prefixes the names of the vDPA devices created by the plugin
+const vdpaDevicePrefix = "sriovdp-"
+
+// vdpaDeviceName returns the name of the vDPA device the plugin creates on a device
+func vdpaDeviceName(deviceID string) string {
+	return vdpaDevicePrefix + deviceID
+}
+
+// createVdpaOf returns the type of the vDPA devices to create on the devices of a selector object, if any
+func createVdpaOf(selector interface{}) types.VdpaType {
+	if nf, ok := selector.(*types.NetDeviceSelectors); ok && nf.CreateVdpa {
+		return nf.VdpaType
+	}
+	return ""
+}
+
+// createVdpaDevices creates a vDPA device of the given type on the selected devices lacking one and returns
+// them rebuilt with their vDPA device. Devices that still lack a vDPA device of the type are dropped.
+func (rm *resourceManager) createVdpaDevices(log klog.Logger, dp types.DeviceProvider, rc *types.ResourceConfig,
+	selectorIndex int, vdpaType types.VdpaType, devices []types.HostDevice) []types.HostDevice {
+	log = log.WithValues("selectorIndex", selectorIndex, "vdpaType", vdpaType)
+	keep := make(map[string]bool, len(devices))
+	created := false
+	for _, dev := range devices {
+		id := dev.GetDeviceID()
+		if netDev, ok := dev.(types.PciNetDevice); ok && netDev.GetVdpaDevice() != nil {
+			keep[id] = true
+			continue
+		}
+		name := vdpaDeviceName(id)
+		if err := utils.CreateVdpaDevice(id, name, types.SupportedVdpaTypes[vdpaType]); err != nil {
+			log.Error(err, "Unable to create vDPA device, dropping device from the pool", "deviceID", id)
+			continue
+		}
+		log.Info("Created vDPA device", "deviceID", id, "vdpaDevice", name)
+		keep[id] = true
+		created = true
+	}
+	if !created {
+		return filterDeviceIDs(devices, keep)
+	}
+	// the devices look up their vDPA device when built, hence the ones that got one are built again
+	rebuilt := make([]types.HostDevice, 0, len(keep))
+	for _, dev := range filterDeviceIDs(dp.GetDevices(rc, selectorIndex), keep) {
+		if netDev, ok := dev.(types.PciNetDevice); ok {
+			if vdpaDev := netDev.GetVdpaDevice(); vdpaDev != nil && vdpaDev.GetType() == vdpaType {
+				rebuilt = append(rebuilt, dev)
+				continue
+			}
+		}
+		log.Info("Device has no vDPA device of the requested type, dropping it from the pool", "deviceID", dev.GetDeviceID())
+	}
+	return rebuilt
+}
+
+// deleteCreatedVdpaDevices deletes the vDPA devices the plugin created for the old resource servers on the devices
+// that no current resource server serves anymore. Devices still recorded as allocated to containers keep their
+// vDPA device, as do devices of resource claims in DRA mode, or all devices when claims cannot be queried.
+func (rm *resourceManager) deleteCreatedVdpaDevices(old, current map[string]*managedServer) {
+	served := servedDeviceIDs(current)
+	var claimed func(deviceID string) bool
+	for key, s := range old {
+		if !createsVdpa(s.config) {
+			continue
+		}
+		for _, id := range s.deviceIDs {
+			if served[id] {
+				continue
+			}
+			name := vdpaDeviceName(id)
+			if vdpaDev := rm.rFactory.GetVdpaDevice(id); vdpaDev == nil || vdpaDev.GetParent() != name {
+				continue
+			}
+			log := rm.log.WithValues("resourceName", key, "deviceID", id, "vdpaDevice", name)
+			if rm.draMode && claimed == nil {
+				var err error
+				if claimed, err = rm.assignedDevices(); err != nil {
+					log.Error(err, "Not deleting vDPA device, unable to tell whether the device is claimed")
+					continue
+				}
+			}
+			if rm.deviceInUse(id) || (claimed != nil && claimed(id)) {
+				log.Info("Not deleting vDPA device of device allocated to a container")
+				continue
+			}
+			if err := utils.DeleteVdpaDevice(name); err != nil {
+				log.Error(err, "Unable to delete vDPA device")
+				continue
+			}
+			log.Info("Deleted vDPA device")
+		}
+	}
+}
+
+// deleteStaleVdpaDevices deletes the vDPA devices named like the ones the plugin creates on the devices no current
+// resource server serves, e.g. devices of pools removed while the plugin was not running. Devices recorded as
+// allocated or reported as assigned to containers by the PodResources API keep their vDPA device, as do all
+// devices when the PodResources API is unavailable.
+func (rm *resourceManager) deleteStaleVdpaDevices(current map[string]*managedServer) {
+	names, err := utils.ListVdpaDeviceNames()
+	if err != nil {
+		// hosts without vDPA support have no vDPA devices to delete
+		rm.log.V(2).Info("Unable to list vDPA devices", "err", err)
+		return
+	}
+	served := servedDeviceIDs(current)
+	var assigned func(deviceID string) bool
+	for _, name := range names {
+		id, ok := strings.CutPrefix(name, vdpaDevicePrefix)
+		if !ok || served[id] {
+			continue
+		}
+		log := rm.log.WithValues("deviceID", id, "vdpaDevice", name)
+		if assigned == nil {
+			if assigned, err = rm.assignedDevices(); err != nil {
+				log.Error(err, "Not deleting stale vDPA devices, unable to tell whether their devices are in use")
+				return
+			}
+		}
+		if rm.deviceInUse(id) || assigned(id) {
+			log.Info("Not deleting stale vDPA device of device allocated to a container")
+			continue
+		}
+		if err := utils.DeleteVdpaDevice(name); err != nil {
+			log.Error(err, "Unable to delete stale vDPA device")
+			continue
+		}
+		log.Info("Deleted stale vDPA device")
+	}
+}
+
+// createsVdpa tells whether any selector object of a resource config creates vDPA devices
+func createsVdpa(rc *types.ResourceConfig) bool {
+	for _, selector := range rc.SelectorObjs {
+		if createVdpaOf(selector) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+	utilmocks "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils/mocks"
+)
+
+var _ = Describe("Creating vDPA devices", func() {
+	var (
+		vdpaProvider *utilmocks.VdpaProvider
+		origProvider utils.VdpaProvider
+	)
+	newVdpaDevice := func(name string, vdpaType types.VdpaType) *mocks.VdpaDevice {
+		vdpaDev := &mocks.VdpaDevice{}
+		vdpaDev.On("GetParent").Return(name).
+			On("GetType").Return(vdpaType)
+		return vdpaDev
+	}
+	newDevice := func(id string, vdpaDev types.VdpaDevice) *mocks.PciNetDevice {
+		dev := &mocks.PciNetDevice{}
+		dev.On("GetDeviceID").Return(id).
+			On("GetVdpaDevice").Return(vdpaDev)
+		return dev
+	}
+	BeforeEach(func() {
+		vdpaProvider = &utilmocks.VdpaProvider{}
+		origProvider = utils.GetVdpaProvider()
+		utils.SetVdpaProviderInst(vdpaProvider)
+	})
+	AfterEach(func() {
+		utils.SetVdpaProviderInst(origProvider)
+	})
+
+	Describe("getting the vDPA type of a selector object", func() {
+		It("should only return the vdpaType when createVdpa is set", func() {
+			Expect(createVdpaOf(&types.NetDeviceSelectors{VdpaType: "vhost", CreateVdpa: true})).To(Equal(types.VdpaVhostType))
+			Expect(createVdpaOf(&types.NetDeviceSelectors{VdpaType: "vhost"})).To(BeEmpty())
+			Expect(createVdpaOf(&types.AccelDeviceSelectors{})).To(BeEmpty())
+		})
+	})
+	Describe("creating vDPA devices on the selected devices", func() {
+		var (
+			rm *resourceManager
+			rc *types.ResourceConfig
+			dp *mocks.DeviceProvider
+		)
+		BeforeEach(func() {
+			rc = &types.ResourceConfig{ResourceName: "pool", DeviceType: types.NetDeviceType}
+			rm = &resourceManager{cliParams: cliParams{resourcePrefix: "test"}, configList: []*types.ResourceConfig{rc}}
+			dp = &mocks.DeviceProvider{}
+		})
+		It("should keep devices that have a vDPA device without creating one", func() {
+			devs := []types.HostDevice{newDevice("0000:01:10.0", newVdpaDevice("vdpa0", types.VdpaVhostType))}
+
+			Expect(rm.createVdpaDevices(klog.Background(), dp, rc, 0, types.VdpaVhostType, devs)).To(Equal(devs))
+			vdpaProvider.AssertNotCalled(GinkgoT(), "AddVdpaDevice", "0000:01:10.0", "sriovdp-0000:01:10.0")
+			dp.AssertNotCalled(GinkgoT(), "GetDevices")
+		})
+		It("should create the missing vDPA devices and drop the devices failing to get one", func() {
+			fs := &utils.FakeFilesystem{
+				Dirs: []string{"sys/bus/vdpa/devices/sriovdp-0000:01:10.1", "sys/bus/vdpa/drivers/vhost_vdpa"},
+				Symlinks: map[string]string{
+					"sys/bus/vdpa/devices/sriovdp-0000:01:10.1/driver": "../../drivers/vhost_vdpa",
+				},
+			}
+			defer fs.Use()()
+			vdpaProvider.On("AddVdpaDevice", "0000:01:10.1", "sriovdp-0000:01:10.1").Return(nil).
+				On("AddVdpaDevice", "0000:01:10.2", "sriovdp-0000:01:10.2").Return(errors.New("not supported"))
+
+			devs := []types.HostDevice{
+				newDevice("0000:01:10.0", newVdpaDevice("vdpa0", types.VdpaVhostType)),
+				newDevice("0000:01:10.1", nil),
+				newDevice("0000:01:10.2", nil),
+			}
+			rebuilt := []types.HostDevice{
+				newDevice("0000:01:10.0", newVdpaDevice("vdpa0", types.VdpaVhostType)),
+				newDevice("0000:01:10.1", newVdpaDevice("sriovdp-0000:01:10.1", types.VdpaVhostType)),
+				newDevice("0000:01:10.2", nil),
+			}
+			dp.On("GetDevices", rc, 0).Return(rebuilt)
+
+			Expect(rm.createVdpaDevices(klog.Background(), dp, rc, 0, types.VdpaVhostType, devs)).To(Equal(rebuilt[:2]))
+		})
+	})
+	Describe("deleting created vDPA devices", func() {
+		It("should only delete the unused vDPA devices created for a pool on devices no longer served", func() {
+			checkpoint := &mocks.AllocationCheckpoint{}
+			checkpoint.On("GetDevices", "test/pool").Return([]string{"0000:01:10.3"})
+			rf := &mocks.ResourceFactory{}
+			rf.On("GetVdpaDevice", "0000:01:10.1").Return(newVdpaDevice("sriovdp-0000:01:10.1", types.VdpaVhostType)).
+				On("GetVdpaDevice", "0000:01:10.2").Return(newVdpaDevice("vdpa2", types.VdpaVhostType)).
+				On("GetVdpaDevice", "0000:01:10.3").Return(newVdpaDevice("sriovdp-0000:01:10.3", types.VdpaVhostType))
+			rm := &resourceManager{
+				cliParams:  cliParams{resourcePrefix: "test"},
+				configList: []*types.ResourceConfig{{ResourceName: "pool"}},
+				checkpoint: checkpoint,
+				rFactory:   rf,
+				log:        klog.Background(),
+			}
+			vdpaProvider.On("DeleteVdpaDevice", "sriovdp-0000:01:10.1").Return(nil)
+
+			old := map[string]*managedServer{
+				"test/pool": {
+					config: &types.ResourceConfig{SelectorObjs: []interface{}{
+						&types.NetDeviceSelectors{VdpaType: "vhost", CreateVdpa: true}}},
+					deviceIDs: []string{"0000:01:10.0", "0000:01:10.1", "0000:01:10.2", "0000:01:10.3"},
+				},
+				"test/other": {
+					config:    &types.ResourceConfig{SelectorObjs: []interface{}{&types.NetDeviceSelectors{VdpaType: "vhost"}}},
+					deviceIDs: []string{"0000:01:10.4"},
+				},
+			}
+			current := map[string]*managedServer{"test/pool": {deviceIDs: []string{"0000:01:10.0"}}}
+
+			rm.deleteCreatedVdpaDevices(old, current)
+			vdpaProvider.AssertNumberOfCalls(GinkgoT(), "DeleteVdpaDevice", 1)
+			rf.AssertNotCalled(GinkgoT(), "GetVdpaDevice", "0000:01:10.0")
+			rf.AssertNotCalled(GinkgoT(), "GetVdpaDevice", "0000:01:10.4")
+		})
+		It("should not delete the vDPA devices of devices claimed through DRA", func() {
+			rf := &mocks.ResourceFactory{}
+			rf.On("GetVdpaDevice", "0000:01:10.1").Return(newVdpaDevice("sriovdp-0000:01:10.1", types.VdpaVhostType)).
+				On("GetVdpaDevice", "0000:01:10.2").Return(newVdpaDevice("sriovdp-0000:01:10.2", types.VdpaVhostType))
+			podResources := &mocks.PodResourcesClient{}
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{}, nil).
+				On("GetClaimDevices", mock.Anything, "sriovnetwork.k8snetworkplumbingwg.io").Return([]string{"0000-01-10-2"}, nil)
+			rm := &resourceManager{
+				cliParams:    cliParams{resourcePrefix: "test", draMode: true, draDriverName: "sriovnetwork.k8snetworkplumbingwg.io"},
+				rFactory:     rf,
+				podResources: podResources,
+				log:          klog.Background(),
+			}
+			vdpaProvider.On("DeleteVdpaDevice", "sriovdp-0000:01:10.1").Return(nil)
+
+			pool := &mocks.ResourcePool{}
+			pool.On("GetConfig").Return(&types.ResourceConfig{ResourceName: "pool", SelectorObjs: []interface{}{
+				&types.NetDeviceSelectors{VdpaType: "vhost", CreateVdpa: true}}}).
+				On("GetDevicePool").Return(map[string]types.HostDevice{"0000:01:10.1": nil, "0000:01:10.2": nil})
+			old := rm.managedPools([]types.ResourcePool{pool})
+			Expect(old).To(HaveKey("test/pool"))
+
+			rm.deleteCreatedVdpaDevices(old, rm.managedPools(nil))
+			vdpaProvider.AssertNumberOfCalls(GinkgoT(), "DeleteVdpaDevice", 1)
+			vdpaProvider.AssertNotCalled(GinkgoT(), "DeleteVdpaDevice", "sriovdp-0000:01:10.2")
+		})
+	})
+	Describe("deleting stale vDPA devices", func() {
+		var (
+			rm           *resourceManager
+			podResources *mocks.PodResourcesClient
+			current      map[string]*managedServer
+		)
+		BeforeEach(func() {
+			checkpoint := &mocks.AllocationCheckpoint{}
+			checkpoint.On("GetDevices", "test/pool").Return([]string{"0000:01:10.3"})
+			podResources = &mocks.PodResourcesClient{}
+			rm = &resourceManager{
+				cliParams:    cliParams{resourcePrefix: "test"},
+				configList:   []*types.ResourceConfig{{ResourceName: "pool"}},
+				checkpoint:   checkpoint,
+				podResources: podResources,
+				log:          klog.Background(),
+			}
+			current = map[string]*managedServer{"test/pool": {deviceIDs: []string{"0000:01:10.0"}}}
+			vdpaProvider.On("ListVdpaDeviceNames").Return([]string{
+				"vdpa0", "sriovdp-0000:01:10.0", "sriovdp-0000:01:10.1", "sriovdp-0000:01:10.2", "sriovdp-0000:01:10.3",
+			}, nil)
+		})
+		It("should only delete the created vDPA devices of devices neither served nor in use", func() {
+			pod := types.DeviceAssignment{Namespace: "default", Pod: "pod", Container: "app"}
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{
+				"test/removed": {"0000:01:10.2": pod},
+			}, nil)
+			vdpaProvider.On("DeleteVdpaDevice", "sriovdp-0000:01:10.1").Return(nil)
+
+			rm.deleteStaleVdpaDevices(current)
+			vdpaProvider.AssertNumberOfCalls(GinkgoT(), "DeleteVdpaDevice", 1)
+			vdpaProvider.AssertCalled(GinkgoT(), "DeleteVdpaDevice", "sriovdp-0000:01:10.1")
+		})
+		It("should not delete any vDPA device when the PodResources API is unavailable", func() {
+			podResources.On("GetDeviceAssignments", mock.Anything).Return(nil, errors.New("no kubelet"))
+
+			rm.deleteStaleVdpaDevices(current)
+			vdpaProvider.AssertNotCalled(GinkgoT(), "DeleteVdpaDevice", mock.Anything)
+		})
+	})
+})
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+)
+
+// configSettleInterval is the time the config directory must stay unchanged before the config file is read
+var configSettleInterval = 2 * time.Second
+
+// configWatcher notifies when the content of the config file changes.
+// The parent directory is watched instead of the file itself since ConfigMap
+// volumes update their files by atomically swapping a symlink.
+type configWatcher struct {
+	configFile string
+	watcher    *fsnotify.Watcher
+	lastConfig []byte
+}
+
+// newConfigWatcher returns a configWatcher for the given config file
+func newConfigWatcher(configFile string) (*configWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create config file watcher: %v", err)
+	}
+	if err := watcher.Add(filepath.Dir(configFile)); err != nil {
+		_ = watcher.Close()
+		return nil, fmt.Errorf("unable to watch config file %s: %v", configFile, err)
+	}
+	// a missing file is not an error here; it will be reported on reload
+	lastConfig, _ := os.ReadFile(configFile)
+	return &configWatcher{
+		configFile: configFile,
+		watcher:    watcher,
+		lastConfig: lastConfig,
+	}, nil
+}
+
+// Run forwards config file changes to notifyCh until stopCh is closed.
+// Bursts of file system events are coalesced into a single notification.
+func (cw *configWatcher) Run(notifyCh chan<- struct{}, stopCh <-chan struct{}) {
+	defer cw.watcher.Close() //nolint:errcheck
+	settle := time.NewTimer(configSettleInterval)
+	settle.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case event, ok := <-cw.watcher.Events:
+			if !ok {
+				return
+			}
+			klog.V(2).InfoS("Config watcher event", "event", event.String())
+			settle.Reset(configSettleInterval)
+		case err, ok := <-cw.watcher.Errors:
+			if !ok {
+				return
+			}
+			klog.ErrorS(err, "Config watcher error")
+		case <-settle.C:
+			if cw.changed() {
+				select {
+				case notifyCh <- struct{}{}:
+				default: // a reload is already pending
+				}
+			}
+		}
+	}
+}
+
+// changed returns true if the config file content differs from the last seen content
+func (cw *configWatcher) changed() bool {
+	rawBytes, err := os.ReadFile(cw.configFile)
+	if err != nil {
+		klog.InfoS("Config watcher unable to read config file", "configFile", cw.configFile, "err", err)
+		return false
+	}
+	if bytes.Equal(rawBytes, cw.lastConfig) {
+		return false
+	}
+	cw.lastConfig = rawBytes
+	return true
+}
+
+// deviceWatcher notifies when PCI or auxiliary devices are added to or removed from the host,
+// e.g. when VFs are created through sriov_numvfs or SFs through devlink. Notifications are
+// only sent once the set of devices stayed the same for a whole interval, so that the
+// devices being created one after the other are picked up by a single rediscovery.
+type deviceWatcher struct {
+	interval    time.Duration
+	lastDevices []string
+	pending     bool
+}
+
+// newDeviceWatcher returns a deviceWatcher polling sysfs every interval
+func newDeviceWatcher(interval time.Duration) *deviceWatcher {
+	devices, err := utils.ListHostDevices()
+	if err != nil {
+		klog.InfoS("Device watcher unable to list host devices", "err", err)
+	}
+	return &deviceWatcher{
+		interval:    interval,
+		lastDevices: devices,
+	}
+}
+
+// Run forwards host device changes to notifyCh until stopCh is closed
+func (dw *deviceWatcher) Run(notifyCh chan<- struct{}, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(dw.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			if dw.settled() {
+				select {
+				case notifyCh <- struct{}{}:
+				default: // a rediscovery is already pending
+				}
+			}
+		}
+	}
+}
+
+// settled returns true if host devices changed and then stayed the same since the last poll
+func (dw *deviceWatcher) settled() bool {
+	devices, err := utils.ListHostDevices()
+	if err != nil {
+		klog.InfoS("Device watcher unable to list host devices", "err", err)
+		return false
+	}
+	if !slices.Equal(devices, dw.lastDevices) {
+		klog.V(2).InfoS("Device watcher detected host devices changes")
+		dw.lastDevices = devices
+		dw.pending = true
+		return false
+	}
+	if dw.pending {
+		dw.pending = false
+		return true
+	}
+	return false
+}
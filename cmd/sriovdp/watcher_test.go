@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+)
+
+var _ = Describe("Watchers", func() {
+	Describe("watching the config file", func() {
+		var (
+			dir, configFile string
+			origInterval    time.Duration
+			notifyCh        chan struct{}
+			stopCh          chan struct{}
+		)
+		// run starts watching the config file and returns the number of notifications received until the
+		// directory changes done by update settled
+		run := func(update func()) int {
+			cw, err := newConfigWatcher(configFile)
+			Expect(err).NotTo(HaveOccurred())
+			go cw.Run(notifyCh, stopCh)
+
+			update()
+			notifications := 0
+			Consistently(func() int {
+				select {
+				case <-notifyCh:
+					notifications++
+				default:
+				}
+				return notifications
+			}, 5*configSettleInterval, configSettleInterval/10).Should(BeNumerically("<=", 1))
+			return notifications
+		}
+		BeforeEach(func() {
+			origInterval = configSettleInterval
+			configSettleInterval = 100 * time.Millisecond
+			dir = GinkgoT().TempDir()
+			configFile = filepath.Join(dir, "config.json")
+			// notifications are buffered like the reload channel of handleEvents
+			notifyCh = make(chan struct{}, 1)
+			stopCh = make(chan struct{})
+		})
+		AfterEach(func() {
+			close(stopCh)
+			configSettleInterval = origInterval
+		})
+
+		It("should notify once when the config file is atomically replaced", func() {
+			Expect(os.WriteFile(configFile, []byte(`{"resourceList":[]}`), 0o644)).To(Succeed())
+
+			Expect(run(func() {
+				tmpFile := filepath.Join(dir, ".config.json.tmp")
+				Expect(os.WriteFile(tmpFile, []byte(`{"resourceList":[{"resourceName":"sriov"}]}`), 0o644)).To(Succeed())
+				Expect(os.Rename(tmpFile, configFile)).To(Succeed())
+			})).To(Equal(1))
+		})
+		It("should notify once when the data symlink of a ConfigMap volume is swapped", func() {
+			// ConfigMap volumes link the file to ..data/<file>, ..data being a symlink to a timestamped directory
+			Expect(os.Mkdir(filepath.Join(dir, "..2026_10_18_00_00_00.1"), 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "..2026_10_18_00_00_00.1", "config.json"),
+				[]byte(`{"resourceList":[]}`), 0o644)).To(Succeed())
+			Expect(os.Symlink("..2026_10_18_00_00_00.1", filepath.Join(dir, "..data"))).To(Succeed())
+			Expect(os.Symlink(filepath.Join("..data", "config.json"), configFile)).To(Succeed())
+
+			Expect(run(func() {
+				Expect(os.Mkdir(filepath.Join(dir, "..2026_10_18_00_01_00.2"), 0o755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(dir, "..2026_10_18_00_01_00.2", "config.json"),
+					[]byte(`{"resourceList":[{"resourceName":"sriov"}]}`), 0o644)).To(Succeed())
+				Expect(os.Symlink("..2026_10_18_00_01_00.2", filepath.Join(dir, "..data_tmp"))).To(Succeed())
+				Expect(os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data"))).To(Succeed())
+				Expect(os.RemoveAll(filepath.Join(dir, "..2026_10_18_00_00_00.1"))).To(Succeed())
+			})).To(Equal(1))
+		})
+		It("should not notify when other files of the directory change", func() {
+			Expect(os.WriteFile(configFile, []byte(`{"resourceList":[]}`), 0o644)).To(Succeed())
+
+			Expect(run(func() {
+				Expect(os.WriteFile(filepath.Join(dir, "other.json"), []byte(`{}`), 0o644)).To(Succeed())
+				Expect(os.Rename(filepath.Join(dir, "other.json"), filepath.Join(dir, "other.json.bak"))).To(Succeed())
+			})).To(BeZero())
+		})
+	})
+	Describe("watching host devices", func() {
+		It("should only notify once the host devices stopped changing", func() {
+			fs := &utils.FakeFilesystem{
+				Dirs: []string{"sys/bus/pci/devices/0000:3b:00.0", "sys/bus/auxiliary/devices/mlx5_core.sf.1"},
+			}
+			defer fs.Use()()
+			dw := newDeviceWatcher(time.Second)
+			Expect(dw.settled()).To(BeFalse())
+
+			// a VF is created, then another one before the next poll
+			Expect(os.Mkdir(filepath.Join(fs.RootDir, "sys/bus/pci/devices/0000:3b:00.2"), 0o755)).To(Succeed())
+			Expect(dw.settled()).To(BeFalse())
+			Expect(os.Mkdir(filepath.Join(fs.RootDir, "sys/bus/pci/devices/0000:3b:00.3"), 0o755)).To(Succeed())
+			Expect(dw.settled()).To(BeFalse())
+
+			Expect(dw.settled()).To(BeTrue())
+			Expect(dw.settled()).To(BeFalse())
+		})
+		It("should notify through Run once a change settled", func() {
+			fs := &utils.FakeFilesystem{Dirs: []string{"sys/bus/pci/devices/0000:3b:00.0"}}
+			defer fs.Use()()
+			notifyCh := make(chan struct{}, 1)
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			go newDeviceWatcher(50*time.Millisecond).Run(notifyCh, stopCh)
+
+			Consistently(notifyCh, 200*time.Millisecond).ShouldNot(Receive())
+			Expect(os.Mkdir(filepath.Join(fs.RootDir, "sys/bus/pci/devices/0000:3b:00.2"), 0o755)).To(Succeed())
+			Eventually(notifyCh, time.Second).Should(Receive())
+		})
+	})
+})
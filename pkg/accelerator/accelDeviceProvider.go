@@ -17,9 +17,10 @@ package accelerator
 import (
 	"fmt"
 
-	"github.com/golang/glog"
 	"github.com/jaypipes/ghw"
+	"k8s.io/klog/v2"
 
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/resources"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
 )
@@ -27,6 +28,7 @@ import (
 type accelDeviceProvider struct {
 	deviceList []*ghw.PCIDevice
 	rFactory   types.ResourceFactory
+	log        klog.Logger
 }
 
 // NewAccelDeviceProvider DeviceProvider implementation from accelDeviceProvider instance
@@ -34,6 +36,7 @@ func NewAccelDeviceProvider(rf types.ResourceFactory) types.DeviceProvider {
 	return &accelDeviceProvider{
 		rFactory:   rf,
 		deviceList: make([]*ghw.PCIDevice, 0),
+		log:        klog.Background().WithName("accelerator"),
 	}
 }
 
@@ -41,13 +44,18 @@ func (ap *accelDeviceProvider) GetDiscoveredDevices() []*ghw.PCIDevice {
 	return ap.deviceList
 }
 
+// ResetDevices clears the list of discovered devices
+func (ap *accelDeviceProvider) ResetDevices() {
+	ap.deviceList = make([]*ghw.PCIDevice, 0)
+}
+
 func (ap *accelDeviceProvider) GetDevices(rc *types.ResourceConfig, selectorIndex int) []types.HostDevice {
 	newHostDevices := make([]types.HostDevice, 0)
 	for _, device := range ap.deviceList {
 		if newDevice, err := NewAccelDevice(device, ap.rFactory, rc); err == nil {
 			newHostDevices = append(newHostDevices, newDevice)
 		} else {
-			glog.Errorf("accelerator GetDevices() error creating new device: %q", err)
+			ap.log.Error(err, "Error creating new device", "pciAddress", device.Address)
 		}
 	}
 	return newHostDevices
@@ -57,15 +65,15 @@ func (ap *accelDeviceProvider) AddTargetDevices(devices []*ghw.PCIDevice, device
 	for _, device := range devices {
 		devClass, err := utils.ParseDeviceID(device.Class.ID)
 		if err != nil {
-			glog.Warningf("accelerator AddTargetDevices(): unable to parse device class for device %+v %q", device, err)
+			ap.log.Info("Unable to parse device class", "pciAddress", device.Address, "err", err)
 			continue
 		}
 
 		if devClass == int64(deviceCode) {
 			vendorName := utils.NormalizeVendorName(device.Vendor.Name)
 			productName := utils.NormalizeProductName(device.Product.Name)
-			glog.Infof("accelerator AddTargetDevices(): device found: %-12s\t%-12s\t%-20s\t%-40s", device.Address,
-				device.Class.ID, vendorName, productName)
+			ap.log.Info("Device found", "pciAddress", device.Address, "class", device.Class.ID,
+				"vendor", vendorName, "product", productName)
 
 			ap.deviceList = append(ap.deviceList, device)
 		}
@@ -73,7 +81,6 @@ func (ap *accelDeviceProvider) AddTargetDevices(devices []*ghw.PCIDevice, device
 	return nil
 }
 
-//nolint:gocyclo
 func (ap *accelDeviceProvider) GetFilteredDevices(devices []types.HostDevice,
 	rc *types.ResourceConfig, selectorIndex int) ([]types.HostDevice, error) {
 	filteredDevice := devices
@@ -86,43 +93,75 @@ func (ap *accelDeviceProvider) GetFilteredDevices(devices []types.HostDevice,
 		return filteredDevice, fmt.Errorf("unable to convert SelectorObj to AccelDeviceSelectors")
 	}
 
+	log := ap.log.WithValues("resourceName", rc.ResourceName, "selectorIndex", selectorIndex)
+	return ap.filterDevices(log, filteredDevice, af), nil
+}
+
+// filterDevices returns the devices matching every selector of an AccelDeviceSelectors object
+func (ap *accelDeviceProvider) filterDevices(log klog.Logger, devices []types.HostDevice,
+	af *types.AccelDeviceSelectors) []types.HostDevice {
+	filteredDevice := devices
 	rf := ap.rFactory
 	// filter by vendor list
-	if len(af.Vendors) > 0 {
-		if selector, err := rf.GetSelector("vendors", af.Vendors); err == nil {
-			filteredDevice = selector.Filter(filteredDevice)
-		}
-	}
+	filteredDevice = rf.FilterBySelector(log, "vendors", af.Vendors, filteredDevice)
 
 	// filter by device list
-	if len(af.Devices) > 0 {
-		if selector, err := rf.GetSelector("devices", af.Devices); err == nil {
-			filteredDevice = selector.Filter(filteredDevice)
-		}
-	}
+	filteredDevice = rf.FilterBySelector(log, "devices", af.Devices, filteredDevice)
 
 	// filter by driver list
-	if len(af.Drivers) > 0 {
-		if selector, err := rf.GetSelector("drivers", af.Drivers); err == nil {
-			filteredDevice = selector.Filter(filteredDevice)
-		}
-	}
+	filteredDevice = rf.FilterBySelector(log, "drivers", af.Drivers, filteredDevice)
 
 	// filter by pciAddresses list
-	if len(af.PciAddresses) > 0 {
-		if selector, err := rf.GetSelector("pciAddresses", af.PciAddresses); err == nil {
-			filteredDevice = selector.Filter(filteredDevice)
-		}
-	}
+	filteredDevice = rf.FilterBySelector(log, "pciAddresses", af.PciAddresses, filteredDevice)
 
-	return filteredDevice, nil
+	// filter by nested selector groups
+	return resources.FilterBySelectorGroups(log, filteredDevice, af.Not, af.AnyOf, af.AllOf, ap.filterDevices)
 }
 
 func (ap *accelDeviceProvider) ValidConfig(rc *types.ResourceConfig) bool {
 	for _, selector := range rc.SelectorObjs {
-		_, ok := selector.(*types.AccelDeviceSelectors)
+		af, ok := selector.(*types.AccelDeviceSelectors)
 		if !ok {
-			glog.Errorf("unable to convert SelectorObjs to AccelDeviceSelectors")
+			ap.log.Error(nil, "Unable to convert SelectorObjs to AccelDeviceSelectors", "resourceName", rc.ResourceName)
+			return false
+		}
+		if af.BindDriver != "" && len(af.Drivers) > 0 {
+			ap.log.Error(nil, "Invalid config: bindDriver and drivers are mutually exclusive options", "resourceName", rc.ResourceName)
+			return false
+		}
+		if !ap.validPatterns(rc.ResourceName, af) || !ap.validSelectorGroups(rc.ResourceName, af) {
+			return false
+		}
+	}
+	return true
+}
+
+// validSelectorGroups checks that none of the nested selector groups is empty and that they only select devices
+func (ap *accelDeviceProvider) validSelectorGroups(resourceName string, af *types.AccelDeviceSelectors) bool {
+	for _, group := range resources.SelectorGroups(af.Not, af.AnyOf, af.AllOf) {
+		if resources.EmptySelectorGroup(group) {
+			ap.log.Error(nil, "Invalid config: empty not, anyOf or allOf selector group", "resourceName", resourceName)
+			return false
+		}
+		if group.BindDriver != "" {
+			ap.log.Error(nil, "Invalid config: bindDriver is not supported in not, anyOf and allOf groups", "resourceName", resourceName)
+			return false
+		}
+		if !ap.validSelectorGroups(resourceName, group) {
+			return false
+		}
+	}
+	return true
+}
+
+// validPatterns checks the glob and regular expression patterns of a selector object and its nested groups
+func (ap *accelDeviceProvider) validPatterns(resourceName string, af *types.AccelDeviceSelectors) bool {
+	if err := resources.ValidatePatterns(af.Drivers, af.PciAddresses); err != nil {
+		ap.log.Error(err, "Invalid config: invalid selector pattern", "resourceName", resourceName)
+		return false
+	}
+	for _, group := range resources.SelectorGroups(af.Not, af.AnyOf, af.AllOf) {
+		if group != nil && !ap.validPatterns(resourceName, group) {
 			return false
 		}
 	}
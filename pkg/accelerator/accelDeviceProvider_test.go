@@ -91,7 +91,7 @@ var _ = Describe("AcceleratorProvider", func() {
 
 			defer fs.Use()()
 
-			rf := factory.NewResourceFactory("fake", "fake", true, false)
+			rf := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			p := accelerator.NewAccelDeviceProvider(rf)
 			config := &types.ResourceConfig{
 				DeviceType: types.AcceleratorType,
@@ -147,7 +147,7 @@ var _ = Describe("AcceleratorProvider", func() {
 	Describe("getting Filtered devices", func() {
 		Context("using selectors", func() {
 			It("should correctly filter devices", func() {
-				rf := factory.NewResourceFactory("fake", "fake", false, false)
+				rf := factory.NewResourceFactory("fake", "fake", false, false, nil, nil)
 				p := accelerator.NewAccelDeviceProvider(rf)
 				all := make([]types.HostDevice, 5)
 				mocked := make([]mocks.AccelDevice, 5)
@@ -162,7 +162,8 @@ var _ = Describe("AcceleratorProvider", func() {
 						On("GetVendor").Return(ve[i]).
 						On("GetDeviceCode").Return(de[i]).
 						On("GetDriver").Return(md[i]).
-						On("GetPciAddr").Return(pa[i])
+						On("GetPciAddr").Return(pa[i]).
+						On("GetDeviceID").Return(pa[i])
 
 					all[i] = &mocked[i]
 				}
@@ -177,6 +178,17 @@ var _ = Describe("AcceleratorProvider", func() {
 					{"drivers", &types.AccelDeviceSelectors{DeviceSelectors: types.DeviceSelectors{Drivers: []string{"igb_uio"}}}, []types.HostDevice{all[0], all[1], all[2]}},
 					{"pciAddresses", &types.AccelDeviceSelectors{GenericPciDeviceSelectors: types.GenericPciDeviceSelectors{PciAddresses: []string{"0000:03:02.0", "0000:03:02.3"}}},
 						[]types.HostDevice{all[0], all[3]}},
+					{"not", &types.AccelDeviceSelectors{DeviceSelectors: types.DeviceSelectors{Drivers: []string{"igb_uio"}},
+						Not: &types.AccelDeviceSelectors{DeviceSelectors: types.DeviceSelectors{Vendors: []string{"8086"}}}},
+						[]types.HostDevice{all[2]}},
+					{"anyOf", &types.AccelDeviceSelectors{AnyOf: []*types.AccelDeviceSelectors{
+						{DeviceSelectors: types.DeviceSelectors{Devices: []string{"123a"}}},
+						{DeviceSelectors: types.DeviceSelectors{Drivers: []string{"vfio-pci"}}},
+					}}, []types.HostDevice{all[1], all[4]}},
+					{"allOf", &types.AccelDeviceSelectors{AllOf: []*types.AccelDeviceSelectors{
+						{DeviceSelectors: types.DeviceSelectors{Devices: []string{"abcd"}}},
+						{DeviceSelectors: types.DeviceSelectors{Vendors: []string{"1111"}}},
+					}}, []types.HostDevice{all[2]}},
 				}
 
 				for _, tc := range testCases {
@@ -217,7 +229,7 @@ var _ = Describe("AcceleratorProvider", func() {
 				Expect(actual).To(ConsistOf(matchingDevices))
 			})
 			It("should error if the selector index is out of bounds", func() {
-				rf := factory.NewResourceFactory("fake", "fake", false, false)
+				rf := factory.NewResourceFactory("fake", "fake", false, false, nil, nil)
 				p := accelerator.NewAccelDeviceProvider(rf)
 				devs := make([]types.HostDevice, 0)
 
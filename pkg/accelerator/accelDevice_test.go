@@ -52,7 +52,7 @@ var _ = Describe("Accelerator", func() {
 				}
 				defer fs.Use()()
 
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				in := newPciDeviceFn()
 				config := &types.ResourceConfig{}
 
@@ -98,7 +98,7 @@ var _ = Describe("Accelerator", func() {
 				}
 				defer fs.Use()()
 
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				in := newPciDeviceFn()
 				config := &types.ResourceConfig{}
 
@@ -121,7 +121,7 @@ var _ = Describe("Accelerator", func() {
 				}
 				defer fs.Use()()
 
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				in := newPciDeviceFn()
 				config := &types.ResourceConfig{}
 
@@ -145,7 +145,7 @@ var _ = Describe("Accelerator", func() {
 				}
 				defer fs.Use()()
 
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				in := newPciDeviceFn()
 				config := &types.ResourceConfig{ExcludeTopology: true}
 
@@ -163,7 +163,7 @@ var _ = Describe("Accelerator", func() {
 				}
 				defer fs.Use()()
 
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				in := newPciDeviceFn()
 				config := &types.ResourceConfig{}
 
@@ -184,7 +184,7 @@ var _ = Describe("Accelerator", func() {
 				}
 				defer fs.Use()()
 
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				in := newPciDeviceFn()
 				config := &types.ResourceConfig{}
 
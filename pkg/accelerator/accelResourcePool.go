@@ -15,7 +15,7 @@
 package accelerator
 
 import (
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/resources"
@@ -42,7 +42,7 @@ func NewAccelResourcePool(rc *types.ResourceConfig, devicePool map[string]types.
 
 // Overrides GetDeviceSpecs
 func (rp *accelResourcePool) GetDeviceSpecs(deviceIDs []string) []*pluginapi.DeviceSpec {
-	glog.Infof("GetDeviceSpecs(): for devices: %v", deviceIDs)
+	klog.V(4).InfoS("GetDeviceSpecs", "resourceName", rp.GetResourceName(), "deviceIDs", deviceIDs)
 	devSpecs := make([]*pluginapi.DeviceSpec, 0)
 
 	devicePool := rp.GetDevicePool()
@@ -20,8 +20,8 @@ package auxnetdevice
 import (
 	"fmt"
 
-	"github.com/golang/glog"
 	"github.com/jaypipes/ghw"
+	"k8s.io/klog/v2"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/devices"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/infoprovider"
@@ -34,18 +34,20 @@ type auxNetDevice struct {
 	types.HostDevice
 	devices.GenNetDevice
 	auxType string
+	vdpaDev types.VdpaDevice
 }
 
 // NewAuxNetDevice returns an instance of AciNetDevice interface
 func NewAuxNetDevice(dev *ghw.PCIDevice, deviceID string, rFactory types.ResourceFactory,
 	rc *types.ResourceConfig, selectorIndex int) (types.AuxNetDevice, error) {
+	var vdpaDev types.VdpaDevice
 	var nf *types.AuxNetDeviceSelectors
 	driverName, err := utils.GetDriverName(dev.Address)
 	if err != nil {
 		return nil, err
 	}
 
-	infoProviders := rFactory.GetDefaultInfoProvider(deviceID, driverName)
+	infoProviders := rFactory.GetDefaultInfoProvider(deviceID, driverName, rc.VfioMode)
 	if rc.AdditionalInfo != nil {
 		infoProviders = append(infoProviders, infoprovider.NewExtraInfoProvider(dev.Address, rc.AdditionalInfo))
 	}
@@ -56,13 +58,21 @@ func NewAuxNetDevice(dev *ghw.PCIDevice, deviceID string, rFactory types.Resourc
 		nf, ok = rc.SelectorObjs[selectorIndex].(*types.AuxNetDeviceSelectors)
 	}
 	if ok {
-		if nf.IsRdma {
+		// Add InfoProviders based on Selector data
+		if nf.VdpaType != "" {
+			vdpaDev = rFactory.GetAuxVdpaDevice(deviceID)
+			if vdpaDev == nil {
+				klog.InfoS("No vDPA device found", "deviceID", deviceID)
+			} else {
+				infoProviders = append(infoProviders, infoprovider.NewVdpaInfoProvider(nf.VdpaType, vdpaDev))
+			}
+		} else if nf.IsRdma {
 			rdmaSpec := rFactory.GetRdmaSpec(types.AuxNetDeviceType, deviceID)
 			if rdmaSpec.IsRdma() {
 				isRdma = true
-				infoProviders = append(infoProviders, infoprovider.NewRdmaInfoProvider(rdmaSpec))
+				infoProviders = append(infoProviders, infoprovider.NewRdmaInfoProvider(rdmaSpec, rc.Rdma))
 			} else {
-				glog.Warningf("RDMA resources for %s not found. Are RDMA modules loaded?", deviceID)
+				klog.InfoS("RDMA resources not found, are RDMA modules loaded?", "deviceID", deviceID)
 			}
 		}
 
@@ -70,17 +80,21 @@ func NewAuxNetDevice(dev *ghw.PCIDevice, deviceID string, rFactory types.Resourc
 			if infoprovider.VhostNetDeviceExist() {
 				infoProviders = append(infoProviders, infoprovider.NewVhostNetInfoProvider())
 			} else {
-				glog.Warningf("vhost-net is required in the configuration for %s but /dev/vhost-net doesn't exist", deviceID)
+				klog.InfoS("vhost-net is required in the configuration but /dev/vhost-net doesn't exist", "deviceID", deviceID)
 			}
 		}
 	}
 
-	hostDev, err := devices.NewHostDeviceImpl(dev, deviceID, rFactory, rc, infoProviders)
+	netDev, err := devices.NewGenNetDevice(deviceID, types.AuxNetDeviceType, isRdma)
 	if err != nil {
 		return nil, err
 	}
+	if netDev.GetUplinkRepresentor() != "" {
+		infoProviders = append(infoProviders,
+			infoprovider.NewRepresentorInfoProvider(netDev.GetUplinkRepresentor(), netDev.GetRepresentor()))
+	}
 
-	netDev, err := devices.NewGenNetDevice(deviceID, types.AuxNetDeviceType, isRdma)
+	hostDev, err := devices.NewHostDeviceImpl(dev, deviceID, rFactory, rc, infoProviders)
 	if err != nil {
 		return nil, err
 	}
@@ -94,9 +108,15 @@ func NewAuxNetDevice(dev *ghw.PCIDevice, deviceID string, rFactory types.Resourc
 		HostDevice:   hostDev,
 		GenNetDevice: *netDev,
 		auxType:      auxType,
+		vdpaDev:      vdpaDev,
 	}, nil
 }
 
 func (ad *auxNetDevice) GetAuxType() string {
 	return ad.auxType
 }
+
+// GetVdpaDevice returns the vDPA device of the auxiliary device, if any
+func (ad *auxNetDevice) GetVdpaDevice() types.VdpaDevice {
+	return ad.vdpaDev
+}
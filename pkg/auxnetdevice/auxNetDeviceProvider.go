@@ -20,9 +20,10 @@ package auxnetdevice
 import (
 	"fmt"
 
-	"github.com/golang/glog"
 	"github.com/jaypipes/ghw"
+	"k8s.io/klog/v2"
 
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/resources"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
 )
@@ -30,6 +31,7 @@ import (
 type auxNetDeviceProvider struct {
 	deviceList []*ghw.PCIDevice
 	rFactory   types.ResourceFactory
+	log        klog.Logger
 }
 
 // NewAuxNetDeviceProvider DeviceProvider implementation from auxNetDeviceProvider instance
@@ -37,6 +39,7 @@ func NewAuxNetDeviceProvider(rf types.ResourceFactory) types.DeviceProvider {
 	return &auxNetDeviceProvider{
 		rFactory:   rf,
 		deviceList: make([]*ghw.PCIDevice, 0),
+		log:        klog.Background().WithName("auxnetdevice"),
 	}
 }
 
@@ -44,6 +47,11 @@ func (ap *auxNetDeviceProvider) GetDiscoveredDevices() []*ghw.PCIDevice {
 	return ap.deviceList
 }
 
+// ResetDevices clears the list of discovered devices
+func (ap *auxNetDeviceProvider) ResetDevices() {
+	ap.deviceList = make([]*ghw.PCIDevice, 0)
+}
+
 func (ap *auxNetDeviceProvider) GetDevices(rc *types.ResourceConfig, selectorIndex int) []types.HostDevice {
 	newAuxDevices := make([]types.HostDevice, 0)
 	for _, device := range ap.deviceList {
@@ -51,20 +59,18 @@ func (ap *auxNetDeviceProvider) GetDevices(rc *types.ResourceConfig, selectorInd
 		auxDevs, err := utils.GetSriovnetProvider().GetAuxNetDevicesFromPci(device.Address)
 		if err == nil {
 			if len(auxDevs) == 0 {
-				glog.Warningf("auxnetdevice GetDevices(): no auxiliary devices for PCI %s", device.Address)
+				ap.log.Info("No auxiliary devices found", "pciAddress", device.Address)
 				continue
 			}
 			for _, auxDev := range auxDevs {
 				if newDevice, err := NewAuxNetDevice(device, auxDev, ap.rFactory, rc, selectorIndex); err == nil {
 					newAuxDevices = append(newAuxDevices, newDevice)
 				} else {
-					glog.Warningf("auxnetdevice GetDevices(): error creating new device %s PCI %s: %q",
-						auxDev, device.Address, err)
+					ap.log.Info("Error creating new device", "deviceID", auxDev, "pciAddress", device.Address, "err", err)
 				}
 			}
 		} else {
-			glog.Warningf("auxNetDevice GetDevices(): error getting auxnetdevices from device %s: %q",
-				device.Address, err)
+			ap.log.Info("Error getting auxiliary devices", "pciAddress", device.Address, "err", err)
 		}
 	}
 	return newAuxDevices
@@ -74,22 +80,21 @@ func (ap *auxNetDeviceProvider) AddTargetDevices(devices []*ghw.PCIDevice, devic
 	for _, device := range devices {
 		devClass, err := utils.ParseDeviceID(device.Class.ID)
 		if err != nil {
-			glog.Warningf("auxNetDevice AddTargetDevices(): unable to parse device class for device %+v %q", device, err)
+			ap.log.Info("Unable to parse device class", "pciAddress", device.Address, "err", err)
 			continue
 		}
 
 		if devClass == int64(deviceCode) {
 			vendorName := utils.NormalizeVendorName(device.Vendor.Name)
 			productName := utils.NormalizeProductName(device.Product.Name)
-			glog.Infof("auxnetdevice AddTargetDevices(): device found: %-12s\t%-12s\t%-20s\t%-40s", device.Address,
-				device.Class.ID, vendorName, productName)
+			ap.log.Info("Device found", "pciAddress", device.Address, "class", device.Class.ID,
+				"vendor", vendorName, "product", productName)
 			ap.deviceList = append(ap.deviceList, device)
 		}
 	}
 	return nil
 }
 
-//nolint:gocyclo
 func (ap *auxNetDeviceProvider) GetFilteredDevices(devices []types.HostDevice, rc *types.ResourceConfig,
 	selectorIndex int) ([]types.HostDevice, error) {
 	filteredDevice := devices
@@ -102,58 +107,43 @@ func (ap *auxNetDeviceProvider) GetFilteredDevices(devices []types.HostDevice, r
 		return filteredDevice, fmt.Errorf("unable to convert SelectorObj to AuxNetDeviceSelectors")
 	}
 
+	log := ap.log.WithValues("resourceName", rc.ResourceName, "selectorIndex", selectorIndex)
+	return ap.filterDevices(log, filteredDevice, nf), nil
+}
+
+// filterDevices returns the devices matching every selector of an AuxNetDeviceSelectors object
+//
+//nolint:gocyclo
+func (ap *auxNetDeviceProvider) filterDevices(log klog.Logger, devices []types.HostDevice,
+	nf *types.AuxNetDeviceSelectors) []types.HostDevice {
+	filteredDevice := devices
 	rf := ap.rFactory
 	// filter by vendor list
-	if len(nf.Vendors) > 0 {
-		if selector, err := rf.GetSelector("vendors", nf.Vendors); err == nil {
-			filteredDevice = selector.Filter(filteredDevice)
-		}
-	}
+	filteredDevice = rf.FilterBySelector(log, "vendors", nf.Vendors, filteredDevice)
 
 	// filter by device list
-	if len(nf.Devices) > 0 {
-		if selector, err := rf.GetSelector("devices", nf.Devices); err == nil {
-			filteredDevice = selector.Filter(filteredDevice)
-		}
-	}
+	filteredDevice = rf.FilterBySelector(log, "devices", nf.Devices, filteredDevice)
 
 	// filter by driver list
-	if len(nf.Drivers) > 0 {
-		if selector, err := rf.GetSelector("drivers", nf.Drivers); err == nil {
-			filteredDevice = selector.Filter(filteredDevice)
-		}
-	}
+	filteredDevice = rf.FilterBySelector(log, "drivers", nf.Drivers, filteredDevice)
 
 	// filter by auxiliary device type list
-	if len(nf.AuxTypes) > 0 {
-		if selector, err := rf.GetSelector("auxTypes", nf.AuxTypes); err == nil {
-			filteredDevice = selector.Filter(filteredDevice)
-		}
-	}
+	filteredDevice = rf.FilterBySelector(log, "auxTypes", nf.AuxTypes, filteredDevice)
 
 	// filter by PfNames list
-	if len(nf.PfNames) > 0 {
-		if selector, err := rf.GetSelector("pfNames", nf.PfNames); err == nil {
-			filteredDevice = selector.Filter(filteredDevice)
-		}
-	}
+	filteredDevice = rf.FilterBySelector(log, "pfNames", nf.PfNames, filteredDevice)
 
 	// filter by RootDevices list
-	if len(nf.RootDevices) > 0 {
-		if selector, err := rf.GetSelector("rootDevices", nf.RootDevices); err == nil {
-			filteredDevice = selector.Filter(filteredDevice)
-		}
-	}
+	filteredDevice = rf.FilterBySelector(log, "rootDevices", nf.RootDevices, filteredDevice)
 
 	// filter by linkTypes list
-	if len(nf.LinkTypes) > 0 {
-		if len(nf.LinkTypes) > 1 {
-			glog.Warningf("Link type selector should have a single value.")
-		}
-		if selector, err := rf.GetSelector("linkTypes", nf.LinkTypes); err == nil {
-			filteredDevice = selector.Filter(filteredDevice)
-		}
+	if len(nf.LinkTypes) > 1 {
+		log.Info("Link type selector should have a single value", "linkTypes", nf.LinkTypes)
 	}
+	filteredDevice = rf.FilterBySelector(log, "linkTypes", nf.LinkTypes, filteredDevice)
+
+	// filter by PF eswitch mode list
+	filteredDevice = rf.FilterBySelector(log, "eswitchModes", nf.EswitchModes, filteredDevice)
 
 	// filter for rdma devices
 	if nf.IsRdma {
@@ -163,10 +153,25 @@ func (ap *auxNetDeviceProvider) GetFilteredDevices(devices []types.HostDevice, r
 				rdmaDevices = append(rdmaDevices, dev)
 			}
 		}
+		resources.LogDroppedDevices(log, "isRdma", []string{"true"}, filteredDevice, rdmaDevices)
 		filteredDevice = rdmaDevices
 	}
 
-	return filteredDevice, nil
+	// filter for vDPA-capable devices
+	if nf.VdpaType != "" {
+		vdpaDevices := make([]types.HostDevice, 0)
+		for _, dev := range filteredDevice {
+			vdpaDev := dev.(types.AuxNetDevice).GetVdpaDevice()
+			if vdpaDev != nil && vdpaDev.GetType() == nf.VdpaType {
+				vdpaDevices = append(vdpaDevices, dev)
+			}
+		}
+		resources.LogDroppedDevices(log, "vdpaType", []string{string(nf.VdpaType)}, filteredDevice, vdpaDevices)
+		filteredDevice = vdpaDevices
+	}
+
+	// filter by nested selector groups
+	return resources.FilterBySelectorGroups(log, filteredDevice, nf.Not, nf.AnyOf, nf.AllOf, ap.filterDevices)
 }
 
 // ValidConfig performs validation of AuxNetDeviceSelectors
@@ -174,20 +179,68 @@ func (ap *auxNetDeviceProvider) ValidConfig(rc *types.ResourceConfig) bool {
 	for _, selector := range rc.SelectorObjs {
 		nf, ok := selector.(*types.AuxNetDeviceSelectors)
 		if !ok {
-			glog.Errorf("unable to convert SelectorObj to AuxNetDeviceSelectors")
+			ap.log.Error(nil, "Unable to convert SelectorObj to AuxNetDeviceSelectors", "resourceName", rc.ResourceName)
 			return false
 		}
 		if len(nf.AuxTypes) == 0 {
-			glog.Errorf("AuxTypes are not specified")
+			ap.log.Error(nil, "AuxTypes are not specified", "resourceName", rc.ResourceName)
 			return false
 		}
-		// Check that only supported auxiliary device types are specified
-		// TODO ATM only SFs are supported; review this in the future if new types are added
-		for _, auxType := range nf.AuxTypes {
-			if auxType != "sf" {
-				glog.Errorf("Only \"sf\" auxiliary device type currently supported")
-				return false
-			}
+		if nf.IsRdma && nf.VdpaType != "" {
+			ap.log.Error(nil, "Invalid config: VdpaType and IsRdma are mutually exclusive options", "resourceName", rc.ResourceName)
+			return false
+		}
+		if !ap.validAuxTypes(rc.ResourceName, nf) || !ap.validPatterns(rc.ResourceName, nf) ||
+			!ap.validSelectorGroups(rc.ResourceName, nf) {
+			return false
+		}
+	}
+	return true
+}
+
+// validAuxTypes checks that only supported auxiliary device types are specified
+func (ap *auxNetDeviceProvider) validAuxTypes(resourceName string, nf *types.AuxNetDeviceSelectors) bool {
+	// TODO ATM only SFs are supported; review this in the future if new types are added
+	for _, auxType := range nf.AuxTypes {
+		if auxType != "sf" {
+			ap.log.Error(nil, "Only \"sf\" auxiliary device type currently supported", "resourceName", resourceName,
+				"auxType", auxType)
+			return false
+		}
+	}
+	return true
+}
+
+// validSelectorGroups checks that the nested selector groups only select devices of supported auxiliary
+// device types. IsRdma, VdpaType and NeedVhostNet also set up the selected devices and are only supported at the
+// top level.
+func (ap *auxNetDeviceProvider) validSelectorGroups(resourceName string, nf *types.AuxNetDeviceSelectors) bool {
+	for _, group := range resources.SelectorGroups(nf.Not, nf.AnyOf, nf.AllOf) {
+		if resources.EmptySelectorGroup(group) {
+			ap.log.Error(nil, "Invalid config: empty not, anyOf or allOf selector group", "resourceName", resourceName)
+			return false
+		}
+		if group.IsRdma || group.VdpaType != "" || group.NeedVhostNet {
+			ap.log.Error(nil, "Invalid config: isRdma, vdpaType and needVhostNet are not supported in not, anyOf and allOf groups",
+				"resourceName", resourceName)
+			return false
+		}
+		if !ap.validAuxTypes(resourceName, group) || !ap.validSelectorGroups(resourceName, group) {
+			return false
+		}
+	}
+	return true
+}
+
+// validPatterns checks the glob and regular expression patterns of a selector object and its nested groups
+func (ap *auxNetDeviceProvider) validPatterns(resourceName string, nf *types.AuxNetDeviceSelectors) bool {
+	if err := resources.ValidatePatterns(nf.Drivers, nf.PfNames, nf.RootDevices); err != nil {
+		ap.log.Error(err, "Invalid config: invalid selector pattern", "resourceName", resourceName)
+		return false
+	}
+	for _, group := range resources.SelectorGroups(nf.Not, nf.AnyOf, nf.AllOf) {
+		if group != nil && !ap.validPatterns(resourceName, group) {
+			return false
 		}
 	}
 	return true
@@ -18,6 +18,8 @@
 package auxnetdevice_test
 
 import (
+	"fmt"
+
 	"github.com/jaypipes/ghw"
 	"github.com/jaypipes/pcidb"
 	. "github.com/onsi/ginkgo/v2"
@@ -35,7 +37,7 @@ import (
 var _ = Describe("AuxNetDeviceProvider", func() {
 	DescribeTable("validating configuration",
 		func(rc *types.ResourceConfig, expected bool) {
-			rf := factory.NewResourceFactory("fake", "fake", true, false)
+			rf := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			p := auxnetdevice.NewAuxNetDeviceProvider(rf)
 			actual := p.ValidConfig(rc)
 			Expect(actual).To(Equal(expected))
@@ -58,6 +60,42 @@ var _ = Describe("AuxNetDeviceProvider", func() {
 		Entry("supported auxiliary device types",
 			&types.ResourceConfig{SelectorObjs: []interface{}{&types.AuxNetDeviceSelectors{AuxTypes: []string{"sf", "sf"}}}},
 			true),
+		Entry("unsupported auxiliary device types specified in nested group",
+			&types.ResourceConfig{SelectorObjs: []interface{}{&types.AuxNetDeviceSelectors{AuxTypes: []string{"sf"},
+				AnyOf: []*types.AuxNetDeviceSelectors{{AuxTypes: []string{"eth"}}}}}},
+			false),
+		Entry("isRdma specified in nested group",
+			&types.ResourceConfig{SelectorObjs: []interface{}{&types.AuxNetDeviceSelectors{AuxTypes: []string{"sf"},
+				Not: &types.AuxNetDeviceSelectors{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{IsRdma: true}}}}},
+			false),
+		Entry("isRdma and vdpaType specified",
+			&types.ResourceConfig{SelectorObjs: []interface{}{&types.AuxNetDeviceSelectors{AuxTypes: []string{"sf"}, VdpaType: "vhost",
+				GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{IsRdma: true}}}},
+			false),
+		Entry("vdpaType specified in nested group",
+			&types.ResourceConfig{SelectorObjs: []interface{}{&types.AuxNetDeviceSelectors{AuxTypes: []string{"sf"},
+				AnyOf: []*types.AuxNetDeviceSelectors{{VdpaType: "vhost"}}}}},
+			false),
+		Entry("empty nested group",
+			&types.ResourceConfig{SelectorObjs: []interface{}{&types.AuxNetDeviceSelectors{AuxTypes: []string{"sf"},
+				AllOf: []*types.AuxNetDeviceSelectors{nil}}}},
+			false),
+		Entry("nested group without selectors",
+			&types.ResourceConfig{SelectorObjs: []interface{}{&types.AuxNetDeviceSelectors{AuxTypes: []string{"sf"},
+				Not: &types.AuxNetDeviceSelectors{}}}},
+			false),
+		Entry("invalid pfNames pattern",
+			&types.ResourceConfig{SelectorObjs: []interface{}{&types.AuxNetDeviceSelectors{AuxTypes: []string{"sf"},
+				GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{PfNames: []string{"^ens[0-9+f0"}}}}},
+			false),
+		Entry("invalid drivers pattern in nested group",
+			&types.ResourceConfig{SelectorObjs: []interface{}{&types.AuxNetDeviceSelectors{AuxTypes: []string{"sf"},
+				Not: &types.AuxNetDeviceSelectors{DeviceSelectors: types.DeviceSelectors{Drivers: []string{"mlx5[_"}}}}}},
+			false),
+		Entry("supported nested groups",
+			&types.ResourceConfig{SelectorObjs: []interface{}{&types.AuxNetDeviceSelectors{AuxTypes: []string{"sf"},
+				Not: &types.AuxNetDeviceSelectors{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{PfNames: []string{"eth0"}}}}}},
+			true),
 	)
 	Describe("getting new instance of auxNetDeviceProvider", func() {
 		Context("with correct arguments", func() {
@@ -131,7 +169,7 @@ var _ = Describe("AuxNetDeviceProvider", func() {
 				On("GetAuxNetDevicesFromPci", "0000:02:00.0").Return([]string{}, nil)
 			utils.SetSriovnetProviderInst(&fakeSriovnetProvider)
 
-			rf := factory.NewResourceFactory("fake", "fake", true, false)
+			rf := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			p := auxnetdevice.NewAuxNetDeviceProvider(rf)
 			config := &types.ResourceConfig{
 				DeviceType: types.AuxNetDeviceType,
@@ -179,7 +217,7 @@ var _ = Describe("AuxNetDeviceProvider", func() {
 	Describe("getting Filtered devices", func() {
 		Context("using selectors", func() {
 			It("should correctly filter devices", func() {
-				rf := factory.NewResourceFactory("fake", "fake", false, false)
+				rf := factory.NewResourceFactory("fake", "fake", false, false, nil, nil)
 				p := auxnetdevice.NewAuxNetDeviceProvider(rf)
 				all := make([]types.HostDevice, 5)
 				mocked := make([]tmocks.AuxNetDevice, 5)
@@ -192,6 +230,12 @@ var _ = Describe("AuxNetDeviceProvider", func() {
 				lt := []string{"ether", "infiniband", "ether", "ether", "ether"}
 				rd := []bool{false, true, false, false, true}
 				at := []string{"eth", "rdma", "sf", "sf", "eth"}
+				em := []string{"legacy", "switchdev", "switchdev", "switchdev", "legacy"}
+				vdpaVhost := &tmocks.VdpaDevice{}
+				vdpaVhost.On("GetType").Return(types.VdpaVhostType)
+				vdpaVirtio := &tmocks.VdpaDevice{}
+				vdpaVirtio.On("GetType").Return(types.VdpaVirtioType)
+				vd := []types.VdpaDevice{nil, nil, vdpaVhost, vdpaVirtio, nil}
 
 				for i := range mocked {
 					mocked[i].
@@ -203,7 +247,10 @@ var _ = Describe("AuxNetDeviceProvider", func() {
 						On("GetLinkType").Return(lt[i]).
 						On("GetFuncID").Return(-1).
 						On("IsRdma").Return(rd[i]).
-						On("GetAuxType").Return(at[i])
+						On("GetAuxType").Return(at[i]).
+						On("GetEswitchMode").Return(em[i]).
+						On("GetVdpaDevice").Return(vd[i]).
+						On("GetDeviceID").Return(fmt.Sprintf("dev.%d", i))
 
 					all[i] = &mocked[i]
 				}
@@ -220,8 +267,18 @@ var _ = Describe("AuxNetDeviceProvider", func() {
 					{"rootDevices", &types.AuxNetDeviceSelectors{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{RootDevices: []string{"0000:86:00.0", "0000:86:00.4"}}}, []types.HostDevice{all[0], all[4]}},
 					{"linkTypes", &types.AuxNetDeviceSelectors{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{LinkTypes: []string{"infiniband"}}}, []types.HostDevice{all[1]}},
 					{"linkTypes multi", &types.AuxNetDeviceSelectors{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{LinkTypes: []string{"infiniband", "ether"}}}, all},
+					{"eswitchModes", &types.AuxNetDeviceSelectors{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{EswitchModes: []string{"legacy"}}},
+						[]types.HostDevice{all[0], all[4]}},
 					{"rdma", &types.AuxNetDeviceSelectors{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{IsRdma: true}}, []types.HostDevice{all[1], all[4]}},
 					{"auxTypes", &types.AuxNetDeviceSelectors{AuxTypes: []string{"sf", "sf"}}, []types.HostDevice{all[2], all[3]}},
+					{"vdpaType", &types.AuxNetDeviceSelectors{VdpaType: "vhost"}, []types.HostDevice{all[2]}},
+					{"not", &types.AuxNetDeviceSelectors{DeviceSelectors: types.DeviceSelectors{Vendors: []string{"15b3"}},
+						Not: &types.AuxNetDeviceSelectors{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{LinkTypes: []string{"infiniband"}}}},
+						[]types.HostDevice{all[2], all[3]}},
+					{"anyOf", &types.AuxNetDeviceSelectors{AnyOf: []*types.AuxNetDeviceSelectors{
+						{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{PfNames: []string{"eth0"}}},
+						{DeviceSelectors: types.DeviceSelectors{Devices: []string{"101b"}}},
+					}}, []types.HostDevice{all[0], all[1]}},
 				}
 
 				for _, tc := range testCases {
@@ -263,7 +320,7 @@ var _ = Describe("AuxNetDeviceProvider", func() {
 				Expect(actual).To(ConsistOf(matchingDevices))
 			})
 			It("should error if the selector index is out of bounds", func() {
-				rf := factory.NewResourceFactory("fake", "fake", false, false)
+				rf := factory.NewResourceFactory("fake", "fake", false, false, nil, nil)
 				p := auxnetdevice.NewAuxNetDeviceProvider(rf)
 				devs := make([]types.HostDevice, 0)
 
@@ -75,7 +75,7 @@ var _ = Describe("AuxNetDevice", func() {
 					On("GetNetDevicesFromAux", auxDevID).Return([]string{"eth0"}, nil)
 				utils.SetSriovnetProviderInst(&fakeSriovnetProvider)
 
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				in := newPciDevice("0000:00:00.1")
 				rc := &types.ResourceConfig{}
 
@@ -127,7 +127,8 @@ var _ = Describe("AuxNetDevice", func() {
 			}
 			rdma1.On("IsRdma").Return(true).
 				On("GetRdmaDeviceSpec").Return(fake1ds).
-				On("GetRdmaDeviceName").Return("mlx5_3")
+				On("GetRdmaDeviceName").Return("mlx5_3").
+				On("GetRdmaPortInfo").Return(nil)
 
 			rdma2 := &tmocks.RdmaSpec{}
 			rdma2.On("IsRdma").Return(false)
@@ -150,8 +151,8 @@ var _ = Describe("AuxNetDevice", func() {
 			mockInfo2.On("GetEnvVal").Return(mockEnv2)
 			mockInfo2.On("GetDeviceSpecs").Return(nil)
 			mockInfo2.On("GetMounts").Return(nil)
-			f.On("GetDefaultInfoProvider", auxDevName1, "mlx5_core").Return([]types.DeviceInfoProvider{mockInfo1}).
-				On("GetDefaultInfoProvider", auxDevName2, "mlx5_core").Return([]types.DeviceInfoProvider{mockInfo2}).
+			f.On("GetDefaultInfoProvider", auxDevName1, "mlx5_core", types.VfioMode("")).Return([]types.DeviceInfoProvider{mockInfo1}).
+				On("GetDefaultInfoProvider", auxDevName2, "mlx5_core", types.VfioMode("")).Return([]types.DeviceInfoProvider{mockInfo2}).
 				On("GetRdmaSpec", types.AuxNetDeviceType, auxDevName1).Return(rdma1).
 				On("GetRdmaSpec", types.AuxNetDeviceType, auxDevName2).Return(rdma2)
 
@@ -271,7 +272,7 @@ var _ = Describe("AuxNetDevice", func() {
 					On("GetNetDevicesFromAux", auxDevID).Return([]string{"eth0"}, nil)
 				utils.SetSriovnetProviderInst(&fakeSriovnetProvider)
 
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				in := newPciDevice("0000:00:00.1")
 				rc := &types.ResourceConfig{
 					ResourceName:   "fake",
@@ -302,6 +303,61 @@ var _ = Describe("AuxNetDevice", func() {
 			})
 		})
 
+		Context("with vdpaType", func() {
+			It("should provide the vDPA device and its device specs", func() {
+				fs := &utils.FakeFilesystem{
+					Dirs: []string{
+						"sys/bus/pci/devices/0000:00:00.1/net/net0",
+						"sys/bus/pci/drivers/mlx5_core",
+					},
+					Symlinks: map[string]string{
+						"sys/bus/pci/devices/0000:00:00.1/driver": "../../../../bus/pci/drivers/mlx5_core",
+					},
+					Files: map[string][]byte{"sys/bus/pci/devices/0000:00:00.1/numa_node": []byte("0")},
+				}
+				defer fs.Use()()
+				utils.SetDefaultMockNetlinkProvider()
+				auxDevID := "mlx5_core.sf.0"
+				fakeSriovnetProvider := mocks.SriovnetProvider{}
+				fakeSriovnetProvider.
+					On("GetUplinkRepresentorFromAux", auxDevID).Return("net0", nil).
+					On("GetPfPciFromAux", auxDevID).Return("0000:00:00.1", nil).
+					On("GetSfIndexByAuxDev", auxDevID).Return(1, nil).
+					On("GetNetDevicesFromAux", auxDevID).Return([]string{"eth0"}, nil)
+				utils.SetSriovnetProviderInst(&fakeSriovnetProvider)
+
+				vdpaDev := &tmocks.VdpaDevice{}
+				vdpaDev.On("GetType").Return(types.VdpaVhostType).
+					On("GetPath").Return("/dev/vhost-vdpa-1", nil)
+				mockInfo := &tmocks.DeviceInfoProvider{}
+				mockInfo.On("GetName").Return("generic").
+					On("GetEnvVal").Return(types.AdditionalInfo{"deviceID": auxDevID}).
+					On("GetDeviceSpecs").Return(nil).
+					On("GetMounts").Return(nil)
+				f := &tmocks.ResourceFactory{}
+				f.On("GetDefaultInfoProvider", auxDevID, "mlx5_core", types.VfioMode("")).Return([]types.DeviceInfoProvider{mockInfo}).
+					On("GetAuxVdpaDevice", auxDevID).Return(vdpaDev)
+
+				in := newPciDevice("0000:00:00.1")
+				rc := &types.ResourceConfig{
+					ResourceName:   "fake",
+					ResourcePrefix: "fake",
+					DeviceType:     types.AuxNetDeviceType,
+					SelectorObjs:   []interface{}{&types.AuxNetDeviceSelectors{VdpaType: types.VdpaVhostType}},
+				}
+
+				dev, err := auxnetdevice.NewAuxNetDevice(in, auxDevID, f, rc, 0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dev).NotTo(BeNil())
+				Expect(dev.GetVdpaDevice()).To(Equal(vdpaDev))
+				Expect(dev.IsRdma()).To(BeFalse())
+				Expect(dev.GetDeviceSpecs()).To(ConsistOf(
+					And(HaveField("HostPath", "/dev/vhost-vdpa-1"), HaveField("ContainerPath", "/dev/vhost-vdpa-1")),
+				))
+				f.AssertNotCalled(t, "GetRdmaSpec", types.AuxNetDeviceType, auxDevID)
+			})
+		})
+
 		Context("with needVhostNet", func() {
 			It("should provide expected environment variables and mounts", func() {
 				fs := &utils.FakeFilesystem{
@@ -331,7 +387,7 @@ var _ = Describe("AuxNetDevice", func() {
 					On("GetNetDevicesFromAux", auxDevID).Return([]string{"eth0"}, nil)
 				utils.SetSriovnetProviderInst(&fakeSriovnetProvider)
 
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				in := newPciDevice("0000:00:00.1")
 				rc := &types.ResourceConfig{
 					ResourceName:   "fake",
@@ -400,7 +456,7 @@ var _ = Describe("AuxNetDevice", func() {
 					On("GetNetDevicesFromAux", auxDevID).Return([]string{"eth0"}, nil)
 				utils.SetSriovnetProviderInst(&fakeSriovnetProvider)
 
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				in := newPciDevice("0000:00:00.1")
 				rc := &types.ResourceConfig{
 					ResourceName:   "fake",
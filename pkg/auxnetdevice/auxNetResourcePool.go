@@ -18,11 +18,17 @@
 package auxnetdevice
 
 import (
-	"github.com/golang/glog"
+	"fmt"
+	"strings"
+
+	nettypes "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/devices"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/resources"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
 )
 
 const (
@@ -31,21 +37,24 @@ const (
 
 type auxNetResourcePool struct {
 	*resources.ResourcePoolImpl
+	nadutils types.NadUtils
 }
 
 var _ types.ResourcePool = &auxNetResourcePool{}
 
 // NewAuxNetResourcePool returns an instance of resourcePool
-func NewAuxNetResourcePool(rc *types.ResourceConfig, devicePool map[string]types.HostDevice) types.ResourcePool {
+func NewAuxNetResourcePool(nadutils types.NadUtils, rc *types.ResourceConfig,
+	devicePool map[string]types.HostDevice) types.ResourcePool {
 	rp := resources.NewResourcePool(rc, devicePool)
 	return &auxNetResourcePool{
 		ResourcePoolImpl: rp,
+		nadutils:         nadutils,
 	}
 }
 
 // Overrides GetDeviceSpecs
 func (ap *auxNetResourcePool) GetDeviceSpecs(deviceIDs []string) []*pluginapi.DeviceSpec {
-	glog.Infof("GetDeviceSpecs(): for devices: %v", deviceIDs)
+	klog.V(4).InfoS("GetDeviceSpecs", "resourceName", ap.GetResourceName(), "deviceIDs", deviceIDs)
 	devSpecs := make([]*pluginapi.DeviceSpec, 0)
 
 	devicePool := ap.GetDevicePool()
@@ -69,3 +78,80 @@ func (ap *auxNetResourcePool) GetDeviceSpecs(deviceIDs []string) []*pluginapi.De
 func (ap *auxNetResourcePool) GetCDIName() string {
 	return auxPoolType
 }
+
+// StoreDeviceInfoFile stores the Device Info files according to the
+// k8snetworkplumbingwg/device-info-spec
+// for the requested deviceIDs. Devices backed by a vDPA device get a vdpa record, the others an auxiliary one.
+func (ap *auxNetResourcePool) StoreDeviceInfoFile(resourceNamePrefix string, deviceIDs []string) error {
+	devicePool := ap.GetDevicePool()
+	resource := fmt.Sprintf("%s/%s", resourceNamePrefix, ap.GetConfig().ResourceName)
+	for _, id := range deviceIDs {
+		auxDev, ok := devicePool[id].(types.AuxNetDevice)
+		if !ok {
+			return fmt.Errorf("storeDeviceInfoFile: Only auxNetDevices are supported")
+		}
+		if err := ap.nadutils.CleanDeviceInfoFile(resource, id); err != nil {
+			return err
+		}
+		var err error
+		if vdpaDev := auxDev.GetVdpaDevice(); vdpaDev != nil {
+			err = ap.nadutils.SaveDeviceInfoFile(resource, id, vdpaDeviceInfo(auxDev, vdpaDev))
+		} else {
+			err = ap.nadutils.SaveAuxDeviceInfoFile(resource, id, auxDeviceInfo(auxDev))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vdpaDeviceInfo returns the Device Info of an auxiliary device backed by a vDPA device
+func vdpaDeviceInfo(auxDev types.AuxNetDevice, vdpaDev types.VdpaDevice) *nettypes.DeviceInfo {
+	vdpaDevice := devices.GetVdpaDeviceInfo(vdpaDev)
+	vdpaDevice.PfPciAddress = auxDev.GetPfPciAddr()
+	if auxDev.GetEswitchMode() == utils.EswitchModeSwitchdev {
+		vdpaDevice.RepresentorDevice = auxDev.GetRepresentor()
+	}
+	return &nettypes.DeviceInfo{
+		Type:    nettypes.DeviceInfoTypeVDPA,
+		Version: nettypes.DeviceInfoVersion,
+		Vdpa:    vdpaDevice,
+	}
+}
+
+// auxDeviceInfo returns the Device Info of an auxiliary device
+func auxDeviceInfo(auxDev types.AuxNetDevice) *types.AuxiliaryDevice {
+	info := &types.AuxiliaryDevice{
+		Name:         auxDev.GetDeviceID(),
+		PfPciAddress: auxDev.GetPfPciAddr(),
+	}
+	if auxDev.GetEswitchMode() == utils.EswitchModeSwitchdev {
+		info.RepresentorDevice = auxDev.GetRepresentor()
+	}
+	if auxDev.IsRdma() {
+		rdmaDevices := utils.GetRdmaProvider().GetRdmaDevicesForAuxdev(info.Name)
+		if len(rdmaDevices) == 0 {
+			klog.ErrorS(nil, "No RDMA devices available for RDMA capable device", "deviceID", info.Name)
+		} else {
+			info.RdmaDevice = strings.Join(rdmaDevices, ",")
+		}
+	}
+	return info
+}
+
+// CleanDeviceInfoFile cleans the Device Info files of the given deviceIDs
+func (ap *auxNetResourcePool) CleanDeviceInfoFile(resourceNamePrefix string, deviceIDs []string) error {
+	errors := make([]string, 0)
+	resource := fmt.Sprintf("%s/%s", resourceNamePrefix, ap.GetConfig().ResourceName)
+	for _, id := range deviceIDs {
+		if err := ap.nadutils.CleanDeviceInfoFile(resource, id); err != nil {
+			// Continue trying to clean.
+			errors = append(errors, err.Error())
+		}
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, ","))
+	}
+	return nil
+}
@@ -18,13 +18,19 @@
 package auxnetdevice_test
 
 import (
+	"fmt"
+
+	nettypes "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/auxnetdevice"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+	utilmocks "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils/mocks"
 )
 
 var _ = Describe("AuxNetResourcePool", func() {
@@ -36,7 +42,7 @@ var _ = Describe("AuxNetResourcePool", func() {
 		}
 		devs := map[string]types.HostDevice{}
 
-		rp := auxnetdevice.NewAuxNetResourcePool(rc, devs)
+		rp := auxnetdevice.NewAuxNetResourcePool(&mocks.NadUtils{}, rc, devs)
 
 		It("should return a valid instance of the pool", func() {
 			Expect(rp).ToNot(BeNil())
@@ -74,7 +80,7 @@ var _ = Describe("AuxNetResourcePool", func() {
 
 			devs := map[string]types.HostDevice{"fake1": fake1, "fake2": fake2, "fake3": fake3}
 
-			rp := auxnetdevice.NewAuxNetResourcePool(rc, devs)
+			rp := auxnetdevice.NewAuxNetResourcePool(&mocks.NadUtils{}, rc, devs)
 
 			devIDs := []string{"fake1", "fake2"}
 
@@ -89,4 +95,130 @@ var _ = Describe("AuxNetResourcePool", func() {
 			})
 		})
 	})
+	Describe("device info files", func() {
+		Context("for vdpa devices", func() {
+			rc := &types.ResourceConfig{
+				ResourceName:   "fakeResource",
+				ResourcePrefix: "fakeOrg.io",
+				SelectorObjs:   []interface{}{&types.AuxNetDeviceSelectors{VdpaType: "vhost"}},
+			}
+
+			fakeVdpa := &mocks.VdpaDevice{}
+			fakeVdpa.On("GetParent").Return("vdpa1").
+				On("GetPath").Return("/dev/vhost-vdpa5", nil).
+				On("GetType").Return(types.VdpaVhostType)
+
+			fake1 := &mocks.AuxNetDevice{}
+			fake1.On("GetVdpaDevice").Return(fakeVdpa).
+				On("GetPfPciAddr").Return("0000:01:00.0").
+				On("GetEswitchMode").Return("switchdev").
+				On("GetRepresentor").Return("pf0sf1")
+			devs := map[string]types.HostDevice{"fake1": fake1}
+
+			It("should call nadutils to create a well formatted DeviceInfo object", func() {
+				nadutils := &mocks.NadUtils{}
+				nadutils.On("SaveDeviceInfoFile", "fakeOrg.io/fakeResource", "fake1", mock.Anything).
+					Return(func(rName, id string, devInfo *nettypes.DeviceInfo) error {
+						if devInfo.Type != nettypes.DeviceInfoTypeVDPA ||
+							devInfo.Vdpa == nil ||
+							devInfo.Vdpa.ParentDevice != "vdpa1" ||
+							devInfo.Vdpa.Driver != "vhost" ||
+							devInfo.Vdpa.Path != "/dev/vhost-vdpa5" ||
+							devInfo.Vdpa.PfPciAddress != "0000:01:00.0" ||
+							devInfo.Vdpa.RepresentorDevice != "pf0sf1" {
+							return fmt.Errorf("wrong device info %+v", devInfo)
+						}
+						return nil
+					})
+				nadutils.On("CleanDeviceInfoFile", "fakeOrg.io/fakeResource", "fake1").Return(nil)
+
+				rp := auxnetdevice.NewAuxNetResourcePool(nadutils, rc, devs)
+				err := rp.StoreDeviceInfoFile("fakeOrg.io", []string{"fake1"})
+				Expect(err).ToNot(HaveOccurred())
+				nadutils.AssertExpectations(GinkgoT())
+			})
+		})
+		Context("for auxiliary devices", func() {
+			rc := &types.ResourceConfig{
+				ResourceName:   "fakeResource",
+				ResourcePrefix: "fakeOrg.io",
+				SelectorObjs:   []interface{}{&types.AuxNetDeviceSelectors{AuxTypes: []string{"sf"}}},
+			}
+
+			fake1 := &mocks.AuxNetDevice{}
+			fake1.On("GetVdpaDevice").Return(nil).
+				On("GetDeviceID").Return("mlx5_core.sf.1").
+				On("GetPfPciAddr").Return("0000:01:00.0").
+				On("GetEswitchMode").Return("switchdev").
+				On("GetRepresentor").Return("pf0sf1").
+				On("IsRdma").Return(true)
+			fake2 := &mocks.AuxNetDevice{}
+			fake2.On("GetVdpaDevice").Return(nil).
+				On("GetDeviceID").Return("mlx5_core.sf.2").
+				On("GetPfPciAddr").Return("0000:01:00.0").
+				On("GetEswitchMode").Return("legacy").
+				On("IsRdma").Return(false)
+
+			devs := map[string]types.HostDevice{"mlx5_core.sf.1": fake1, "mlx5_core.sf.2": fake2}
+			deviceIDs := []string{"mlx5_core.sf.1", "mlx5_core.sf.2"}
+
+			var origRdmaProvider utils.RdmaProvider
+			BeforeEach(func() {
+				origRdmaProvider = utils.GetRdmaProvider()
+				fakeRdmaProvider := &utilmocks.RdmaProvider{}
+				fakeRdmaProvider.On("GetRdmaDevicesForAuxdev", "mlx5_core.sf.1").Return([]string{"mlx5_4"})
+				utils.SetRdmaProviderInst(fakeRdmaProvider)
+			})
+			AfterEach(func() {
+				utils.SetRdmaProviderInst(origRdmaProvider)
+			})
+
+			It("should call nadutils to create well formatted auxiliary DeviceInfo objects", func() {
+				nadutils := &mocks.NadUtils{}
+				nadutils.On("SaveAuxDeviceInfoFile", "fakeOrg.io/fakeResource", "mlx5_core.sf.1", &types.AuxiliaryDevice{
+					Name: "mlx5_core.sf.1", RdmaDevice: "mlx5_4", PfPciAddress: "0000:01:00.0", RepresentorDevice: "pf0sf1",
+				}).Return(nil)
+				nadutils.On("SaveAuxDeviceInfoFile", "fakeOrg.io/fakeResource", "mlx5_core.sf.2", &types.AuxiliaryDevice{
+					Name: "mlx5_core.sf.2", PfPciAddress: "0000:01:00.0",
+				}).Return(nil)
+				nadutils.On("CleanDeviceInfoFile", "fakeOrg.io/fakeResource", "mlx5_core.sf.1").Return(nil)
+				nadutils.On("CleanDeviceInfoFile", "fakeOrg.io/fakeResource", "mlx5_core.sf.2").Return(nil)
+
+				rp := auxnetdevice.NewAuxNetResourcePool(nadutils, rc, devs)
+				err := rp.StoreDeviceInfoFile("fakeOrg.io", deviceIDs)
+				Expect(err).ToNot(HaveOccurred())
+				nadutils.AssertExpectations(GinkgoT())
+			})
+			It("should return the error of nadutils", func() {
+				nadutils := &mocks.NadUtils{}
+				nadutils.On("SaveAuxDeviceInfoFile", "fakeOrg.io/fakeResource", "mlx5_core.sf.1", mock.Anything).
+					Return(fmt.Errorf("file exists"))
+				nadutils.On("CleanDeviceInfoFile", "fakeOrg.io/fakeResource", "mlx5_core.sf.1").Return(nil)
+
+				rp := auxnetdevice.NewAuxNetResourcePool(nadutils, rc, devs)
+				err := rp.StoreDeviceInfoFile("fakeOrg.io", deviceIDs)
+				Expect(err).To(MatchError("file exists"))
+			})
+			It("should call nadutils to clean the DeviceInfo objects", func() {
+				nadutils := &mocks.NadUtils{}
+				nadutils.On("CleanDeviceInfoFile", "fakeOrg.io/fakeResource", "mlx5_core.sf.1").Return(nil)
+				nadutils.On("CleanDeviceInfoFile", "fakeOrg.io/fakeResource", "mlx5_core.sf.2").Return(nil)
+
+				rp := auxnetdevice.NewAuxNetResourcePool(nadutils, rc, devs)
+				err := rp.CleanDeviceInfoFile("fakeOrg.io", deviceIDs)
+				Expect(err).ToNot(HaveOccurred())
+				nadutils.AssertExpectations(GinkgoT())
+			})
+			It("should continue cleaning the DeviceInfo objects on error", func() {
+				nadutils := &mocks.NadUtils{}
+				nadutils.On("CleanDeviceInfoFile", "fakeOrg.io/fakeResource", "mlx5_core.sf.1").Return(fmt.Errorf("busy"))
+				nadutils.On("CleanDeviceInfoFile", "fakeOrg.io/fakeResource", "mlx5_core.sf.2").Return(nil)
+
+				rp := auxnetdevice.NewAuxNetResourcePool(nadutils, rc, devs)
+				err := rp.CleanDeviceInfoFile("fakeOrg.io", deviceIDs)
+				Expect(err).To(MatchError("busy"))
+				nadutils.AssertExpectations(GinkgoT())
+			})
+		})
+	})
 })
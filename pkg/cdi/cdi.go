@@ -18,24 +18,46 @@
 package cdi
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/container-orchestrated-devices/container-device-interface/pkg/cdi"
 	cdiSpecs "github.com/container-orchestrated-devices/container-device-interface/specs-go"
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 )
 
-const cdiSpecPrefix = "sriov-dp-"
+const (
+	cdiSpecPrefix = "sriov-dp-"
+	cdiSpecExt    = ".yaml"
+
+	// DefaultSpecDir is the default directory CDI specs are written to
+	DefaultSpecDir = cdi.DefaultDynamicDir
+	// DefaultSpecVersion is the default CDI specification version of the generated specs
+	DefaultSpecVersion = cdiSpecs.CurrentVersion
+)
+
+var (
+	// specDir is the directory CDI specs are written to and reconciled in
+	specDir = DefaultSpecDir
+	// specVersion is the CDI specification version of the generated specs
+	specVersion = DefaultSpecVersion
+	// supportedSpecVersions are the CDI specification versions the CDI library can write, oldest first
+	supportedSpecVersions = []string{"0.1.0", "0.2.0", "0.3.0", "0.4.0", "0.5.0"}
+)
 
 // CDI represents CDI API required by Device plugin
 type CDI interface {
 	CreateCDISpecForPool(resourcePrefix string, rPool types.ResourcePool) error
 	CreateContainerAnnotations(devicesIDs []string, resourcePrefix, resourceKind string) (map[string]string, error)
-	CleanupSpecs() error
+	GetQualifiedNames(devicesIDs []string, resourcePrefix, resourceKind string) []string
+	RemoveStaleSpecs(resourceNames map[string]bool) error
 }
 
 // impl implements CDI interface
@@ -47,27 +69,50 @@ func New() CDI {
 	return &impl{}
 }
 
-// CreateCDISpecForPool creates CDI spec file with specified devices
+// Configure sets the directory CDI specs are written to and the CDI specification version of the specs.
+// Empty values keep the defaults.
+func Configure(dir, version string) error {
+	if version != "" {
+		if specVersionIndex(version) < 0 {
+			return fmt.Errorf("unsupported CDI spec version %q", version)
+		}
+		specVersion = version
+	}
+	if dir != "" {
+		specDir = dir
+	}
+	// the registry reports spec conflicts in other files here, which do not prevent writing our specs
+	if err := cdi.GetRegistry(cdi.WithSpecDirs(specDir)).GetErrors(); len(err) > 0 {
+		klog.V(3).InfoS("CDI registry reported errors", "errors", err)
+	}
+	return nil
+}
+
+// CreateCDISpecForPool creates CDI spec file with specified devices.
+// The spec file is only rewritten when its contents change.
 func (c *impl) CreateCDISpecForPool(resourcePrefix string, rPool types.ResourcePool) error {
 	cdiDevices := make([]cdiSpecs.Device, 0)
 	cdiSpec := cdiSpecs.Spec{
-		Version: cdiSpecs.CurrentVersion,
+		Version: specVersion,
 		Kind:    resourcePrefix + "/" + rPool.GetCDIName(),
 		Devices: cdiDevices,
+		// spec level edits are applied once per container whichever devices of the pool it gets
+		ContainerEdits: cdiSpecs.ContainerEdits{Hooks: toCDIHooks(rPool.GetConfig().CDIHooks)},
 	}
 
-	for _, dev := range rPool.GetDevices() {
-		containerEdit := cdiSpecs.ContainerEdits{
-			DeviceNodes: make([]*cdiSpecs.DeviceNode, 0),
-		}
+	devices := rPool.GetDevices()
+	ids := make([]string, 0, len(devices))
+	for id := range devices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
 
-		for _, spec := range rPool.GetDeviceSpecs([]string{dev.GetID()}) {
-			deviceNode := cdiSpecs.DeviceNode{
-				Path:        spec.ContainerPath,
-				HostPath:    spec.HostPath,
-				Permissions: "rw",
-			}
-			containerEdit.DeviceNodes = append(containerEdit.DeviceNodes, &deviceNode)
+	for _, id := range ids {
+		dev := devices[id]
+		containerEdit, err := containerEdits(resourcePrefix, rPool, dev.GetID())
+		if err != nil {
+			klog.ErrorS(err, "Can not create CDI container edits", "deviceID", dev.GetID())
+			return err
 		}
 		device := cdiSpecs.Device{
 			Name:           dev.GetID(),
@@ -76,37 +121,122 @@ func (c *impl) CreateCDISpecForPool(resourcePrefix string, rPool types.ResourceP
 		cdiSpec.Devices = append(cdiSpec.Devices, device)
 	}
 
+	required, err := cdi.MinimumRequiredVersion(&cdiSpec)
+	if err != nil {
+		return err
+	}
+	if specVersionIndex(required) > specVersionIndex(specVersion) {
+		err = fmt.Errorf("CDI spec of resource %s requires CDI spec version %s, configured version is %s",
+			rPool.GetResourceName(), required, specVersion)
+		klog.ErrorS(err, "Can not create CDI spec")
+		return err
+	}
+
 	name, err := cdi.GenerateNameForSpec(&cdiSpec)
 	if err != nil {
-		glog.Errorf("GenerateNameForSpec(): can not generate name: %v", err)
+		klog.ErrorS(err, "Can not generate CDI spec name")
 		return err
 	}
 
-	// this will overwrite any existing file for this spec with the same name
-	err = cdi.GetRegistry().SpecDB().WriteSpec(&cdiSpec, fmt.Sprintf("%s%s-%s", cdiSpecPrefix, name, rPool.GetResourceName()))
+	specName := fmt.Sprintf("%s%s-%s", cdiSpecPrefix, name, rPool.GetResourceName())
+	if specUnchanged(filepath.Join(specDir, specName+cdiSpecExt), &cdiSpec) {
+		klog.V(3).InfoS("CDI spec is up to date", "spec", specName)
+		return nil
+	}
+
+	// the spec is written to a temporary file which then atomically replaces any existing file with the same name
+	err = cdi.GetRegistry().SpecDB().WriteSpec(&cdiSpec, specName)
 	if err != nil {
-		glog.Errorf("CreateCDISpecForPool(): can not create CDI json: %v", err)
+		klog.ErrorS(err, "Can not create CDI json")
 		return err
 	}
 
 	return nil
 }
 
+// containerEdits returns the container edits of a device: its device nodes, environment variables and mounts
+func containerEdits(resourcePrefix string, rPool types.ResourcePool, deviceID string) (cdiSpecs.ContainerEdits, error) {
+	containerEdit := cdiSpecs.ContainerEdits{
+		DeviceNodes: make([]*cdiSpecs.DeviceNode, 0),
+	}
+
+	for _, spec := range rPool.GetDeviceSpecs([]string{deviceID}) {
+		deviceNode := cdiSpecs.DeviceNode{
+			Path:        spec.ContainerPath,
+			HostPath:    spec.HostPath,
+			Permissions: "rw",
+		}
+		containerEdit.DeviceNodes = append(containerEdit.DeviceNodes, &deviceNode)
+	}
+
+	envs, err := rPool.GetDeviceEnvs(resourcePrefix, deviceID)
+	if err != nil {
+		return containerEdit, err
+	}
+	for _, key := range sortedKeys(envs) {
+		containerEdit.Env = append(containerEdit.Env, key+"="+envs[key])
+	}
+
+	for _, mnt := range rPool.GetMounts([]string{deviceID}) {
+		options := []string{"bind", "rw"}
+		if mnt.ReadOnly {
+			options = []string{"bind", "ro"}
+		}
+		containerEdit.Mounts = append(containerEdit.Mounts, &cdiSpecs.Mount{
+			HostPath:      mnt.HostPath,
+			ContainerPath: mnt.ContainerPath,
+			Options:       options,
+		})
+	}
+
+	return containerEdit, nil
+}
+
+// toCDIHooks converts the CDI hooks of a resource config to CDI spec hooks
+func toCDIHooks(hooks []types.CDIHook) []*cdiSpecs.Hook {
+	if len(hooks) == 0 {
+		return nil
+	}
+	cdiHooks := make([]*cdiSpecs.Hook, 0, len(hooks))
+	for _, h := range hooks {
+		cdiHooks = append(cdiHooks, &cdiSpecs.Hook{
+			HookName: h.HookName,
+			Path:     h.Path,
+			Args:     h.Args,
+			Env:      h.Env,
+			Timeout:  h.Timeout,
+		})
+	}
+	return cdiHooks
+}
+
+// ValidateHooks checks that CDI hooks of a resource config are valid OCI hooks
+func ValidateHooks(hooks []types.CDIHook) error {
+	edits := cdi.ContainerEdits{ContainerEdits: &cdiSpecs.ContainerEdits{Hooks: toCDIHooks(hooks)}}
+	return edits.Validate()
+}
+
+// sortedKeys returns the keys of a map in a stable order, so that the generated specs are stable
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // CreateContainerAnnotations creates container annotations based on CDI spec for a container runtime
 func (c *impl) CreateContainerAnnotations(devicesIDs []string, resourcePrefix, resourceKind string) (map[string]string, error) {
 	annotations := make(map[string]string, 0)
 	annoKey, err := cdi.AnnotationKey(resourcePrefix, resourceKind)
 	if err != nil {
-		glog.Errorf("CreateContainerAnnotations(): can't create container annotation: %v", err)
+		klog.ErrorS(err, "Can't create container annotation")
 		return nil, err
 	}
-	devices := make([]string, 0)
-	for _, id := range devicesIDs {
-		devices = append(devices, cdi.QualifiedName(resourcePrefix, resourceKind, id))
-	}
-	annoValue, err := cdi.AnnotationValue(devices)
+	annoValue, err := cdi.AnnotationValue(c.GetQualifiedNames(devicesIDs, resourcePrefix, resourceKind))
 	if err != nil {
-		glog.Errorf("CreateContainerAnnotations(): can't create container annotation: %v", err)
+		klog.ErrorS(err, "Can't create container annotation")
 		return nil, err
 	}
 	annotations[annoKey] = annoValue
@@ -114,19 +244,83 @@ func (c *impl) CreateContainerAnnotations(devicesIDs []string, resourcePrefix, r
 	return annotations, nil
 }
 
-// CleanupSpecs removes previously-created CDI specs
-func (c *impl) CleanupSpecs() error {
-	for _, dir := range cdi.GetRegistry().GetSpecDirectories() {
-		specs, err := filepath.Glob(filepath.Join(dir, cdiSpecPrefix+"*"))
-		if err != nil {
-			return err
+// GetQualifiedNames returns the fully qualified CDI device names of the given devices
+func (c *impl) GetQualifiedNames(devicesIDs []string, resourcePrefix, resourceKind string) []string {
+	devices := make([]string, 0, len(devicesIDs))
+	for _, id := range devicesIDs {
+		devices = append(devices, cdi.QualifiedName(resourcePrefix, resourceKind, id))
+	}
+	return devices
+}
+
+// specVersionIndex returns the position of version in supportedSpecVersions, or -1 if it is not supported
+func specVersionIndex(version string) int {
+	for i, v := range supportedSpecVersions {
+		if v == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// specUnchanged returns true if the spec file at path has the same contents as the given spec
+func specUnchanged(path string, spec *cdiSpecs.Spec) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	existing, err := cdi.ParseSpec(data)
+	if err != nil || existing == nil {
+		return false
+	}
+	// round-trip the new spec so that both specs are compared in the same form
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return false
+	}
+	expected, err := cdi.ParseSpec(raw)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(existing, expected)
+}
+
+// RemoveStaleSpecs removes previously-created CDI specs of resources which are not in resourceNames.
+// resourceNames are fully qualified resource names, i.e. <resourcePrefix>/<resourceName>
+func (c *impl) RemoveStaleSpecs(resourceNames map[string]bool) error {
+	specs, err := filepath.Glob(filepath.Join(specDir, cdiSpecPrefix+"*"))
+	if err != nil {
+		return err
+	}
+	for _, path := range specs {
+		if resourceNames[specResourceName(path)] {
+			continue
 		}
-		for _, spec := range specs {
-			if err := os.Remove(spec); err != nil {
-				return err
-			}
+		klog.InfoS("Removing stale CDI spec", "spec", path)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
 		}
 	}
 
 	return nil
 }
+
+// specResourceName returns the fully qualified resource name of a spec created by the device plugin,
+// or an empty string if the spec can not be read
+func specResourceName(path string) string {
+	spec, err := cdi.ReadSpec(path, 0)
+	if err != nil {
+		klog.V(3).InfoS("Can not read CDI spec", "spec", path, "error", err)
+		return ""
+	}
+	name, err := cdi.GenerateNameForSpec(spec.Spec)
+	if err != nil {
+		return ""
+	}
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	resourceName := strings.TrimPrefix(base, cdiSpecPrefix+name+"-")
+	if resourceName == base {
+		return ""
+	}
+	return spec.GetVendor() + "/" + resourceName
+}
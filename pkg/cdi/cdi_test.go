@@ -18,12 +18,20 @@
 package cdi_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/container-orchestrated-devices/container-device-interface/pkg/cdi"
+	cdiSpecs "github.com/container-orchestrated-devices/container-device-interface/specs-go"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	cdiPkg "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
 )
 
 func TestCdi(t *testing.T) {
@@ -44,4 +52,142 @@ var _ = Describe("Cdi", func() {
 			Expect(annotations[annoKey]).To(Equal(annoVal))
 		})
 	})
+	Context("creating the CDI spec of a pool", func() {
+		var specDir string
+		BeforeEach(func() {
+			specDir = GinkgoT().TempDir()
+			Expect(cdiPkg.Configure(specDir, cdiPkg.DefaultSpecVersion)).To(Succeed())
+		})
+		It("should write the device nodes, environment variables and mounts of every device and the hooks of the pool", func() {
+			deviceID := "0000:00:00.1"
+			timeout := 5
+			rc := &types.ResourceConfig{ResourceName: "pool", CDIHooks: []types.CDIHook{
+				{HookName: "createContainer", Path: "/usr/bin/hook", Args: []string{"hook", "create"}, Timeout: &timeout}}}
+			pool := &mocks.ResourcePool{}
+			pool.On("GetCDIName").Return("net").
+				On("GetResourceName").Return("pool").
+				On("GetConfig").Return(rc).
+				On("GetDevices").Return(map[string]*pluginapi.Device{deviceID: {ID: deviceID}}).
+				On("GetDeviceSpecs", []string{deviceID}).Return([]*pluginapi.DeviceSpec{
+				{HostPath: "/dev/vfio/1", ContainerPath: "/dev/vfio/1", Permissions: "rw"}}).
+				On("GetDeviceEnvs", "example.com", deviceID).Return(map[string]string{
+				"PCIDEVICE_EXAMPLE_COM_POOL_0000_00_00_1_INFO": `{"0000:00:00.1":{}}`,
+				"PCIDEVICE_EXAMPLE_COM_POOL_0000_00_00_1":      deviceID}, nil).
+				On("GetMounts", []string{deviceID}).Return([]*pluginapi.Mount{
+				{HostPath: "/run/token", ContainerPath: "/run/token", ReadOnly: true}})
+
+			Expect(cdiPkg.New().CreateCDISpecForPool("example.com", pool)).To(Succeed())
+
+			files, err := filepath.Glob(filepath.Join(specDir, "sriov-dp-*"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			spec, err := cdi.ReadSpec(files[0], 0)
+			Expect(err).NotTo(HaveOccurred())
+			dev := spec.GetDevice(deviceID)
+			Expect(dev).NotTo(BeNil())
+			edits := dev.ContainerEdits
+			Expect(edits.DeviceNodes).To(ConsistOf(
+				&cdiSpecs.DeviceNode{Path: "/dev/vfio/1", HostPath: "/dev/vfio/1", Permissions: "rw"}))
+			Expect(edits.Env).To(Equal([]string{"PCIDEVICE_EXAMPLE_COM_POOL_0000_00_00_1=0000:00:00.1",
+				`PCIDEVICE_EXAMPLE_COM_POOL_0000_00_00_1_INFO={"0000:00:00.1":{}}`}))
+			Expect(edits.Mounts).To(ConsistOf(
+				&cdiSpecs.Mount{HostPath: "/run/token", ContainerPath: "/run/token", Options: []string{"bind", "ro"}}))
+			Expect(edits.Hooks).To(BeEmpty())
+			Expect(spec.ContainerEdits.Hooks).To(ConsistOf(&cdiSpecs.Hook{HookName: "createContainer", Path: "/usr/bin/hook",
+				Args: []string{"hook", "create"}, Timeout: &timeout}))
+		})
+	})
+	Context("reconciling CDI specs", func() {
+		var specDir string
+		BeforeEach(func() {
+			specDir = GinkgoT().TempDir()
+			Expect(cdiPkg.Configure(specDir, cdiPkg.DefaultSpecVersion)).To(Succeed())
+		})
+		It("should not rewrite a spec whose contents did not change", func() {
+			Expect(cdiPkg.New().CreateCDISpecForPool("example.com", newPool("pool", "0000:00:00.1"))).To(Succeed())
+			files, err := filepath.Glob(filepath.Join(specDir, "sriov-dp-*"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			past := time.Now().Add(-time.Hour).Truncate(time.Second)
+			Expect(os.Chtimes(files[0], past, past)).To(Succeed())
+
+			Expect(cdiPkg.New().CreateCDISpecForPool("example.com", newPool("pool", "0000:00:00.1"))).To(Succeed())
+			info, err := os.Stat(files[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.ModTime()).To(Equal(past))
+
+			Expect(cdiPkg.New().CreateCDISpecForPool("example.com", newPool("pool", "0000:00:00.1", "0000:00:00.2"))).To(Succeed())
+			info, err = os.Stat(files[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.ModTime()).NotTo(Equal(past))
+			spec, err := cdi.ReadSpec(files[0], 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.GetDevice("0000:00:00.2")).NotTo(BeNil())
+		})
+		It("should only remove the specs of resources which are no longer configured", func() {
+			Expect(cdiPkg.New().CreateCDISpecForPool("example.com", newPool("pool", "0000:00:00.1"))).To(Succeed())
+			Expect(cdiPkg.New().CreateCDISpecForPool("example.com", newPool("old_pool", "0000:00:00.2"))).To(Succeed())
+			other := filepath.Join(specDir, "other.yaml")
+			Expect(os.WriteFile(other, []byte{}, 0o644)).To(Succeed())
+
+			Expect(cdiPkg.New().RemoveStaleSpecs(map[string]bool{"example.com/pool": true})).To(Succeed())
+
+			files, err := filepath.Glob(filepath.Join(specDir, "sriov-dp-*"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			Expect(filepath.Base(files[0])).To(HaveSuffix("-pool.yaml"))
+			Expect(other).To(BeAnExistingFile())
+		})
+		It("should write the configured CDI spec version", func() {
+			Expect(cdiPkg.Configure("", "0.4.0")).To(Succeed())
+			defer func() { Expect(cdiPkg.Configure("", cdiPkg.DefaultSpecVersion)).To(Succeed()) }()
+			// PCI addresses as device names require CDI spec version 0.5.0
+			Expect(cdiPkg.New().CreateCDISpecForPool("example.com", newPool("pool", "0000:00:00.1"))).NotTo(Succeed())
+			Expect(cdiPkg.Configure("", "0.5.0")).To(Succeed())
+			Expect(cdiPkg.New().CreateCDISpecForPool("example.com", newPool("pool", "0000:00:00.1"))).To(Succeed())
+			files, err := filepath.Glob(filepath.Join(specDir, "sriov-dp-*"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			spec, err := cdi.ReadSpec(files[0], 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Version).To(Equal("0.5.0"))
+		})
+		It("should reject unsupported CDI spec versions", func() {
+			Expect(cdiPkg.Configure("", "1.0.0")).NotTo(Succeed())
+		})
+	})
+	DescribeTable("validating CDI hooks",
+		func(hooks []types.CDIHook, valid bool) {
+			err := cdiPkg.ValidateHooks(hooks)
+			if valid {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("no hooks", nil, true),
+		Entry("valid hook", []types.CDIHook{{HookName: "prestart", Path: "/usr/bin/hook", Env: []string{"A=b"}}}, true),
+		Entry("unknown hook name", []types.CDIHook{{HookName: "prerun", Path: "/usr/bin/hook"}}, false),
+		Entry("empty path", []types.CDIHook{{HookName: "prestart"}}, false),
+		Entry("invalid environment variable", []types.CDIHook{{HookName: "prestart", Path: "/usr/bin/hook", Env: []string{"A"}}},
+			false),
+	)
 })
+
+// newPool returns a mocked resource pool with the given devices
+func newPool(resourceName string, deviceIDs ...string) *mocks.ResourcePool {
+	devices := make(map[string]*pluginapi.Device, len(deviceIDs))
+	pool := &mocks.ResourcePool{}
+	for _, id := range deviceIDs {
+		devices[id] = &pluginapi.Device{ID: id}
+		pool.On("GetDeviceSpecs", []string{id}).Return([]*pluginapi.DeviceSpec{
+			{HostPath: "/dev/net/" + id, ContainerPath: "/dev/net/" + id, Permissions: "rw"}}).
+			On("GetDeviceEnvs", "example.com", id).Return(map[string]string{"PCIDEVICE_" + id: id}, nil).
+			On("GetMounts", []string{id}).Return([]*pluginapi.Mount{})
+	}
+	pool.On("GetCDIName").Return("net").
+		On("GetResourceName").Return(resourceName).
+		On("GetConfig").Return(&types.ResourceConfig{ResourceName: resourceName}).
+		On("GetDevices").Return(devices)
+	return pool
+}
@@ -12,24 +12,6 @@ type CDI struct {
 	mock.Mock
 }
 
-// CleanupSpecs provides a mock function with no fields
-func (_m *CDI) CleanupSpecs() error {
-	ret := _m.Called()
-
-	if len(ret) == 0 {
-		panic("no return value specified for CleanupSpecs")
-	}
-
-	var r0 error
-	if rf, ok := ret.Get(0).(func() error); ok {
-		r0 = rf()
-	} else {
-		r0 = ret.Error(0)
-	}
-
-	return r0
-}
-
 // CreateCDISpecForPool provides a mock function with given fields: resourcePrefix, rPool
 func (_m *CDI) CreateCDISpecForPool(resourcePrefix string, rPool types.ResourcePool) error {
 	ret := _m.Called(resourcePrefix, rPool)
@@ -78,6 +60,44 @@ func (_m *CDI) CreateContainerAnnotations(devicesIDs []string, resourcePrefix st
 	return r0, r1
 }
 
+// GetQualifiedNames provides a mock function with given fields: devicesIDs, resourcePrefix, resourceKind
+func (_m *CDI) GetQualifiedNames(devicesIDs []string, resourcePrefix string, resourceKind string) []string {
+	ret := _m.Called(devicesIDs, resourcePrefix, resourceKind)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetQualifiedNames")
+	}
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func([]string, string, string) []string); ok {
+		r0 = rf(devicesIDs, resourcePrefix, resourceKind)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
+// RemoveStaleSpecs provides a mock function with given fields: resourceNames
+func (_m *CDI) RemoveStaleSpecs(resourceNames map[string]bool) error {
+	ret := _m.Called(resourceNames)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveStaleSpecs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(map[string]bool) error); ok {
+		r0 = rf(resourceNames)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewCDI creates a new instance of CDI. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewCDI(t interface {
@@ -0,0 +1,156 @@
+// Package checkpoint persists the devices handed out by Allocate in a node-local file
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+)
+
+const checkpointVersion = 1
+
+// checkpointData is the on-disk format of the checkpoint
+type checkpointData struct {
+	Version int `json:"version"`
+	// Allocations holds the allocated device IDs keyed by fully qualified resource name
+	Allocations map[string][]string `json:"allocations"`
+}
+
+type fileCheckpoint struct {
+	path        string
+	lock        sync.Mutex
+	allocations map[string]map[string]struct{}
+}
+
+var _ types.AllocationCheckpoint = &fileCheckpoint{}
+
+// New returns an AllocationCheckpoint stored in the file at path, loading the allocations it already holds.
+// A missing file is treated as an empty checkpoint.
+func New(path string) (types.AllocationCheckpoint, error) {
+	cp := &fileCheckpoint{
+		path:        path,
+		allocations: make(map[string]map[string]struct{}),
+	}
+	rawBytes, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cp, nil
+		}
+		return cp, fmt.Errorf("error reading checkpoint file %s: %v", path, err)
+	}
+	data := &checkpointData{}
+	if err := json.Unmarshal(rawBytes, data); err != nil {
+		return cp, fmt.Errorf("error unmarshalling checkpoint file %s: %v", path, err)
+	}
+	if data.Version != checkpointVersion {
+		return cp, fmt.Errorf("unsupported checkpoint version %d in %s", data.Version, path)
+	}
+	for resourceName, deviceIDs := range data.Allocations {
+		cp.addLocked(resourceName, deviceIDs)
+	}
+	return cp, nil
+}
+
+// AddDevices records devices of a resource as allocated and writes the checkpoint file
+func (cp *fileCheckpoint) AddDevices(resourceName string, deviceIDs []string) error {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	if !cp.addLocked(resourceName, deviceIDs) {
+		return nil
+	}
+	return cp.writeLocked()
+}
+
+// GetDevices returns the devices of a resource recorded as allocated
+func (cp *fileCheckpoint) GetDevices(resourceName string) []string {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	return sortedIDs(cp.allocations[resourceName])
+}
+
+// Reconcile replaces the recorded allocations with the devices in use and returns the recorded
+// devices that are no longer in use
+func (cp *fileCheckpoint) Reconcile(inUse map[string][]string) (map[string][]string, error) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	current := make(map[string]map[string]struct{})
+	for resourceName, deviceIDs := range inUse {
+		for _, id := range deviceIDs {
+			if current[resourceName] == nil {
+				current[resourceName] = make(map[string]struct{})
+			}
+			current[resourceName][id] = struct{}{}
+		}
+	}
+	stale := make(map[string][]string)
+	for resourceName, ids := range cp.allocations {
+		for id := range ids {
+			if _, ok := current[resourceName][id]; !ok {
+				stale[resourceName] = append(stale[resourceName], id)
+			}
+		}
+	}
+	for resourceName := range stale {
+		sort.Strings(stale[resourceName])
+	}
+	cp.allocations = current
+	return stale, cp.writeLocked()
+}
+
+// addLocked records devices of a resource and returns true if any of them was not recorded yet
+func (cp *fileCheckpoint) addLocked(resourceName string, deviceIDs []string) bool {
+	added := false
+	for _, id := range deviceIDs {
+		if cp.allocations[resourceName] == nil {
+			cp.allocations[resourceName] = make(map[string]struct{})
+		}
+		if _, ok := cp.allocations[resourceName][id]; !ok {
+			cp.allocations[resourceName][id] = struct{}{}
+			added = true
+		}
+	}
+	return added
+}
+
+// writeLocked atomically replaces the checkpoint file with the recorded allocations
+func (cp *fileCheckpoint) writeLocked() error {
+	data := checkpointData{
+		Version:     checkpointVersion,
+		Allocations: make(map[string][]string, len(cp.allocations)),
+	}
+	for resourceName, ids := range cp.allocations {
+		data.Allocations[resourceName] = sortedIDs(ids)
+	}
+	rawBytes, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error marshalling checkpoint: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cp.path), 0o700); err != nil {
+		return fmt.Errorf("error creating checkpoint directory: %v", err)
+	}
+	tmpFile := cp.path + ".tmp"
+	if err := os.WriteFile(tmpFile, rawBytes, 0o600); err != nil {
+		return fmt.Errorf("error writing checkpoint file %s: %v", tmpFile, err)
+	}
+	if err := os.Rename(tmpFile, cp.path); err != nil {
+		return fmt.Errorf("error replacing checkpoint file %s: %v", cp.path, err)
+	}
+	klog.V(4).InfoS("Checkpoint written", "file", cp.path, "allocations", data.Allocations)
+	return nil
+}
+
+func sortedIDs(ids map[string]struct{}) []string {
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
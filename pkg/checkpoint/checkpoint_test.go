@@ -0,0 +1,64 @@
+package checkpoint_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/checkpoint"
+)
+
+var _ = Describe("Checkpoint", func() {
+	var path string
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "sriovdp", "checkpoint.json")
+	})
+
+	It("should start empty when the file does not exist", func() {
+		cp, err := checkpoint.New(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cp.GetDevices("intel.com/sriov")).To(BeEmpty())
+	})
+	It("should persist the allocated devices", func() {
+		cp, err := checkpoint.New(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cp.AddDevices("intel.com/sriov", []string{"0000:3b:02.1", "0000:3b:02.0"})).To(Succeed())
+		Expect(cp.AddDevices("intel.com/sriov", []string{"0000:3b:02.1"})).To(Succeed())
+
+		loaded, err := checkpoint.New(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.GetDevices("intel.com/sriov")).To(Equal([]string{"0000:3b:02.0", "0000:3b:02.1"}))
+	})
+	It("should return an empty checkpoint and an error for a corrupted file", func() {
+		Expect(os.MkdirAll(filepath.Dir(path), 0o700)).To(Succeed())
+		Expect(os.WriteFile(path, []byte("{not json"), 0o600)).To(Succeed())
+
+		cp, err := checkpoint.New(path)
+		Expect(err).To(HaveOccurred())
+		Expect(cp).NotTo(BeNil())
+		Expect(cp.GetDevices("intel.com/sriov")).To(BeEmpty())
+	})
+	It("should replace the allocations with the devices in use and return the stale ones", func() {
+		cp, err := checkpoint.New(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cp.AddDevices("intel.com/sriov", []string{"0000:3b:02.0", "0000:3b:02.1"})).To(Succeed())
+		Expect(cp.AddDevices("intel.com/removed", []string{"0000:5e:02.0"})).To(Succeed())
+
+		stale, err := cp.Reconcile(map[string][]string{
+			"intel.com/sriov": {"0000:3b:02.1", "0000:3b:02.2"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stale).To(Equal(map[string][]string{
+			"intel.com/sriov":   {"0000:3b:02.0"},
+			"intel.com/removed": {"0000:5e:02.0"},
+		}))
+
+		loaded, err := checkpoint.New(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.GetDevices("intel.com/sriov")).To(Equal([]string{"0000:3b:02.1", "0000:3b:02.2"}))
+		Expect(loaded.GetDevices("intel.com/removed")).To(BeEmpty())
+	})
+})
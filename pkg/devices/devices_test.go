@@ -84,6 +84,43 @@ var _ = Describe("Devices", func() {
 				Expect(dev.GetLinkType()).To(Equal("fakeLinkType"))
 				Expect(dev.GetFuncID()).To(Equal(0))
 				Expect(dev.IsRdma()).To(BeTrue())
+				Expect(dev.GetEswitchMode()).To(Equal("fakeMode"))
+				Expect(dev.GetUplinkRepresentor()).To(Equal(""))
+				Expect(dev.GetRepresentor()).To(Equal(""))
+			})
+			It("should populate representors in switchdev mode", func() {
+				fs := &utils.FakeFilesystem{
+					Dirs: []string{
+						"sys/bus/pci/devices/0000:00:00.0/net/p0",
+						"sys/bus/pci/devices/0000:00:00.1/net/fakeIfName",
+					},
+					Symlinks: map[string]string{
+						"sys/bus/pci/devices/0000:00:00.1/physfn":  "../0000:00:00.0",
+						"sys/bus/pci/devices/0000:00:00.0/virtfn0": "../0000:00:00.1",
+					},
+				}
+				defer fs.Use()()
+				testMockProvider := mocks.NetlinkProvider{}
+				testMockProvider.
+					On("GetLinkAttrs", mock.AnythingOfType("string")).
+					Return(&nl.LinkAttrs{EncapType: "ether"}, nil)
+				testMockProvider.
+					On("GetDevLinkDeviceEswitchAttrs", "0000:00:00.0").
+					Return(&nl.DevlinkDevEswitchAttr{Mode: "switchdev"}, nil)
+				utils.SetNetlinkProviderInst(&testMockProvider)
+				fakeSriovnetProvider := mocks.SriovnetProvider{}
+				fakeSriovnetProvider.
+					On("GetUplinkRepresentor", "0000:00:00.1").Return("p0", nil).
+					On("GetVfRepresentor", "p0", 0).Return("pf0vf0", nil)
+				utils.SetSriovnetProviderInst(&fakeSriovnetProvider)
+
+				dev, err := devices.NewGenNetDevice("0000:00:00.1", types.NetDeviceType, false)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dev.GetEswitchMode()).To(Equal("switchdev"))
+				Expect(dev.GetPfNetName()).To(Equal("p0"))
+				Expect(dev.GetUplinkRepresentor()).To(Equal("p0"))
+				Expect(dev.GetRepresentor()).To(Equal("pf0vf0"))
 			})
 			It("device's PF name is not available", func() {
 				fs := &utils.FakeFilesystem{
@@ -170,6 +207,33 @@ var _ = Describe("Devices", func() {
 				Expect(dev.GetLinkType()).To(Equal("fakeLinkType"))
 				Expect(dev.GetFuncID()).To(Equal(1))
 				Expect(dev.IsRdma()).To(BeTrue())
+				Expect(dev.GetEswitchMode()).To(Equal("fakeMode"))
+				Expect(dev.GetRepresentor()).To(Equal(""))
+			})
+			It("should populate representors in switchdev mode", func() {
+				fakeSriovnetProvider := mocks.SriovnetProvider{}
+				fakeSriovnetProvider.
+					On("GetUplinkRepresentorFromAux", "foo.bar.0").Return("p0", nil).
+					On("GetPfPciFromAux", "foo.bar.0").Return("0000:00:00.0", nil).
+					On("GetSfIndexByAuxDev", "foo.bar.0").Return(1, nil).
+					On("GetNetDevicesFromAux", "foo.bar.0").Return([]string{"fakeIfName"}, nil).
+					On("GetSfRepresentor", "p0", 1).Return("pf0sf1", nil)
+				utils.SetSriovnetProviderInst(&fakeSriovnetProvider)
+				testMockProvider := mocks.NetlinkProvider{}
+				testMockProvider.
+					On("GetLinkAttrs", mock.AnythingOfType("string")).
+					Return(&nl.LinkAttrs{EncapType: "ether"}, nil)
+				testMockProvider.
+					On("GetDevLinkDeviceEswitchAttrs", "0000:00:00.0").
+					Return(&nl.DevlinkDevEswitchAttr{Mode: "switchdev"}, nil)
+				utils.SetNetlinkProviderInst(&testMockProvider)
+
+				dev, err := devices.NewGenNetDevice("foo.bar.0", types.AuxNetDeviceType, false)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dev.GetEswitchMode()).To(Equal("switchdev"))
+				Expect(dev.GetUplinkRepresentor()).To(Equal("p0"))
+				Expect(dev.GetRepresentor()).To(Equal("pf0sf1"))
 			})
 			It("no SF index for auxiliary device", func() {
 				fakeSriovnetProvider := mocks.SriovnetProvider{}
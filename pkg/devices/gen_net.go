@@ -19,8 +19,9 @@ package devices
 
 import (
 	"fmt"
+	"strconv"
 
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
@@ -28,13 +29,19 @@ import (
 
 // GenNetDevice is a generic network device embedded into top level devices
 type GenNetDevice struct {
-	pfName    string
-	pfAddr    string
-	ifName    string
-	linkType  string
-	linkSpeed string
-	funcID    int
-	isRdma    bool
+	pfName      string
+	pfAddr      string
+	ifName      string
+	linkType    string
+	linkSpeed   string
+	mtu         int
+	pfOperState string
+	numaNode    int
+	eswitchMode string
+	uplinkRep   string
+	rep         string
+	funcID      int
+	isRdma      bool
 }
 
 // NewGenNetDevice returns GenNetDevice instance
@@ -42,6 +49,7 @@ func NewGenNetDevice(deviceID string, dt types.DeviceType, isRdma bool) (*GenNet
 	var netNames []string
 	var pfName string
 	var pfAddr string
+	var pciAddr string
 	var funcID int
 	var err error
 
@@ -49,7 +57,7 @@ func NewGenNetDevice(deviceID string, dt types.DeviceType, isRdma bool) (*GenNet
 	switch dt {
 	case types.NetDeviceType:
 		if pfName, err = utils.GetPfName(deviceID); err != nil {
-			glog.Warningf("unable to get PF name %q", err.Error())
+			klog.InfoS("Unable to get PF name", "deviceID", deviceID, "err", err)
 		}
 		if pfAddr, err = utils.GetPfAddr(deviceID); err != nil {
 			return nil, err
@@ -58,6 +66,7 @@ func NewGenNetDevice(deviceID string, dt types.DeviceType, isRdma bool) (*GenNet
 			return nil, err
 		}
 		netNames, _ = utils.GetNetNames(deviceID)
+		pciAddr = deviceID
 	case types.AuxNetDeviceType:
 		if pfName, err = utils.GetPfNameFromAuxDev(deviceID); err != nil {
 			// AuxNetDeviceType by design should have PF, return error if failed to get PF name
@@ -73,6 +82,7 @@ func NewGenNetDevice(deviceID string, dt types.DeviceType, isRdma bool) (*GenNet
 			return nil, err
 		}
 		netNames, _ = utils.GetSriovnetProvider().GetNetDevicesFromAux(deviceID)
+		pciAddr = pfAddr
 	default:
 		return nil, fmt.Errorf("generic netdevices not supported for type %s", dt)
 	}
@@ -82,29 +92,91 @@ func NewGenNetDevice(deviceID string, dt types.DeviceType, isRdma bool) (*GenNet
 		ifName = netNames[0]
 	}
 
-	linkType := ""
-	linkTypeProviderDevice := ifName
-	// If interface name is not available, derive link type from PF
-	if linkTypeProviderDevice == "" {
-		linkTypeProviderDevice = pfName
+	nd := &GenNetDevice{
+		pfName:   pfName,
+		pfAddr:   pfAddr,
+		ifName:   ifName,
+		numaNode: utils.GetDevNode(pciAddr),
+		funcID:   funcID,
+		isRdma:   isRdma,
 	}
-	if linkTypeProviderDevice != "" {
-		la, err := utils.GetNetlinkProvider().GetLinkAttrs(linkTypeProviderDevice)
-		if err != nil {
-			return nil, err
+	if err := nd.setLinkAttrs(); err != nil {
+		return nil, err
+	}
+	nd.setRepresentors(pciAddr, dt)
+	return nd, nil
+}
+
+// setRepresentors sets the eswitch mode of the PF and, in switchdev mode, the uplink representor and the
+// representor of the VF or SF
+func (nd *GenNetDevice) setRepresentors(pciAddr string, dt types.DeviceType) {
+	if pciAddr == "" {
+		return
+	}
+	eswitchMode, err := utils.GetPfEswitchMode(pciAddr)
+	if err != nil {
+		klog.V(2).InfoS("Unable to get eswitch mode", "pciAddress", pciAddr, "err", err)
+		return
+	}
+	nd.eswitchMode = eswitchMode
+	if eswitchMode != utils.EswitchModeSwitchdev || nd.funcID < 0 {
+		return
+	}
+
+	// in switchdev mode the PF netdevice is resolved to the uplink representor
+	nd.uplinkRep = nd.pfName
+	if nd.uplinkRep == "" {
+		return
+	}
+	if dt == types.AuxNetDeviceType {
+		nd.rep, err = utils.GetSriovnetProvider().GetSfRepresentor(nd.uplinkRep, nd.funcID)
+	} else {
+		nd.rep, err = utils.GetSriovnetProvider().GetVfRepresentor(nd.uplinkRep, nd.funcID)
+	}
+	if err != nil {
+		klog.InfoS("Unable to get representor", "uplink", nd.uplinkRep, "funcID", nd.funcID, "err", err)
+	}
+}
+
+// setLinkAttrs sets the link attributes of the device from its network interface, or from the one of its PF
+// when it has none
+func (nd *GenNetDevice) setLinkAttrs() error {
+	linkProviderDevice := nd.ifName
+	if linkProviderDevice == "" {
+		linkProviderDevice = nd.pfName
+	}
+	if linkProviderDevice == "" {
+		return nil
+	}
+
+	la, err := utils.GetNetlinkProvider().GetLinkAttrs(linkProviderDevice)
+	if err != nil {
+		return err
+	}
+	nd.linkType = la.EncapType
+	nd.mtu = la.MTU
+
+	if nd.pfName != "" {
+		pfAttrs := la
+		if nd.pfName != linkProviderDevice {
+			if pfAttrs, err = utils.GetNetlinkProvider().GetLinkAttrs(nd.pfName); err != nil {
+				klog.InfoS("Unable to get PF link attributes", "pfName", nd.pfName, "err", err)
+			}
+		}
+		if pfAttrs != nil {
+			nd.pfOperState = pfAttrs.OperState.String()
 		}
-		linkType = la.EncapType
 	}
 
-	return &GenNetDevice{
-		pfName:    pfName,
-		pfAddr:    pfAddr,
-		ifName:    ifName,
-		linkType:  linkType,
-		linkSpeed: "", // TODO: Get this using utils pkg
-		funcID:    funcID,
-		isRdma:    isRdma,
-	}, nil
+	// VFs without link speed of their own, e.g. without netdevice, share the link of their PF
+	speed, err := utils.GetLinkSpeed(linkProviderDevice)
+	if err != nil && nd.pfName != "" && nd.pfName != linkProviderDevice {
+		speed, err = utils.GetLinkSpeed(nd.pfName)
+	}
+	if err == nil {
+		nd.linkSpeed = strconv.Itoa(speed)
+	}
+	return nil
 }
 
 // GetPfNetName returns PF netdevice name
@@ -122,11 +194,41 @@ func (nd *GenNetDevice) GetNetName() string {
 	return nd.ifName
 }
 
-// GetLinkSpeed returns link speed
+// GetLinkSpeed returns link speed in Mb/s, empty when unknown
 func (nd *GenNetDevice) GetLinkSpeed() string {
 	return nd.linkSpeed
 }
 
+// GetMtu returns the MTU of the network interface
+func (nd *GenNetDevice) GetMtu() int {
+	return nd.mtu
+}
+
+// GetPfOperState returns the operational state of the PF network interface
+func (nd *GenNetDevice) GetPfOperState() string {
+	return nd.pfOperState
+}
+
+// GetNumaNode returns the NUMA node of the device, -1 if unknown
+func (nd *GenNetDevice) GetNumaNode() int {
+	return nd.numaNode
+}
+
+// GetEswitchMode returns the eswitch mode of the PF
+func (nd *GenNetDevice) GetEswitchMode() string {
+	return nd.eswitchMode
+}
+
+// GetUplinkRepresentor returns the uplink representor netdevice, empty if the PF is not in switchdev mode
+func (nd *GenNetDevice) GetUplinkRepresentor() string {
+	return nd.uplinkRep
+}
+
+// GetRepresentor returns the representor netdevice of the VF or SF, empty if the PF is not in switchdev mode
+func (nd *GenNetDevice) GetRepresentor() string {
+	return nd.rep
+}
+
 // GetLinkType returns link type
 func (nd *GenNetDevice) GetLinkType() string {
 	return nd.linkType
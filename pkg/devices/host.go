@@ -46,7 +46,7 @@ func NewHostDeviceImpl(dev *ghw.PCIDevice, deviceID string, rFactory types.Resou
 
 	// Use the default Information Provided if not
 	if len(infoProviders) == 0 {
-		infoProviders = rFactory.GetDefaultInfoProvider(deviceID, driverName)
+		infoProviders = rFactory.GetDefaultInfoProvider(deviceID, driverName, rc.VfioMode)
 		if rc.AdditionalInfo != nil {
 			infoProviders = append(infoProviders, infoprovider.NewExtraInfoProvider(dev.Address, rc.AdditionalInfo))
 		}
@@ -48,7 +48,7 @@ var _ = Describe("HostDevice", func() {
 			}
 			defer fs.Use()()
 
-			f := factory.NewResourceFactory("fake", "fake", true, false)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			pciAddr := "0000:00:00.1"
 			in := newPciDeviceFn(pciAddr)
 			rc := &types.ResourceConfig{}
@@ -72,7 +72,7 @@ var _ = Describe("HostDevice", func() {
 			}
 			defer fs.Use()()
 
-			f := factory.NewResourceFactory("fake", "fake", true, false)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			pciAddr := "0000:00:00.1"
 			in := newPciDeviceFn(pciAddr)
 			rc := &types.ResourceConfig{}
@@ -100,7 +100,7 @@ var _ = Describe("HostDevice", func() {
 			}
 			defer fs.Use()()
 
-			f := factory.NewResourceFactory("fake", "fake", true, false)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			pciAddr := "0000:00:00.1"
 			in := newPciDeviceFn(pciAddr)
 			rc := &types.ResourceConfig{}
@@ -123,7 +123,7 @@ var _ = Describe("HostDevice", func() {
 			}
 			defer fs.Use()()
 
-			f := factory.NewResourceFactory("fake", "fake", true, false)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			pciAddr := "0000:00:00.1"
 			in := newPciDeviceFn(pciAddr)
 			rc := &types.ResourceConfig{}
@@ -147,7 +147,7 @@ var _ = Describe("HostDevice", func() {
 			}
 			defer fs.Use()()
 
-			f := factory.NewResourceFactory("fake", "fake", true, false)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			pciAddr := "0000:00:00.1"
 			in := newPciDeviceFn(pciAddr)
 			rc := &types.ResourceConfig{ExcludeTopology: true}
@@ -202,8 +202,8 @@ var _ = Describe("HostDevice", func() {
 		mockInfo2.On("GetEnvVal").Return(mockEnv2)
 		mockInfo2.On("GetDeviceSpecs").Return(mockSpec2)
 		mockInfo2.On("GetMounts").Return(nil)
-		f.On("GetDefaultInfoProvider", pciAddr1, "mlx5_core").Return([]types.DeviceInfoProvider{mockInfo1}).
-			On("GetDefaultInfoProvider", pciAddr2, "mlx5_core").Return([]types.DeviceInfoProvider{mockInfo2})
+		f.On("GetDefaultInfoProvider", pciAddr1, "mlx5_core", types.VfioMode("")).Return([]types.DeviceInfoProvider{mockInfo1}).
+			On("GetDefaultInfoProvider", pciAddr2, "mlx5_core", types.VfioMode("")).Return([]types.DeviceInfoProvider{mockInfo2})
 
 		in1 := newPciDeviceFn(pciAddr1)
 		in2 := newPciDeviceFn(pciAddr2)
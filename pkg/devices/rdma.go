@@ -18,7 +18,10 @@
 package devices
 
 import (
-	"github.com/golang/glog"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
@@ -59,7 +62,7 @@ func (r *rdmaSpec) IsRdma() bool {
 	// This scenario cann happen if the device is discovered, assigned to a pod and then the plugin is restarted.
 	rdma, err := utils.HasRdmaParam(bus, r.deviceID)
 	if err != nil {
-		glog.Infof("HasRdmaParam(): unable to get Netlink RDMA param for device %s : %q", r.deviceID, err)
+		klog.V(2).InfoS("Unable to get Netlink RDMA param", "deviceID", r.deviceID, "err", err)
 		return false
 	}
 	return rdma
@@ -103,3 +106,47 @@ func (r *rdmaSpec) GetRdmaDeviceName() string {
 	}
 	return ""
 }
+
+// GetRdmaPortInfo returns the attributes of the first port of the RDMA device, nil if unavailable
+func (r *rdmaSpec) GetRdmaPortInfo() *types.RdmaPortInfo {
+	rdmaDev := r.GetRdmaDeviceName()
+	if rdmaDev == "" {
+		return nil
+	}
+	port, err := utils.GetRdmaFirstPort(rdmaDev)
+	if err != nil {
+		klog.V(2).InfoS("Unable to get RDMA port", "rdmaDevice", rdmaDev, "err", err)
+		return nil
+	}
+	info := &types.RdmaPortInfo{Port: port}
+	if info.LinkLayer, err = utils.GetRdmaLinkLayer(rdmaDev, port); err != nil {
+		klog.V(2).InfoS("Unable to get RDMA link layer", "rdmaDevice", rdmaDev, "err", err)
+	}
+	gids, err := utils.GetRdmaGids(rdmaDev, port)
+	if err != nil {
+		klog.V(2).InfoS("Unable to get RDMA GID table", "rdmaDevice", rdmaDev, "err", err)
+		return info
+	}
+	indexes := make([]int, 0, len(gids))
+	for index := range gids {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		info.Gids = append(info.Gids, types.RdmaGid{Index: index, Gid: gids[index], Type: utils.GetRdmaGidType(rdmaDev, port, index)})
+	}
+	if gid, ok := gids[0]; ok {
+		info.PortGUID = portGUID(gid)
+	}
+	return info
+}
+
+// portGUID returns the port GUID held in the interface ID, i.e. the lower 64 bits, of the default GID of a port
+func portGUID(gid string) string {
+	groups := strings.Split(gid, ":")
+	//nolint: mnd
+	if len(groups) != 8 {
+		return ""
+	}
+	return strings.Join(groups[4:], ":")
+}
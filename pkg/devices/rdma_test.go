@@ -127,4 +127,42 @@ var _ = Describe("RdmaSpec", func() {
 			})
 		})
 	})
+	Describe("getting the port info of the RDMA device", func() {
+		It("should return the link layer, port GUID and used GID table entries of the first port", func() {
+			fs := &utils.FakeFilesystem{
+				Dirs: []string{"sys/class/infiniband/mlx5_2/ports/1/gids", "sys/class/infiniband/mlx5_2/ports/2",
+					"sys/class/infiniband/mlx5_2/ports/1/gid_attrs/types"},
+				Files: map[string][]byte{
+					"sys/class/infiniband/mlx5_2/ports/1/link_layer":        []byte("Ethernet\n"),
+					"sys/class/infiniband/mlx5_2/ports/1/gids/0":            []byte("fe80:0000:0000:0000:0ac0:ebff:fe3a:1c2d\n"),
+					"sys/class/infiniband/mlx5_2/ports/1/gids/1":            []byte("fe80:0000:0000:0000:0ac0:ebff:fe3a:1c2d\n"),
+					"sys/class/infiniband/mlx5_2/ports/1/gids/2":            []byte("0000:0000:0000:0000:0000:0000:0000:0000\n"),
+					"sys/class/infiniband/mlx5_2/ports/1/gid_attrs/types/0": []byte("IB/RoCE v1\n"),
+					"sys/class/infiniband/mlx5_2/ports/1/gid_attrs/types/1": []byte("RoCE v2\n"),
+				},
+			}
+			defer fs.Use()()
+			fakeRdmaProvider := mocks.RdmaProvider{}
+			fakeRdmaProvider.On("GetRdmaDevicesForPcidev", "0000:00:00.0").Return([]string{"mlx5_2"})
+			utils.SetRdmaProviderInst(&fakeRdmaProvider)
+
+			spec := devices.NewRdmaSpec(types.NetDeviceType, "0000:00:00.0")
+			Expect(spec.GetRdmaPortInfo()).To(Equal(&types.RdmaPortInfo{
+				Port:      "1",
+				LinkLayer: "Ethernet",
+				PortGUID:  "0ac0:ebff:fe3a:1c2d",
+				Gids: []types.RdmaGid{
+					{Index: 0, Gid: "fe80:0000:0000:0000:0ac0:ebff:fe3a:1c2d", Type: "IB/RoCE v1"},
+					{Index: 1, Gid: "fe80:0000:0000:0000:0ac0:ebff:fe3a:1c2d", Type: "RoCE v2"},
+				},
+			}))
+		})
+		It("should return nil without RDMA device", func() {
+			fakeRdmaProvider := mocks.RdmaProvider{}
+			fakeRdmaProvider.On("GetRdmaDevicesForPcidev", "0000:00:00.0").Return([]string{})
+			utils.SetRdmaProviderInst(&fakeRdmaProvider)
+
+			Expect(devices.NewRdmaSpec(types.NetDeviceType, "0000:00:00.0").GetRdmaPortInfo()).To(BeNil())
+		})
+	})
 })
@@ -19,8 +19,9 @@ package devices
 import (
 	"fmt"
 
-	"github.com/golang/glog"
 	"github.com/k8snetworkplumbingwg/govdpa/pkg/kvdpa"
+	nettypes "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"k8s.io/klog/v2"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
@@ -56,10 +57,39 @@ func (v *vdpaDevice) GetPath() (string, error) {
 func GetVdpaDevice(pciAddr string) types.VdpaDevice {
 	detailVdpaDev, err := utils.GetVdpaProvider().GetVdpaDeviceByPci(pciAddr)
 	if err != nil {
-		glog.Infof("%s - No vDPA device found: %s", pciAddr, err)
+		klog.V(2).InfoS("No vDPA device found", "pciAddress", pciAddr, "err", err)
 		return nil
 	}
 	return &vdpaDevice{
 		detailVdpaDev,
 	}
 }
+
+// GetAuxVdpaDevice returns a VdpaDevice from a given auxiliary device name
+func GetAuxVdpaDevice(auxDev string) types.VdpaDevice {
+	detailVdpaDev, err := utils.GetVdpaProvider().GetVdpaDeviceByAux(auxDev)
+	if err != nil {
+		klog.V(2).InfoS("No vDPA device found", "deviceID", auxDev, "err", err)
+		return nil
+	}
+	return &vdpaDevice{
+		detailVdpaDev,
+	}
+}
+
+// GetVdpaDeviceInfo returns the device-info-spec description of a vDPA device. The path is only set for
+// vhost-vdpa devices.
+func GetVdpaDeviceInfo(vdpaDev types.VdpaDevice) *nettypes.VdpaDevice {
+	info := &nettypes.VdpaDevice{
+		ParentDevice: vdpaDev.GetParent(),
+		Driver:       string(vdpaDev.GetType()),
+	}
+	if vdpaDev.GetType() == types.VdpaVhostType {
+		vdpaPath, err := vdpaDev.GetPath()
+		if err != nil {
+			klog.ErrorS(err, "Unexpected error when fetching the vdpa device path")
+		}
+		info.Path = vdpaPath
+	}
+	return info
+}
@@ -88,4 +88,26 @@ var _ = Describe("VdpaDevice", func() {
 			fakeVdpaProvider.AssertExpectations(t)
 		})
 	})
+	Context("getting the device of an auxiliary device", func() {
+		It("no valid vdpa device for auxiliary device", func() {
+			fakeVdpaProvider := mocks.VdpaProvider{}
+			fakeVdpaProvider.On("GetVdpaDeviceByAux", "mlx5_core.sf.4").Return(nil, fmt.Errorf("ERROR"))
+			utils.SetVdpaProviderInst(&fakeVdpaProvider)
+			dev := devices.GetAuxVdpaDevice("mlx5_core.sf.4")
+
+			Expect(dev).To(BeNil())
+			fakeVdpaProvider.AssertExpectations(t)
+		})
+		It("supported vdpa type", func() {
+			fakeKvdpaDev := &fakeKvdpaDevice{driver: types.SupportedVdpaTypes[types.VdpaVhostType]}
+			fakeVdpaProvider := mocks.VdpaProvider{}
+			fakeVdpaProvider.On("GetVdpaDeviceByAux", "mlx5_core.sf.4").Return(fakeKvdpaDev, nil)
+			utils.SetVdpaProviderInst(&fakeVdpaProvider)
+			dev := devices.GetAuxVdpaDevice("mlx5_core.sf.4")
+
+			Expect(dev).NotTo(BeNil())
+			Expect(dev.GetType()).To(Equal(types.VdpaVhostType))
+			fakeVdpaProvider.AssertExpectations(t)
+		})
+	})
 })
@@ -0,0 +1,13 @@
+package dra
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDra(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DRA Suite")
+}
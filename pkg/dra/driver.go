@@ -0,0 +1,419 @@
+// Package dra implements a Kubernetes Dynamic Resource Allocation (DRA) kubelet plugin
+// publishing the resource pools of the device plugin as ResourceSlices
+package dra
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	drapb "k8s.io/kubelet/pkg/apis/dra/v1"
+	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
+
+	cdiPkg "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+)
+
+const (
+	// DefaultDriverName is the default name of the DRA driver
+	DefaultDriverName = "sriovnetwork.k8snetworkplumbingwg.io"
+
+	unix             = "unix"
+	pluginSocketName = "dra.sock"
+	probeInterval    = 20 * time.Second
+	apiTimeout       = 30 * time.Second
+)
+
+// Driver is a DRA kubelet plugin serving a set of ResourcePools
+type Driver struct {
+	drapb.UnimplementedDRAPluginServer
+	registerapi.UnimplementedRegistrationServer
+	driverName        string
+	nodeName          string
+	resourcePrefix    string
+	client            kubernetes.Interface
+	cdi               cdiPkg.CDI
+	registrarSockPath string
+	pluginSockPath    string
+	registrarServer   *grpc.Server
+	pluginServer      *grpc.Server
+	lock              sync.RWMutex
+	pools             map[string]types.ResourcePool // keyed by DRA pool name
+	prepared          map[string][]preparedDevices  // devices with device info files, keyed by claim UID
+	stopProbe         chan struct{}
+	publishLock       sync.Mutex
+	log               klog.Logger
+}
+
+// NewKubeClient returns a Kubernetes client using the given kubeconfig file, or the in-cluster config if empty
+func NewKubeClient(kubeConfig string) (kubernetes.Interface, error) {
+	var config *rest.Config
+	var err error
+	if kubeConfig != "" {
+		config, err = clientcmd.BuildConfigFromFlags("", kubeConfig)
+	} else {
+		config, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get Kubernetes client config: %v", err)
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// NewDriver returns a DRA Driver publishing devices for nodeName. resourcePrefix is used for pools
+// that do not override it.
+func NewDriver(driverName, nodeName, resourcePrefix string, client kubernetes.Interface) *Driver {
+	return &Driver{
+		driverName:        driverName,
+		nodeName:          nodeName,
+		resourcePrefix:    resourcePrefix,
+		client:            client,
+		cdi:               cdiPkg.New(),
+		registrarSockPath: filepath.Join(types.SockDir, driverName+"-reg.sock"),
+		pluginSockPath:    filepath.Join(types.DRAPluginsDir, driverName, pluginSocketName),
+		pools:             make(map[string]types.ResourcePool),
+		prepared:          make(map[string][]preparedDevices),
+		log:               klog.Background().WithName("dra").WithValues("driverName", driverName),
+	}
+}
+
+// preparedDevices are the devices of a pool prepared for a claim
+type preparedDevices struct {
+	pool      types.ResourcePool
+	deviceIDs []string
+}
+
+// Start publishes the ResourceSlices of the given pools and starts serving the kubelet
+func (d *Driver) Start(pools []types.ResourcePool) error {
+	if err := d.setPools(pools); err != nil {
+		return err
+	}
+	if err := d.publishResourceSlices(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.pluginSockPath), 0o750); err != nil {
+		return fmt.Errorf("unable to create DRA plugin directory: %v", err)
+	}
+	d.pluginServer = grpc.NewServer()
+	drapb.RegisterDRAPluginServer(d.pluginServer, d)
+	if err := serve(d.pluginServer, d.pluginSockPath); err != nil {
+		return err
+	}
+
+	d.registrarServer = grpc.NewServer()
+	registerapi.RegisterRegistrationServer(d.registrarServer, d)
+	if err := serve(d.registrarServer, d.registrarSockPath); err != nil {
+		d.pluginServer.Stop()
+		return err
+	}
+	d.log.Info("DRA driver started", "endpoint", d.pluginSockPath)
+
+	d.startProbeLoop()
+	return nil
+}
+
+// Stop stops serving the kubelet. Published ResourceSlices are kept so that allocated devices
+// stay known to the scheduler while the driver restarts.
+func (d *Driver) Stop() {
+	if d.stopProbe != nil {
+		close(d.stopProbe)
+		d.stopProbe = nil
+	}
+	for _, srv := range []*grpc.Server{d.registrarServer, d.pluginServer} {
+		if srv != nil {
+			srv.Stop()
+		}
+	}
+	d.registrarServer = nil
+	d.pluginServer = nil
+	for _, sock := range []string{d.registrarSockPath, d.pluginSockPath} {
+		if err := os.Remove(sock); err != nil && !os.IsNotExist(err) {
+			d.log.Error(err, "Unable to remove DRA socket", "socket", sock)
+		}
+	}
+	d.log.Info("DRA driver stopped")
+}
+
+// UpdatePools replaces the served pools and publishes their ResourceSlices
+func (d *Driver) UpdatePools(pools []types.ResourcePool) error {
+	if err := d.setPools(pools); err != nil {
+		return err
+	}
+	return d.publishResourceSlices()
+}
+
+// setPools replaces the served pools. Pools whose resource names map to the same DRA pool name are rejected.
+func (d *Driver) setPools(pools []types.ResourcePool) error {
+	byName := make(map[string]types.ResourcePool, len(pools))
+	for _, pool := range pools {
+		name := d.poolName(pool)
+		if other, ok := byName[name]; ok {
+			return fmt.Errorf("resource pools %s and %s have the same DRA pool name %s",
+				other.GetResourceName(), pool.GetResourceName(), name)
+		}
+		byName[name] = pool
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.pools = byName
+	return nil
+}
+
+// getPools returns a snapshot of the served pools
+func (d *Driver) getPools() map[string]types.ResourcePool {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.pools
+}
+
+// getPrefix returns the resource prefix of a pool
+func (d *Driver) getPrefix(pool types.ResourcePool) string {
+	if prefix := pool.GetResourcePrefix(); prefix != "" {
+		return prefix
+	}
+	return d.resourcePrefix
+}
+
+// serve starts serving srv on a unix socket at sockPath
+func serve(srv *grpc.Server, sockPath string) error {
+	if err := os.Remove(sockPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove stale socket %s: %v", sockPath, err)
+	}
+	lis, err := net.Listen(unix, sockPath)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %v", sockPath, err)
+	}
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			klog.ErrorS(err, "Serving incoming requests failed", "socket", sockPath)
+		}
+	}()
+	return nil
+}
+
+// startProbeLoop periodically health checks the devices of all pools and republishes the
+// ResourceSlices when the health of a device changed
+func (d *Driver) startProbeLoop() {
+	stop := make(chan struct{})
+	d.stopProbe = stop
+	go func() {
+		ticker := time.NewTicker(probeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				changed := false
+				for _, pool := range d.getPools() {
+					if pool.Probe() {
+						changed = true
+					}
+				}
+				if changed {
+					if err := d.publishResourceSlices(); err != nil {
+						d.log.Error(err, "Unable to publish ResourceSlices")
+					}
+				}
+			}
+		}
+	}()
+}
+
+// GetInfo is the RPC invoked by the kubelet plugin watcher
+func (d *Driver) GetInfo(ctx context.Context, rqt *registerapi.InfoRequest) (*registerapi.PluginInfo, error) {
+	return &registerapi.PluginInfo{
+		Type:              registerapi.DRAPlugin,
+		Name:              d.driverName,
+		Endpoint:          d.pluginSockPath,
+		SupportedVersions: []string{drapb.DRAPluginService},
+	}, nil
+}
+
+// NotifyRegistrationStatus is the RPC invoked by the kubelet plugin watcher once registration is done
+func (d *Driver) NotifyRegistrationStatus(ctx context.Context,
+	regstat *registerapi.RegistrationStatus) (*registerapi.RegistrationStatusResponse, error) {
+	if regstat.PluginRegistered {
+		d.log.Info("DRA driver gets registered successfully at Kubelet")
+	} else {
+		d.log.Error(nil, "DRA driver failed to be registered at Kubelet", "error", regstat.Error)
+	}
+	return &registerapi.RegistrationStatusResponse{}, nil
+}
+
+// NodePrepareResources prepares the devices allocated to ResourceClaims and returns their CDI device IDs
+func (d *Driver) NodePrepareResources(ctx context.Context,
+	req *drapb.NodePrepareResourcesRequest) (*drapb.NodePrepareResourcesResponse, error) {
+	resp := &drapb.NodePrepareResourcesResponse{Claims: make(map[string]*drapb.NodePrepareResourceResponse)}
+	for _, claim := range req.Claims {
+		log := d.claimLogger("NodePrepareResources", claim)
+		devices, err := d.prepareClaim(ctx, claim)
+		if err != nil {
+			log.Error(err, "Unable to prepare claim")
+			resp.Claims[claim.UID] = &drapb.NodePrepareResourceResponse{Error: err.Error()}
+			continue
+		}
+		for _, dev := range devices {
+			log.Info("Prepared device", "pool", dev.PoolName, "device", dev.DeviceName, "cdiDeviceIDs", dev.CDIDeviceIDs)
+		}
+		resp.Claims[claim.UID] = &drapb.NodePrepareResourceResponse{Devices: devices}
+	}
+	return resp, nil
+}
+
+// claimLogger returns the logger of a kubelet request for a claim
+func (d *Driver) claimLogger(method string, claim *drapb.Claim) klog.Logger {
+	return d.log.WithValues("method", method, "claim", claim.Namespace+"/"+claim.Name, "claimUID", claim.UID)
+}
+
+// prepareClaim returns the devices of this driver allocated to a claim, writing the CDI specs and
+// device info files of their pools
+func (d *Driver) prepareClaim(ctx context.Context, claim *drapb.Claim) ([]*drapb.Device, error) {
+	results, err := d.claimResults(ctx, claim)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := d.getPools()
+	devices := make([]*drapb.Device, 0)
+	poolDeviceIDs := make(map[string][]string)
+	for _, result := range results {
+		pool, ok := pools[result.Pool]
+		if !ok {
+			return nil, fmt.Errorf("unknown pool %s", result.Pool)
+		}
+		deviceID, ok := lookupDevice(pool, result.Device)
+		if !ok {
+			return nil, fmt.Errorf("unknown device %s in pool %s", result.Device, result.Pool)
+		}
+		poolDeviceIDs[result.Pool] = append(poolDeviceIDs[result.Pool], deviceID)
+		devices = append(devices, &drapb.Device{
+			RequestNames: []string{result.Request},
+			PoolName:     result.Pool,
+			DeviceName:   result.Device,
+			CDIDeviceIDs: d.cdi.GetQualifiedNames([]string{deviceID}, d.getPrefix(pool), pool.GetCDIName()),
+		})
+	}
+
+	prepared := make([]preparedDevices, 0, len(poolDeviceIDs))
+	for poolName, deviceIDs := range poolDeviceIDs {
+		pool := pools[poolName]
+		prefix := d.getPrefix(pool)
+		if err := d.cdi.CreateCDISpecForPool(prefix, pool); err != nil {
+			return nil, fmt.Errorf("unable to create CDI spec for pool %s: %v", poolName, err)
+		}
+		if err := pool.StoreDeviceInfoFile(prefix, deviceIDs); err != nil {
+			return nil, fmt.Errorf("unable to store device info files for pool %s: %v", poolName, err)
+		}
+		prepared = append(prepared, preparedDevices{pool: pool, deviceIDs: deviceIDs})
+	}
+	d.lock.Lock()
+	d.prepared[claim.UID] = prepared
+	d.lock.Unlock()
+	return devices, nil
+}
+
+// claimResults returns the devices of this driver allocated to a claim
+func (d *Driver) claimResults(ctx context.Context, claim *drapb.Claim) ([]resourceapi.DeviceRequestAllocationResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+	rc, err := d.client.ResourceV1().ResourceClaims(claim.Namespace).Get(ctx, claim.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get ResourceClaim: %v", err)
+	}
+	if string(rc.UID) != claim.UID {
+		return nil, fmt.Errorf("ResourceClaim UID %s does not match %s", rc.UID, claim.UID)
+	}
+	if rc.Status.Allocation == nil {
+		return nil, fmt.Errorf("ResourceClaim is not allocated")
+	}
+	results := make([]resourceapi.DeviceRequestAllocationResult, 0)
+	for _, result := range rc.Status.Allocation.Devices.Results {
+		if result.Driver == d.driverName {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// NodeUnprepareResources releases the devices of ResourceClaims by removing the device info files
+// written when they were prepared. Devices themselves are left as they are.
+func (d *Driver) NodeUnprepareResources(ctx context.Context,
+	req *drapb.NodeUnprepareResourcesRequest) (*drapb.NodeUnprepareResourcesResponse, error) {
+	resp := &drapb.NodeUnprepareResourcesResponse{Claims: make(map[string]*drapb.NodeUnprepareResourceResponse)}
+	for _, claim := range req.Claims {
+		log := d.claimLogger("NodeUnprepareResources", claim)
+		if err := d.unprepareClaim(ctx, claim); err != nil {
+			log.Error(err, "Unable to unprepare claim")
+			resp.Claims[claim.UID] = &drapb.NodeUnprepareResourceResponse{Error: err.Error()}
+			continue
+		}
+		log.Info("Unprepared claim")
+		resp.Claims[claim.UID] = &drapb.NodeUnprepareResourceResponse{}
+	}
+	return resp, nil
+}
+
+// unprepareClaim removes the device info files of the devices prepared for a claim. The devices of claims
+// prepared before the driver started are looked up in the ResourceClaim, if it still exists.
+func (d *Driver) unprepareClaim(ctx context.Context, claim *drapb.Claim) error {
+	d.lock.Lock()
+	prepared, ok := d.prepared[claim.UID]
+	d.lock.Unlock()
+	if !ok {
+		prepared = d.allocatedDevices(ctx, claim)
+	}
+
+	errs := make([]string, 0)
+	for _, p := range prepared {
+		if err := p.pool.CleanDeviceInfoFile(d.getPrefix(p.pool), p.deviceIDs); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to clean device info files: %s", strings.Join(errs, ", "))
+	}
+
+	d.lock.Lock()
+	delete(d.prepared, claim.UID)
+	d.lock.Unlock()
+	return nil
+}
+
+// allocatedDevices returns the devices of the served pools allocated to a claim, none if the claim is gone
+func (d *Driver) allocatedDevices(ctx context.Context, claim *drapb.Claim) []preparedDevices {
+	results, err := d.claimResults(ctx, claim)
+	if err != nil {
+		d.claimLogger("NodeUnprepareResources", claim).V(2).Info("Unable to find the devices of the claim", "err", err)
+		return nil
+	}
+	pools := d.getPools()
+	byPool := make(map[string][]string)
+	for _, result := range results {
+		pool, ok := pools[result.Pool]
+		if !ok {
+			continue
+		}
+		if deviceID, ok := lookupDevice(pool, result.Device); ok {
+			byPool[result.Pool] = append(byPool[result.Pool], deviceID)
+		}
+	}
+	prepared := make([]preparedDevices, 0, len(byPool))
+	for poolName, deviceIDs := range byPool {
+		prepared = append(prepared, preparedDevices{pool: pools[poolName], deviceIDs: deviceIDs})
+	}
+	return prepared
+}
@@ -0,0 +1,259 @@
+package dra
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	drapb "k8s.io/kubelet/pkg/apis/dra/v1"
+
+	cdimocks "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/cdi/mocks"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
+)
+
+const (
+	testNode   = "worker-0"
+	testPrefix = "example.com"
+)
+
+func newTestDevice(pciAddr string, health string) *mocks.PciNetDevice {
+	dev := &mocks.PciNetDevice{}
+	dev.On("GetDeviceID").Return(pciAddr).
+		On("GetPciAddr").Return(pciAddr).
+		On("GetVendor").Return("8086").
+		On("GetDeviceCode").Return("154c").
+		On("GetDriver").Return("iavf").
+		On("GetPfNetName").Return("ens1f0").
+		On("GetPfPciAddr").Return("0000:3b:00.0").
+		On("GetNetName").Return("").
+		On("GetLinkType").Return("ether").
+		On("IsRdma").Return(false).
+		On("GetVdpaDevice").Return(nil).
+		On("GetAPIDevice").Return(&pluginapi.Device{
+		ID:       pciAddr,
+		Health:   health,
+		Topology: &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: 1}}},
+	})
+	return dev
+}
+
+func newTestPool(name string, devices map[string]types.HostDevice) *mocks.ResourcePool {
+	rp := &mocks.ResourcePool{}
+	rp.On("GetResourceName").Return(name).
+		On("GetResourcePrefix").Return("").
+		On("GetCDIName").Return(name).
+		On("GetDevicePool").Return(devices)
+	apiDevices := make(map[string]*pluginapi.Device, len(devices))
+	for id, dev := range devices {
+		apiDevices[id] = dev.GetAPIDevice()
+	}
+	rp.On("GetDevices").Return(apiDevices).Maybe()
+	return rp
+}
+
+func listSlices(d *Driver) []resourceapi.ResourceSlice {
+	list, err := d.client.ResourceV1().ResourceSlices().List(context.TODO(), metav1.ListOptions{})
+	Expect(err).NotTo(HaveOccurred())
+	return list.Items
+}
+
+var _ = Describe("Driver", func() {
+	var (
+		d      *Driver
+		cdi    *cdimocks.CDI
+		pool   *mocks.ResourcePool
+		client *fake.Clientset
+	)
+
+	BeforeEach(func() {
+		client = fake.NewClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: testNode, UID: "node-uid"}})
+		cdi = &cdimocks.CDI{}
+		d = NewDriver(DefaultDriverName, testNode, testPrefix, client)
+		d.cdi = cdi
+		pool = newTestPool("intel_sriov_netdevice", map[string]types.HostDevice{
+			"0000:3b:02.1": newTestDevice("0000:3b:02.1", pluginapi.Healthy),
+			"0000:3b:02.0": newTestDevice("0000:3b:02.0", pluginapi.Healthy),
+			"0000:3b:02.2": newTestDevice("0000:3b:02.2", pluginapi.Unhealthy),
+		})
+	})
+
+	Describe("publishing ResourceSlices", func() {
+		It("should publish the healthy devices of a pool with their attributes", func() {
+			Expect(d.UpdatePools([]types.ResourcePool{pool})).To(Succeed())
+
+			slices := listSlices(d)
+			Expect(slices).To(HaveLen(1))
+			slice := slices[0]
+			Expect(slice.Name).To(Equal("worker-0-example-com-intel-sriov-netdevice-0"))
+			Expect(slice.OwnerReferences).To(HaveLen(1))
+			Expect(slice.OwnerReferences[0].Name).To(Equal(testNode))
+			Expect(slice.Spec.Driver).To(Equal(DefaultDriverName))
+			Expect(*slice.Spec.NodeName).To(Equal(testNode))
+			Expect(slice.Spec.Pool).To(Equal(resourceapi.ResourcePool{
+				Name: "example.com/intel-sriov-netdevice", Generation: 1, ResourceSliceCount: 1,
+			}))
+			Expect(slice.Spec.Devices).To(HaveLen(2))
+			Expect(slice.Spec.Devices[0].Name).To(Equal("0000-3b-02-0"))
+			Expect(slice.Spec.Devices[1].Name).To(Equal("0000-3b-02-1"))
+
+			attrs := slice.Spec.Devices[0].Attributes
+			Expect(*attrs[attrVendor].StringValue).To(Equal("8086"))
+			Expect(*attrs[attrDriver].StringValue).To(Equal("iavf"))
+			Expect(*attrs[attrPfName].StringValue).To(Equal("ens1f0"))
+			Expect(*attrs[attrLinkType].StringValue).To(Equal("ether"))
+			Expect(*attrs[attrPciAddress].StringValue).To(Equal("0000:3b:02.0"))
+			Expect(*attrs[attrRdma].BoolValue).To(BeFalse())
+			Expect(*attrs[attrNumaNode].IntValue).To(Equal(int64(1)))
+			Expect(attrs).NotTo(HaveKey(resourceapi.QualifiedName(attrNetName)))
+			Expect(attrs).NotTo(HaveKey(resourceapi.QualifiedName(attrVdpaType)))
+		})
+		It("should not bump the pool generation when the devices did not change", func() {
+			Expect(d.UpdatePools([]types.ResourcePool{pool})).To(Succeed())
+			Expect(d.UpdatePools([]types.ResourcePool{pool})).To(Succeed())
+			Expect(listSlices(d)[0].Spec.Pool.Generation).To(Equal(int64(1)))
+		})
+		It("should bump the pool generation when the devices changed", func() {
+			Expect(d.UpdatePools([]types.ResourcePool{pool})).To(Succeed())
+			changed := newTestPool("intel_sriov_netdevice", map[string]types.HostDevice{
+				"0000:3b:02.0": newTestDevice("0000:3b:02.0", pluginapi.Healthy),
+			})
+			Expect(d.UpdatePools([]types.ResourcePool{changed})).To(Succeed())
+
+			slices := listSlices(d)
+			Expect(slices).To(HaveLen(1))
+			Expect(slices[0].Spec.Pool.Generation).To(Equal(int64(2)))
+			Expect(slices[0].Spec.Devices).To(HaveLen(1))
+		})
+		It("should split large pools into several slices", func() {
+			devices := make(map[string]types.HostDevice)
+			for i := 0; i < resourceapi.ResourceSliceMaxDevices+2; i++ {
+				id := "dev" + string(rune('a'+i/26)) + string(rune('a'+i%26))
+				devices[id] = newTestDevice(id, pluginapi.Healthy)
+			}
+			Expect(d.UpdatePools([]types.ResourcePool{newTestPool("big", devices)})).To(Succeed())
+
+			slices := listSlices(d)
+			Expect(slices).To(HaveLen(2))
+			total := 0
+			for _, slice := range slices {
+				Expect(slice.Spec.Pool.ResourceSliceCount).To(Equal(int64(2)))
+				total += len(slice.Spec.Devices)
+			}
+			Expect(total).To(Equal(resourceapi.ResourceSliceMaxDevices + 2))
+		})
+		It("should reject pools with the same DRA pool name", func() {
+			other := newTestPool("intel-sriov-netdevice", map[string]types.HostDevice{})
+			Expect(d.UpdatePools([]types.ResourcePool{pool, other})).To(MatchError(ContainSubstring("same DRA pool name")))
+		})
+		It("should delete the slices of removed pools", func() {
+			Expect(d.UpdatePools([]types.ResourcePool{pool})).To(Succeed())
+			Expect(d.UpdatePools([]types.ResourcePool{})).To(Succeed())
+			Expect(listSlices(d)).To(BeEmpty())
+		})
+	})
+
+	Describe("preparing resources", func() {
+		newClaim := func(results ...resourceapi.DeviceRequestAllocationResult) *resourceapi.ResourceClaim {
+			return &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "claim", Namespace: "default", UID: "claim-uid"},
+				Status: resourceapi.ResourceClaimStatus{
+					Allocation: &resourceapi.AllocationResult{
+						Devices: resourceapi.DeviceAllocationResult{Results: results},
+					},
+				},
+			}
+		}
+		request := &drapb.NodePrepareResourcesRequest{
+			Claims: []*drapb.Claim{{Namespace: "default", Name: "claim", UID: "claim-uid"}},
+		}
+
+		BeforeEach(func() {
+			Expect(d.setPools([]types.ResourcePool{pool})).To(Succeed())
+		})
+
+		It("should return the CDI devices of the allocated devices", func() {
+			_, err := client.ResourceV1().ResourceClaims("default").Create(context.TODO(), newClaim(
+				resourceapi.DeviceRequestAllocationResult{
+					Request: "vf", Driver: DefaultDriverName, Pool: "example.com/intel-sriov-netdevice", Device: "0000-3b-02-1",
+				},
+				resourceapi.DeviceRequestAllocationResult{
+					Request: "gpu", Driver: "gpu.example.com", Pool: "gpus", Device: "gpu-0",
+				}), metav1.CreateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			cdi.On("GetQualifiedNames", []string{"0000:3b:02.1"}, testPrefix, "intel_sriov_netdevice").
+				Return([]string{"example.com/net-intel_sriov_netdevice=0000:3b:02.1"}).
+				On("CreateCDISpecForPool", testPrefix, pool).Return(nil)
+			pool.On("StoreDeviceInfoFile", testPrefix, []string{"0000:3b:02.1"}).Return(nil)
+
+			resp, err := d.NodePrepareResources(context.TODO(), request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Claims["claim-uid"].Error).To(BeEmpty())
+			Expect(resp.Claims["claim-uid"].Devices).To(Equal([]*drapb.Device{{
+				RequestNames: []string{"vf"},
+				PoolName:     "example.com/intel-sriov-netdevice",
+				DeviceName:   "0000-3b-02-1",
+				CDIDeviceIDs: []string{"example.com/net-intel_sriov_netdevice=0000:3b:02.1"},
+			}}))
+			cdi.AssertExpectations(GinkgoT())
+			pool.AssertExpectations(GinkgoT())
+		})
+		It("should clean the device info files of the prepared devices on unprepare", func() {
+			_, err := client.ResourceV1().ResourceClaims("default").Create(context.TODO(), newClaim(
+				resourceapi.DeviceRequestAllocationResult{
+					Request: "vf", Driver: DefaultDriverName, Pool: "example.com/intel-sriov-netdevice", Device: "0000-3b-02-1",
+				}), metav1.CreateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			cdi.On("GetQualifiedNames", mock.Anything, testPrefix, "intel_sriov_netdevice").Return([]string{}).
+				On("CreateCDISpecForPool", testPrefix, pool).Return(nil)
+			pool.On("StoreDeviceInfoFile", testPrefix, []string{"0000:3b:02.1"}).Return(nil).
+				On("CleanDeviceInfoFile", testPrefix, []string{"0000:3b:02.1"}).Return(nil).Once()
+			_, err = d.NodePrepareResources(context.TODO(), request)
+			Expect(err).NotTo(HaveOccurred())
+			// the claim is gone by the time it is unprepared
+			Expect(client.ResourceV1().ResourceClaims("default").Delete(context.TODO(), "claim", metav1.DeleteOptions{})).
+				To(Succeed())
+
+			resp, err := d.NodeUnprepareResources(context.TODO(), &drapb.NodeUnprepareResourcesRequest{Claims: request.Claims})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Claims["claim-uid"].Error).To(BeEmpty())
+			pool.AssertExpectations(GinkgoT())
+		})
+		It("should clean the device info files of devices prepared before the driver started on unprepare", func() {
+			_, err := client.ResourceV1().ResourceClaims("default").Create(context.TODO(), newClaim(
+				resourceapi.DeviceRequestAllocationResult{
+					Request: "vf", Driver: DefaultDriverName, Pool: "example.com/intel-sriov-netdevice", Device: "0000-3b-02-0",
+				}), metav1.CreateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			pool.On("CleanDeviceInfoFile", testPrefix, []string{"0000:3b:02.0"}).Return(nil).Once()
+
+			resp, err := d.NodeUnprepareResources(context.TODO(), &drapb.NodeUnprepareResourcesRequest{Claims: request.Claims})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Claims["claim-uid"].Error).To(BeEmpty())
+			pool.AssertCalled(GinkgoT(), "CleanDeviceInfoFile", testPrefix, []string{"0000:3b:02.0"})
+		})
+		It("should report an error for an unknown device", func() {
+			_, err := client.ResourceV1().ResourceClaims("default").Create(context.TODO(), newClaim(
+				resourceapi.DeviceRequestAllocationResult{
+					Request: "vf", Driver: DefaultDriverName, Pool: "example.com/intel-sriov-netdevice", Device: "0000-3b-0f-0",
+				}), metav1.CreateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			resp, err := d.NodePrepareResources(context.TODO(), request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Claims["claim-uid"].Error).To(ContainSubstring("unknown device"))
+			cdi.AssertNotCalled(GinkgoT(), "CreateCDISpecForPool", mock.Anything, mock.Anything)
+		})
+		It("should report an error for a claim that does not exist", func() {
+			resp, err := d.NodePrepareResources(context.TODO(), request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Claims["claim-uid"].Error).NotTo(BeEmpty())
+		})
+	})
+})
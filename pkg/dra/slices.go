@@ -0,0 +1,264 @@
+package dra
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+)
+
+// Device attributes published in ResourceSlices, pods select devices with CEL expressions
+// such as device.attributes["sriovnetwork.k8snetworkplumbingwg.io"].pfName == "ens1f0"
+const (
+	attrResourceName = "resourceName"
+	attrVendor       = "vendor"
+	attrDeviceID     = "deviceID"
+	attrDriver       = "driver"
+	attrPciAddress   = "pciAddress"
+	attrPfName       = "pfName"
+	attrPfPciAddress = "pfPciAddress"
+	attrNetName      = "netName"
+	attrLinkType     = "linkType"
+	attrNumaNode     = "numaNode"
+	attrRdma         = "rdma"
+	attrVdpaType     = "vdpaType"
+	attrAuxType      = "auxType"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// sanitizeName turns a string into a valid DNS label
+func sanitizeName(name string) string {
+	return strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// DeviceName returns the name of a device in a ResourceSlice
+func DeviceName(deviceID string) string {
+	return sanitizeName(deviceID)
+}
+
+// poolName returns the DRA pool name of a ResourcePool
+func (d *Driver) poolName(pool types.ResourcePool) string {
+	return d.getPrefix(pool) + "/" + sanitizeName(pool.GetResourceName())
+}
+
+// lookupDevice returns the ID of the device of a pool with the given ResourceSlice device name
+func lookupDevice(pool types.ResourcePool, name string) (string, bool) {
+	for id := range pool.GetDevicePool() {
+		if DeviceName(id) == name {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// deviceAttributes returns the attributes of a device exposed in ResourceSlices
+func deviceAttributes(pool types.ResourcePool, dev types.HostDevice) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	attrs := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute)
+	setString := func(name, value string) {
+		if value != "" && len(value) <= resourceapi.DeviceAttributeMaxValueLength {
+			attrs[resourceapi.QualifiedName(name)] = resourceapi.DeviceAttribute{StringValue: &value}
+		}
+	}
+
+	setString(attrResourceName, pool.GetResourceName())
+	setString(attrVendor, dev.GetVendor())
+	setString(attrDeviceID, dev.GetDeviceCode())
+	setString(attrDriver, dev.GetDriver())
+
+	numaAddr := ""
+	if pciDev, ok := dev.(types.PciDevice); ok {
+		setString(attrPciAddress, pciDev.GetPciAddr())
+		numaAddr = pciDev.GetPciAddr()
+	}
+	if netDev, ok := dev.(types.NetDevice); ok {
+		setString(attrPfName, netDev.GetPfNetName())
+		setString(attrPfPciAddress, netDev.GetPfPciAddr())
+		setString(attrNetName, netDev.GetNetName())
+		setString(attrLinkType, netDev.GetLinkType())
+		rdma := netDev.IsRdma()
+		attrs[attrRdma] = resourceapi.DeviceAttribute{BoolValue: &rdma}
+		if numaAddr == "" {
+			numaAddr = netDev.GetPfPciAddr()
+		}
+	}
+	if vdpaCapable, ok := dev.(interface{ GetVdpaDevice() types.VdpaDevice }); ok {
+		if vdpaDev := vdpaCapable.GetVdpaDevice(); vdpaDev != nil {
+			setString(attrVdpaType, string(vdpaDev.GetType()))
+		}
+	}
+	if auxDev, ok := dev.(types.AuxNetDevice); ok {
+		setString(attrAuxType, auxDev.GetAuxType())
+	}
+
+	if numaNode, ok := deviceNumaNode(dev, numaAddr); ok {
+		attrs[attrNumaNode] = resourceapi.DeviceAttribute{IntValue: &numaNode}
+	}
+	return attrs
+}
+
+// deviceNumaNode returns the NUMA node of a device from its advertised topology or from sysfs
+func deviceNumaNode(dev types.HostDevice, pciAddr string) (int64, bool) {
+	if apiDev := dev.GetAPIDevice(); apiDev != nil && apiDev.Topology != nil && len(apiDev.Topology.Nodes) > 0 {
+		return apiDev.Topology.Nodes[0].ID, true
+	}
+	if pciAddr == "" {
+		return 0, false
+	}
+	if node := utils.GetDevNode(pciAddr); node >= 0 {
+		return int64(node), true
+	}
+	return 0, false
+}
+
+// buildResourceSlices returns the desired ResourceSlices of every pool, keyed by pool name.
+// Unhealthy devices are not published.
+func (d *Driver) buildResourceSlices() map[string][]*resourceapi.ResourceSlice {
+	desired := make(map[string][]*resourceapi.ResourceSlice)
+	for poolName, pool := range d.getPools() {
+		devices := make([]resourceapi.Device, 0)
+		apiDevices := pool.GetDevices()
+		for id, dev := range pool.GetDevicePool() {
+			if apiDev, ok := apiDevices[id]; ok && apiDev.Health != pluginapi.Healthy {
+				continue
+			}
+			devices = append(devices, resourceapi.Device{
+				Name:       DeviceName(id),
+				Attributes: deviceAttributes(pool, dev),
+			})
+		}
+		sort.Slice(devices, func(i, j int) bool { return devices[i].Name < devices[j].Name })
+
+		// a pool with no devices is still published so that the scheduler knows it is empty
+		count := (len(devices) + resourceapi.ResourceSliceMaxDevices - 1) / resourceapi.ResourceSliceMaxDevices
+		if count == 0 {
+			count = 1
+		}
+		slices := make([]*resourceapi.ResourceSlice, 0, count)
+		for i := 0; i < count; i++ {
+			end := min((i+1)*resourceapi.ResourceSliceMaxDevices, len(devices))
+			nodeName := d.nodeName
+			slices = append(slices, &resourceapi.ResourceSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("%s-%s-%d", sanitizeName(d.nodeName), sanitizeName(poolName), i),
+				},
+				Spec: resourceapi.ResourceSliceSpec{
+					Driver:   d.driverName,
+					Pool:     resourceapi.ResourcePool{Name: poolName, ResourceSliceCount: int64(count)},
+					NodeName: &nodeName,
+					Devices:  devices[min(i*resourceapi.ResourceSliceMaxDevices, len(devices)):end],
+				},
+			})
+		}
+		desired[poolName] = slices
+	}
+	return desired
+}
+
+// publishResourceSlices creates, updates and deletes the ResourceSlices of this node so that they
+// match the served pools. The generation of a pool is bumped whenever its devices change.
+func (d *Driver) publishResourceSlices() error {
+	d.publishLock.Lock()
+	defer d.publishLock.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+
+	client := d.client.ResourceV1().ResourceSlices()
+	list, err := client.List(ctx, metav1.ListOptions{
+		FieldSelector: fields.Set{
+			resourceapi.ResourceSliceSelectorNodeName: d.nodeName,
+			resourceapi.ResourceSliceSelectorDriver:   d.driverName,
+		}.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to list ResourceSlices: %v", err)
+	}
+	existing := make(map[string]map[string]*resourceapi.ResourceSlice)
+	for i := range list.Items {
+		slice := &list.Items[i]
+		if existing[slice.Spec.Pool.Name] == nil {
+			existing[slice.Spec.Pool.Name] = make(map[string]*resourceapi.ResourceSlice)
+		}
+		existing[slice.Spec.Pool.Name][slice.Name] = slice
+	}
+
+	owner := d.nodeOwnerReference(ctx)
+	for poolName, slices := range d.buildResourceSlices() {
+		current := existing[poolName]
+		delete(existing, poolName)
+		if sameSlices(current, slices) {
+			continue
+		}
+		generation := int64(0)
+		for _, slice := range current {
+			generation = max(generation, slice.Spec.Pool.Generation)
+		}
+		for _, slice := range slices {
+			slice.Spec.Pool.Generation = generation + 1
+			slice.OwnerReferences = owner
+			if old, ok := current[slice.Name]; ok {
+				slice.ResourceVersion = old.ResourceVersion
+				delete(current, slice.Name)
+				_, err = client.Update(ctx, slice, metav1.UpdateOptions{})
+			} else {
+				_, err = client.Create(ctx, slice, metav1.CreateOptions{})
+			}
+			if err != nil {
+				return fmt.Errorf("unable to publish ResourceSlice %s: %v", slice.Name, err)
+			}
+		}
+		d.log.Info("Published ResourceSlices", "pool", poolName, "count", len(slices), "generation", generation+1)
+		existing[poolName] = current // stale slices of the pool
+	}
+
+	for _, slices := range existing {
+		for name := range slices {
+			if err := client.Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+				return fmt.Errorf("unable to delete ResourceSlice %s: %v", name, err)
+			}
+			d.log.Info("Deleted ResourceSlice", "name", name)
+		}
+	}
+	return nil
+}
+
+// sameSlices returns true if the published slices of a pool already match the desired ones
+func sameSlices(current map[string]*resourceapi.ResourceSlice, desired []*resourceapi.ResourceSlice) bool {
+	if len(current) != len(desired) {
+		return false
+	}
+	for _, slice := range desired {
+		old, ok := current[slice.Name]
+		if !ok || old.Spec.Pool.ResourceSliceCount != slice.Spec.Pool.ResourceSliceCount ||
+			!apiequality.Semantic.DeepEqual(old.Spec.Devices, slice.Spec.Devices) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeOwnerReference returns an owner reference to the Node object so that ResourceSlices are
+// garbage collected with the node
+func (d *Driver) nodeOwnerReference(ctx context.Context) []metav1.OwnerReference {
+	node, err := d.client.CoreV1().Nodes().Get(ctx, d.nodeName, metav1.GetOptions{})
+	if err != nil {
+		d.log.Info("Unable to get node, ResourceSlices are published without owner", "err", err)
+		return nil
+	}
+	return []metav1.OwnerReference{{
+		APIVersion: "v1",
+		Kind:       "Node",
+		Name:       node.Name,
+		UID:        node.UID,
+	}}
+}
@@ -18,7 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/accelerator"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/auxnetdevice"
@@ -34,18 +34,24 @@ type resourceFactory struct {
 	endPointSuffix string
 	pluginWatch    bool
 	useCdi         bool
+	checkpoint     types.AllocationCheckpoint
+	assignments    types.DeviceAssignmentLookup
 }
 
 var instance *resourceFactory
 
-// NewResourceFactory returns an instance of Resource Server factory
-func NewResourceFactory(prefix, suffix string, pluginWatch, useCdi bool) types.ResourceFactory {
+// NewResourceFactory returns an instance of Resource Server factory. Resource servers record the devices they
+// allocate in checkpoint and look up the containers devices are assigned to in assignments, unless they are nil.
+func NewResourceFactory(prefix, suffix string, pluginWatch, useCdi bool,
+	checkpoint types.AllocationCheckpoint, assignments types.DeviceAssignmentLookup) types.ResourceFactory {
 	if instance == nil {
 		return &resourceFactory{
 			endPointPrefix: prefix,
 			endPointSuffix: suffix,
 			pluginWatch:    pluginWatch,
 			useCdi:         useCdi,
+			checkpoint:     checkpoint,
+			assignments:    assignments,
 		}
 	}
 	return instance
@@ -58,18 +64,42 @@ func (rf *resourceFactory) GetResourceServer(rp types.ResourcePool) (types.Resou
 		if prefixOverride := rp.GetResourcePrefix(); prefixOverride != "" {
 			prefix = prefixOverride
 		}
-		return resources.NewResourceServer(prefix, rf.endPointSuffix, rf.pluginWatch, rf.useCdi, rp), nil
+		allocator, err := rf.GetAllocator(rp.GetConfig().AllocationPolicy)
+		if err != nil {
+			return nil, err
+		}
+		return resources.NewResourceServer(prefix, rf.endPointSuffix, rf.pluginWatch, rf.useCdi, rp, allocator,
+			rf.checkpoint, rf.assignments), nil
 	}
 	return nil, fmt.Errorf("factory: unable to get resource pool object")
 }
 
-// GetDefaultInfoProvider returns an instance of DeviceInfoProvider using name as string
-func (rf *resourceFactory) GetDefaultInfoProvider(pciAddr, name string) []types.DeviceInfoProvider {
+// GetAllocator returns an instance of Allocator for the given allocation policy, nil if no policy is set
+func (rf *resourceFactory) GetAllocator(policy types.AllocationPolicy) (types.Allocator, error) {
+	switch policy {
+	case "":
+		return nil, nil
+	case types.PackedAllocationPolicy:
+		return resources.NewPackedAllocator(), nil
+	case types.SpreadAllocationPolicy:
+		return resources.NewSpreadAllocator(), nil
+	case types.NumaAllocationPolicy:
+		return resources.NewNumaAllocator(), nil
+	case types.BondAllocationPolicy:
+		return resources.NewBondAllocator(), nil
+	default:
+		return nil, fmt.Errorf("GetAllocator(): invalid allocation policy %q", policy)
+	}
+}
+
+// GetDefaultInfoProvider returns an instance of DeviceInfoProvider using name as string.
+// vfioMode selects the VFIO devices exposed for vfio-pci bound devices
+func (rf *resourceFactory) GetDefaultInfoProvider(pciAddr, name string, vfioMode types.VfioMode) []types.DeviceInfoProvider {
 	deviceInfoProvidersList := []types.DeviceInfoProvider{infoprovider.NewGenericInfoProvider(pciAddr)}
 
 	switch name {
 	case "vfio-pci":
-		deviceInfoProvidersList = append(deviceInfoProvidersList, infoprovider.NewVfioInfoProvider(pciAddr))
+		deviceInfoProvidersList = append(deviceInfoProvidersList, infoprovider.NewVfioInfoProvider(pciAddr, vfioMode))
 	case "uio", "igb_uio":
 		deviceInfoProvidersList = append(deviceInfoProvidersList, infoprovider.NewUioInfoProvider(pciAddr))
 	}
@@ -101,15 +131,32 @@ func (rf *resourceFactory) GetSelector(attr string, values []string) (types.Devi
 		return resources.NewAuxTypeSelector(values), nil
 	case "pKeys":
 		return resources.NewPKeySelector(values), nil
+	case "minLinkSpeed":
+		return resources.NewMinLinkSpeedSelector(values), nil
+	case "maxLinkSpeed":
+		return resources.NewMaxLinkSpeedSelector(values), nil
+	case "mtus":
+		return resources.NewMtuSelector(values), nil
+	case "pfOperStates":
+		return resources.NewPfOperStateSelector(values), nil
+	case "numaNodes":
+		return resources.NewNumaNodeSelector(values), nil
+	case "eswitchModes":
+		return resources.NewEswitchModeSelector(values), nil
 	default:
 		return nil, fmt.Errorf("GetSelector(): invalid attribute %s", attr)
 	}
 }
 
-func (rf *resourceFactory) FilterBySelector(selectorName string, values []string, devicesToFilter []types.HostDevice) []types.HostDevice {
+// FilterBySelector returns the devices matching the given selector values, all devices without values.
+// Dropped devices are logged at verbosity 5.
+func (rf *resourceFactory) FilterBySelector(log klog.Logger, selectorName string, values []string,
+	devicesToFilter []types.HostDevice) []types.HostDevice {
 	if len(values) > 0 {
 		if selector, err := rf.GetSelector(selectorName, values); err == nil {
-			return selector.Filter(devicesToFilter)
+			filtered := selector.Filter(devicesToFilter)
+			resources.LogDroppedDevices(log, selectorName, values, devicesToFilter, filtered)
+			return filtered
 		}
 	}
 	return devicesToFilter
@@ -121,11 +168,8 @@ func (rf *resourceFactory) GetResourcePool(rc *types.ResourceConfig, filteredDev
 	for _, dev := range filteredDevice {
 		id := dev.GetDeviceID()
 		devicePool[id] = dev
-		glog.Infof("device added: [identifier: %s, vendor: %s, device: %s, driver: %s]",
-			id,
-			dev.GetVendor(),
-			dev.GetDeviceCode(),
-			dev.GetDriver())
+		klog.InfoS("Device added", "resourceName", rc.ResourceName, "deviceID", id,
+			"vendor", dev.GetVendor(), "device", dev.GetDeviceCode(), "driver", dev.GetDriver())
 	}
 
 	var rPool types.ResourcePool
@@ -151,7 +195,7 @@ func (rf *resourceFactory) GetResourcePool(rc *types.ResourceConfig, filteredDev
 	case types.AuxNetDeviceType:
 		if len(filteredDevice) > 0 {
 			if _, ok := filteredDevice[0].(types.AuxNetDevice); ok {
-				rPool = auxnetdevice.NewAuxNetResourcePool(rc, devicePool)
+				rPool = auxnetdevice.NewAuxNetResourcePool(rf.GetNadUtils(), rc, devicePool)
 			} else {
 				err = fmt.Errorf("invalid device list for AuxNetDeviceType")
 			}
@@ -170,6 +214,11 @@ func (rf *resourceFactory) GetVdpaDevice(pciAddr string) types.VdpaDevice {
 	return devices.GetVdpaDevice(pciAddr)
 }
 
+// GetAuxVdpaDevice returns the vDPA device of an auxiliary device
+func (rf *resourceFactory) GetAuxVdpaDevice(auxDev string) types.VdpaDevice {
+	return devices.GetAuxVdpaDevice(auxDev)
+}
+
 // GetDeviceProvider returns an instance of DeviceProvider based on DeviceType
 func (rf *resourceFactory) GetDeviceProvider(dt types.DeviceType) types.DeviceProvider {
 	switch dt {
@@ -200,7 +249,7 @@ func parseObjectOrSlice[O types.NetDeviceSelectors | types.AccelDeviceSelectors
 		}
 	}
 
-	glog.Infof("%T for resource %s is %+v", slice[0], rc.ResourceName, slice)
+	klog.V(2).InfoS("Parsed selectors", "resourceName", rc.ResourceName, "selectors", slice)
 	interfaceArray := make([]any, len(slice))
 	for i := range slice {
 		interfaceArray[i] = slice[i]
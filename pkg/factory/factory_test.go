@@ -42,36 +42,36 @@ var _ = Describe("Factory", func() {
 	Describe("getting factory instance", func() {
 		Context("always", func() {
 			It("should return the same instance", func() {
-				f0 := factory.NewResourceFactory("fake", "fake", true, false)
+				f0 := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				Expect(f0).NotTo(BeNil())
-				f1 := factory.NewResourceFactory("fake", "fake", true, false)
+				f1 := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				Expect(f1).To(Equal(f0))
 			})
 		})
 	})
 	DescribeTable("getting info provider",
 		func(name string, expected reflect.Type) {
-			f := factory.NewResourceFactory("fake", "fake", true, false)
-			p := f.GetDefaultInfoProvider("fakePCIAddr", name)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
+			p := f.GetDefaultInfoProvider("fakePCIAddr", name, "")
 			Expect(p).To(HaveLen(2)) // for all the providers except netdevice we expect 2 info providers
 			Expect(reflect.TypeOf(p[1])).To(Equal(expected))
 
 		},
-		Entry("vfio-pci", "vfio-pci", reflect.TypeOf(infoprovider.NewVfioInfoProvider("fakePCIAddr"))),
+		Entry("vfio-pci", "vfio-pci", reflect.TypeOf(infoprovider.NewVfioInfoProvider("fakePCIAddr", ""))),
 		Entry("uio", "uio", reflect.TypeOf(infoprovider.NewUioInfoProvider("fakePCIAddr"))),
 		Entry("igb_uio", "igb_uio", reflect.TypeOf(infoprovider.NewUioInfoProvider("fakePCIAddr"))),
 	)
 
 	Describe("getting info provider for generic netdevice", func() {
-		f := factory.NewResourceFactory("fake", "fake", true, false)
-		p := f.GetDefaultInfoProvider("fakePCIAddr", "netdevice")
+		f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
+		p := f.GetDefaultInfoProvider("fakePCIAddr", "netdevice", "")
 		Expect(p).To(HaveLen(1)) // for all the providers except netdevice we expect 2 info providers
 		Expect(reflect.TypeOf(p[0])).To(Equal(reflect.TypeOf(infoprovider.NewGenericInfoProvider("fakePCIAddr"))))
 	})
 
 	DescribeTable("getting selector",
 		func(selector string, shouldSucceed bool, expected reflect.Type) {
-			f := factory.NewResourceFactory("fake", "fake", true, false)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			v := []string{"val1", "val2", "val3"}
 			s, e := f.GetSelector(selector, v)
 
@@ -99,6 +99,7 @@ var _ = Describe("Factory", func() {
 		Entry("linkTypes", "linkTypes", true, reflect.TypeOf(resources.NewLinkTypeSelector([]string{}))),
 		Entry("ddpProfiles", "ddpProfiles", true, reflect.TypeOf(resources.NewDdpSelector([]string{}))),
 		Entry("pKeys", "pKeys", true, reflect.TypeOf(resources.NewPKeySelector([]string{}))),
+		Entry("eswitchModes", "eswitchModes", true, reflect.TypeOf(resources.NewEswitchModeSelector([]string{}))),
 		Entry("invalid", "fakeAndInvalid", false, reflect.TypeOf(nil)),
 	)
 	Describe("getting resource pool for netdevice", func() {
@@ -110,7 +111,7 @@ var _ = Describe("Factory", func() {
 				devs []types.HostDevice
 			)
 			BeforeEach(func() {
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 
 				devs = make([]types.HostDevice, 4)
 				vendors := []string{"8086", "8086", "8086", "1234"}
@@ -179,7 +180,7 @@ var _ = Describe("Factory", func() {
 	DescribeTable("getting resource pool",
 		func(selectorBytes []byte, hasDevices []string) {
 			// create factory
-			f := factory.NewResourceFactory("fake", "fake", true, false)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 
 			// parse selector configuration & create resource config
 			var selectors json.RawMessage
@@ -309,7 +310,7 @@ var _ = Describe("Factory", func() {
 				devs []types.HostDevice
 			)
 			BeforeEach(func() {
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				devs = make([]types.HostDevice, 4)
 				vendors := []string{"8086", "8086", "8086", "8086"}
 				codes := []string{"1111", "1111", "1111", "1111"}
@@ -442,7 +443,7 @@ var _ = Describe("Factory", func() {
 				devs []types.HostDevice
 			)
 			BeforeEach(func() {
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 
 				devs = make([]types.HostDevice, 1)
 				vendors := []string{"8086"}
@@ -500,7 +501,7 @@ var _ = Describe("Factory", func() {
 				devs []types.HostDevice
 			)
 			BeforeEach(func() {
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 
 				devs = make([]types.HostDevice, 4)
 				vendors := []string{"8086", "8086", "15b3", "15b3"}
@@ -563,7 +564,7 @@ var _ = Describe("Factory", func() {
 	})
 	DescribeTable("getting device provider",
 		func(dt types.DeviceType, shouldSucceed bool) {
-			f := factory.NewResourceFactory("fake", "fake", true, false)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			p := f.GetDeviceProvider(dt)
 			if shouldSucceed {
 				Expect(p).NotTo(BeNil())
@@ -588,7 +589,7 @@ var _ = Describe("Factory", func() {
 				Selectors:  &s,
 			}
 
-			f := factory.NewResourceFactory("fake", "fake", true, false)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 
 			_, e := f.GetDeviceFilter(rc)
 			if shouldSucceed {
@@ -611,7 +612,7 @@ var _ = Describe("Factory", func() {
 			mockProvider.On("HasRdmaParam", mock.AnythingOfType("string"),
 				mock.AnythingOfType("string")).Return(false, nil)
 			utils.SetNetlinkProviderInst(mockProvider)
-			f := factory.NewResourceFactory("fake", "fake", true, false)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			rs1 := f.GetRdmaSpec(types.NetDeviceType, "0000:00:00.1")
 			rs2 := f.GetRdmaSpec(types.AcceleratorType, "0000:00:00.2")
 			rs3 := f.GetRdmaSpec(types.AuxNetDeviceType, "foo.bar.3")
@@ -632,7 +633,7 @@ var _ = Describe("Factory", func() {
 	})
 	Describe("getting resource server", func() {
 		Context("when resource pool is nil", func() {
-			f := factory.NewResourceFactory("fake", "fake", true, false)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			rs, e := f.GetResourceServer(nil)
 			It("should fail", func() {
 				Expect(e).To(HaveOccurred())
@@ -640,15 +641,50 @@ var _ = Describe("Factory", func() {
 			})
 		})
 		Context("when resource pool uses overridden prefix", func() {
-			f := factory.NewResourceFactory("fake", "fake", true, false)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			rp := mocks.ResourcePool{}
 			rp.On("GetResourcePrefix").Return("overridden").
-				On("GetResourceName").Return("fake")
+				On("GetResourceName").Return("fake").
+				On("GetConfig").Return(&types.ResourceConfig{})
 			rs, e := f.GetResourceServer(&rp)
 			It("should not fail", func() {
 				Expect(e).NotTo(HaveOccurred())
 				Expect(rs).NotTo(BeNil())
 			})
 		})
+		Context("when resource pool uses an invalid allocation policy", func() {
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
+			rp := mocks.ResourcePool{}
+			rp.On("GetResourcePrefix").Return("").
+				On("GetResourceName").Return("fake").
+				On("GetConfig").Return(&types.ResourceConfig{AllocationPolicy: "fake"})
+			rs, e := f.GetResourceServer(&rp)
+			It("should fail", func() {
+				Expect(e).To(HaveOccurred())
+				Expect(rs).To(BeNil())
+			})
+		})
 	})
+	DescribeTable("getting allocator",
+		func(policy types.AllocationPolicy, shouldSucceed, shouldBeNil bool) {
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
+			a, e := f.GetAllocator(policy)
+			if shouldSucceed {
+				Expect(e).NotTo(HaveOccurred())
+			} else {
+				Expect(e).To(HaveOccurred())
+			}
+			if shouldBeNil {
+				Expect(a).To(BeNil())
+			} else {
+				Expect(a).NotTo(BeNil())
+			}
+		},
+		Entry("no policy", types.AllocationPolicy(""), true, true),
+		Entry("packed", types.PackedAllocationPolicy, true, false),
+		Entry("spread", types.SpreadAllocationPolicy, true, false),
+		Entry("numa", types.NumaAllocationPolicy, true, false),
+		Entry("bond", types.BondAllocationPolicy, true, false),
+		Entry("invalid", types.AllocationPolicy("fake"), false, true),
+	)
 })
@@ -18,9 +18,10 @@
 package infoprovider
 
 import (
+	"fmt"
 	"strings"
 
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
@@ -31,12 +32,15 @@ rdmaInfoProvider provides the RDMA information
 */
 type rdmaInfoProvider struct {
 	rdmaSpec types.RdmaSpec
+	config   *types.RdmaConfig
 }
 
-// NewRdmaInfoProvider returns a new Rdma Information Provider
-func NewRdmaInfoProvider(rdmaSpec types.RdmaSpec) types.DeviceInfoProvider {
+// NewRdmaInfoProvider returns a new Rdma Information Provider, exposing the RDMA character devices allowed by config.
+// config may be nil.
+func NewRdmaInfoProvider(rdmaSpec types.RdmaSpec, config *types.RdmaConfig) types.DeviceInfoProvider {
 	return &rdmaInfoProvider{
 		rdmaSpec: rdmaSpec,
+		config:   config,
 	}
 }
 
@@ -49,18 +53,47 @@ func (ip *rdmaInfoProvider) GetName() string {
 
 func (ip *rdmaInfoProvider) GetDeviceSpecs() []*pluginapi.DeviceSpec {
 	if !ip.rdmaSpec.IsRdma() {
-		glog.Errorf("GetDeviceSpecs(): rdma is required in the configuration but the device is not rdma device")
+		klog.ErrorS(nil, "RDMA is required in the configuration but the device is not an RDMA device")
 		return nil
 	}
 
-	devsSpec := ip.rdmaSpec.GetRdmaDeviceSpec()
-	glog.Infof("GetDeviceSpecs(): GetRdmaDeviceSpec returned %v", devsSpec)
+	devsSpec := ip.deviceSpecs()
+	klog.V(4).InfoS("RDMA device specs", "deviceSpecs", devsSpec)
 	return devsSpec
 }
 
+// deviceSpecs returns the RDMA character devices of the device, leaving out the optional ones not allowed by the config
+func (ip *rdmaInfoProvider) deviceSpecs() []*pluginapi.DeviceSpec {
+	devsSpec := ip.rdmaSpec.GetRdmaDeviceSpec()
+	if ip.config == nil || ip.config.CharDevices == nil {
+		return devsSpec
+	}
+	allowed := make(map[string]bool, len(ip.config.CharDevices))
+	for _, name := range ip.config.CharDevices {
+		allowed[name] = true
+	}
+	filtered := make([]*pluginapi.DeviceSpec, 0, len(devsSpec))
+	for _, devSpec := range devsSpec {
+		if name := optionalCharDevice(devSpec.ContainerPath); name == "" || allowed[name] {
+			filtered = append(filtered, devSpec)
+		}
+	}
+	return filtered
+}
+
+// optionalCharDevice returns the name of the optional RDMA character device at path, empty if not optional
+func optionalCharDevice(path string) string {
+	for _, name := range types.RdmaOptionalCharDevices {
+		if strings.Contains(path, name) {
+			return name
+		}
+	}
+	return ""
+}
+
 func (ip *rdmaInfoProvider) GetEnvVal() types.AdditionalInfo {
 	envs := make(map[string]string, 0)
-	devsSpec := ip.rdmaSpec.GetRdmaDeviceSpec()
+	devsSpec := ip.deviceSpecs()
 	for _, devSpec := range devsSpec {
 		switch {
 		case strings.Contains(devSpec.ContainerPath, "uverbs"):
@@ -79,6 +112,30 @@ func (ip *rdmaInfoProvider) GetEnvVal() types.AdditionalInfo {
 		envs["rdma_dev"] = rdmadev
 	}
 
+	if port := ip.rdmaSpec.GetRdmaPortInfo(); port != nil {
+		envs["rdma_port"] = port.Port
+		if port.LinkLayer != "" {
+			envs["rdma_link_layer"] = port.LinkLayer
+		}
+		if port.PortGUID != "" {
+			envs["rdma_port_guid"] = port.PortGUID
+		}
+		gids := make([]string, 0, len(port.Gids))
+		gidTypes := make([]string, 0, len(port.Gids))
+		for _, gid := range port.Gids {
+			gids = append(gids, fmt.Sprintf("%d=%s", gid.Index, gid.Gid))
+			if gid.Type != "" {
+				gidTypes = append(gidTypes, fmt.Sprintf("%d=%s", gid.Index, gid.Type))
+			}
+		}
+		if len(gids) > 0 {
+			envs["rdma_gids"] = strings.Join(gids, ",")
+		}
+		if len(gidTypes) > 0 {
+			envs["rdma_gid_types"] = strings.Join(gidTypes, ",")
+		}
+	}
+
 	return envs
 }
 
@@ -23,6 +23,7 @@ import (
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/infoprovider"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
 )
 
@@ -30,7 +31,7 @@ var _ = Describe("rdmaInfoProvider", func() {
 	Describe("creating new rdmaInfoProvider", func() {
 		It("should return valid rdmaInfoProvider object", func() {
 			rdma := &mocks.RdmaSpec{}
-			dip := infoprovider.NewRdmaInfoProvider(rdma)
+			dip := infoprovider.NewRdmaInfoProvider(rdma, nil)
 			Expect(dip).NotTo(BeNil())
 			// FIXME: Expect(reflect.TypeOf(dip)).To(Equal(reflect.TypeOf(&rdmaInfoProvider{})))
 		})
@@ -39,7 +40,7 @@ var _ = Describe("rdmaInfoProvider", func() {
 		It("should return an empty map for non-rdma device", func() {
 			rdma := &mocks.RdmaSpec{}
 			rdma.On("IsRdma").Return(false)
-			dip := infoprovider.NewRdmaInfoProvider(rdma)
+			dip := infoprovider.NewRdmaInfoProvider(rdma, nil)
 			Expect(dip.GetDeviceSpecs()).To(BeNil())
 		})
 		It("should return non empty map for rdma device", func() {
@@ -51,9 +52,26 @@ var _ = Describe("rdmaInfoProvider", func() {
 			rdma.On("IsRdma").Return(true).
 				On("GetRdmaDeviceSpec").Return(rdmaSpecs)
 
-			dip := infoprovider.NewRdmaInfoProvider(rdma)
+			dip := infoprovider.NewRdmaInfoProvider(rdma, nil)
 			Expect(dip.GetDeviceSpecs()).To(HaveLen(2))
 		})
+		It("should only return the optional character devices allowed by the config", func() {
+			rdma := &mocks.RdmaSpec{}
+			rdma.On("IsRdma").Return(true).
+				On("GetRdmaDeviceSpec").Return([]*pluginapi.DeviceSpec{
+				{ContainerPath: "/dev/infiniband/issm4"},
+				{ContainerPath: "/dev/infiniband/umad4"},
+				{ContainerPath: "/dev/infiniband/uverbs4"},
+				{ContainerPath: "/dev/infiniband/rdma_cm"}})
+
+			dip := infoprovider.NewRdmaInfoProvider(rdma, &types.RdmaConfig{CharDevices: []string{"rdma_cm"}})
+			Expect(dip.GetDeviceSpecs()).To(ConsistOf(
+				&pluginapi.DeviceSpec{ContainerPath: "/dev/infiniband/uverbs4"},
+				&pluginapi.DeviceSpec{ContainerPath: "/dev/infiniband/rdma_cm"}))
+
+			dip = infoprovider.NewRdmaInfoProvider(rdma, &types.RdmaConfig{CharDevices: []string{}})
+			Expect(dip.GetDeviceSpecs()).To(ConsistOf(&pluginapi.DeviceSpec{ContainerPath: "/dev/infiniband/uverbs4"}))
+		})
 	})
 	Describe("GetEnvVal", func() {
 		It("should the rdma mounts from deviceSpecs", func() {
@@ -64,8 +82,9 @@ var _ = Describe("rdmaInfoProvider", func() {
 				{ContainerPath: "/dev/infiniband/umad4"},
 				{ContainerPath: "/dev/infiniband/uverbs4"},
 				{ContainerPath: "/dev/infiniband/rdma_cm"}}).
-				On("GetRdmaDeviceName").Return("mlx5_3")
-			dip := infoprovider.NewRdmaInfoProvider(rdma)
+				On("GetRdmaDeviceName").Return("mlx5_3").
+				On("GetRdmaPortInfo").Return(nil)
+			dip := infoprovider.NewRdmaInfoProvider(rdma, nil)
 			dip.GetDeviceSpecs()
 			envs := dip.GetEnvVal()
 			Expect(envs).To(HaveLen(5))
@@ -85,11 +104,31 @@ var _ = Describe("rdmaInfoProvider", func() {
 			Expect(exist).To(BeTrue())
 			Expect(rdmadev).To(Equal("mlx5_3"))
 		})
+		It("should return the port attributes and the GID table of the RDMA device", func() {
+			rdma := &mocks.RdmaSpec{}
+			rdma.On("IsRdma").Return(true).
+				On("GetRdmaDeviceSpec").Return([]*pluginapi.DeviceSpec{{ContainerPath: "/dev/infiniband/uverbs4"}}).
+				On("GetRdmaDeviceName").Return("mlx5_3").
+				On("GetRdmaPortInfo").Return(&types.RdmaPortInfo{Port: "1", LinkLayer: "Ethernet",
+				PortGUID: "0ac0:ebff:fe3a:1c2d", Gids: []types.RdmaGid{
+					{Index: 0, Gid: "fe80:0000:0000:0000:0ac0:ebff:fe3a:1c2d", Type: "IB/RoCE v1"},
+					{Index: 1, Gid: "fe80:0000:0000:0000:0ac0:ebff:fe3a:1c2d", Type: "RoCE v2"}}})
+			dip := infoprovider.NewRdmaInfoProvider(rdma, nil)
+			Expect(dip.GetEnvVal()).To(Equal(types.AdditionalInfo{
+				"uverbs":          "/dev/infiniband/uverbs4",
+				"rdma_dev":        "mlx5_3",
+				"rdma_port":       "1",
+				"rdma_link_layer": "Ethernet",
+				"rdma_port_guid":  "0ac0:ebff:fe3a:1c2d",
+				"rdma_gids":       "0=fe80:0000:0000:0000:0ac0:ebff:fe3a:1c2d,1=fe80:0000:0000:0000:0ac0:ebff:fe3a:1c2d",
+				"rdma_gid_types":  "0=IB/RoCE v1,1=RoCE v2",
+			}))
+		})
 	})
 	Describe("GetMounts", func() {
 		It("should always return an empty array", func() {
 			rdma := &mocks.RdmaSpec{}
-			dip := infoprovider.NewRdmaInfoProvider(rdma)
+			dip := infoprovider.NewRdmaInfoProvider(rdma, nil)
 			Expect(dip.GetMounts()).To(BeEmpty())
 		})
 	})
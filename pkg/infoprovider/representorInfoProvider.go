@@ -0,0 +1,51 @@
+package infoprovider
+
+import (
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+)
+
+/*
+representorInfoProvider provides the representor netdevices of a VF or SF whose PF is in switchdev mode
+*/
+type representorInfoProvider struct {
+	uplink      string
+	representor string
+}
+
+// NewRepresentorInfoProvider returns a new Representor Information Provider
+func NewRepresentorInfoProvider(uplink, representor string) types.DeviceInfoProvider {
+	return &representorInfoProvider{
+		uplink:      uplink,
+		representor: representor,
+	}
+}
+
+// *****************************************************************
+/* DeviceInfoProvider Interface */
+
+func (ip *representorInfoProvider) GetName() string {
+	return "representor"
+}
+
+func (ip *representorInfoProvider) GetDeviceSpecs() []*pluginapi.DeviceSpec {
+	return nil
+}
+
+func (ip *representorInfoProvider) GetEnvVal() types.AdditionalInfo {
+	envs := make(map[string]string, 0)
+	if ip.uplink != "" {
+		envs["uplink"] = ip.uplink
+	}
+	if ip.representor != "" {
+		envs["netdev"] = ip.representor
+	}
+	return envs
+}
+
+func (ip *representorInfoProvider) GetMounts() []*pluginapi.Mount {
+	return nil
+}
+
+// *****************************************************************
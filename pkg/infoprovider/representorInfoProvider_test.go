@@ -0,0 +1,40 @@
+package infoprovider_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/infoprovider"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+)
+
+var _ = Describe("representorInfoProvider", func() {
+	Describe("GetName", func() {
+		It("should return the representor info provider name", func() {
+			dip := infoprovider.NewRepresentorInfoProvider("p0", "pf0vf1")
+			Expect(dip.GetName()).To(Equal("representor"))
+		})
+	})
+	Describe("GetDeviceSpecs", func() {
+		It("should not add device specs", func() {
+			dip := infoprovider.NewRepresentorInfoProvider("p0", "pf0vf1")
+			Expect(dip.GetDeviceSpecs()).To(BeEmpty())
+		})
+	})
+	Describe("GetEnvVal", func() {
+		It("should return the uplink and the representor netdevices", func() {
+			dip := infoprovider.NewRepresentorInfoProvider("p0", "pf0vf1")
+			Expect(dip.GetEnvVal()).To(Equal(types.AdditionalInfo{"uplink": "p0", "netdev": "pf0vf1"}))
+		})
+		It("should omit an unknown representor", func() {
+			dip := infoprovider.NewRepresentorInfoProvider("p0", "")
+			Expect(dip.GetEnvVal()).To(Equal(types.AdditionalInfo{"uplink": "p0"}))
+		})
+	})
+	Describe("GetMounts", func() {
+		It("should not add mounts", func() {
+			dip := infoprovider.NewRepresentorInfoProvider("p0", "pf0vf1")
+			Expect(dip.GetMounts()).To(BeEmpty())
+		})
+	})
+})
@@ -15,29 +15,62 @@
 package infoprovider
 
 import (
-	"github.com/golang/glog"
+	"os"
+
+	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
 )
 
+var (
+	// HostIommufd is the path of the iommufd device, on the host and in containers
+	// used to check path for unit-tests
+	HostIommufd = "/dev/iommu"
+)
+
 /*
 vfioInfoProvider implements DeviceInfoProvider
 */
 type vfioInfoProvider struct {
 	pciAddr   string
+	mode      types.VfioMode
 	vfioMount string
 }
 
-// NewVfioInfoProvider create instance of VFIO DeviceInfoProvider
-func NewVfioInfoProvider(pciAddr string) types.DeviceInfoProvider {
+// NewVfioInfoProvider create instance of VFIO DeviceInfoProvider exposing the VFIO devices of the given mode
+func NewVfioInfoProvider(pciAddr string, mode types.VfioMode) types.DeviceInfoProvider {
 	return &vfioInfoProvider{
 		pciAddr:   pciAddr,
+		mode:      mode,
 		vfioMount: "/dev/vfio/vfio",
 	}
 }
 
+// IommufdDeviceExist returns true if the iommufd device exists
+func IommufdDeviceExist() bool {
+	_, err := os.Stat(HostIommufd)
+	return err == nil
+}
+
+// exposedDevices returns whether the legacy group devices and the cdev devices are exposed
+func (rp *vfioInfoProvider) exposedDevices() (group, cdev bool) {
+	switch rp.mode {
+	case types.VfioModeCdev:
+		return false, true
+	case types.VfioModeBoth:
+		return true, true
+	case types.VfioModeAuto:
+		if _, err := utils.GetVFIOCdevFile(rp.pciAddr); err == nil && IommufdDeviceExist() {
+			return true, true
+		}
+		return true, false
+	default:
+		return true, false
+	}
+}
+
 // *****************************************************************
 /* DeviceInfoProvider Interface */
 
@@ -47,21 +80,48 @@ func (rp *vfioInfoProvider) GetName() string {
 
 func (rp *vfioInfoProvider) GetDeviceSpecs() []*pluginapi.DeviceSpec {
 	devSpecs := make([]*pluginapi.DeviceSpec, 0)
-	devSpecs = append(devSpecs, &pluginapi.DeviceSpec{
-		HostPath:      rp.vfioMount,
-		ContainerPath: rp.vfioMount,
-		Permissions:   "rw",
-	})
-
-	vfioDevHost, vfioDevContainer, err := utils.GetVFIODeviceFile(rp.pciAddr)
-	if err != nil {
-		glog.Errorf("GetDeviceSpecs(): error getting vfio device file for device: %s, %s", rp.pciAddr, err.Error())
-	} else {
+	group, cdev := rp.exposedDevices()
+
+	if group {
 		devSpecs = append(devSpecs, &pluginapi.DeviceSpec{
-			HostPath:      vfioDevHost,
-			ContainerPath: vfioDevContainer,
+			HostPath:      rp.vfioMount,
+			ContainerPath: rp.vfioMount,
 			Permissions:   "rw",
 		})
+
+		vfioDevHost, vfioDevContainer, err := utils.GetVFIODeviceFile(rp.pciAddr)
+		if err != nil {
+			klog.ErrorS(err, "Error getting vfio device file", "pciAddress", rp.pciAddr)
+		} else {
+			devSpecs = append(devSpecs, &pluginapi.DeviceSpec{
+				HostPath:      vfioDevHost,
+				ContainerPath: vfioDevContainer,
+				Permissions:   "rw",
+			})
+		}
+	}
+
+	if cdev {
+		vfioCdev, err := utils.GetVFIOCdevFile(rp.pciAddr)
+		if err != nil {
+			klog.ErrorS(err, "Error getting vfio cdev", "pciAddress", rp.pciAddr)
+		} else {
+			devSpecs = append(devSpecs, &pluginapi.DeviceSpec{
+				HostPath:      vfioCdev,
+				ContainerPath: vfioCdev,
+				Permissions:   "rw",
+			})
+		}
+
+		if IommufdDeviceExist() {
+			devSpecs = append(devSpecs, &pluginapi.DeviceSpec{
+				HostPath:      HostIommufd,
+				ContainerPath: HostIommufd,
+				Permissions:   "rw",
+			})
+		} else {
+			klog.V(2).InfoS("iommufd device not found, is the iommufd module loaded?", "pciAddress", rp.pciAddr)
+		}
 	}
 
 	return devSpecs
@@ -69,13 +129,29 @@ func (rp *vfioInfoProvider) GetDeviceSpecs() []*pluginapi.DeviceSpec {
 
 func (rp *vfioInfoProvider) GetEnvVal() types.AdditionalInfo {
 	envs := make(map[string]string, 0)
-	envs["mount"] = "/dev/vfio/vfio"
+	group, cdev := rp.exposedDevices()
+
+	if group {
+		envs["mount"] = rp.vfioMount
+
+		_, vfioDevContainer, err := utils.GetVFIODeviceFile(rp.pciAddr)
+		if err != nil {
+			klog.ErrorS(err, "Error getting vfio device file", "pciAddress", rp.pciAddr)
+		} else {
+			envs["dev-mount"] = vfioDevContainer
+		}
+	}
 
-	_, vfioDevContainer, err := utils.GetVFIODeviceFile(rp.pciAddr)
-	if err != nil {
-		glog.Errorf("GetEnvVal(): error getting vfio device file for device: %s, %s", rp.pciAddr, err.Error())
-	} else {
-		envs["dev-mount"] = vfioDevContainer
+	if cdev {
+		vfioCdev, err := utils.GetVFIOCdevFile(rp.pciAddr)
+		if err != nil {
+			klog.ErrorS(err, "Error getting vfio cdev", "pciAddress", rp.pciAddr)
+		} else {
+			envs["cdev-mount"] = vfioCdev
+		}
+		if IommufdDeviceExist() {
+			envs["iommufd-mount"] = HostIommufd
+		}
 	}
 
 	return envs
@@ -18,18 +18,21 @@
 package infoprovider_test
 
 import (
+	"path/filepath"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/infoprovider"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
 )
 
 var _ = Describe("vfioInfoProvider", func() {
 	Describe("creating new vfioInfoProvider", func() {
 		It("should return valid vfioInfoProvider object", func() {
-			dip := infoprovider.NewVfioInfoProvider("fakePCIAddr")
+			dip := infoprovider.NewVfioInfoProvider("fakePCIAddr", "")
 			Expect(dip).NotTo(BeNil())
 			// FIXME: Expect(reflect.TypeOf(dip)).To(Equal(reflect.TypeOf(&vfioInfoProvider{})))
 		})
@@ -38,7 +41,7 @@ var _ = Describe("vfioInfoProvider", func() {
 		func(fs *utils.FakeFilesystem, pciAddr string, expected []*pluginapi.DeviceSpec) {
 			defer fs.Use()()
 
-			dip := infoprovider.NewVfioInfoProvider(pciAddr)
+			dip := infoprovider.NewVfioInfoProvider(pciAddr, "")
 			specs := dip.GetDeviceSpecs()
 			Expect(specs).To(ConsistOf(expected))
 		},
@@ -65,9 +68,70 @@ var _ = Describe("vfioInfoProvider", func() {
 			},
 		),
 	)
+	Describe("exposing VFIO cdev and iommufd devices", func() {
+		var (
+			fs          *utils.FakeFilesystem
+			origIommufd string
+		)
+		BeforeEach(func() {
+			fs = &utils.FakeFilesystem{
+				Dirs: []string{
+					"sys/bus/pci/devices/0000:02:00.0/vfio-dev/vfio7", "sys/kernel/iommu_groups/0", "dev",
+				},
+				Files: map[string][]byte{"dev/iommu": nil},
+				Symlinks: map[string]string{
+					"sys/bus/pci/devices/0000:02:00.0/iommu_group": "../../../../kernel/iommu_groups/0",
+				},
+			}
+			origIommufd = infoprovider.HostIommufd
+		})
+		AfterEach(func() {
+			infoprovider.HostIommufd = origIommufd
+		})
+		groupSpecs := []*pluginapi.DeviceSpec{
+			{HostPath: "/dev/vfio/0", ContainerPath: "/dev/vfio/0", Permissions: "rw"},
+			{HostPath: "/dev/vfio/vfio", ContainerPath: "/dev/vfio/vfio", Permissions: "rw"},
+		}
+		cdevSpecs := []*pluginapi.DeviceSpec{
+			{HostPath: "/dev/vfio/devices/vfio7", ContainerPath: "/dev/vfio/devices/vfio7", Permissions: "rw"},
+		}
+		DescribeTable("GetDeviceSpecs and GetEnvVal",
+			func(mode types.VfioMode, iommufd bool, expectedSpecs []*pluginapi.DeviceSpec, expectedEnvs types.AdditionalInfo) {
+				defer fs.Use()()
+				infoprovider.HostIommufd = filepath.Join(fs.RootDir, "dev/iommu")
+				if !iommufd {
+					infoprovider.HostIommufd = filepath.Join(fs.RootDir, "dev/nonexistent")
+				}
+				if _, ok := expectedEnvs["iommufd-mount"]; ok {
+					// the iommufd device is exposed under the path it has on the host
+					expectedSpecs = append(expectedSpecs, &pluginapi.DeviceSpec{
+						HostPath: infoprovider.HostIommufd, ContainerPath: infoprovider.HostIommufd, Permissions: "rw"})
+					expectedEnvs["iommufd-mount"] = infoprovider.HostIommufd
+				}
+
+				dip := infoprovider.NewVfioInfoProvider("0000:02:00.0", mode)
+				Expect(dip.GetDeviceSpecs()).To(ConsistOf(expectedSpecs))
+				Expect(dip.GetEnvVal()).To(Equal(expectedEnvs))
+			},
+			Entry("group mode", types.VfioModeGroup, true, groupSpecs,
+				types.AdditionalInfo{"mount": "/dev/vfio/vfio", "dev-mount": "/dev/vfio/0"}),
+			Entry("cdev mode", types.VfioModeCdev, true, cdevSpecs,
+				types.AdditionalInfo{"cdev-mount": "/dev/vfio/devices/vfio7", "iommufd-mount": "/dev/iommu"}),
+			Entry("both modes", types.VfioModeBoth, true, append(append([]*pluginapi.DeviceSpec{}, groupSpecs...), cdevSpecs...),
+				types.AdditionalInfo{"mount": "/dev/vfio/vfio", "dev-mount": "/dev/vfio/0",
+					"cdev-mount": "/dev/vfio/devices/vfio7", "iommufd-mount": "/dev/iommu"}),
+			Entry("auto mode with iommufd", types.VfioModeAuto, true, append(append([]*pluginapi.DeviceSpec{}, groupSpecs...), cdevSpecs...),
+				types.AdditionalInfo{"mount": "/dev/vfio/vfio", "dev-mount": "/dev/vfio/0",
+					"cdev-mount": "/dev/vfio/devices/vfio7", "iommufd-mount": "/dev/iommu"}),
+			Entry("auto mode without iommufd", types.VfioModeAuto, false, groupSpecs,
+				types.AdditionalInfo{"mount": "/dev/vfio/vfio", "dev-mount": "/dev/vfio/0"}),
+			Entry("cdev mode without iommufd", types.VfioModeCdev, false, cdevSpecs,
+				types.AdditionalInfo{"cdev-mount": "/dev/vfio/devices/vfio7"}),
+		)
+	})
 	Describe("getting mounts", func() {
 		It("should always return empty array of mounts", func() {
-			dip := infoprovider.NewVfioInfoProvider("fakeAddr")
+			dip := infoprovider.NewVfioInfoProvider("fakeAddr", "")
 			Expect(dip.GetMounts()).To(BeEmpty())
 		})
 	})
@@ -84,7 +148,7 @@ var _ = Describe("vfioInfoProvider", func() {
 			}
 			defer fs.Use()()
 
-			dip := infoprovider.NewVfioInfoProvider(pciAddr)
+			dip := infoprovider.NewVfioInfoProvider(pciAddr, "")
 			envs := dip.GetEnvVal()
 			Expect(envs).To(HaveLen(2))
 			devMount, exist := envs["dev-mount"]
@@ -95,7 +159,7 @@ var _ = Describe("vfioInfoProvider", func() {
 			Expect(vfioMount).To(Equal("/dev/vfio/vfio"))
 		})
 		It("should return only mount when VFIO device file lookup fails", func() {
-			dip := infoprovider.NewVfioInfoProvider("nonexistent")
+			dip := infoprovider.NewVfioInfoProvider("nonexistent", "")
 			envs := dip.GetEnvVal()
 			Expect(envs).To(HaveLen(1))
 			_, exist := envs["dev-mount"]
@@ -106,7 +170,7 @@ var _ = Describe("vfioInfoProvider", func() {
 		})
 		It("should compute VFIO device file on the fly and reflect filesystem changes", func() {
 			pciAddr := "0000:02:00.0"
-			dip := infoprovider.NewVfioInfoProvider(pciAddr)
+			dip := infoprovider.NewVfioInfoProvider(pciAddr, "")
 
 			fs1 := &utils.FakeFilesystem{
 				Dirs: []string{
@@ -20,7 +20,7 @@ package infoprovider
 import (
 	"os"
 
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
@@ -91,13 +91,13 @@ func (ip *vhostNetInfoProvider) GetName() string {
 
 func (ip *vhostNetInfoProvider) GetDeviceSpecs() []*pluginapi.DeviceSpec {
 	if !VhostNetDeviceExist() {
-		glog.Errorf("GetDeviceSpecs(): /dev/vhost-net doesn't exist")
+		klog.ErrorS(nil, "/dev/vhost-net doesn't exist")
 		return nil
 	}
 	deviceSpec := getVhostNetDeviceSpec()
 
 	if !tunDeviceExist() {
-		glog.Errorf("GetDeviceSpecs(): /dev/net/tun doesn't exist")
+		klog.ErrorS(nil, "/dev/net/tun doesn't exist")
 		return nil
 	}
 	deviceSpec = append(deviceSpec, getTunDeviceSpec()...)
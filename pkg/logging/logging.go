@@ -0,0 +1,51 @@
+// Package logging sets up the structured logger used by all packages of the device plugin
+package logging
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+)
+
+// Log output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// minSlogLevel lets every record through the slog handler, verbosity is filtered by klog -v
+const minSlogLevel = slog.Level(-128)
+
+// InitFlags registers the klog flags (-v, -vmodule, -logtostderr, -log_dir, ...) on flagset,
+// flag.CommandLine when nil
+func InitFlags(flagset *flag.FlagSet) {
+	if flagset == nil {
+		flagset = flag.CommandLine
+	}
+	klog.InitFlags(flagset)
+	// keep logging to files unless -logtostderr is given, as images/entrypoint.sh expects
+	if f := flagset.Lookup("logtostderr"); f != nil {
+		_ = f.Value.Set("false")
+		f.DefValue = "false"
+	}
+}
+
+// Setup makes klog emit log lines in the given format. Text lines are written by klog itself,
+// JSON lines are written to stderr.
+func Setup(format string) error {
+	switch format {
+	case FormatText:
+		return nil
+	case FormatJSON:
+		handler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: minSlogLevel})
+		// klog keeps filtering by verbosity and hands the records to the slog handler
+		klog.SetLoggerWithOptions(logr.FromSlogHandler(handler), klog.ContextualLogger(false))
+		return nil
+	default:
+		return fmt.Errorf("unsupported log format %q, must be %q or %q", format, FormatText, FormatJSON)
+	}
+}
@@ -0,0 +1,30 @@
+package logging
+
+import (
+	"flag"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/klog/v2"
+)
+
+var _ = Describe("Logging", func() {
+	AfterEach(func() {
+		klog.ClearLogger()
+	})
+	It("should accept the text format", func() {
+		Expect(Setup(FormatText)).To(Succeed())
+	})
+	It("should accept the json format", func() {
+		Expect(Setup(FormatJSON)).To(Succeed())
+	})
+	It("should register the klog flags logging to files by default", func() {
+		flagset := flag.NewFlagSet("test", flag.ContinueOnError)
+		InitFlags(flagset)
+		Expect(flagset.Lookup("v")).NotTo(BeNil())
+		Expect(flagset.Lookup("logtostderr").Value.String()).To(Equal("false"))
+	})
+	It("should reject unknown formats", func() {
+		Expect(Setup("xml")).To(MatchError(ContainSubstring("unsupported log format")))
+	})
+})
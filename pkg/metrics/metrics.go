@@ -0,0 +1,198 @@
+// Package metrics exposes Prometheus metrics of the device plugin
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/klog/v2"
+)
+
+const (
+	namespace = "sriov_device_plugin"
+
+	resourceLabel   = "resource"
+	healthLabel     = "health"
+	deviceTypeLabel = "device_type"
+
+	readHeaderTimeout = 10 * time.Second
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	devices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "devices",
+		Help:      "Number of devices advertised for the resource.",
+	}, []string{resourceLabel})
+	healthyDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "healthy_devices",
+		Help:      "Number of healthy devices advertised for the resource.",
+	}, []string{resourceLabel})
+	allocatedDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "allocated_devices",
+		Help:      "Number of devices of the resource assigned to containers, as reported by the PodResources API.",
+	}, []string{resourceLabel})
+	allocateRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "allocate_requests_total",
+		Help:      "Number of Allocate calls received for the resource.",
+	}, []string{resourceLabel})
+	allocateErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "allocate_errors_total",
+		Help:      "Number of Allocate calls for the resource that returned an error.",
+	}, []string{resourceLabel})
+	allocateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "allocate_duration_seconds",
+		Help:      "Latency of Allocate calls for the resource.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12), //nolint:mnd
+	}, []string{resourceLabel})
+	deviceInfoFileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "device_info_file_errors_total",
+		Help:      "Number of failures to write device info files for the resource.",
+	}, []string{resourceLabel})
+	cdiErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cdi_errors_total",
+		Help:      "Number of failures to write CDI specs or annotations for the resource.",
+	}, []string{resourceLabel})
+	healthTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "device_health_transitions_total",
+		Help:      "Number of device health changes detected by health checks, by new health state.",
+	}, []string{resourceLabel, healthLabel})
+	kubeletRegistrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kubelet_reregistrations_total",
+		Help:      "Number of re-registrations with the kubelet after its socket disappeared.",
+	}, []string{resourceLabel})
+	discoveredDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "discovered_devices",
+		Help:      "Number of host devices found by the last discovery, by device type.",
+	}, []string{deviceTypeLabel})
+	configReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Number of resource config reloads, by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		devices,
+		healthyDevices,
+		allocatedDevices,
+		allocateRequests,
+		allocateErrors,
+		allocateDuration,
+		deviceInfoFileErrors,
+		cdiErrors,
+		healthTransitions,
+		kubeletRegistrations,
+		discoveredDevices,
+		configReloads,
+	)
+}
+
+// Handler returns the HTTP handler serving the metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Serve starts an HTTP listener serving the metrics on bindAddress in the background
+func Serve(bindAddress string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	srv := &http.Server{
+		Addr:              bindAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	go func() {
+		klog.InfoS("Serving metrics", "address", bindAddress)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.ErrorS(err, "Metrics server failed", "address", bindAddress)
+		}
+	}()
+	return srv
+}
+
+// SetDevices records the total and healthy number of devices of a resource
+func SetDevices(resource string, total, healthy int) {
+	devices.WithLabelValues(resource).Set(float64(total))
+	healthyDevices.WithLabelValues(resource).Set(float64(healthy))
+}
+
+// SetAllocatedDevices records the number of allocated devices of a resource
+func SetAllocatedDevices(resource string, allocated int) {
+	allocatedDevices.WithLabelValues(resource).Set(float64(allocated))
+}
+
+// ObserveAllocate records an Allocate call of a resource, its duration and whether it failed
+func ObserveAllocate(resource string, start time.Time, err error) {
+	allocateRequests.WithLabelValues(resource).Inc()
+	allocateDuration.WithLabelValues(resource).Observe(time.Since(start).Seconds())
+	if err != nil {
+		allocateErrors.WithLabelValues(resource).Inc()
+	}
+}
+
+// DeviceInfoFileError records a failure to write a device info file of a resource
+func DeviceInfoFileError(resource string) {
+	deviceInfoFileErrors.WithLabelValues(resource).Inc()
+}
+
+// CDIError records a failure to write a CDI spec or annotation of a resource
+func CDIError(resource string) {
+	cdiErrors.WithLabelValues(resource).Inc()
+}
+
+// HealthTransition records a device of a resource changing its health to the given state
+func HealthTransition(resource, health string) {
+	healthTransitions.WithLabelValues(resource, health).Inc()
+}
+
+// KubeletReregistration records a re-registration of a resource with the kubelet
+func KubeletReregistration(resource string) {
+	kubeletRegistrations.WithLabelValues(resource).Inc()
+}
+
+// SetDiscoveredDevices records the number of host devices found for a device type
+func SetDiscoveredDevices(deviceType string, count int) {
+	discoveredDevices.WithLabelValues(deviceType).Set(float64(count))
+}
+
+// ConfigReload records a config reload and whether it failed
+func ConfigReload(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	configReloads.WithLabelValues(result).Inc()
+}
+
+// DeleteResource removes all metrics of a resource that is no longer served
+func DeleteResource(resource string) {
+	labels := prometheus.Labels{resourceLabel: resource}
+	for _, vec := range []*prometheus.MetricVec{
+		devices.MetricVec, healthyDevices.MetricVec, allocatedDevices.MetricVec,
+		allocateRequests.MetricVec, allocateErrors.MetricVec, allocateDuration.MetricVec,
+		deviceInfoFileErrors.MetricVec, cdiErrors.MetricVec, healthTransitions.MetricVec,
+		kubeletRegistrations.MetricVec,
+	} {
+		vec.DeletePartialMatch(labels)
+	}
+}
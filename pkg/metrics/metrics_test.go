@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("Metrics", func() {
+	const resource = "example.com/fake"
+
+	AfterEach(func() {
+		DeleteResource(resource)
+	})
+
+	It("should record device counts", func() {
+		SetDevices(resource, 4, 3)
+		SetAllocatedDevices(resource, 2)
+		Expect(testutil.ToFloat64(devices.WithLabelValues(resource))).To(Equal(4.0))
+		Expect(testutil.ToFloat64(healthyDevices.WithLabelValues(resource))).To(Equal(3.0))
+		Expect(testutil.ToFloat64(allocatedDevices.WithLabelValues(resource))).To(Equal(2.0))
+	})
+	It("should record Allocate calls and errors", func() {
+		ObserveAllocate(resource, time.Now(), nil)
+		ObserveAllocate(resource, time.Now(), errors.New("fake error"))
+		Expect(testutil.ToFloat64(allocateRequests.WithLabelValues(resource))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(allocateErrors.WithLabelValues(resource))).To(Equal(1.0))
+		Expect(testutil.CollectAndCount(allocateDuration)).To(Equal(1))
+	})
+	It("should record failures, health transitions and re-registrations", func() {
+		DeviceInfoFileError(resource)
+		CDIError(resource)
+		HealthTransition(resource, "Unhealthy")
+		HealthTransition(resource, "Unhealthy")
+		KubeletReregistration(resource)
+		Expect(testutil.ToFloat64(deviceInfoFileErrors.WithLabelValues(resource))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(cdiErrors.WithLabelValues(resource))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(healthTransitions.WithLabelValues(resource, "Unhealthy"))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(kubeletRegistrations.WithLabelValues(resource))).To(Equal(1.0))
+	})
+	It("should delete all metrics of a resource", func() {
+		SetDevices(resource, 1, 1)
+		HealthTransition(resource, "Healthy")
+		DeleteResource(resource)
+		Expect(testutil.CollectAndCount(devices)).To(Equal(0))
+		Expect(testutil.CollectAndCount(healthTransitions)).To(Equal(0))
+	})
+	It("should serve the metrics", func() {
+		SetDiscoveredDevices("netDevice", 8)
+		rec := httptest.NewRecorder()
+		Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		Expect(rec.Code).To(Equal(200))
+		Expect(rec.Body.String()).To(ContainSubstring(`sriov_device_plugin_discovered_devices{device_type="netDevice"} 8`))
+	})
+})
@@ -1,12 +1,32 @@
 package netdevice
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
 	nettypes "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	nadutils "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 )
 
+// deviceInfoDir is the directory of the Device Info files written by device plugins
+var deviceInfoDir = "/var/run/k8s.cni.cncf.io/devinfo/dp"
+
+// auxDeviceInfoFile is a Device Info file holding the record of an auxiliary network device
+type auxDeviceInfoFile struct {
+	*nettypes.DeviceInfo
+	Auxiliary *types.AuxiliaryDevice `json:"auxiliary,omitempty"`
+}
+
+// deviceLocks serializes the writers of the Device Info file of a device, keyed by resource name and device ID.
+// Allocate and the device assignment tracker write them concurrently.
+var deviceLocks sync.Map
+
 // nadutils implements types.NadUtils interface
 // It's purpose is to wrap the utilities provided by github.com/k8snetworkplumbingwg/network-attachment-definition-client
 // in order to make mocking easy for Unit Tests
@@ -14,13 +34,91 @@ type nadUtils struct {
 }
 
 func (nu *nadUtils) SaveDeviceInfoFile(resourceName, deviceID string, devInfo *nettypes.DeviceInfo) error {
+	defer lockDevice(resourceName, deviceID)()
 	return nadutils.SaveDeviceInfoForDP(resourceName, deviceID, devInfo)
 }
 
 func (nu *nadUtils) CleanDeviceInfoFile(resourceName, deviceID string) error {
+	defer lockDevice(resourceName, deviceID)()
 	return nadutils.CleanDeviceInfoForDP(resourceName, deviceID)
 }
 
+// SaveAuxDeviceInfoFile saves the Device Info file of an auxiliary network device. Like the files saved by
+// the nadutils package, it is read-only and is not overwritten.
+func (nu *nadUtils) SaveAuxDeviceInfoFile(resourceName, deviceID string, auxDev *types.AuxiliaryDevice) error {
+	defer lockDevice(resourceName, deviceID)()
+	if auxDev == nil {
+		return fmt.Errorf("auxiliary device information is null")
+	}
+	rawBytes, err := json.Marshal(auxDeviceInfoFile{
+		DeviceInfo: &nettypes.DeviceInfo{Type: types.DeviceInfoTypeAuxiliary, Version: nettypes.DeviceInfoVersion},
+		Auxiliary:  auxDev,
+	})
+	if err != nil {
+		return err
+	}
+	path := deviceInfoPath(resourceName, deviceID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o444)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(rawBytes); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SetDeviceInfoAssignment adds the container a device is assigned to to its Device Info file, under the
+// "assignment" key. The other fields of the file are kept as they are. Devices without Device Info file are ignored.
+func (nu *nadUtils) SetDeviceInfoAssignment(resourceName, deviceID string, assignment *types.DeviceAssignment) error {
+	defer lockDevice(resourceName, deviceID)()
+	path := deviceInfoPath(resourceName, deviceID)
+	rawBytes, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(rawBytes, &fields); err != nil {
+		return fmt.Errorf("error unmarshalling Device Info file %s: %v", path, err)
+	}
+	if fields["assignment"], err = json.Marshal(assignment); err != nil {
+		return err
+	}
+	if rawBytes, err = json.Marshal(fields); err != nil {
+		return err
+	}
+	// Replace the file rather than writing to it, so readers never see a partial file. Like the files saved by
+	// the nadutils package, it is read-only.
+	tmpFile := path + ".tmp"
+	if err := os.Remove(tmpFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.WriteFile(tmpFile, rawBytes, 0o444); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, path)
+}
+
+// deviceInfoPath returns the path of the Device Info file of a device, named like the nadutils package does
+func deviceInfoPath(resourceName, deviceID string) string {
+	return filepath.Join(deviceInfoDir, fmt.Sprintf("%s-%s-device.json",
+		strings.ReplaceAll(resourceName, "/", "-"), strings.ReplaceAll(deviceID, "/", "-")))
+}
+
+// lockDevice locks the files of a device and returns the function unlocking them
+func lockDevice(resourceName, deviceID string) func() {
+	lock, _ := deviceLocks.LoadOrStore(resourceName+"/"+deviceID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	return lock.(*sync.Mutex).Unlock
+}
+
 // NewNadUtils returns a new NadUtils
 func NewNadUtils() types.NadUtils {
 	return &nadUtils{}
@@ -16,10 +16,12 @@ package netdevice
 
 import (
 	"fmt"
+	"strconv"
 
-	"github.com/golang/glog"
 	"github.com/jaypipes/ghw"
+	"k8s.io/klog/v2"
 
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/resources"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
 )
@@ -27,6 +29,7 @@ import (
 type netDeviceProvider struct {
 	deviceList []*ghw.PCIDevice
 	rFactory   types.ResourceFactory
+	log        klog.Logger
 }
 
 // NewNetDeviceProvider DeviceProvider implementation from netDeviceProvider instance
@@ -34,6 +37,7 @@ func NewNetDeviceProvider(rf types.ResourceFactory) types.DeviceProvider {
 	return &netDeviceProvider{
 		rFactory:   rf,
 		deviceList: make([]*ghw.PCIDevice, 0),
+		log:        klog.Background().WithName("netdevice"),
 	}
 }
 
@@ -41,13 +45,18 @@ func (np *netDeviceProvider) GetDiscoveredDevices() []*ghw.PCIDevice {
 	return np.deviceList
 }
 
+// ResetDevices clears the list of discovered devices
+func (np *netDeviceProvider) ResetDevices() {
+	np.deviceList = make([]*ghw.PCIDevice, 0)
+}
+
 func (np *netDeviceProvider) GetDevices(rc *types.ResourceConfig, selectorIndex int) []types.HostDevice {
 	newHostDevices := make([]types.HostDevice, 0)
 	for _, device := range np.deviceList {
 		if newDevice, err := NewPciNetDevice(device, np.rFactory, rc, selectorIndex); err == nil {
 			newHostDevices = append(newHostDevices, newDevice)
 		} else {
-			glog.Errorf("netdevice GetDevices(): error creating new device: %q", err)
+			np.log.Error(err, "Error creating new device", "pciAddress", device.Address)
 		}
 	}
 	return newHostDevices
@@ -57,15 +66,15 @@ func (np *netDeviceProvider) AddTargetDevices(devices []*ghw.PCIDevice, deviceCo
 	for _, device := range devices {
 		devClass, err := utils.ParseDeviceID(device.Class.ID)
 		if err != nil {
-			glog.Warningf("netdevice AddTargetDevices(): unable to parse device class for device %+v %q", device, err)
+			np.log.Info("Unable to parse device class", "pciAddress", device.Address, "err", err)
 			continue
 		}
 
 		if devClass == int64(deviceCode) {
 			vendorName := utils.NormalizeVendorName(device.Vendor.Name)
 			productName := utils.NormalizeProductName(device.Product.Name)
-			glog.Infof("netdevice AddTargetDevices(): device found: %-12s\t%-12s\t%-20s\t%-40s", device.Address,
-				device.Class.ID, vendorName, productName)
+			np.log.Info("Device found", "pciAddress", device.Address, "class", device.Class.ID,
+				"vendor", vendorName, "product", productName)
 			// exclude netdevice in-use in host
 			if isDefaultRoute, _ := utils.HasDefaultRoute(device.Address); !isDefaultRoute {
 				aPF := utils.IsSriovPF(device.Address)
@@ -80,7 +89,6 @@ func (np *netDeviceProvider) AddTargetDevices(devices []*ghw.PCIDevice, deviceCo
 	return nil
 }
 
-//nolint:gocyclo
 func (np *netDeviceProvider) GetFilteredDevices(devices []types.HostDevice,
 	rc *types.ResourceConfig, selectorIndex int) ([]types.HostDevice, error) {
 	filteredDevice := devices
@@ -93,40 +101,66 @@ func (np *netDeviceProvider) GetFilteredDevices(devices []types.HostDevice,
 		return filteredDevice, fmt.Errorf("unable to convert SelectorObj to NetDeviceSelectors")
 	}
 
+	log := np.log.WithValues("resourceName", rc.ResourceName, "selectorIndex", selectorIndex)
+	return np.filterDevices(log, filteredDevice, nf), nil
+}
+
+// filterDevices returns the devices matching every selector of a NetDeviceSelectors object
+//
+//nolint:gocyclo
+func (np *netDeviceProvider) filterDevices(log klog.Logger, devices []types.HostDevice,
+	nf *types.NetDeviceSelectors) []types.HostDevice {
+	filteredDevice := devices
 	rf := np.rFactory
 
 	// filter by vendor list
-	filteredDevice = rf.FilterBySelector("vendors", nf.Vendors, filteredDevice)
+	filteredDevice = rf.FilterBySelector(log, "vendors", nf.Vendors, filteredDevice)
 
 	// filter by device list
-	filteredDevice = rf.FilterBySelector("devices", nf.Devices, filteredDevice)
+	filteredDevice = rf.FilterBySelector(log, "devices", nf.Devices, filteredDevice)
 
 	// filter by driver list
-	filteredDevice = rf.FilterBySelector("drivers", nf.Drivers, filteredDevice)
+	filteredDevice = rf.FilterBySelector(log, "drivers", nf.Drivers, filteredDevice)
 
 	// filter by pciAddresses list
-	filteredDevice = rf.FilterBySelector("pciAddresses", nf.PciAddresses, filteredDevice)
+	filteredDevice = rf.FilterBySelector(log, "pciAddresses", nf.PciAddresses, filteredDevice)
 
 	// filter by acpiIndexes list
-	filteredDevice = rf.FilterBySelector("acpiIndexes", nf.AcpiIndexes, filteredDevice)
+	filteredDevice = rf.FilterBySelector(log, "acpiIndexes", nf.AcpiIndexes, filteredDevice)
 
 	// filter by PfNames list
-	filteredDevice = rf.FilterBySelector("pfNames", nf.PfNames, filteredDevice)
+	filteredDevice = rf.FilterBySelector(log, "pfNames", nf.PfNames, filteredDevice)
 
 	// filter by RootDevices list
-	filteredDevice = rf.FilterBySelector("rootDevices", nf.RootDevices, filteredDevice)
+	filteredDevice = rf.FilterBySelector(log, "rootDevices", nf.RootDevices, filteredDevice)
 
 	// filter by linkTypes list
 	if len(nf.LinkTypes) > 1 {
-		glog.Warningf("Link type selector should have a single value.")
+		log.Info("Link type selector should have a single value", "linkTypes", nf.LinkTypes)
 	}
-	filteredDevice = rf.FilterBySelector("linkTypes", nf.LinkTypes, filteredDevice)
+	filteredDevice = rf.FilterBySelector(log, "linkTypes", nf.LinkTypes, filteredDevice)
 
 	// filter by DDP Profiles list
-	filteredDevice = rf.FilterBySelector("ddpProfiles", nf.DDPProfiles, filteredDevice)
+	filteredDevice = rf.FilterBySelector(log, "ddpProfiles", nf.DDPProfiles, filteredDevice)
 
 	// filter by PKeys list
-	filteredDevice = rf.FilterBySelector("pKeys", nf.PKeys, filteredDevice)
+	filteredDevice = rf.FilterBySelector(log, "pKeys", nf.PKeys, filteredDevice)
+
+	// filter by link speed range
+	filteredDevice = rf.FilterBySelector(log, "minLinkSpeed", limitSelectorValues(nf.MinLinkSpeed), filteredDevice)
+	filteredDevice = rf.FilterBySelector(log, "maxLinkSpeed", limitSelectorValues(nf.MaxLinkSpeed), filteredDevice)
+
+	// filter by MTU list
+	filteredDevice = rf.FilterBySelector(log, "mtus", intSelectorValues(nf.Mtus), filteredDevice)
+
+	// filter by PF operational state list
+	filteredDevice = rf.FilterBySelector(log, "pfOperStates", nf.PfOperStates, filteredDevice)
+
+	// filter by NUMA node list
+	filteredDevice = rf.FilterBySelector(log, "numaNodes", intSelectorValues(nf.NumaNodes), filteredDevice)
+
+	// filter by PF eswitch mode list
+	filteredDevice = rf.FilterBySelector(log, "eswitchModes", nf.EswitchModes, filteredDevice)
 
 	// filter for rdma devices
 	if nf.IsRdma {
@@ -136,25 +170,31 @@ func (np *netDeviceProvider) GetFilteredDevices(devices []types.HostDevice,
 				rdmaDevices = append(rdmaDevices, dev)
 			}
 		}
+		resources.LogDroppedDevices(log, "isRdma", []string{"true"}, filteredDevice, rdmaDevices)
 		filteredDevice = rdmaDevices
 	}
 
-	// filter for vDPA-capable devices
+	// filter for vDPA-capable devices, devices lacking a vDPA device get one created when requested
 	if nf.VdpaType != "" {
 		vdpaDevices := make([]types.HostDevice, 0)
 		for _, dev := range filteredDevice {
 			vdpaDev := dev.(types.PciNetDevice).GetVdpaDevice()
 			if vdpaDev == nil {
+				if nf.CreateVdpa {
+					vdpaDevices = append(vdpaDevices, dev)
+				}
 				continue
 			}
 			if vType := vdpaDev.GetType(); vType != types.VdpaInvalidType && vType == nf.VdpaType {
 				vdpaDevices = append(vdpaDevices, dev)
 			}
 		}
+		resources.LogDroppedDevices(log, "vdpaType", []string{string(nf.VdpaType)}, filteredDevice, vdpaDevices)
 		filteredDevice = vdpaDevices
 	}
 
-	return filteredDevice, nil
+	// filter by nested selector groups
+	return resources.FilterBySelectorGroups(log, filteredDevice, nf.Not, nf.AnyOf, nf.AllOf, np.filterDevices)
 }
 
 // ValidConfig performs validation of NetDeviceSelectors
@@ -162,13 +202,91 @@ func (np *netDeviceProvider) ValidConfig(rc *types.ResourceConfig) bool {
 	for _, selector := range rc.SelectorObjs {
 		nf, ok := selector.(*types.NetDeviceSelectors)
 		if !ok {
-			glog.Errorf("unable to convert SelectorObj to NetDeviceSelectors")
+			np.log.Error(nil, "Unable to convert SelectorObj to NetDeviceSelectors", "resourceName", rc.ResourceName)
 			return false
 		}
 		if nf.IsRdma && nf.VdpaType != "" {
-			glog.Errorf("invalid config: VdpaType and IsRdma are mutually exclusive options")
+			np.log.Error(nil, "Invalid config: VdpaType and IsRdma are mutually exclusive options", "resourceName", rc.ResourceName)
+			return false
+		}
+		if !np.validLinkSpeeds(rc.ResourceName, nf) {
+			return false
+		}
+		if nf.CreateVdpa && nf.VdpaType == "" {
+			np.log.Error(nil, "Invalid config: createVdpa requires vdpaType", "resourceName", rc.ResourceName)
+			return false
+		}
+		if nf.BindDriver != "" && len(nf.Drivers) > 0 {
+			np.log.Error(nil, "Invalid config: bindDriver and drivers are mutually exclusive options", "resourceName", rc.ResourceName)
+			return false
+		}
+		if !np.validPatterns(rc.ResourceName, nf) || !np.validSelectorGroups(rc.ResourceName, nf) {
 			return false
 		}
 	}
 	return true
 }
+
+// validSelectorGroups checks that the nested selector groups only select devices. IsRdma, VdpaType, CreateVdpa,
+// NeedVhostNet and BindDriver also set up the selected devices and are only supported at the top level.
+func (np *netDeviceProvider) validSelectorGroups(resourceName string, nf *types.NetDeviceSelectors) bool {
+	for _, group := range resources.SelectorGroups(nf.Not, nf.AnyOf, nf.AllOf) {
+		if resources.EmptySelectorGroup(group) {
+			np.log.Error(nil, "Invalid config: empty not, anyOf or allOf selector group", "resourceName", resourceName)
+			return false
+		}
+		if group.IsRdma || group.VdpaType != "" || group.CreateVdpa || group.NeedVhostNet || group.BindDriver != "" {
+			np.log.Error(nil, "Invalid config: isRdma, vdpaType, createVdpa, needVhostNet and bindDriver are not supported "+
+				"in not, anyOf and allOf groups", "resourceName", resourceName)
+			return false
+		}
+		if !np.validLinkSpeeds(resourceName, group) {
+			return false
+		}
+		if !np.validSelectorGroups(resourceName, group) {
+			return false
+		}
+	}
+	return true
+}
+
+// validLinkSpeeds checks that the link speed bounds of a selector object do not exclude every link speed
+func (np *netDeviceProvider) validLinkSpeeds(resourceName string, nf *types.NetDeviceSelectors) bool {
+	if nf.MaxLinkSpeed > 0 && nf.MinLinkSpeed > nf.MaxLinkSpeed {
+		np.log.Error(nil, "Invalid config: minLinkSpeed is greater than maxLinkSpeed", "resourceName", resourceName,
+			"minLinkSpeed", nf.MinLinkSpeed, "maxLinkSpeed", nf.MaxLinkSpeed)
+		return false
+	}
+	return true
+}
+
+// validPatterns checks the glob and regular expression patterns of a selector object and its nested groups
+func (np *netDeviceProvider) validPatterns(resourceName string, nf *types.NetDeviceSelectors) bool {
+	if err := resources.ValidatePatterns(nf.Drivers, nf.PciAddresses, nf.PfNames, nf.RootDevices); err != nil {
+		np.log.Error(err, "Invalid config: invalid selector pattern", "resourceName", resourceName)
+		return false
+	}
+	for _, group := range resources.SelectorGroups(nf.Not, nf.AnyOf, nf.AllOf) {
+		if group != nil && !np.validPatterns(resourceName, group) {
+			return false
+		}
+	}
+	return true
+}
+
+// intSelectorValues converts numeric selector values to the values selectors are created from
+func intSelectorValues(values []int) []string {
+	strValues := make([]string, 0, len(values))
+	for _, v := range values {
+		strValues = append(strValues, strconv.Itoa(v))
+	}
+	return strValues
+}
+
+// limitSelectorValues returns the selector values of a limit, none when the limit is not set
+func limitSelectorValues(limit int) []string {
+	if limit <= 0 {
+		return nil
+	}
+	return intSelectorValues([]int{limit})
+}
@@ -91,7 +91,7 @@ var _ = Describe("NetDeviceProvider", func() {
 
 			defer fs.Use()()
 
-			rf := factory.NewResourceFactory("fake", "fake", true, false)
+			rf := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			p := netdevice.NewNetDeviceProvider(rf)
 			config := &types.ResourceConfig{
 				DeviceType: types.NetDeviceType,
@@ -138,7 +138,7 @@ var _ = Describe("NetDeviceProvider", func() {
 	Describe("getting Filtered devices", func() {
 		Context("using selectors", func() {
 			It("should correctly filter devices", func() {
-				rf := factory.NewResourceFactory("fake", "fake", false, false)
+				rf := factory.NewResourceFactory("fake", "fake", false, false, nil, nil)
 				p := netdevice.NewNetDeviceProvider(rf)
 				all := make([]types.HostDevice, 5)
 				mocked := make([]mocks.PciNetDevice, 5)
@@ -153,6 +153,11 @@ var _ = Describe("NetDeviceProvider", func() {
 				dd := []string{"E710 PPPoE and PPPoL2TPv2", "fake", "fake", "gtp", "profile"}
 				rd := []bool{false, true, false, false, true}
 				vd := []string{"vhost", "vhost", "", "", "virtio"}
+				ls := []string{"10000", "25000", "25000", "100000", ""}
+				mt := []int{1500, 9000, 1500, 1500, 9000}
+				ps := []string{"up", "up", "down", "up", "unknown"}
+				nn := []int{0, 0, 1, 1, -1}
+				em := []string{"switchdev", "legacy", "switchdev", "", "legacy"}
 
 				rdmaYes := &mocks.RdmaSpec{}
 				rdmaYes.On("IsRdma").Return(true)
@@ -177,7 +182,13 @@ var _ = Describe("NetDeviceProvider", func() {
 						On("GetLinkType").Return(lt[i]).
 						On("GetDDPProfiles").Return(dd[i]).
 						On("GetFuncID").Return(-1).
-						On("IsRdma").Return(rd[i])
+						On("IsRdma").Return(rd[i]).
+						On("GetDeviceID").Return(pa[i]).
+						On("GetLinkSpeed").Return(ls[i]).
+						On("GetMtu").Return(mt[i]).
+						On("GetPfOperState").Return(ps[i]).
+						On("GetNumaNode").Return(nn[i]).
+						On("GetEswitchMode").Return(em[i])
 
 					switch vd[i] {
 					case "vhost":
@@ -208,6 +219,36 @@ var _ = Describe("NetDeviceProvider", func() {
 					{"rdma", &types.NetDeviceSelectors{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{IsRdma: true}}, []types.HostDevice{all[1], all[4]}},
 					{"vdpa-vhost", &types.NetDeviceSelectors{VdpaType: "vhost"}, []types.HostDevice{all[0], all[1]}},
 					{"vdpa-virtio", &types.NetDeviceSelectors{VdpaType: "virtio"}, []types.HostDevice{all[4]}},
+					{"vdpa-vhost created", &types.NetDeviceSelectors{VdpaType: "vhost", CreateVdpa: true},
+						[]types.HostDevice{all[0], all[1], all[2], all[3]}},
+					{"minLinkSpeed", &types.NetDeviceSelectors{MinLinkSpeed: 25000}, []types.HostDevice{all[1], all[2], all[3]}},
+					{"maxLinkSpeed", &types.NetDeviceSelectors{MaxLinkSpeed: 25000}, []types.HostDevice{all[0], all[1], all[2]}},
+					{"link speed range", &types.NetDeviceSelectors{MinLinkSpeed: 25000, MaxLinkSpeed: 25000}, []types.HostDevice{all[1], all[2]}},
+					{"mtus", &types.NetDeviceSelectors{Mtus: []int{9000}}, []types.HostDevice{all[1], all[4]}},
+					{"pfOperStates", &types.NetDeviceSelectors{PfOperStates: []string{"up"}}, []types.HostDevice{all[0], all[1], all[3]}},
+					{"numaNodes", &types.NetDeviceSelectors{NumaNodes: []int{1}}, []types.HostDevice{all[2], all[3]}},
+					{"eswitchModes", &types.NetDeviceSelectors{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{EswitchModes: []string{"switchdev"}}},
+						[]types.HostDevice{all[0], all[2]}},
+					{"not", &types.NetDeviceSelectors{Not: &types.NetDeviceSelectors{DeviceSelectors: types.DeviceSelectors{Drivers: []string{"igb_uio"}}}},
+						[]types.HostDevice{all[3], all[4]}},
+					{"devices except pfNames", &types.NetDeviceSelectors{DeviceSelectors: types.DeviceSelectors{Devices: []string{"abcd"}},
+						Not: &types.NetDeviceSelectors{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{PfNames: []string{"eth1"}}}},
+						[]types.HostDevice{all[0]}},
+					{"anyOf", &types.NetDeviceSelectors{AnyOf: []*types.NetDeviceSelectors{
+						{DeviceSelectors: types.DeviceSelectors{Drivers: []string{"iavf"}}},
+						{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{LinkTypes: []string{"infiniband"}}},
+					}}, []types.HostDevice{all[1], all[3]}},
+					{"allOf", &types.NetDeviceSelectors{AllOf: []*types.NetDeviceSelectors{
+						{DeviceSelectors: types.DeviceSelectors{Drivers: []string{"igb_uio"}}},
+						{Not: &types.NetDeviceSelectors{DeviceSelectors: types.DeviceSelectors{Vendors: []string{"8086"}}}},
+					}}, []types.HostDevice{all[2]}},
+					{"nested groups", &types.NetDeviceSelectors{AnyOf: []*types.NetDeviceSelectors{
+						{AllOf: []*types.NetDeviceSelectors{
+							{DeviceSelectors: types.DeviceSelectors{Vendors: []string{"8086"}}},
+							{GenericNetDeviceSelectors: types.GenericNetDeviceSelectors{LinkTypes: []string{"ether"}}},
+						}},
+						{DeviceSelectors: types.DeviceSelectors{Drivers: []string{"vfio-pci"}}},
+					}}, []types.HostDevice{all[0], all[4]}},
 				}
 
 				for _, tc := range testCases {
@@ -249,7 +290,7 @@ var _ = Describe("NetDeviceProvider", func() {
 				Expect(actual).To(ConsistOf(matchingDevices))
 			})
 			It("should error if the selector index is out of bounds", func() {
-				rf := factory.NewResourceFactory("fake", "fake", false, false)
+				rf := factory.NewResourceFactory("fake", "fake", false, false, nil, nil)
 				p := netdevice.NewNetDeviceProvider(rf)
 				devs := make([]types.HostDevice, 0)
 
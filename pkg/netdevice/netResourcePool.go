@@ -18,10 +18,11 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/golang/glog"
 	nettypes "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/devices"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/resources"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
@@ -46,7 +47,7 @@ func NewNetResourcePool(nadutils types.NadUtils, rc *types.ResourceConfig,
 
 // Overrides GetDeviceSpecs
 func (rp *netResourcePool) GetDeviceSpecs(deviceIDs []string) []*pluginapi.DeviceSpec {
-	glog.Infof("GetDeviceSpecs(): for devices: %v", deviceIDs)
+	klog.V(4).InfoS("GetDeviceSpecs", "resourceName", rp.GetResourceName(), "deviceIDs", deviceIDs)
 	devSpecs := make([]*pluginapi.DeviceSpec, 0)
 
 	devicePool := rp.GetDevicePool()
@@ -82,28 +83,8 @@ func (rp *netResourcePool) StoreDeviceInfoFile(resourceNamePrefix string, device
 
 		vdpaDev := netDev.GetVdpaDevice()
 		if vdpaDev != nil {
-			var vdpaDevice *nettypes.VdpaDevice = nil
-			if vdpaDev.GetType() == types.VdpaVhostType {
-				vdpaPath, err := vdpaDev.GetPath()
-				if err == nil {
-					vdpaDevice = &nettypes.VdpaDevice{
-						ParentDevice: vdpaDev.GetParent(),
-						Driver:       string(vdpaDev.GetType()),
-						Path:         vdpaPath,
-						PciAddress:   netDev.GetPciAddr(),
-					}
-				} else {
-					glog.Errorf("Unexpected error when fetching the vdpa device path: %s", err)
-				}
-			}
-			// either virtio/vDPA case or not able to get a mount path for vhost/vDPA
-			if vdpaDevice == nil {
-				vdpaDevice = &nettypes.VdpaDevice{
-					ParentDevice: vdpaDev.GetParent(),
-					Driver:       string(vdpaDev.GetType()),
-					PciAddress:   netDev.GetPciAddr(),
-				}
-			}
+			vdpaDevice := devices.GetVdpaDeviceInfo(vdpaDev)
+			vdpaDevice.PciAddress = netDev.GetPciAddr()
 
 			devInfo = nettypes.DeviceInfo{
 				Type:    nettypes.DeviceInfoTypeVDPA,
@@ -119,10 +100,15 @@ func (rp *netResourcePool) StoreDeviceInfoFile(resourceNamePrefix string, device
 				},
 			}
 
+			if netDev.GetEswitchMode() == utils.EswitchModeSwitchdev {
+				devInfo.Pci.PfPciAddress = netDev.GetPfPciAddr()
+				devInfo.Pci.RepresentorDevice = netDev.GetRepresentor()
+			}
+
 			if netDev.IsRdma() {
 				rdmaDevices := utils.GetRdmaProvider().GetRdmaDevicesForPcidev(devInfo.Pci.PciAddress)
 				if len(rdmaDevices) == 0 {
-					glog.Errorf("No RDMA devices available for RDMA capable device: %s", devInfo.Pci.PciAddress)
+					klog.ErrorS(nil, "No RDMA devices available for RDMA capable device", "pciAddress", devInfo.Pci.PciAddress)
 				} else {
 					devInfo.Pci.RdmaDevice = strings.Join(rdmaDevices, ",")
 				}
@@ -139,10 +125,10 @@ func (rp *netResourcePool) StoreDeviceInfoFile(resourceNamePrefix string, device
 	return nil
 }
 
-// CleanDeviceInfoFile cleans the Device Info files
-func (rp *netResourcePool) CleanDeviceInfoFile(resourceNamePrefix string) error {
+// CleanDeviceInfoFile cleans the Device Info files of the given deviceIDs
+func (rp *netResourcePool) CleanDeviceInfoFile(resourceNamePrefix string, deviceIDs []string) error {
 	errors := make([]string, 0)
-	for id := range rp.GetDevicePool() {
+	for _, id := range deviceIDs {
 		resource := fmt.Sprintf("%s/%s", resourceNamePrefix, rp.GetConfig().ResourceName)
 		if err := rp.nadutils.CleanDeviceInfoFile(resource, id); err != nil {
 			// Continue trying to clean.
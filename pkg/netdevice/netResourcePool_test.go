@@ -33,7 +33,7 @@ import (
 
 var _ = Describe("NetResourcePool", func() {
 	Context("getting a new instance of the pool", func() {
-		rf := factory.NewResourceFactory("fake", "fake", true, false)
+		rf := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 		nadutils := rf.GetNadUtils()
 		rc := &types.ResourceConfig{
 			ResourceName:   "fake",
@@ -50,7 +50,7 @@ var _ = Describe("NetResourcePool", func() {
 	})
 	Describe("getting DeviceSpecs", func() {
 		Context("for multiple devices", func() {
-			rf := factory.NewResourceFactory("fake", "fake", true, false)
+			rf := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			nadutils := rf.GetNadUtils()
 			rc := &types.ResourceConfig{
 				ResourceName:   "fake",
@@ -115,11 +115,15 @@ var _ = Describe("NetResourcePool", func() {
 			fake1 := &mocks.PciNetDevice{}
 			fake1.On("GetPciAddr").Return("0000:01:00.1").
 				On("GetVdpaDevice").Return(nil).
-				On("IsRdma").Return(true)
+				On("IsRdma").Return(true).
+				On("GetEswitchMode").Return("switchdev").
+				On("GetPfPciAddr").Return("0000:01:00.0").
+				On("GetRepresentor").Return("pf0vf1")
 			fake2 := &mocks.PciNetDevice{}
 			fake2.On("GetPciAddr").Return("0000:01:00.2").
 				On("GetVdpaDevice").Return(nil).
-				On("IsRdma").Return(false)
+				On("IsRdma").Return(false).
+				On("GetEswitchMode").Return("legacy")
 			pcis := map[string]types.HostDevice{"fake1": fake1, "fake2": fake2}
 
 			fakeRdmaProvider := utilsmocks.RdmaProvider{}
@@ -136,6 +140,9 @@ var _ = Describe("NetResourcePool", func() {
 						if devInfo.Pci.RdmaDevice != "rdmadevice1" {
 							return fmt.Errorf("wrong rdma device")
 						}
+						if devInfo.Pci.PfPciAddress != "0000:01:00.0" || devInfo.Pci.RepresentorDevice != "pf0vf1" {
+							return fmt.Errorf("wrong representor device")
+						}
 						return nil
 					})
 				nadutils.On("SaveDeviceInfoFile", "fakeOrg.io/fakeResource", "fake2", Anything).
@@ -146,6 +153,9 @@ var _ = Describe("NetResourcePool", func() {
 						if devInfo.Pci.RdmaDevice != "" {
 							return fmt.Errorf("wrong rdma device")
 						}
+						if devInfo.Pci.PfPciAddress != "" || devInfo.Pci.RepresentorDevice != "" {
+							return fmt.Errorf("unexpected representor device")
+						}
 						return nil
 					})
 				nadutils.On("CleanDeviceInfoFile", "fakeOrg.io/fakeResource", "fake1").Return(nil)
@@ -160,7 +170,7 @@ var _ = Describe("NetResourcePool", func() {
 				nadutils.On("CleanDeviceInfoFile", "fakeOrg.io/fakeResource", "fake1").Return(nil)
 				nadutils.On("CleanDeviceInfoFile", "fakeOrg.io/fakeResource", "fake2").Return(nil)
 				rp := netdevice.NewNetResourcePool(nadutils, rc, pcis)
-				err := rp.CleanDeviceInfoFile("fakeOrg.io")
+				err := rp.CleanDeviceInfoFile("fakeOrg.io", []string{"fake1", "fake2"})
 				nadutils.AssertExpectations(t)
 				Expect(err).ToNot(HaveOccurred())
 			})
@@ -230,7 +240,7 @@ var _ = Describe("NetResourcePool", func() {
 				nadutils.On("CleanDeviceInfoFile", "fakeOrg.io/fakeResource", "fake1").Return(nil)
 				nadutils.On("CleanDeviceInfoFile", "fakeOrg.io/fakeResource", "fake2").Return(nil)
 				rp := netdevice.NewNetResourcePool(nadutils, rc, pcis)
-				err := rp.CleanDeviceInfoFile("fakeOrg.io")
+				err := rp.CleanDeviceInfoFile("fakeOrg.io", []string{"fake1", "fake2"})
 				Expect(err).ToNot(HaveOccurred())
 				nadutils.AssertExpectations(t)
 			})
@@ -15,8 +15,8 @@
 package netdevice
 
 import (
-	"github.com/golang/glog"
 	"github.com/jaypipes/ghw"
+	"k8s.io/klog/v2"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/devices"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/infoprovider"
@@ -46,7 +46,7 @@ func NewPciNetDevice(dev *ghw.PCIDevice,
 		return nil, err
 	}
 
-	infoProviders := rFactory.GetDefaultInfoProvider(dev.Address, driverName)
+	infoProviders := rFactory.GetDefaultInfoProvider(dev.Address, driverName, rc.VfioMode)
 	if rc.AdditionalInfo != nil {
 		infoProviders = append(infoProviders, infoprovider.NewExtraInfoProvider(dev.Address, rc.AdditionalInfo))
 	}
@@ -61,7 +61,7 @@ func NewPciNetDevice(dev *ghw.PCIDevice,
 		if nf.VdpaType != "" {
 			vdpaDev = rFactory.GetVdpaDevice(dev.Address)
 			if vdpaDev == nil {
-				glog.Warningf("No vDPA device found for device %s", dev.Address)
+				klog.InfoS("No vDPA device found", "pciAddress", dev.Address)
 			} else {
 				infoProviders = append(infoProviders, infoprovider.NewVdpaInfoProvider(nf.VdpaType, vdpaDev))
 			}
@@ -69,31 +69,35 @@ func NewPciNetDevice(dev *ghw.PCIDevice,
 			rdmaSpec := rFactory.GetRdmaSpec(types.NetDeviceType, dev.Address)
 			if rdmaSpec.IsRdma() {
 				isRdma = true
-				infoProviders = append(infoProviders, infoprovider.NewRdmaInfoProvider(rdmaSpec))
+				infoProviders = append(infoProviders, infoprovider.NewRdmaInfoProvider(rdmaSpec, rc.Rdma))
 			} else {
-				glog.Warningf("RDMA resources for %s not found. Are RDMA modules loaded?", dev.Address)
+				klog.InfoS("RDMA resources not found, are RDMA modules loaded?", "pciAddress", dev.Address)
 			}
 		}
 		if nf.NeedVhostNet {
 			if infoprovider.VhostNetDeviceExist() {
 				infoProviders = append(infoProviders, infoprovider.NewVhostNetInfoProvider())
 			} else {
-				glog.Warningf("vhost-net is required in the configuration for %s but /dev/vhost-net doesn't exist", dev.Address)
+				klog.InfoS("vhost-net is required in the configuration but /dev/vhost-net doesn't exist", "pciAddress", dev.Address)
 			}
 		}
 	}
 
-	hostDev, err := devices.NewHostDeviceImpl(dev, dev.Address, rFactory, rc, infoProviders)
+	netDev, err := devices.NewGenNetDevice(dev.Address, types.NetDeviceType, isRdma)
 	if err != nil {
 		return nil, err
 	}
+	if netDev.GetUplinkRepresentor() != "" {
+		infoProviders = append(infoProviders,
+			infoprovider.NewRepresentorInfoProvider(netDev.GetUplinkRepresentor(), netDev.GetRepresentor()))
+	}
 
-	pciDev, err := devices.NewGenPciDevice(dev)
+	hostDev, err := devices.NewHostDeviceImpl(dev, dev.Address, rFactory, rc, infoProviders)
 	if err != nil {
 		return nil, err
 	}
 
-	netDev, err := devices.NewGenNetDevice(dev.Address, types.NetDeviceType, isRdma)
+	pciDev, err := devices.NewGenPciDevice(dev)
 	if err != nil {
 		return nil, err
 	}
@@ -103,7 +107,7 @@ func NewPciNetDevice(dev *ghw.PCIDevice,
 		pciAddr := pciDev.GetPciAddr()
 		pKey, err = utils.GetPKey(pciAddr)
 		if err != nil {
-			glog.Infof("getPKey(): unable to get PKey for device %s : %q", pciAddr, err)
+			klog.V(2).InfoS("Unable to get PKey", "pciAddress", pciAddr, "err", err)
 		}
 	}
 
@@ -129,7 +133,7 @@ func (nd *pciNetDevice) GetDDPProfiles() string {
 				// default to ddptool if devlink failed
 				ddpProfile, err = utils.GetDDPProfiles(pciAddr)
 				if err != nil {
-					glog.Infof("GetDDPProfiles(): unable to get ddp profiles for PCI %s and PF PCI device %s : %q", pciAddr, pfPCI, err)
+					klog.V(2).InfoS("Unable to get DDP profiles", "pciAddress", pciAddr, "pfPciAddress", pfPCI, "err", err)
 					return ""
 				}
 			}
@@ -138,7 +142,7 @@ func (nd *pciNetDevice) GetDDPProfiles() string {
 		var err error
 		ddpProfile, err = utils.GetDDPProfiles(pciAddr)
 		if err != nil {
-			glog.Infof("GetDDPProfiles(): unable to get ddp profiles for PCI %s and PF PCI device %s : %q", pciAddr, nd.GetPfPciAddr(), err)
+			klog.V(2).InfoS("Unable to get DDP profiles", "pciAddress", pciAddr, "pfPciAddress", nd.GetPfPciAddr(), "err", err)
 			return ""
 		}
 	}
@@ -64,7 +64,7 @@ var _ = Describe("PciNetDevice", func() {
 				defer fs.Use()()
 				utils.SetDefaultMockNetlinkProvider()
 
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				in := newPciDeviceFn("0000:00:00.1")
 				rc := &types.ResourceConfig{}
 
@@ -127,7 +127,8 @@ var _ = Describe("PciNetDevice", func() {
 			}
 			rdma1.On("IsRdma").Return(true).
 				On("GetRdmaDeviceSpec").Return(fake1ds).
-				On("GetRdmaDeviceName").Return("mlx5_1")
+				On("GetRdmaDeviceName").Return("mlx5_1").
+				On("GetRdmaPortInfo").Return(nil)
 
 			rdma2 := &mocks.RdmaSpec{}
 			rdma2.On("IsRdma").Return(false)
@@ -148,8 +149,8 @@ var _ = Describe("PciNetDevice", func() {
 			mockInfo2.On("GetEnvVal").Return(mockEnv2)
 			mockInfo2.On("GetDeviceSpecs").Return(nil)
 			mockInfo2.On("GetMounts").Return(nil)
-			f.On("GetDefaultInfoProvider", pciAddr1, "mlx5_core").Return([]types.DeviceInfoProvider{mockInfo1}).
-				On("GetDefaultInfoProvider", pciAddr2, "mlx5_core").Return([]types.DeviceInfoProvider{mockInfo2}).
+			f.On("GetDefaultInfoProvider", pciAddr1, "mlx5_core", types.VfioMode("")).Return([]types.DeviceInfoProvider{mockInfo1}).
+				On("GetDefaultInfoProvider", pciAddr2, "mlx5_core", types.VfioMode("")).Return([]types.DeviceInfoProvider{mockInfo2}).
 				On("GetRdmaSpec", types.NetDeviceType, pciAddr1).Return(rdma1).
 				On("GetRdmaSpec", types.NetDeviceType, pciAddr2).Return(rdma2)
 
@@ -268,7 +269,7 @@ var _ = Describe("PciNetDevice", func() {
 				},
 			}
 
-			f := factory.NewResourceFactory("fake", "fake", true, false)
+			f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 			in := newPciDeviceFn("0000:00:00.1")
 			It("should add the vhost-net deviceSpec", func() {
 				defer fs.Use()()
@@ -354,7 +355,7 @@ var _ = Describe("PciNetDevice", func() {
 				defer fs.Use()()
 				utils.SetDefaultMockNetlinkProvider()
 
-				f := factory.NewResourceFactory("fake", "fake", true, false)
+				f := factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 				in := newPciDeviceFn("0000:00:00.1")
 				rc := &types.ResourceConfig{}
 
@@ -420,8 +421,8 @@ var _ = Describe("PciNetDevice", func() {
 			f := &mocks.ResourceFactory{}
 			f.On("GetVdpaDevice", "0000:00:00.1").Return(fakeVdpaVhost).
 				On("GetVdpaDevice", "0000:00:00.2").Return(fakeVdpaVirtio).
-				On("GetDefaultInfoProvider", "0000:00:00.1", "mlx5_core").Return([]types.DeviceInfoProvider{defaultInfo1}).
-				On("GetDefaultInfoProvider", "0000:00:00.2", "ifcvf").Return([]types.DeviceInfoProvider{defaultInfo2})
+				On("GetDefaultInfoProvider", "0000:00:00.1", "mlx5_core", types.VfioMode("")).Return([]types.DeviceInfoProvider{defaultInfo1}).
+				On("GetDefaultInfoProvider", "0000:00:00.2", "ifcvf", types.VfioMode("")).Return([]types.DeviceInfoProvider{defaultInfo2})
 
 			in1 := newPciDeviceFn("0000:00:00.1")
 			in2 := newPciDeviceFn("0000:00:00.2")
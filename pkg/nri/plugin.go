@@ -0,0 +1,319 @@
+// Package nri implements an NRI plugin moving the network devices allocated to a container into the network
+// namespace of its pod when the container is created, and back to the host when the container is removed
+package nri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/container-orchestrated-devices/container-device-interface/pkg/cdi"
+	"github.com/containerd/nri/pkg/api"
+	"github.com/containerd/nri/pkg/stub"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+)
+
+const (
+	// DefaultSocketPath is the default path of the NRI socket of the container runtime
+	DefaultSocketPath = api.DefaultSocketPath
+	// DefaultPluginIndex is the default index of the plugin, which orders it among the other NRI plugins
+	DefaultPluginIndex = "90"
+	pluginName         = "sriov-network-device-plugin"
+
+	envPrefix        = "PCIDEVICE_"
+	envInfoSuffix    = "_INFO"
+	networkNamespace = "network"
+	// reconnectInterval is the interval to reconnect to the container runtime after losing the connection
+	reconnectInterval = 5 * time.Second
+)
+
+// movedDevice is a network device moved into the network namespace of a pod
+type movedDevice struct {
+	podID      string
+	deviceID   string
+	netName    string // netdevice name on the host
+	ifIndex    int    // interface index of the netdevice in the network namespace of the pod
+	rdmaDevice string // RDMA device moved along with the netdevice, empty if none
+}
+
+// Plugin is an NRI plugin moving the netdevices of the served devices allocated to a container, and their RDMA
+// devices when the RDMA subsystem is in exclusive mode, into the network namespace of the pod of the container
+type Plugin struct {
+	socketPath string
+	index      string
+	lock       sync.Mutex
+	devices    map[string]types.NetDevice // served network devices keyed by device ID
+	moved      map[string][]movedDevice   // devices moved into the network namespace of a pod keyed by container ID
+}
+
+// NewPlugin returns a Plugin connecting to the container runtime NRI socket at socketPath
+func NewPlugin(socketPath, index string) *Plugin {
+	return &Plugin{
+		socketPath: socketPath,
+		index:      index,
+		devices:    make(map[string]types.NetDevice),
+		moved:      make(map[string][]movedDevice),
+	}
+}
+
+// SetDevices replaces the served devices. Only devices with a netdevice are moved into pod network namespaces.
+func (p *Plugin) SetDevices(devices []types.HostDevice) {
+	netDevices := make(map[string]types.NetDevice, len(devices))
+	for _, dev := range devices {
+		if netDev, ok := dev.(types.NetDevice); ok && netDev.GetNetName() != "" {
+			netDevices[dev.GetDeviceID()] = netDev
+		}
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.devices = netDevices
+}
+
+// Run registers the plugin to the container runtime and handles its events until stopCh is closed,
+// reconnecting whenever the connection to the container runtime is lost
+func (p *Plugin) Run(stopCh <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, err := stub.New(p, stub.WithPluginName(pluginName), stub.WithPluginIdx(p.index),
+		stub.WithSocketPath(p.socketPath), stub.WithOnClose(func() {}))
+	if err != nil {
+		klog.ErrorS(err, "Unable to create NRI plugin")
+		return
+	}
+	go func() {
+		<-stopCh
+		cancel()
+		s.Stop()
+	}()
+
+	for {
+		klog.InfoS("Connecting NRI plugin to the container runtime", "socket", p.socketPath)
+		if err := s.Run(ctx); err != nil {
+			klog.InfoS("NRI plugin disconnected from the container runtime", "err", err)
+		}
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(reconnectInterval):
+		}
+	}
+}
+
+// CreateContainer moves the network devices allocated to the container into the network namespace of its pod.
+// Devices that are not in the host network namespace, because they were moved by a previous instance of the
+// container or by a CNI plugin, are left where they are.
+func (p *Plugin) CreateContainer(_ context.Context, pod *api.PodSandbox, ctr *api.Container) (
+	*api.ContainerAdjustment, []*api.ContainerUpdate, error) {
+	devices := p.containerDevices(ctr)
+	if len(devices) == 0 {
+		return nil, nil, nil
+	}
+	log := klog.LoggerWithValues(klog.Background(), "pod", pod.GetNamespace()+"/"+pod.GetName(), "container", ctr.GetName())
+	netnsPath := podNetnsPath(pod)
+	if netnsPath == "" {
+		log.Info("Pod uses the host network namespace, not moving its network devices")
+		return nil, nil, nil
+	}
+	exclusiveRdma := rdmaExclusive()
+
+	moved := make([]movedDevice, 0, len(devices))
+	absent := make([]string, 0)
+	for _, dev := range devices {
+		m := movedDevice{podID: pod.GetId(), deviceID: dev.GetDeviceID(), netName: dev.GetNetName()}
+		if exclusiveRdma && dev.IsRdma() {
+			m.rdmaDevice = rdmaDeviceName(dev)
+		}
+		ifIndex, err := moveToNetns(m, netnsPath)
+		if errors.Is(err, utils.ErrLinkNotFound) {
+			log.Info("Device is not in the host network namespace, not moving it", "deviceID", m.deviceID,
+				"netName", m.netName)
+			absent = append(absent, m.deviceID)
+			continue
+		}
+		if err != nil {
+			// leave the host as it was before the container creation failed
+			restoreToHost(log, moved, netnsPath)
+			return nil, nil, fmt.Errorf("unable to move device %s into the network namespace of pod %s/%s: %v",
+				m.deviceID, pod.GetNamespace(), pod.GetName(), err)
+		}
+		m.ifIndex = ifIndex
+		log.Info("Moved device into the pod network namespace", "deviceID", m.deviceID, "netName", m.netName,
+			"ifIndex", m.ifIndex, "rdmaDevice", m.rdmaDevice)
+		moved = append(moved, m)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	// the devices a previous instance of the container moved are now moved back when this one is removed
+	moved = append(moved, p.takeMoved(pod.GetId(), absent)...)
+	if len(moved) > 0 {
+		p.moved[ctr.GetId()] = moved
+	}
+	return nil, nil, nil
+}
+
+// takeMoved removes the given devices from the devices other containers of a pod moved into its network namespace
+// and returns them. The caller must hold the lock.
+func (p *Plugin) takeMoved(podID string, deviceIDs []string) []movedDevice {
+	taken := make([]movedDevice, 0)
+	for _, id := range deviceIDs {
+		for ctrID, moved := range p.moved {
+			i := slices.IndexFunc(moved, func(m movedDevice) bool { return m.podID == podID && m.deviceID == id })
+			if i < 0 {
+				continue
+			}
+			taken = append(taken, moved[i])
+			if moved = slices.Delete(moved, i, i+1); len(moved) == 0 {
+				delete(p.moved, ctrID)
+			} else {
+				p.moved[ctrID] = moved
+			}
+			break
+		}
+	}
+	return taken
+}
+
+// RemoveContainer moves the network devices the plugin moved for the container back into the host network
+// namespace. Devices of containers created before the plugin started get back to the host when the network
+// namespace of the pod is deleted.
+func (p *Plugin) RemoveContainer(_ context.Context, pod *api.PodSandbox, ctr *api.Container) error {
+	p.lock.Lock()
+	moved := p.moved[ctr.GetId()]
+	delete(p.moved, ctr.GetId())
+	p.lock.Unlock()
+	if len(moved) == 0 {
+		return nil
+	}
+	netnsPath := podNetnsPath(pod)
+	if netnsPath == "" {
+		return nil
+	}
+	if _, err := os.Stat(netnsPath); err != nil {
+		// the kernel moves the devices back into the host network namespace when deleting a network namespace
+		return nil
+	}
+	log := klog.LoggerWithValues(klog.Background(), "pod", pod.GetNamespace()+"/"+pod.GetName(), "container", ctr.GetName())
+	restoreToHost(log, moved, netnsPath)
+	return nil
+}
+
+// containerDevices returns the served network devices allocated to a container, found in the device plugin
+// environment variables and in the CDI devices and annotations of the container
+func (p *Plugin) containerDevices(ctr *api.Container) []types.NetDevice {
+	ids := make(map[string]bool)
+	for _, env := range ctr.GetEnv() {
+		key, value, found := strings.Cut(env, "=")
+		if !found || !strings.HasPrefix(key, envPrefix) || strings.HasSuffix(key, envInfoSuffix) {
+			continue
+		}
+		for _, id := range strings.Split(value, ",") {
+			ids[id] = true
+		}
+	}
+	cdiDevices := make([]string, 0, len(ctr.GetCDIDevices()))
+	for _, dev := range ctr.GetCDIDevices() {
+		cdiDevices = append(cdiDevices, dev.GetName())
+	}
+	if _, annotated, err := cdi.ParseAnnotations(ctr.GetAnnotations()); err == nil {
+		cdiDevices = append(cdiDevices, annotated...)
+	}
+	for _, name := range cdiDevices {
+		if _, _, id, err := cdi.ParseQualifiedName(name); err == nil {
+			ids[id] = true
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	devices := make([]types.NetDevice, 0, len(ids))
+	for id := range ids {
+		if dev, ok := p.devices[id]; ok {
+			devices = append(devices, dev)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].GetDeviceID() < devices[j].GetDeviceID() })
+	return devices
+}
+
+// podNetnsPath returns the path of the network namespace of a pod, empty if the pod uses the host network namespace
+func podNetnsPath(pod *api.PodSandbox) string {
+	for _, ns := range pod.GetLinux().GetNamespaces() {
+		if ns.GetType() == networkNamespace {
+			return ns.GetPath()
+		}
+	}
+	return ""
+}
+
+// rdmaExclusive returns true if the RDMA subsystem is in exclusive network namespace mode
+func rdmaExclusive() bool {
+	mode, err := utils.GetNetlinkProvider().GetRdmaNetnsMode()
+	if err != nil {
+		klog.V(2).InfoS("Unable to get RDMA network namespace mode", "err", err)
+		return false
+	}
+	return mode == types.RdmaNetnsModeExclusive
+}
+
+// rdmaDeviceName returns the RDMA device of a network device, empty if none
+func rdmaDeviceName(dev types.NetDevice) string {
+	var rdmaDevices []string
+	switch d := dev.(type) {
+	case types.PciNetDevice:
+		rdmaDevices = utils.GetRdmaProvider().GetRdmaDevicesForPcidev(d.GetPciAddr())
+	case types.AuxNetDevice:
+		rdmaDevices = utils.GetRdmaProvider().GetRdmaDevicesForAuxdev(d.GetDeviceID())
+	}
+	if len(rdmaDevices) == 0 {
+		return ""
+	}
+	return rdmaDevices[0]
+}
+
+// moveToNetns moves a device and its RDMA device into the network namespace at netnsPath and returns the
+// interface index of the netdevice in that network namespace
+func moveToNetns(dev movedDevice, netnsPath string) (int, error) {
+	ifIndex, err := utils.GetNetlinkProvider().MoveLinkToNetns(dev.netName, netnsPath)
+	if err != nil {
+		return 0, err
+	}
+	if dev.rdmaDevice == "" {
+		return ifIndex, nil
+	}
+	if err := utils.GetNetlinkProvider().MoveRdmaDeviceToNetns(dev.rdmaDevice, netnsPath); err != nil {
+		if lerr := utils.GetNetlinkProvider().MoveLinkToHostNetns(ifIndex, dev.netName, netnsPath); lerr != nil {
+			klog.ErrorS(lerr, "Unable to move device back to the host", "deviceID", dev.deviceID)
+		}
+		return 0, err
+	}
+	return ifIndex, nil
+}
+
+// restoreToHost moves devices from the network namespace at netnsPath back into the host network namespace,
+// finding their netdevices by the interface index they got there and restoring their host netdevice names
+func restoreToHost(log klog.Logger, devices []movedDevice, netnsPath string) {
+	for _, dev := range devices {
+		if err := utils.GetNetlinkProvider().MoveLinkToHostNetns(dev.ifIndex, dev.netName, netnsPath); err != nil {
+			log.Error(err, "Unable to move device back to the host", "deviceID", dev.deviceID)
+		} else {
+			log.Info("Moved device back to the host", "deviceID", dev.deviceID, "netName", dev.netName)
+		}
+		if dev.rdmaDevice == "" {
+			continue
+		}
+		if err := utils.GetNetlinkProvider().MoveRdmaDeviceToHostNetns(dev.rdmaDevice, netnsPath); err != nil {
+			log.Error(err, "Unable to move RDMA device back to the host", "deviceID", dev.deviceID,
+				"rdmaDevice", dev.rdmaDevice)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package nri
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/containerd/nri/pkg/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+	utilmocks "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils/mocks"
+)
+
+// newNetDevice returns a mocked PCI network device
+func newNetDevice(pciAddr, netName string, rdma bool) *mocks.PciNetDevice {
+	dev := &mocks.PciNetDevice{}
+	dev.On("GetDeviceID").Return(pciAddr).
+		On("GetPciAddr").Return(pciAddr).
+		On("GetNetName").Return(netName).
+		On("IsRdma").Return(rdma)
+	return dev
+}
+
+// newPod returns a pod sandbox in the given network namespace, in the host network namespace when empty
+func newPod(netnsPath string) *api.PodSandbox {
+	pod := &api.PodSandbox{Id: "pod-id", Name: "pod", Namespace: "default", Linux: &api.LinuxPodSandbox{}}
+	if netnsPath != "" {
+		pod.Linux.Namespaces = []*api.LinuxNamespace{{Type: "network", Path: netnsPath}}
+	}
+	return pod
+}
+
+var _ = Describe("NRI plugin", func() {
+	var (
+		plugin      *Plugin
+		netlink     *utilmocks.NetlinkProvider
+		rdma        *utilmocks.RdmaProvider
+		origNetlink utils.NetlinkProvider
+		origRdma    utils.RdmaProvider
+		netnsPath   string
+		pod         *api.PodSandbox
+		ctx         = context.Background()
+	)
+	BeforeEach(func() {
+		origNetlink = utils.GetNetlinkProvider()
+		origRdma = utils.GetRdmaProvider()
+		netlink = &utilmocks.NetlinkProvider{}
+		rdma = &utilmocks.RdmaProvider{}
+		utils.SetNetlinkProviderInst(netlink)
+		utils.SetRdmaProviderInst(rdma)
+
+		// the network namespace of the pod only has to exist for the tests
+		netnsPath = GinkgoT().TempDir()
+		pod = newPod(netnsPath)
+		nonNetDevice := &mocks.HostDevice{}
+		nonNetDevice.On("GetDeviceID").Return("0000:00:01.0")
+		plugin = NewPlugin(DefaultSocketPath, DefaultPluginIndex)
+		plugin.SetDevices([]types.HostDevice{
+			newNetDevice("0000:3b:02.1", "ens1f0v1", false),
+			newNetDevice("0000:3b:02.2", "ens1f0v2", true),
+			newNetDevice("0000:3b:02.3", "", false),
+			nonNetDevice,
+		})
+	})
+	AfterEach(func() {
+		utils.SetNetlinkProviderInst(origNetlink)
+		utils.SetRdmaProviderInst(origRdma)
+	})
+
+	Context("creating and removing a container", func() {
+		It("should move the netdevices of the devices allocated to the container and move them back", func() {
+			ctr := &api.Container{Id: "ctr", Name: "ctr", Env: []string{
+				"PCIDEVICE_INTEL_COM_SRIOV=0000:3b:02.1,0000:3b:02.3,0000:00:01.0",
+				`PCIDEVICE_INTEL_COM_SRIOV_INFO={"0000:3b:02.1":{}}`,
+				"PATH=/usr/bin"},
+				Annotations: map[string]string{"cdi.k8s.io/intel.com_net": "intel.com/net=0000:3b:02.2"}}
+			netlink.On("GetRdmaNetnsMode").Return("shared", nil).
+				On("MoveLinkToNetns", "ens1f0v1", netnsPath).Return(11, nil).
+				On("MoveLinkToNetns", "ens1f0v2", netnsPath).Return(12, nil)
+
+			_, _, err := plugin.CreateContainer(ctx, pod, ctr)
+			Expect(err).NotTo(HaveOccurred())
+			netlink.AssertNumberOfCalls(GinkgoT(), "MoveLinkToNetns", 2)
+			netlink.AssertNotCalled(GinkgoT(), "MoveRdmaDeviceToNetns", mock.Anything, mock.Anything)
+
+			netlink.On("MoveLinkToHostNetns", 11, "ens1f0v1", netnsPath).Return(nil).
+				On("MoveLinkToHostNetns", 12, "ens1f0v2", netnsPath).Return(nil)
+			Expect(plugin.RemoveContainer(ctx, pod, ctr)).To(Succeed())
+			netlink.AssertNumberOfCalls(GinkgoT(), "MoveLinkToHostNetns", 2)
+		})
+		It("should move the RDMA devices along when the RDMA subsystem is in exclusive mode", func() {
+			ctr := &api.Container{Id: "ctr", Name: "ctr", CDIDevices: []*api.CDIDevice{{Name: "intel.com/net=0000:3b:02.2"}}}
+			rdma.On("GetRdmaDevicesForPcidev", "0000:3b:02.2").Return([]string{"mlx5_2"})
+			netlink.On("GetRdmaNetnsMode").Return("exclusive", nil).
+				On("MoveRdmaDeviceToNetns", "mlx5_2", netnsPath).Return(nil).
+				On("MoveLinkToNetns", "ens1f0v2", netnsPath).Return(12, nil)
+
+			_, _, err := plugin.CreateContainer(ctx, pod, ctr)
+			Expect(err).NotTo(HaveOccurred())
+			netlink.AssertExpectations(GinkgoT())
+
+			netlink.On("MoveLinkToHostNetns", 12, "ens1f0v2", netnsPath).Return(nil).
+				On("MoveRdmaDeviceToHostNetns", "mlx5_2", netnsPath).Return(nil)
+			Expect(plugin.RemoveContainer(ctx, pod, ctr)).To(Succeed())
+			netlink.AssertExpectations(GinkgoT())
+		})
+		It("should restore the moved devices and fail when a device can not be moved", func() {
+			ctr := &api.Container{Id: "ctr", Name: "ctr", Env: []string{"PCIDEVICE_INTEL_COM_SRIOV=0000:3b:02.1,0000:3b:02.2"}}
+			netlink.On("GetRdmaNetnsMode").Return("shared", nil).
+				On("MoveLinkToNetns", "ens1f0v1", netnsPath).Return(11, nil).
+				On("MoveLinkToNetns", "ens1f0v2", netnsPath).Return(0, fmt.Errorf("failed")).
+				On("MoveLinkToHostNetns", 11, "ens1f0v1", netnsPath).Return(nil)
+
+			_, _, err := plugin.CreateContainer(ctx, pod, ctr)
+			Expect(err).To(HaveOccurred())
+			netlink.AssertExpectations(GinkgoT())
+		})
+		It("should leave the devices that are not in the host network namespace where they are", func() {
+			ctr := &api.Container{Id: "ctr", Name: "ctr", Env: []string{"PCIDEVICE_INTEL_COM_SRIOV=0000:3b:02.1,0000:3b:02.2"}}
+			netlink.On("GetRdmaNetnsMode").Return("shared", nil).
+				On("MoveLinkToNetns", "ens1f0v1", netnsPath).Return(0, fmt.Errorf("%w: ens1f0v1", utils.ErrLinkNotFound)).
+				On("MoveLinkToNetns", "ens1f0v2", netnsPath).Return(12, nil)
+
+			_, _, err := plugin.CreateContainer(ctx, pod, ctr)
+			Expect(err).NotTo(HaveOccurred())
+
+			netlink.On("MoveLinkToHostNetns", 12, "ens1f0v2", netnsPath).Return(nil)
+			Expect(plugin.RemoveContainer(ctx, pod, ctr)).To(Succeed())
+			netlink.AssertNumberOfCalls(GinkgoT(), "MoveLinkToHostNetns", 1)
+		})
+		It("should hand the devices of a restarted container over to its new instance", func() {
+			env := []string{"PCIDEVICE_INTEL_COM_SRIOV=0000:3b:02.1"}
+			first := &api.Container{Id: "ctr-1", Name: "ctr", Env: env}
+			second := &api.Container{Id: "ctr-2", Name: "ctr", Env: env}
+			netlink.On("GetRdmaNetnsMode").Return("shared", nil).
+				On("MoveLinkToNetns", "ens1f0v1", netnsPath).Return(11, nil).Once().
+				On("MoveLinkToNetns", "ens1f0v1", netnsPath).Return(0, fmt.Errorf("%w: ens1f0v1", utils.ErrLinkNotFound))
+
+			_, _, err := plugin.CreateContainer(ctx, pod, first)
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = plugin.CreateContainer(ctx, pod, second)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(plugin.RemoveContainer(ctx, pod, first)).To(Succeed())
+			netlink.AssertNotCalled(GinkgoT(), "MoveLinkToHostNetns", mock.Anything, mock.Anything, mock.Anything)
+			netlink.On("MoveLinkToHostNetns", 11, "ens1f0v1", netnsPath).Return(nil)
+			Expect(plugin.RemoveContainer(ctx, pod, second)).To(Succeed())
+			netlink.AssertNumberOfCalls(GinkgoT(), "MoveLinkToHostNetns", 1)
+		})
+		It("should not move the devices of a pod in the host network namespace", func() {
+			ctr := &api.Container{Id: "ctr", Name: "ctr", Env: []string{"PCIDEVICE_INTEL_COM_SRIOV=0000:3b:02.1"}}
+			_, _, err := plugin.CreateContainer(ctx, newPod(""), ctr)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plugin.RemoveContainer(ctx, newPod(""), ctr)).To(Succeed())
+			netlink.AssertNotCalled(GinkgoT(), "MoveLinkToNetns", mock.Anything, mock.Anything)
+			netlink.AssertNotCalled(GinkgoT(), "MoveLinkToHostNetns", mock.Anything, mock.Anything, mock.Anything)
+		})
+		It("should ignore containers without served network devices", func() {
+			ctr := &api.Container{Id: "ctr", Name: "ctr", Env: []string{"PCIDEVICE_INTEL_COM_SRIOV=0000:00:01.0"}}
+			_, _, err := plugin.CreateContainer(ctx, pod, ctr)
+			Expect(err).NotTo(HaveOccurred())
+			netlink.AssertNotCalled(GinkgoT(), "GetRdmaNetnsMode")
+		})
+		It("should not move back the netdevices of containers created before the plugin started", func() {
+			ctr := &api.Container{Id: "ctr", Name: "ctr", Env: []string{"PCIDEVICE_INTEL_COM_SRIOV=0000:3b:02.1"}}
+			Expect(plugin.RemoveContainer(ctx, pod, ctr)).To(Succeed())
+			netlink.AssertNotCalled(GinkgoT(), "MoveLinkToHostNetns", mock.Anything, mock.Anything, mock.Anything)
+		})
+		It("should not move back the devices when the network namespace of the pod is gone", func() {
+			ctr := &api.Container{Id: "ctr", Name: "ctr", Env: []string{"PCIDEVICE_INTEL_COM_SRIOV=0000:3b:02.1"}}
+			netlink.On("GetRdmaNetnsMode").Return("shared", nil).
+				On("MoveLinkToNetns", "ens1f0v1", netnsPath).Return(11, nil)
+			_, _, err := plugin.CreateContainer(ctx, pod, ctr)
+			Expect(err).NotTo(HaveOccurred())
+
+			gone := newPod(filepath.Join(netnsPath, "gone"))
+			Expect(plugin.RemoveContainer(ctx, gone, ctr)).To(Succeed())
+			netlink.AssertNotCalled(GinkgoT(), "MoveLinkToHostNetns", mock.Anything, mock.Anything, mock.Anything)
+		})
+	})
+})
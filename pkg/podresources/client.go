@@ -0,0 +1,97 @@
+// Package podresources queries the Kubelet PodResources API for the devices assigned to containers
+package podresources
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+)
+
+const unix = "unix"
+
+type client struct {
+	socket string
+}
+
+// NewClient returns a PodResourcesClient connecting to the Kubelet PodResources socket
+func NewClient(socket string) types.PodResourcesClient {
+	return &client{socket: socket}
+}
+
+// GetDeviceAssignments returns the containers devices are assigned to keyed by resource name and device ID
+func (c *client) GetDeviceAssignments(ctx context.Context) (map[string]map[string]types.DeviceAssignment, error) {
+	resp, err := c.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return deviceAssignments(resp), nil
+}
+
+// GetClaimDevices returns the names of the devices of a DRA driver allocated to containers through resource claims
+func (c *client) GetClaimDevices(ctx context.Context, driverName string) ([]string, error) {
+	resp, err := c.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return claimDevices(resp, driverName), nil
+}
+
+// list returns the resources of all pods of the node
+func (c *client) list(ctx context.Context) (*podresourcesapi.ListPodResourcesResponse, error) {
+	conn, err := grpc.NewClient(unix+":"+c.socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to PodResources API at %s: %v", c.socket, err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	resp, err := podresourcesapi.NewPodResourcesListerClient(conn).List(ctx, &podresourcesapi.ListPodResourcesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pod resources: %v", err)
+	}
+	return resp, nil
+}
+
+// deviceAssignments collects the devices of every container of a List response
+func deviceAssignments(resp *podresourcesapi.ListPodResourcesResponse) map[string]map[string]types.DeviceAssignment {
+	assignments := make(map[string]map[string]types.DeviceAssignment)
+	for _, pod := range resp.GetPodResources() {
+		for _, container := range pod.GetContainers() {
+			for _, dev := range container.GetDevices() {
+				resourceName := dev.GetResourceName()
+				if assignments[resourceName] == nil {
+					assignments[resourceName] = make(map[string]types.DeviceAssignment)
+				}
+				for _, id := range dev.GetDeviceIds() {
+					assignments[resourceName][id] = types.DeviceAssignment{
+						Namespace: pod.GetNamespace(),
+						Pod:       pod.GetName(),
+						Container: container.GetName(),
+					}
+				}
+			}
+		}
+	}
+	return assignments
+}
+
+// claimDevices returns the names of the devices of a DRA driver in the resource claims of the containers of resp
+func claimDevices(resp *podresourcesapi.ListPodResourcesResponse, driverName string) []string {
+	devices := make([]string, 0)
+	for _, pod := range resp.GetPodResources() {
+		for _, container := range pod.GetContainers() {
+			for _, claim := range container.GetDynamicResources() {
+				for _, res := range claim.GetClaimResources() {
+					if res.GetDriverName() == driverName {
+						devices = append(devices, res.GetDeviceName())
+					}
+				}
+			}
+		}
+	}
+	return devices
+}
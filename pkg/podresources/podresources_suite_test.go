@@ -0,0 +1,13 @@
+package podresources
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestPodResources(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "PodResources Suite")
+}
@@ -0,0 +1,173 @@
+package podresources
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+)
+
+// AssignFunc is called when a device of a tracked pool gets assigned to a container
+type AssignFunc func(resourceName, deviceID string, assignment types.DeviceAssignment)
+
+// SyncFunc is called with all the device assignments, keyed by resource name and device ID, after every
+// successful listing
+type SyncFunc func(assignments map[string]map[string]types.DeviceAssignment)
+
+// Tracker periodically lists the devices assigned to containers through the PodResources API and keeps
+// track of the container each device of the tracked pools is assigned to
+type Tracker struct {
+	client      types.PodResourcesClient
+	interval    time.Duration
+	onAssign    AssignFunc
+	onSync      SyncFunc
+	lock        sync.RWMutex
+	pools       map[string][]string // device IDs keyed by resource name
+	assignments map[string]map[string]types.DeviceAssignment
+	lastErr     string
+}
+
+var _ types.DeviceAssignmentLookup = &Tracker{}
+
+// NewTracker returns a Tracker listing the device assignments every interval. onAssign and onSync may be nil.
+func NewTracker(client types.PodResourcesClient, interval time.Duration, onAssign AssignFunc,
+	onSync SyncFunc) *Tracker {
+	return &Tracker{
+		client:      client,
+		interval:    interval,
+		onAssign:    onAssign,
+		onSync:      onSync,
+		pools:       make(map[string][]string),
+		assignments: make(map[string]map[string]types.DeviceAssignment),
+	}
+}
+
+// SetPools replaces the tracked pools, given as device IDs keyed by fully qualified resource name
+func (t *Tracker) SetPools(pools map[string][]string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.pools = pools
+}
+
+// Run syncs the device assignments every interval until stopCh is closed
+func (t *Tracker) Run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		t.syncAndLog()
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncAndLog syncs the device assignments, logging failures only when they change to avoid flooding the log
+func (t *Tracker) syncAndLog() {
+	ctx, cancel := context.WithTimeout(context.Background(), t.interval)
+	defer cancel()
+	err := t.Sync(ctx)
+	switch {
+	case err != nil && err.Error() != t.lastErr:
+		klog.ErrorS(err, "Unable to track device assignments")
+		t.lastErr = err.Error()
+	case err == nil && t.lastErr != "":
+		klog.InfoS("Tracking device assignments again")
+		t.lastErr = ""
+	}
+}
+
+// Sync lists the device assignments, records the number of assigned devices of every tracked pool and calls
+// onAssign for the devices of the tracked pools that got assigned to a container since the last sync, then
+// onSync with all the assignments
+func (t *Tracker) Sync(ctx context.Context) error {
+	assignments, err := t.client.GetDeviceAssignments(ctx)
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	previous := t.assignments
+	t.assignments = assignments
+	changed := make(map[string]map[string]types.DeviceAssignment)
+	allocated := make(map[string]int, len(t.pools))
+	for resourceName, deviceIDs := range t.pools {
+		allocated[resourceName] = 0
+		for _, id := range deviceIDs {
+			assignment, ok := assignments[resourceName][id]
+			if !ok {
+				continue
+			}
+			allocated[resourceName]++
+			if old, ok := previous[resourceName][id]; ok && old == assignment {
+				continue
+			}
+			if changed[resourceName] == nil {
+				changed[resourceName] = make(map[string]types.DeviceAssignment)
+			}
+			changed[resourceName][id] = assignment
+		}
+	}
+	t.lock.Unlock()
+
+	for resourceName, count := range allocated {
+		metrics.SetAllocatedDevices(resourceName, count)
+	}
+	for resourceName, devices := range changed {
+		for id, assignment := range devices {
+			klog.InfoS("Device is assigned to a container", "resourceName", resourceName, "deviceID", id,
+				"pod", assignment.Namespace+"/"+assignment.Pod, "container", assignment.Container)
+
+			if t.onAssign != nil {
+				t.onAssign(resourceName, id, assignment)
+			}
+		}
+	}
+	if t.onSync != nil {
+		t.onSync(assignments)
+	}
+	return nil
+}
+
+// GetDeviceAssignment returns the container a device of a resource is assigned to, if any
+func (t *Tracker) GetDeviceAssignment(resourceName, deviceID string) (types.DeviceAssignment, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	assignment, ok := t.assignments[resourceName][deviceID]
+	return assignment, ok
+}
+
+// GetPoolAssignments returns the container every device of the tracked pools is assigned to, nil for devices
+// that are not assigned, keyed by resource name and device ID
+func (t *Tracker) GetPoolAssignments() map[string]map[string]*types.DeviceAssignment {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	pools := make(map[string]map[string]*types.DeviceAssignment, len(t.pools))
+	for resourceName, deviceIDs := range t.pools {
+		devices := make(map[string]*types.DeviceAssignment, len(deviceIDs))
+		for _, id := range deviceIDs {
+			if assignment, ok := t.assignments[resourceName][id]; ok {
+				devices[id] = &assignment
+			} else {
+				devices[id] = nil
+			}
+		}
+		pools[resourceName] = devices
+	}
+	return pools
+}
+
+// ServeHTTP serves the device assignments of the tracked pools as JSON
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(t.GetPoolAssignments()); err != nil {
+		klog.ErrorS(err, "Unable to encode device assignments")
+	}
+}
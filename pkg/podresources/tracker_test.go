@@ -0,0 +1,150 @@
+package podresources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
+)
+
+var _ = Describe("PodResources", func() {
+	podA := types.DeviceAssignment{Namespace: "default", Pod: "pod-a", Container: "app"}
+	podB := types.DeviceAssignment{Namespace: "default", Pod: "pod-b", Container: "app"}
+
+	Describe("listing device assignments", func() {
+		It("should key the containers by resource name and device ID", func() {
+			resp := &podresourcesapi.ListPodResourcesResponse{
+				PodResources: []*podresourcesapi.PodResources{{
+					Name:      "pod-a",
+					Namespace: "default",
+					Containers: []*podresourcesapi.ContainerResources{{
+						Name: "app",
+						Devices: []*podresourcesapi.ContainerDevices{
+							{ResourceName: "intel.com/sriov", DeviceIds: []string{"0000:3b:02.0", "0000:3b:02.1"}},
+						},
+					}},
+				}},
+			}
+			Expect(deviceAssignments(resp)).To(Equal(map[string]map[string]types.DeviceAssignment{
+				"intel.com/sriov": {"0000:3b:02.0": podA, "0000:3b:02.1": podA},
+			}))
+		})
+		It("should list the claim devices of a DRA driver", func() {
+			resp := &podresourcesapi.ListPodResourcesResponse{
+				PodResources: []*podresourcesapi.PodResources{{
+					Name:      "pod-a",
+					Namespace: "default",
+					Containers: []*podresourcesapi.ContainerResources{{
+						Name: "app",
+						DynamicResources: []*podresourcesapi.DynamicResource{{
+							ClaimName: "vfs",
+							ClaimResources: []*podresourcesapi.ClaimResource{
+								{DriverName: "sriovnetwork.k8snetworkplumbingwg.io", PoolName: "intel.com/sriov", DeviceName: "0000-3b-02-0"},
+								{DriverName: "gpu.example.com", PoolName: "gpus", DeviceName: "gpu-0"},
+							},
+						}},
+					}},
+				}},
+			}
+			Expect(claimDevices(resp, "sriovnetwork.k8snetworkplumbingwg.io")).To(Equal([]string{"0000-3b-02-0"}))
+		})
+	})
+
+	Describe("tracking device assignments", func() {
+		var (
+			client   *mocks.PodResourcesClient
+			tracker  *Tracker
+			assigned []string
+		)
+		BeforeEach(func() {
+			client = &mocks.PodResourcesClient{}
+			assigned = nil
+			tracker = NewTracker(client, 0, func(resourceName, deviceID string, a types.DeviceAssignment) {
+				assigned = append(assigned, resourceName+"="+deviceID+"@"+a.Pod)
+			}, nil)
+			tracker.SetPools(map[string][]string{"intel.com/sriov": {"0000:3b:02.0", "0000:3b:02.1"}})
+		})
+
+		It("should notify newly assigned devices of the tracked pools only", func() {
+			client.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{
+				"intel.com/sriov": {"0000:3b:02.0": podA},
+				"nvidia.com/gpu":  {"gpu-0": podA},
+			}, nil).Once()
+			Expect(tracker.Sync(context.TODO())).To(Succeed())
+			Expect(assigned).To(ConsistOf("intel.com/sriov=0000:3b:02.0@pod-a"))
+
+			client.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{
+				"intel.com/sriov": {"0000:3b:02.0": podA, "0000:3b:02.1": podB},
+			}, nil).Once()
+			Expect(tracker.Sync(context.TODO())).To(Succeed())
+			Expect(assigned).To(ConsistOf("intel.com/sriov=0000:3b:02.0@pod-a", "intel.com/sriov=0000:3b:02.1@pod-b"))
+
+			a, ok := tracker.GetDeviceAssignment("intel.com/sriov", "0000:3b:02.1")
+			Expect(ok).To(BeTrue())
+			Expect(a).To(Equal(podB))
+		})
+		It("should keep the last assignments when listing fails", func() {
+			client.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{
+				"intel.com/sriov": {"0000:3b:02.0": podA},
+			}, nil).Once().
+				On("GetDeviceAssignments", mock.Anything).Return(nil, fmt.Errorf("no kubelet")).Once()
+			Expect(tracker.Sync(context.TODO())).To(Succeed())
+			Expect(tracker.Sync(context.TODO())).NotTo(Succeed())
+
+			_, ok := tracker.GetDeviceAssignment("intel.com/sriov", "0000:3b:02.0")
+			Expect(ok).To(BeTrue())
+		})
+		It("should pass all the assignments to onSync after a successful listing only", func() {
+			var synced []map[string]map[string]types.DeviceAssignment
+			tracker = NewTracker(client, 0, nil, func(assignments map[string]map[string]types.DeviceAssignment) {
+				synced = append(synced, assignments)
+			})
+			assignments := map[string]map[string]types.DeviceAssignment{"nvidia.com/gpu": {"gpu-0": podA}}
+			client.On("GetDeviceAssignments", mock.Anything).Return(assignments, nil).Once().
+				On("GetDeviceAssignments", mock.Anything).Return(nil, fmt.Errorf("no kubelet")).Once()
+			Expect(tracker.Sync(context.TODO())).To(Succeed())
+			Expect(tracker.Sync(context.TODO())).NotTo(Succeed())
+			Expect(synced).To(Equal([]map[string]map[string]types.DeviceAssignment{assignments}))
+		})
+		It("should record the number of assigned devices of the tracked pools", func() {
+			allocated := func() string {
+				rec := httptest.NewRecorder()
+				metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+				return rec.Body.String()
+			}
+			client.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{
+				"intel.com/sriov": {"0000:3b:02.0": podA, "0000:3b:02.1": podB},
+			}, nil).Once().
+				On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{
+				"intel.com/sriov": {"0000:3b:02.1": podB},
+			}, nil).Once()
+			Expect(tracker.Sync(context.TODO())).To(Succeed())
+			Expect(allocated()).To(ContainSubstring(`sriov_device_plugin_allocated_devices{resource="intel.com/sriov"} 2`))
+			Expect(tracker.Sync(context.TODO())).To(Succeed())
+			Expect(allocated()).To(ContainSubstring(`sriov_device_plugin_allocated_devices{resource="intel.com/sriov"} 1`))
+		})
+		It("should serve the assignments of every device of the tracked pools", func() {
+			client.On("GetDeviceAssignments", mock.Anything).Return(map[string]map[string]types.DeviceAssignment{
+				"intel.com/sriov": {"0000:3b:02.0": podA},
+			}, nil)
+			Expect(tracker.Sync(context.TODO())).To(Succeed())
+
+			rec := httptest.NewRecorder()
+			tracker.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/assignments", nil))
+			served := map[string]map[string]*types.DeviceAssignment{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &served)).To(Succeed())
+			Expect(served).To(Equal(map[string]map[string]*types.DeviceAssignment{
+				"intel.com/sriov": {"0000:3b:02.0": &podA, "0000:3b:02.1": nil},
+			}))
+		})
+	})
+})
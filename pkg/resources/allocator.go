@@ -0,0 +1,287 @@
+package resources
+
+import (
+	"sort"
+	"strconv"
+
+	"k8s.io/klog/v2"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+)
+
+// groupKeyFunc returns the key of the group a device belongs to
+type groupKeyFunc func(dev types.HostDevice) string
+
+// deviceGroup is a set of available devices sharing the same group key
+type deviceGroup struct {
+	key       string
+	deviceIDs []string
+}
+
+// groupAllocator chooses preferred devices by grouping the available devices with groupBy.
+// Devices are either packed onto as few groups as possible or spread across as many groups as possible.
+// When packing, devices within the chosen groups can be further spread by spreadBy.
+type groupAllocator struct {
+	groupBy  groupKeyFunc
+	spreadBy groupKeyFunc
+	spread   bool
+}
+
+// NewPackedAllocator returns an Allocator preferring devices of as few PFs as possible
+func NewPackedAllocator() types.Allocator {
+	return &groupAllocator{groupBy: pfGroupKey}
+}
+
+// NewSpreadAllocator returns an Allocator preferring devices spread across as many PFs as possible
+func NewSpreadAllocator() types.Allocator {
+	return &groupAllocator{groupBy: pfGroupKey, spread: true}
+}
+
+// NewNumaAllocator returns an Allocator preferring devices of as few NUMA nodes as possible
+func NewNumaAllocator() types.Allocator {
+	return &groupAllocator{groupBy: numaGroupKey}
+}
+
+// NewBondAllocator returns an Allocator preferring devices whose PFs are members of the same bond,
+// spread across the bond members
+func NewBondAllocator() types.Allocator {
+	return &groupAllocator{groupBy: bondGroupKey, spreadBy: pfGroupKey}
+}
+
+// Allocate returns the preferred device IDs for a container allocation request.
+// Devices the kubelet must include are always part of the result.
+func (ga *groupAllocator) Allocate(rqt *pluginapi.ContainerPreferredAllocationRequest, rp types.ResourcePool) []string {
+	size := int(rqt.AllocationSize)
+	devicePool := rp.GetDevicePool()
+
+	selected := make(map[string]bool, size)
+	result := make([]string, 0, size)
+	preferredKeys := make([]string, 0)
+	usedSpreadKeys := make([]string, 0)
+	for _, id := range rqt.MustIncludeDeviceIDs {
+		if selected[id] {
+			continue
+		}
+		selected[id] = true
+		result = append(result, id)
+		preferredKeys = append(preferredKeys, groupKey(devicePool, id, ga.groupBy))
+		if ga.spreadBy != nil {
+			usedSpreadKeys = append(usedSpreadKeys, groupKey(devicePool, id, ga.spreadBy))
+		}
+	}
+
+	remaining := size - len(result)
+	if remaining <= 0 {
+		return result
+	}
+
+	available := make([]string, 0, len(rqt.AvailableDeviceIDs))
+	for _, id := range rqt.AvailableDeviceIDs {
+		if !selected[id] {
+			selected[id] = true
+			available = append(available, id)
+		}
+	}
+	groups := groupDevices(devicePool, available, ga.groupBy)
+
+	var picked []string
+	if ga.spread {
+		picked = spreadDevices(orderForSpread(groups, preferredKeys), remaining)
+	} else {
+		picked = ga.packDevices(devicePool, orderForPack(groups, preferredKeys, remaining), remaining, usedSpreadKeys)
+	}
+	result = append(result, picked...)
+	klog.V(5).InfoS("Preferred devices", "deviceIDs", result, "available", rqt.AvailableDeviceIDs)
+	return result
+}
+
+// groupKey returns the group key of a device, devices unknown to the pool form their own group
+func groupKey(devicePool map[string]types.HostDevice, id string, groupBy groupKeyFunc) string {
+	dev, ok := devicePool[id]
+	if !ok {
+		return id
+	}
+	if key := groupBy(dev); key != "" {
+		return key
+	}
+	return id
+}
+
+// groupDevices groups device IDs by their group key, keeping both groups and device IDs in a stable order
+func groupDevices(devicePool map[string]types.HostDevice, ids []string, groupBy groupKeyFunc) []*deviceGroup {
+	sortedIDs := append([]string(nil), ids...)
+	sort.Strings(sortedIDs)
+
+	groups := make([]*deviceGroup, 0)
+	index := make(map[string]*deviceGroup)
+	for _, id := range sortedIDs {
+		key := groupKey(devicePool, id, groupBy)
+		group, ok := index[key]
+		if !ok {
+			group = &deviceGroup{key: key}
+			index[key] = group
+			groups = append(groups, group)
+		}
+		group.deviceIDs = append(group.deviceIDs, id)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].key < groups[j].key
+	})
+	return groups
+}
+
+// orderForPack orders groups so that groups already used by the allocation come first, followed by
+// the smallest groups able to satisfy the remaining request and finally by the largest other groups
+func orderForPack(groups []*deviceGroup, preferredKeys []string, size int) []*deviceGroup {
+	preferred, rest := splitGroups(groups, preferredKeys)
+	for _, group := range preferred {
+		size -= len(group.deviceIDs)
+	}
+	sort.SliceStable(rest, func(i, j int) bool {
+		li, lj := len(rest[i].deviceIDs), len(rest[j].deviceIDs)
+		fitI, fitJ := li >= size, lj >= size
+		switch {
+		case fitI && fitJ:
+			return li < lj
+		case fitI != fitJ:
+			return fitI
+		default:
+			return li > lj
+		}
+	})
+	return append(preferred, rest...)
+}
+
+// orderForSpread orders groups so that groups not yet used by the allocation come first, largest groups first
+func orderForSpread(groups []*deviceGroup, usedKeys []string) []*deviceGroup {
+	used, rest := splitGroups(groups, usedKeys)
+	bySize := func(g []*deviceGroup) {
+		sort.SliceStable(g, func(i, j int) bool {
+			return len(g[i].deviceIDs) > len(g[j].deviceIDs)
+		})
+	}
+	bySize(rest)
+	bySize(used)
+	return append(rest, used...)
+}
+
+// splitGroups splits groups into the ones matching keys, in the order of keys, and all other groups
+func splitGroups(groups []*deviceGroup, keys []string) (matching, rest []*deviceGroup) {
+	index := make(map[string]*deviceGroup, len(groups))
+	for _, group := range groups {
+		index[group.key] = group
+	}
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if group, ok := index[key]; ok && !seen[key] {
+			matching = append(matching, group)
+		}
+		seen[key] = true
+	}
+	for _, group := range groups {
+		if !seen[group.key] {
+			rest = append(rest, group)
+		}
+	}
+	return matching, rest
+}
+
+// packDevices takes up to size devices from groups in order, optionally spreading within each group by spreadBy
+// while preferring the spreadBy groups not in usedSpreadKeys
+func (ga *groupAllocator) packDevices(devicePool map[string]types.HostDevice, groups []*deviceGroup,
+	size int, usedSpreadKeys []string) []string {
+	picked := make([]string, 0, size)
+	for _, group := range groups {
+		if len(picked) >= size {
+			break
+		}
+		ids := group.deviceIDs
+		if ga.spreadBy != nil {
+			ids = spreadDevices(orderForSpread(groupDevices(devicePool, ids, ga.spreadBy), usedSpreadKeys), len(ids))
+		}
+		for _, id := range ids {
+			if len(picked) >= size {
+				break
+			}
+			picked = append(picked, id)
+		}
+	}
+	return picked
+}
+
+// spreadDevices takes up to size devices from groups in a round-robin manner
+func spreadDevices(groups []*deviceGroup, size int) []string {
+	picked := make([]string, 0, size)
+	for i := 0; len(picked) < size; i++ {
+		found := false
+		for _, group := range groups {
+			if i < len(group.deviceIDs) && len(picked) < size {
+				picked = append(picked, group.deviceIDs[i])
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return picked
+}
+
+// pfGroupKey groups devices by their parent PF
+func pfGroupKey(dev types.HostDevice) string {
+	if netDev, ok := dev.(types.NetDevice); ok {
+		return netDev.GetPfPciAddr()
+	}
+	return ""
+}
+
+// numaGroupKey groups devices by their NUMA node
+func numaGroupKey(dev types.HostDevice) string {
+	if apiDev := dev.GetAPIDevice(); apiDev != nil && apiDev.Topology != nil && len(apiDev.Topology.Nodes) > 0 {
+		return strconv.FormatInt(apiDev.Topology.Nodes[0].ID, 10)
+	}
+	pciAddr := ""
+	if pciDev, ok := dev.(types.PciDevice); ok {
+		pciAddr = pciDev.GetPciAddr()
+	} else if netDev, ok := dev.(types.NetDevice); ok {
+		pciAddr = netDev.GetPfPciAddr()
+	}
+	if pciAddr == "" {
+		return ""
+	}
+	if node := utils.GetDevNode(pciAddr); node >= 0 {
+		return strconv.Itoa(node)
+	}
+	return ""
+}
+
+// bondGroupKey groups devices by the bond their parent PF is a member of,
+// devices of PFs not part of a bond are grouped by their PF
+func bondGroupKey(dev types.HostDevice) string {
+	netDev, ok := dev.(types.NetDevice)
+	if !ok {
+		return ""
+	}
+	if pfName := netDev.GetPfNetName(); pfName != "" {
+		attrs, err := utils.GetNetlinkProvider().GetLinkAttrs(pfName)
+		if err != nil {
+			klog.InfoS("Unable to get link attributes of PF, grouping by PF", "pfName", pfName, "err", err)
+		} else if attrs.MasterIndex > 0 && isBond(attrs.MasterIndex) {
+			return "bond-" + strconv.Itoa(attrs.MasterIndex)
+		}
+	}
+	return netDev.GetPfPciAddr()
+}
+
+// isBond returns true if the net device with the given index is a bond, PFs can also be enslaved to
+// other masters such as bridges or VRFs
+func isBond(index int) bool {
+	linkType, err := utils.GetNetlinkProvider().GetLinkTypeByIndex(index)
+	if err != nil {
+		klog.InfoS("Unable to get the type of the PF master, grouping by PF", "masterIndex", index, "err", err)
+		return false
+	}
+	return linkType == "bond"
+}
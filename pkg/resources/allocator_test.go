@@ -0,0 +1,139 @@
+package resources
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	nl "github.com/vishvananda/netlink"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+	utilsmocks "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils/mocks"
+)
+
+func newAllocatorTestDevice(id, pfAddr, pfName string, numaNode int64) *mocks.PciNetDevice {
+	dev := &mocks.PciNetDevice{}
+	dev.On("GetDeviceID").Return(id).
+		On("GetPciAddr").Return(id).
+		On("GetPfPciAddr").Return(pfAddr).
+		On("GetPfNetName").Return(pfName).
+		On("GetAPIDevice").Return(&pluginapi.Device{
+		ID:       id,
+		Topology: &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: numaNode}}},
+	})
+	return dev
+}
+
+var _ = Describe("Allocator", func() {
+	var (
+		rp        *mocks.ResourcePool
+		available []string
+	)
+
+	BeforeEach(func() {
+		// PF 0000:01:00.0 (ens1f0) has 4 VFs on NUMA node 0
+		// PF 0000:01:00.1 (ens1f1) has 2 VFs on NUMA node 0
+		// PF 0000:81:00.0 (ens2f0) has 3 VFs on NUMA node 1
+		devicePool := map[string]types.HostDevice{
+			"0000:01:02.0": newAllocatorTestDevice("0000:01:02.0", "0000:01:00.0", "ens1f0", 0),
+			"0000:01:02.1": newAllocatorTestDevice("0000:01:02.1", "0000:01:00.0", "ens1f0", 0),
+			"0000:01:02.2": newAllocatorTestDevice("0000:01:02.2", "0000:01:00.0", "ens1f0", 0),
+			"0000:01:02.3": newAllocatorTestDevice("0000:01:02.3", "0000:01:00.0", "ens1f0", 0),
+			"0000:01:0a.0": newAllocatorTestDevice("0000:01:0a.0", "0000:01:00.1", "ens1f1", 0),
+			"0000:01:0a.1": newAllocatorTestDevice("0000:01:0a.1", "0000:01:00.1", "ens1f1", 0),
+			"0000:81:02.0": newAllocatorTestDevice("0000:81:02.0", "0000:81:00.0", "ens2f0", 1),
+			"0000:81:02.1": newAllocatorTestDevice("0000:81:02.1", "0000:81:00.0", "ens2f0", 1),
+			"0000:81:02.2": newAllocatorTestDevice("0000:81:02.2", "0000:81:00.0", "ens2f0", 1),
+		}
+		available = make([]string, 0, len(devicePool))
+		for id := range devicePool {
+			available = append(available, id)
+		}
+		rp = &mocks.ResourcePool{}
+		rp.On("GetDevicePool").Return(devicePool)
+	})
+
+	request := func(size int32, mustInclude ...string) *pluginapi.ContainerPreferredAllocationRequest {
+		return &pluginapi.ContainerPreferredAllocationRequest{
+			AvailableDeviceIDs:   available,
+			MustIncludeDeviceIDs: mustInclude,
+			AllocationSize:       size,
+		}
+	}
+
+	Describe("packed allocator", func() {
+		It("should pick the smallest PF able to satisfy the request", func() {
+			Expect(NewPackedAllocator().Allocate(request(2), rp)).
+				To(ConsistOf("0000:01:0a.0", "0000:01:0a.1"))
+		})
+		It("should fill the largest PFs first when no PF can satisfy the request", func() {
+			Expect(NewPackedAllocator().Allocate(request(5), rp)).
+				To(ConsistOf("0000:01:02.0", "0000:01:02.1", "0000:01:02.2", "0000:01:02.3", "0000:81:02.0"))
+		})
+		It("should prefer the PF of the devices that must be included", func() {
+			Expect(NewPackedAllocator().Allocate(request(3, "0000:81:02.2"), rp)).
+				To(ConsistOf("0000:81:02.0", "0000:81:02.1", "0000:81:02.2"))
+		})
+		It("should only return the devices that must be included when they satisfy the request", func() {
+			Expect(NewPackedAllocator().Allocate(request(1, "0000:81:02.2"), rp)).
+				To(ConsistOf("0000:81:02.2"))
+		})
+	})
+
+	Describe("spread allocator", func() {
+		It("should pick devices of different PFs", func() {
+			Expect(NewSpreadAllocator().Allocate(request(3), rp)).
+				To(ConsistOf("0000:01:02.0", "0000:81:02.0", "0000:01:0a.0"))
+		})
+		It("should prefer PFs not used by the devices that must be included", func() {
+			Expect(NewSpreadAllocator().Allocate(request(2, "0000:01:02.3"), rp)).
+				To(ConsistOf("0000:01:02.3", "0000:81:02.0"))
+		})
+		It("should not return more devices than available", func() {
+			Expect(NewSpreadAllocator().Allocate(request(12), rp)).To(HaveLen(len(available)))
+		})
+	})
+
+	Describe("numa allocator", func() {
+		It("should pick devices of a single NUMA node", func() {
+			Expect(NewNumaAllocator().Allocate(request(3), rp)).
+				To(ConsistOf("0000:81:02.0", "0000:81:02.1", "0000:81:02.2"))
+		})
+		It("should prefer the NUMA node of the devices that must be included", func() {
+			Expect(NewNumaAllocator().Allocate(request(2, "0000:01:0a.1"), rp)).
+				To(ConsistOf("0000:01:0a.1", "0000:01:02.0"))
+		})
+	})
+
+	Describe("bond allocator", func() {
+		var origProvider utils.NetlinkProvider
+		BeforeEach(func() {
+			origProvider = utils.GetNetlinkProvider()
+			mockProvider := &utilsmocks.NetlinkProvider{}
+			mockProvider.On("GetLinkAttrs", "ens1f0").Return(&nl.LinkAttrs{MasterIndex: 10}, nil).
+				On("GetLinkAttrs", "ens1f1").Return(&nl.LinkAttrs{MasterIndex: 10}, nil).
+				On("GetLinkAttrs", "ens2f0").Return(&nl.LinkAttrs{MasterIndex: 20}, nil).
+				On("GetLinkAttrs", mock.AnythingOfType("string")).Return(&nl.LinkAttrs{}, nil).
+				On("GetLinkTypeByIndex", 10).Return("bond", nil).
+				On("GetLinkTypeByIndex", 20).Return("bridge", nil)
+			utils.SetNetlinkProviderInst(mockProvider)
+		})
+		AfterEach(func() {
+			utils.SetNetlinkProviderInst(origProvider)
+		})
+		It("should spread devices across the members of a single bond", func() {
+			Expect(NewBondAllocator().Allocate(request(4), rp)).
+				To(ConsistOf("0000:01:02.0", "0000:01:02.1", "0000:01:0a.0", "0000:01:0a.1"))
+		})
+		It("should prefer the bond of the devices that must be included", func() {
+			Expect(NewBondAllocator().Allocate(request(2, "0000:01:02.3"), rp)).
+				To(ConsistOf("0000:01:02.3", "0000:01:0a.0"))
+		})
+		It("should group devices of PFs enslaved to another master than a bond by PF", func() {
+			dev := newAllocatorTestDevice("0000:81:02.0", "0000:81:00.0", "ens2f0", 1)
+			Expect(bondGroupKey(dev)).To(Equal("0000:81:00.0"))
+		})
+	})
+})
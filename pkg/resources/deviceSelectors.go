@@ -2,8 +2,14 @@ package resources
 
 import (
 	"fmt"
+	"path"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 )
@@ -11,8 +17,23 @@ import (
 const (
 	rngSplitTotal   = 2
 	fieldSplitTotal = 2
+	// droppedDeviceMsg is the message logged for every device dropped by a selector
+	droppedDeviceMsg = "Device dropped by selector"
+	// regexPrefix starts the selector values matched as regular expressions
+	regexPrefix = "^"
+	// globChars are the characters turning a selector value into a glob pattern
+	globChars = "*?["
 )
 
+// patternCache holds the compiled regular expressions of selector values, see ResetPatternCache
+var patternCache sync.Map
+
+// ResetPatternCache drops the compiled regular expressions of selector values, so that the cache does not keep
+// the patterns of previous configs
+func ResetPatternCache() {
+	patternCache.Clear()
+}
+
 // NewVendorSelector returns a DeviceSelector interface for vendor list
 func NewVendorSelector(vendors []string) types.DeviceSelector {
 	return &vendorSelector{vendors: vendors}
@@ -66,7 +87,7 @@ func (s *driverSelector) Filter(inDevices []types.HostDevice) []types.HostDevice
 	filteredList := make([]types.HostDevice, 0)
 	for _, dev := range inDevices {
 		devDriver := dev.GetDriver()
-		if contains(s.drivers, devDriver) {
+		if matchesAny(s.drivers, devDriver) {
 			filteredList = append(filteredList, dev)
 		}
 	}
@@ -86,7 +107,7 @@ func (s *pciAddressSelector) Filter(inDevices []types.HostDevice) []types.HostDe
 	filteredList := make([]types.HostDevice, 0)
 	for _, dev := range inDevices {
 		pciAddr := dev.(types.PciDevice).GetPciAddr()
-		if contains(s.pciAddresses, pciAddr) {
+		if matchesAny(s.pciAddresses, pciAddr) {
 			filteredList = append(filteredList, dev)
 		}
 	}
@@ -190,6 +211,122 @@ func (s *linkTypeSelector) Filter(inDevices []types.HostDevice) []types.HostDevi
 	return filteredList
 }
 
+// NewMinLinkSpeedSelector returns a DeviceSelector interface keeping net devices with a link speed, in Mb/s,
+// of at least the first value
+func NewMinLinkSpeedSelector(speeds []string) types.DeviceSelector {
+	return &linkSpeedSelector{speeds: speeds, atLeast: true}
+}
+
+// NewMaxLinkSpeedSelector returns a DeviceSelector interface keeping net devices with a link speed, in Mb/s,
+// of at most the first value
+func NewMaxLinkSpeedSelector(speeds []string) types.DeviceSelector {
+	return &linkSpeedSelector{speeds: speeds, atLeast: false}
+}
+
+type linkSpeedSelector struct {
+	speeds  []string
+	atLeast bool
+}
+
+func (s *linkSpeedSelector) Filter(inDevices []types.HostDevice) []types.HostDevice {
+	filteredList := make([]types.HostDevice, 0)
+	limit, err := strconv.Atoi(s.speeds[0])
+	if err != nil {
+		return filteredList
+	}
+	for _, dev := range inDevices {
+		speed, err := strconv.Atoi(dev.(types.NetDevice).GetLinkSpeed())
+		if err != nil {
+			// Exclude devices with unknown link speed
+			continue
+		}
+		if (s.atLeast && speed >= limit) || (!s.atLeast && speed <= limit) {
+			filteredList = append(filteredList, dev)
+		}
+	}
+	return filteredList
+}
+
+// NewMtuSelector returns a DeviceSelector interface for net device MTU list
+func NewMtuSelector(mtus []string) types.DeviceSelector {
+	return &mtuSelector{mtus: mtus}
+}
+
+type mtuSelector struct {
+	mtus []string
+}
+
+func (s *mtuSelector) Filter(inDevices []types.HostDevice) []types.HostDevice {
+	filteredList := make([]types.HostDevice, 0)
+	for _, dev := range inDevices {
+		mtu := strconv.Itoa(dev.(types.NetDevice).GetMtu())
+		if contains(s.mtus, mtu) {
+			filteredList = append(filteredList, dev)
+		}
+	}
+	return filteredList
+}
+
+// NewPfOperStateSelector returns a DeviceSelector interface for PF operational state list
+func NewPfOperStateSelector(operStates []string) types.DeviceSelector {
+	return &pfOperStateSelector{operStates: operStates}
+}
+
+type pfOperStateSelector struct {
+	operStates []string
+}
+
+func (s *pfOperStateSelector) Filter(inDevices []types.HostDevice) []types.HostDevice {
+	filteredList := make([]types.HostDevice, 0)
+	for _, dev := range inDevices {
+		operState := dev.(types.NetDevice).GetPfOperState()
+		if operState != "" && getItem(s.operStates, operState) != "" {
+			filteredList = append(filteredList, dev)
+		}
+	}
+	return filteredList
+}
+
+// NewNumaNodeSelector returns a DeviceSelector interface for NUMA node list
+func NewNumaNodeSelector(numaNodes []string) types.DeviceSelector {
+	return &numaNodeSelector{numaNodes: numaNodes}
+}
+
+type numaNodeSelector struct {
+	numaNodes []string
+}
+
+func (s *numaNodeSelector) Filter(inDevices []types.HostDevice) []types.HostDevice {
+	filteredList := make([]types.HostDevice, 0)
+	for _, dev := range inDevices {
+		numaNode := strconv.Itoa(dev.(types.NetDevice).GetNumaNode())
+		if contains(s.numaNodes, numaNode) {
+			filteredList = append(filteredList, dev)
+		}
+	}
+	return filteredList
+}
+
+// NewEswitchModeSelector returns a DeviceSelector interface for the eswitch mode list of the PF
+func NewEswitchModeSelector(eswitchModes []string) types.DeviceSelector {
+	return &eswitchModeSelector{eswitchModes: eswitchModes}
+}
+
+type eswitchModeSelector struct {
+	eswitchModes []string
+}
+
+func (s *eswitchModeSelector) Filter(inDevices []types.HostDevice) []types.HostDevice {
+	filteredList := make([]types.HostDevice, 0)
+	for _, dev := range inDevices {
+		eswitchMode := dev.(types.NetDevice).GetEswitchMode()
+		if eswitchMode != "" && getItem(s.eswitchModes, eswitchMode) != "" {
+			filteredList = append(filteredList, dev)
+		}
+	}
+	return filteredList
+}
+
 // NewAuxTypeSelector returns an interface for auxTypes list
 func NewAuxTypeSelector(auxTypes []string) types.DeviceSelector {
 	return &auxTypeSelector{auxTypes: auxTypes}
@@ -210,6 +347,95 @@ func (s *auxTypeSelector) Filter(inDevices []types.HostDevice) []types.HostDevic
 	return filteredList
 }
 
+// LogDroppedDevices logs, at verbosity 5, every device of inDevices that a selector did not keep in outDevices
+// along with the device value the selector values were matched against, and reports it to the drop recorder
+func LogDroppedDevices(log klog.Logger, selectorName string, values []string, inDevices, outDevices []types.HostDevice) {
+	recorder := dropRecorder.Load()
+	if !log.V(5).Enabled() && recorder == nil {
+		return
+	}
+	kept := make(map[string]bool, len(outDevices))
+	for _, dev := range outDevices {
+		kept[dev.GetDeviceID()] = true
+	}
+	for _, dev := range inDevices {
+		if kept[dev.GetDeviceID()] {
+			continue
+		}
+		dropped := DroppedDevice{DeviceID: dev.GetDeviceID(), Selector: selectorName, Values: values}
+		kv := []any{"deviceID", dropped.DeviceID, "selector", selectorName, "values", values}
+		if value, ok := selectedValue(selectorName, dev); ok {
+			dropped.DeviceValue = value
+			kv = append(kv, "deviceValue", value)
+		}
+		if recorder != nil {
+			(*recorder)(dropped)
+		}
+		log.V(5).Info(droppedDeviceMsg, kv...)
+	}
+}
+
+// selectedValue returns the value of a device matched against the values of a selector
+func selectedValue(selectorName string, dev types.HostDevice) (string, bool) {
+	switch selectorName {
+	case "vendors":
+		return dev.GetVendor(), true
+	case "devices":
+		return dev.GetDeviceCode(), true
+	case "drivers":
+		return dev.GetDriver(), true
+	case "pciAddresses", "acpiIndexes":
+		if pciDev, ok := dev.(types.PciDevice); ok {
+			if selectorName == "pciAddresses" {
+				return pciDev.GetPciAddr(), true
+			}
+			return pciDev.GetAcpiIndex(), true
+		}
+	case "pfNames", "rootDevices", "linkTypes", "isRdma", "minLinkSpeed", "maxLinkSpeed", "mtus", "pfOperStates", "numaNodes",
+		"eswitchModes":
+		if netDev, ok := dev.(types.NetDevice); ok {
+			return netSelectedValue(selectorName, netDev), true
+		}
+	case "auxTypes":
+		if auxDev, ok := dev.(types.AuxNetDevice); ok {
+			return auxDev.GetAuxType(), true
+		}
+	case "vdpaType":
+		if vdpaCapable, ok := dev.(interface{ GetVdpaDevice() types.VdpaDevice }); ok {
+			if vdpaDev := vdpaCapable.GetVdpaDevice(); vdpaDev != nil {
+				return string(vdpaDev.GetType()), true
+			}
+			return "", true
+		}
+	}
+
+	return "", false
+}
+
+// netSelectedValue returns the value of a net device matched against the values of a net device selector
+func netSelectedValue(selectorName string, dev types.NetDevice) string {
+	switch selectorName {
+	case "pfNames":
+		return fmt.Sprintf("%s#%d", dev.GetPfNetName(), dev.GetFuncID())
+	case "rootDevices":
+		return fmt.Sprintf("%s#%d", dev.GetPfPciAddr(), dev.GetFuncID())
+	case "linkTypes":
+		return dev.GetLinkType()
+	case "minLinkSpeed", "maxLinkSpeed":
+		return dev.GetLinkSpeed()
+	case "mtus":
+		return strconv.Itoa(dev.GetMtu())
+	case "pfOperStates":
+		return dev.GetPfOperState()
+	case "numaNodes":
+		return strconv.Itoa(dev.GetNumaNode())
+	case "eswitchModes":
+		return dev.GetEswitchMode()
+	default:
+		return strconv.FormatBool(dev.IsRdma())
+	}
+}
+
 func contains(hay []string, needle string) bool {
 	for _, s := range hay {
 		if s == needle {
@@ -219,15 +445,77 @@ func contains(hay []string, needle string) bool {
 	return false
 }
 
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, value, false) {
+			return true
+		}
+	}
+	return false
+}
+
 func getItem(hay []string, needle string) string {
 	for _, item := range hay {
-		if strings.EqualFold(strings.Split(item, "#")[0], needle) {
+		if matchPattern(strings.Split(item, "#")[0], needle, true) {
 			return item
 		}
 	}
 	return ""
 }
 
+// matchPattern reports whether value matches pattern. Patterns starting with "^" are regular expressions,
+// patterns containing any of "*?[" are glob patterns matching the whole value and other patterns must equal
+// the value. foldCase makes globs and plain values match case-insensitively.
+func matchPattern(pattern, value string, foldCase bool) bool {
+	switch {
+	case strings.HasPrefix(pattern, regexPrefix):
+		re, err := compilePattern(pattern)
+		return err == nil && re.MatchString(value)
+	case strings.ContainsAny(pattern, globChars):
+		if foldCase {
+			pattern, value = strings.ToLower(pattern), strings.ToLower(value)
+		}
+		matched, err := path.Match(pattern, value)
+		return err == nil && matched
+	case foldCase:
+		return strings.EqualFold(pattern, value)
+	default:
+		return pattern == value
+	}
+}
+
+// compilePattern returns the compiled regular expression of a selector value
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+// ValidatePatterns returns an error for the first selector value that is not a valid regular expression or glob
+// pattern. VF index ranges following "#" are not part of the pattern.
+func ValidatePatterns(selectorValues ...[]string) error {
+	for _, item := range slices.Concat(selectorValues...) {
+		pattern := strings.Split(item, "#")[0]
+		switch {
+		case strings.HasPrefix(pattern, regexPrefix):
+			if _, err := compilePattern(pattern); err != nil {
+				return fmt.Errorf("invalid regular expression %q: %v", pattern, err)
+			}
+		case strings.ContainsAny(pattern, globChars):
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid glob pattern %q: %v", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
 func isSelected(devIdx int, selector string) bool {
 	if strings.Contains(selector, "#") {
 		// Selector does contain index in next format:
@@ -239,7 +527,7 @@ func isSelected(devIdx int, selector string) bool {
 		// in selector pool
 		fields := strings.Split(selector, "#")
 		if len(fields) != fieldSplitTotal {
-			fmt.Printf("Failed to parse %s PF (name|address) selector, probably incorrect separator character usage\n", selector)
+			klog.ErrorS(nil, "Failed to parse PF selector", "selector", selector, "reason", "probably incorrect separator character usage")
 			return false
 		}
 		entries := strings.Split(fields[1], ",")
@@ -247,17 +535,17 @@ func isSelected(devIdx int, selector string) bool {
 			if strings.Contains(entries[i], "-") {
 				rng := strings.Split(entries[i], "-")
 				if len(rng) != rngSplitTotal {
-					fmt.Printf("Failed to parse %s PF (name|address) selector, probably incorrect range character usage\n", selector)
+					klog.ErrorS(nil, "Failed to parse PF selector", "selector", selector, "reason", "probably incorrect range character usage")
 					return false
 				}
 				rngSt, err := strconv.Atoi(rng[0])
 				if err != nil {
-					fmt.Printf("Failed to parse %s PF (name|address) selector, start range is incorrect\n", selector)
+					klog.ErrorS(nil, "Failed to parse PF selector", "selector", selector, "reason", "start range is incorrect")
 					return false
 				}
 				rngEnd, err := strconv.Atoi(rng[1])
 				if err != nil {
-					fmt.Printf("Failed to parse %s PF (name|address) selector, end range is incorrect\n", selector)
+					klog.ErrorS(nil, "Failed to parse PF selector", "selector", selector, "reason", "end range is incorrect")
 					return false
 				}
 				if devIdx >= rngSt && devIdx <= rngEnd {
@@ -266,7 +554,7 @@ func isSelected(devIdx int, selector string) bool {
 			} else {
 				funcid, err := strconv.Atoi(entries[i])
 				if err != nil {
-					fmt.Printf("Failed to parse %s PF (name|address) selector, index is incorrect\n", selector)
+					klog.ErrorS(nil, "Failed to parse PF selector", "selector", selector, "reason", "index is incorrect")
 					return false
 				}
 				if devIdx == funcid {
@@ -1,6 +1,7 @@
 package resources_test
 
 import (
+	"github.com/go-logr/logr/funcr"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -86,6 +87,20 @@ var _ = Describe("DeviceSelectors", func() {
 				Expect(filtered).To(ContainElement(&dev0))
 				Expect(filtered).NotTo(ContainElement(&dev1))
 			})
+			It("should return devices matching driver name patterns", func() {
+				sel := resources.NewDriverSelector([]string{"^mlx5_", "i40*"})
+
+				dev0 := mocks.PciNetDevice{}
+				dev0.On("GetDriver").Return("mlx5_core")
+				dev1 := mocks.PciNetDevice{}
+				dev1.On("GetDriver").Return("i40evf")
+				dev2 := mocks.PciNetDevice{}
+				dev2.On("GetDriver").Return("vfio-pci")
+
+				filtered := sel.Filter([]types.HostDevice{&dev0, &dev1, &dev2})
+
+				Expect(filtered).To(Equal([]types.HostDevice{&dev0, &dev1}))
+			})
 		})
 	})
 	Describe("pciAddress selector", func() {
@@ -118,6 +133,20 @@ var _ = Describe("DeviceSelectors", func() {
 				Expect(filtered).NotTo(ContainElement(&dev2))
 				Expect(filtered).NotTo(ContainElement(&dev3))
 			})
+			It("should return devices matching pci address patterns", func() {
+				sel := resources.NewPciAddressSelector([]string{"0000:3b:*", "^0000:5e:02\\.[0-3]$"})
+
+				dev0 := mocks.PciNetDevice{}
+				dev0.On("GetPciAddr").Return("0000:3b:02.0")
+				dev1 := mocks.PciNetDevice{}
+				dev1.On("GetPciAddr").Return("0000:5e:02.3")
+				dev2 := mocks.PciNetDevice{}
+				dev2.On("GetPciAddr").Return("0000:5e:02.4")
+
+				filtered := sel.Filter([]types.HostDevice{&dev0, &dev1, &dev2})
+
+				Expect(filtered).To(Equal([]types.HostDevice{&dev0, &dev1}))
+			})
 		})
 	})
 	Describe("pfName selector", func() {
@@ -185,6 +214,26 @@ var _ = Describe("DeviceSelectors", func() {
 				Expect(filtered).NotTo(ContainElement(&dev9))
 				Expect(filtered).To(ContainElement(&dev10))
 			})
+			It("should return devices matching PF name patterns and their VF index ranges", func() {
+				sel := resources.NewPfNameSelector([]string{"ENS*F0#0-1", "^enp[0-9]+s0f1$"})
+
+				dev0 := mocks.PciNetDevice{}
+				dev0.On("GetPfNetName").Return("ens785f0")
+				dev0.On("GetFuncID").Return(1)
+				dev1 := mocks.PciNetDevice{}
+				dev1.On("GetPfNetName").Return("ens1f0")
+				dev1.On("GetFuncID").Return(2)
+				dev2 := mocks.PciNetDevice{}
+				dev2.On("GetPfNetName").Return("enp59s0f1")
+				dev2.On("GetFuncID").Return(4)
+				dev3 := mocks.PciNetDevice{}
+				dev3.On("GetPfNetName").Return("enp59s0f1np1")
+				dev3.On("GetFuncID").Return(0)
+
+				filtered := sel.Filter([]types.HostDevice{&dev0, &dev1, &dev2, &dev3})
+
+				Expect(filtered).To(Equal([]types.HostDevice{&dev0, &dev2}))
+			})
 		})
 	})
 
@@ -246,6 +295,31 @@ var _ = Describe("DeviceSelectors", func() {
 				Expect(filtered).NotTo(ContainElement(&dev9))
 				Expect(filtered).To(ContainElement(&dev10))
 			})
+			It("should return devices matching PF address patterns and their VF index ranges", func() {
+				sel := resources.NewRootDeviceSelector([]string{"0000:3b:00.?#2-3"})
+
+				dev0 := mocks.PciNetDevice{}
+				dev0.On("GetPfPciAddr").Return("0000:3B:00.1")
+				dev0.On("GetFuncID").Return(3)
+				dev1 := mocks.PciNetDevice{}
+				dev1.On("GetPfPciAddr").Return("0000:3b:00.0")
+				dev1.On("GetFuncID").Return(0)
+
+				filtered := sel.Filter([]types.HostDevice{&dev0, &dev1})
+
+				Expect(filtered).To(Equal([]types.HostDevice{&dev0}))
+			})
+		})
+	})
+	Describe("validating patterns", func() {
+		It("should accept plain values, globs and regular expressions with VF index ranges", func() {
+			Expect(resources.ValidatePatterns([]string{"ens1f0", "ens*f0#0-3"}, []string{"^mlx5_.*$#1"})).To(Succeed())
+		})
+		It("should reject invalid regular expressions", func() {
+			Expect(resources.ValidatePatterns([]string{"^mlx5_("})).To(MatchError(ContainSubstring("invalid regular expression")))
+		})
+		It("should reject invalid glob patterns", func() {
+			Expect(resources.ValidatePatterns(nil, []string{"0000:3b:[0-"})).To(MatchError(ContainSubstring("invalid glob pattern")))
 		})
 	})
 
@@ -275,6 +349,90 @@ var _ = Describe("DeviceSelectors", func() {
 			})
 		})
 	})
+	Describe("link speed selectors", func() {
+		Context("filtering", func() {
+			dev0 := mocks.PciNetDevice{}
+			dev0.On("GetLinkSpeed").Return("10000")
+			dev1 := mocks.PciNetDevice{}
+			dev1.On("GetLinkSpeed").Return("25000")
+			dev2 := mocks.PciNetDevice{}
+			dev2.On("GetLinkSpeed").Return("")
+			in := []types.HostDevice{&dev0, &dev1, &dev2}
+
+			It("should return devices with at least the min link speed", func() {
+				filtered := resources.NewMinLinkSpeedSelector([]string{"25000"}).Filter(in)
+
+				Expect(filtered).To(ConsistOf(&dev1))
+			})
+			It("should return devices with at most the max link speed", func() {
+				filtered := resources.NewMaxLinkSpeedSelector([]string{"10000"}).Filter(in)
+
+				Expect(filtered).To(ConsistOf(&dev0))
+			})
+		})
+	})
+	Describe("mtus selector", func() {
+		Context("filtering", func() {
+			It("should return devices matching the MTU list", func() {
+				dev0 := mocks.PciNetDevice{}
+				dev0.On("GetMtu").Return(1500)
+				dev1 := mocks.PciNetDevice{}
+				dev1.On("GetMtu").Return(9000)
+
+				filtered := resources.NewMtuSelector([]string{"9000"}).Filter([]types.HostDevice{&dev0, &dev1})
+
+				Expect(filtered).To(ConsistOf(&dev1))
+			})
+		})
+	})
+	Describe("pfOperStates selector", func() {
+		Context("filtering", func() {
+			It("should return devices whose PF operational state is in the list", func() {
+				dev0 := mocks.PciNetDevice{}
+				dev0.On("GetPfOperState").Return("up")
+				dev1 := mocks.PciNetDevice{}
+				dev1.On("GetPfOperState").Return("down")
+				dev2 := mocks.PciNetDevice{}
+				dev2.On("GetPfOperState").Return("")
+
+				filtered := resources.NewPfOperStateSelector([]string{"UP"}).Filter([]types.HostDevice{&dev0, &dev1, &dev2})
+
+				Expect(filtered).To(ConsistOf(&dev0))
+			})
+		})
+	})
+	Describe("numaNodes selector", func() {
+		Context("filtering", func() {
+			It("should return devices attached to the listed NUMA nodes", func() {
+				dev0 := mocks.PciNetDevice{}
+				dev0.On("GetNumaNode").Return(0)
+				dev1 := mocks.PciNetDevice{}
+				dev1.On("GetNumaNode").Return(1)
+				dev2 := mocks.PciNetDevice{}
+				dev2.On("GetNumaNode").Return(-1)
+
+				filtered := resources.NewNumaNodeSelector([]string{"1"}).Filter([]types.HostDevice{&dev0, &dev1, &dev2})
+
+				Expect(filtered).To(ConsistOf(&dev1))
+			})
+		})
+	})
+	Describe("eswitchModes selector", func() {
+		Context("filtering", func() {
+			It("should return devices whose PF eswitch mode is in the list", func() {
+				dev0 := mocks.PciNetDevice{}
+				dev0.On("GetEswitchMode").Return("switchdev")
+				dev1 := mocks.AuxNetDevice{}
+				dev1.On("GetEswitchMode").Return("legacy")
+				dev2 := mocks.PciNetDevice{}
+				dev2.On("GetEswitchMode").Return("")
+
+				filtered := resources.NewEswitchModeSelector([]string{"switchdev"}).Filter([]types.HostDevice{&dev0, &dev1, &dev2})
+
+				Expect(filtered).To(ConsistOf(&dev0))
+			})
+		})
+	})
 	Describe("auxTypes selector", func() {
 		Context("filtering", func() {
 			It("should return devices matching the correct aux type", func() {
@@ -317,4 +475,35 @@ var _ = Describe("DeviceSelectors", func() {
 			})
 		})
 	})
+	Describe("logging dropped devices", func() {
+		It("should log every device dropped by a selector with its value at verbosity 5", func() {
+			dev0 := mocks.PciNetDevice{}
+			dev0.On("GetDeviceID").Return("0000:01:00.1").On("GetDriver").Return("vfio-pci")
+			dev1 := mocks.PciNetDevice{}
+			dev1.On("GetDeviceID").Return("0000:01:00.2").On("GetDriver").Return("iavf")
+
+			lines := make([]string, 0)
+			log := funcr.New(func(prefix, args string) {
+				lines = append(lines, args)
+			}, funcr.Options{Verbosity: 5})
+			in := []types.HostDevice{&dev0, &dev1}
+			resources.LogDroppedDevices(log, "drivers", []string{"vfio-pci"}, in, in[:1])
+
+			Expect(lines).To(ConsistOf(And(
+				ContainSubstring(`"msg"="Device dropped by selector"`),
+				ContainSubstring(`"deviceID"="0000:01:00.2"`),
+				ContainSubstring(`"selector"="drivers"`),
+				ContainSubstring(`"deviceValue"="iavf"`),
+			)))
+		})
+		It("should not log below verbosity 5", func() {
+			dev0 := mocks.PciNetDevice{}
+			lines := make([]string, 0)
+			log := funcr.New(func(prefix, args string) {
+				lines = append(lines, args)
+			}, funcr.Options{Verbosity: 4})
+			resources.LogDroppedDevices(log, "drivers", []string{"vfio-pci"}, []types.HostDevice{&dev0}, nil)
+			Expect(lines).To(BeEmpty())
+		})
+	})
 })
@@ -17,22 +17,29 @@ package resources
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"regexp"
 	"strings"
+	"sync"
 
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
 )
 
 const (
-	poolType = "net-pci"
+	poolType   = "net-pci"
+	vfioDriver = "vfio-pci"
 )
 
 // ResourcePoolImpl implements stub ResourcePool interface
 type ResourcePoolImpl struct {
 	config     *types.ResourceConfig
+	lock       sync.RWMutex
 	devicePool map[string]types.HostDevice
+	health     map[string]string // device health found by the last Probe, keyed by device ID
 }
 
 var _ types.ResourcePool = &ResourcePoolImpl{}
@@ -42,6 +49,7 @@ func NewResourcePool(rc *types.ResourceConfig, devicePool map[string]types.HostD
 	return &ResourcePoolImpl{
 		config:     rc,
 		devicePool: devicePool,
+		health:     make(map[string]string),
 	}
 }
 
@@ -66,29 +74,97 @@ func (rp *ResourcePoolImpl) GetResourcePrefix() string {
 	return rp.config.ResourcePrefix
 }
 
-// GetDevices returns a map of Kubelet API devices
+// GetDevices returns a map of Kubelet API devices. The devices are copies holding the health found by the last
+// Probe, so they can be read while the pool is probed
 func (rp *ResourcePoolImpl) GetDevices() map[string]*pluginapi.Device {
-	devices := make(map[string]*pluginapi.Device)
+	rp.lock.RLock()
+	defer rp.lock.RUnlock()
+	devices := make(map[string]*pluginapi.Device, len(rp.devicePool))
 	for id, dev := range rp.devicePool {
-		devices[id] = dev.GetAPIDevice()
+		apiDev := dev.GetAPIDevice()
+		device := &pluginapi.Device{ID: apiDev.ID, Health: apiDev.Health, Topology: apiDev.Topology}
+		if health, ok := rp.health[id]; ok {
+			device.Health = health
+		}
+		devices[id] = device
 	}
 	return devices
 }
 
-// Probe - does device healthcheck. Not implemented
+// Probe does a health check of every device in the pool and records the
+// health of its API device. Returns true if the health of any device changed
 func (rp *ResourcePoolImpl) Probe() bool {
-	// TO-DO: Implement this
-	return false
+	current := rp.GetDevices()
+	health := make(map[string]string, len(current))
+	for id, dev := range rp.GetDevicePool() {
+		health[id] = pluginapi.Healthy
+		if err := checkDeviceHealth(dev); err != nil {
+			health[id] = pluginapi.Unhealthy
+			klog.V(2).InfoS("Device is unhealthy", "resourceName", rp.GetResourceName(), "deviceID", id, "err", err)
+		}
+	}
+
+	changed := false
+	rp.lock.Lock()
+	defer rp.lock.Unlock()
+	for id, h := range health {
+		if apiDev, ok := current[id]; ok && apiDev.Health != h {
+			klog.InfoS("Device changed health", "resourceName", rp.GetResourceName(), "deviceID", id,
+				"from", apiDev.Health, "to", h)
+			changed = true
+		}
+		rp.health[id] = h
+	}
+	return changed
+}
+
+// checkDeviceHealth returns an error describing why a device is not usable anymore
+func checkDeviceHealth(dev types.HostDevice) error {
+	switch d := dev.(type) {
+	case types.PciDevice:
+		pciAddr := d.GetPciAddr()
+		if !utils.IsPciDevicePresent(pciAddr) {
+			return fmt.Errorf("PCI device %s is not present", pciAddr)
+		}
+		driver, err := utils.GetDriverName(pciAddr)
+		if err != nil {
+			return err
+		}
+		if driver != dev.GetDriver() {
+			return fmt.Errorf("driver changed from %s to %s", dev.GetDriver(), driver)
+		}
+		if driver == vfioDriver {
+			vfioDevHost, _, err := utils.GetVFIODeviceFile(pciAddr)
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(vfioDevHost); err != nil {
+				return fmt.Errorf("vfio device file %s is not present", vfioDevHost)
+			}
+		}
+	case types.AuxNetDevice:
+		if !utils.IsAuxDevicePresent(d.GetDeviceID()) {
+			return fmt.Errorf("auxiliary device %s is not present", d.GetDeviceID())
+		}
+	}
+
+	if netDev, ok := dev.(types.NetDevice); ok {
+		if pfAddr := netDev.GetPfPciAddr(); pfAddr != "" && !utils.IsNetlinkStatusUp(pfAddr) {
+			return fmt.Errorf("link of PF %s is down", pfAddr)
+		}
+	}
+	return nil
 }
 
 // GetDeviceSpecs returns list of plugin API device specs for a list of device IDs
 func (rp *ResourcePoolImpl) GetDeviceSpecs(deviceIDs []string) []*pluginapi.DeviceSpec {
-	glog.Infof("GetDeviceSpecs(): for devices: %v", deviceIDs)
+	klog.V(4).InfoS("GetDeviceSpecs", "resourceName", rp.GetResourceName(), "deviceIDs", deviceIDs)
 	devSpecs := make([]*pluginapi.DeviceSpec, 0)
+	devicePool := rp.GetDevicePool()
 
 	// Add vfio group specific devices
 	for _, id := range deviceIDs {
-		if dev, ok := rp.devicePool[id]; ok {
+		if dev, ok := devicePool[id]; ok {
 			newSpecs := dev.GetDeviceSpecs()
 			for _, ds := range newSpecs {
 				if !rp.DeviceSpecExist(devSpecs, ds) {
@@ -105,44 +181,68 @@ func (rp *ResourcePoolImpl) GetDeviceSpecs(deviceIDs []string) []*pluginapi.Devi
 // environment variable key base on PCIDEVICE_<prefix>_<resource-name>_INFO that contains info from all the
 // requested info providers for every pci address allocated
 func (rp *ResourcePoolImpl) GetEnvs(prefix string, deviceIDs []string) (map[string]string, error) {
-	glog.Infof("GetEnvs(): for devices: %v", deviceIDs)
+	klog.V(4).InfoS("GetEnvs", "resourceName", rp.GetResourceName(), "deviceIDs", deviceIDs)
 	devInfos := make(map[string]map[string]types.AdditionalInfo, 0)
 	IDList := []string{}
+	devicePool := rp.GetDevicePool()
 	// Consolidates all ExtraEnvVariables
 	for _, id := range deviceIDs {
-		if dev, ok := rp.devicePool[id]; ok {
+		if dev, ok := devicePool[id]; ok {
 			envs := dev.GetEnvVal()
 			devInfos[id] = envs
 			IDList = append(IDList, id)
 		}
 	}
 
-	envs := make(map[string]string)
-
-	// construct PCIDEVICE_<prefix>_<resource-name> environment variable
 	key := fmt.Sprintf("%s_%s_%s", "PCIDEVICE", prefix, rp.GetResourceName())
 	key = strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
-	envs[key] = strings.Join(IDList, ",")
+	return deviceEnvs(key, IDList, devInfos)
+}
 
-	// construct PCIDEVICE_<prefix>_<resource-name>_INFO environment variable
-	key = fmt.Sprintf("%s_%s_%s_INFO", "PCIDEVICE", prefix, rp.GetResourceName())
-	key = strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+// GetDeviceEnvs returns a map with two keys for a single device.
+// environment variable key base on PCIDEVICE_<prefix>_<resource-name>_<device-id> with the device ID
+// environment variable key base on PCIDEVICE_<prefix>_<resource-name>_<device-id>_INFO that contains info from all
+// the requested info providers for the device
+// Characters of the device ID that are not letters or digits are replaced with underscores.
+func (rp *ResourcePoolImpl) GetDeviceEnvs(prefix, deviceID string) (map[string]string, error) {
+	dev, ok := rp.GetDevicePool()[deviceID]
+	if !ok {
+		return nil, fmt.Errorf("device %s not found in pool %s", deviceID, rp.GetResourceName())
+	}
+	key := fmt.Sprintf("%s_%s_%s_%s", "PCIDEVICE", prefix, rp.GetResourceName(), deviceID)
+	key = strings.ToUpper(nonEnvKeyChars.ReplaceAllString(key, "_"))
+	return deviceEnvs(key, []string{deviceID}, map[string]map[string]types.AdditionalInfo{deviceID: dev.GetEnvVal()})
+}
+
+// nonEnvKeyChars matches the characters that are replaced in environment variable keys
+var nonEnvKeyChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// deviceEnvs returns the environment variable key with the list of device IDs and the key suffixed with _INFO
+// with the info of the devices
+func deviceEnvs(key string, deviceIDs []string, devInfos map[string]map[string]types.AdditionalInfo) (map[string]string, error) {
+	envs := make(map[string]string)
+
+	// construct the environment variable with the list of device IDs
+	envs[key] = strings.Join(deviceIDs, ",")
+
+	// construct the _INFO environment variable
 	envData, err := json.Marshal(devInfos)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal environment variable object: %v", err)
 	}
-	envs[key] = string(envData)
+	envs[key+"_INFO"] = string(envData)
 
 	return envs, nil
 }
 
 // GetMounts returns a list of Mount for device IDs
 func (rp *ResourcePoolImpl) GetMounts(deviceIDs []string) []*pluginapi.Mount {
-	glog.Infof("GetMounts(): for devices: %v", deviceIDs)
+	klog.V(4).InfoS("GetMounts", "resourceName", rp.GetResourceName(), "deviceIDs", deviceIDs)
 	devMounts := make([]*pluginapi.Mount, 0)
+	devicePool := rp.GetDevicePool()
 
 	for _, id := range deviceIDs {
-		if dev, ok := rp.devicePool[id]; ok {
+		if dev, ok := devicePool[id]; ok {
 			mnt := dev.GetMounts()
 			devMounts = append(devMounts, mnt...)
 		}
@@ -162,9 +262,23 @@ func (rp *ResourcePoolImpl) DeviceSpecExist(specs []*pluginapi.DeviceSpec, newSp
 
 // GetDevicePool returns HostDevice pool as a map
 func (rp *ResourcePoolImpl) GetDevicePool() map[string]types.HostDevice {
+	rp.lock.RLock()
+	defer rp.lock.RUnlock()
 	return rp.devicePool
 }
 
+// SetDevicePool replaces the HostDevice pool
+func (rp *ResourcePoolImpl) SetDevicePool(devicePool map[string]types.HostDevice) {
+	rp.lock.Lock()
+	defer rp.lock.Unlock()
+	rp.devicePool = devicePool
+	for id := range rp.health {
+		if _, ok := devicePool[id]; !ok {
+			delete(rp.health, id)
+		}
+	}
+}
+
 // StoreDeviceInfoFile does nothing. DeviceType-specific ResourcePools might
 // store information according to the k8snetworkplumbingwg/device-info-spec
 func (rp *ResourcePoolImpl) StoreDeviceInfoFile(resourceNamePrefix string, deviceIDs []string) error {
@@ -173,7 +287,7 @@ func (rp *ResourcePoolImpl) StoreDeviceInfoFile(resourceNamePrefix string, devic
 
 // CleanDeviceInfoFile does nothing. DeviceType-specific ResourcePools might
 // clean the Device Info file
-func (rp *ResourcePoolImpl) CleanDeviceInfoFile(resourceNamePrefix string) error {
+func (rp *ResourcePoolImpl) CleanDeviceInfoFile(resourceNamePrefix string, deviceIDs []string) error {
 	return nil
 }
 
@@ -1,6 +1,9 @@
 package resources_test
 
 import (
+	"os"
+	"path/filepath"
+
 	"github.com/jaypipes/ghw"
 	"github.com/jaypipes/pcidb"
 	. "github.com/onsi/ginkgo/v2"
@@ -11,6 +14,7 @@ import (
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/netdevice"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/resources"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
 )
 
@@ -49,7 +53,7 @@ var _ = Describe("ResourcePool", func() {
 				"sys/bus/pci/devices/0000:00:00.1/physfn":      "../0000:01:00.0",
 			},
 		}
-		f = factory.NewResourceFactory("fake", "fake", true, false)
+		f = factory.NewResourceFactory("fake", "fake", true, false, nil, nil)
 		rc = &types.ResourceConfig{SelectorObjs: []interface{}{types.NetDeviceSelectors{}}}
 		devs = []string{"0000:00:00.1", "0000:00:00.2"}
 	})
@@ -103,6 +107,35 @@ var _ = Describe("ResourcePool", func() {
 
 		})
 	})
+	Describe("getting environment variables", func() {
+		var pool types.ResourcePool
+		BeforeEach(func() {
+			d := &mocks.HostDevice{}
+			d.On("GetEnvVal").Return(map[string]types.AdditionalInfo{"generic": {"deviceID": "mlx5_core.sf.4"}})
+			pool = resources.NewResourcePool(&types.ResourceConfig{ResourceName: "sf_pool"},
+				map[string]types.HostDevice{"mlx5_core.sf.4": d})
+		})
+		It("should return the variables of all devices", func() {
+			envs, err := pool.GetEnvs("example.com", []string{"mlx5_core.sf.4"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs).To(Equal(map[string]string{
+				"PCIDEVICE_EXAMPLE_COM_SF_POOL":      "mlx5_core.sf.4",
+				"PCIDEVICE_EXAMPLE_COM_SF_POOL_INFO": `{"mlx5_core.sf.4":{"generic":{"deviceID":"mlx5_core.sf.4"}}}`,
+			}))
+		})
+		It("should return the variables of a single device named after the device", func() {
+			envs, err := pool.GetDeviceEnvs("example.com", "mlx5_core.sf.4")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs).To(Equal(map[string]string{
+				"PCIDEVICE_EXAMPLE_COM_SF_POOL_MLX5_CORE_SF_4":      "mlx5_core.sf.4",
+				"PCIDEVICE_EXAMPLE_COM_SF_POOL_MLX5_CORE_SF_4_INFO": `{"mlx5_core.sf.4":{"generic":{"deviceID":"mlx5_core.sf.4"}}}`,
+			}))
+		})
+		It("should fail for an unknown device", func() {
+			_, err := pool.GetDeviceEnvs("example.com", "mlx5_core.sf.5")
+			Expect(err).To(HaveOccurred())
+		})
+	})
 	Describe("GetDevices", func() {
 		It("Returns API devices for PCIDevices in the pool", func() {
 			defer fs.Use()()
@@ -124,4 +157,77 @@ var _ = Describe("ResourcePool", func() {
 			Expect(devices["0000:00:00.2"].ID).To(Equal("0000:00:00.2"))
 		})
 	})
+	Describe("Probe", func() {
+		var (
+			probeFs *utils.FakeFilesystem
+			dev     types.PciNetDevice
+		)
+		BeforeEach(func() {
+			probeFs = &utils.FakeFilesystem{
+				Dirs: []string{
+					"sys/bus/pci/devices/0000:00:00.1",
+					"sys/bus/pci/devices/0000:01:00.0/net/enp2s0f0",
+					"sys/bus/pci/drivers/iavf",
+					"sys/bus/pci/drivers/vfio-pci",
+				},
+				Files: map[string][]byte{
+					"sys/bus/pci/devices/0000:01:00.0/net/enp2s0f0/operstate": []byte("up"),
+				},
+				Symlinks: map[string]string{
+					"sys/bus/pci/devices/0000:00:00.1/driver": "../../../../bus/pci/drivers/iavf",
+					"sys/bus/pci/devices/0000:00:00.1/physfn": "../0000:01:00.0",
+				},
+			}
+		})
+		setup := func() func() {
+			tearDown := probeFs.Use()
+			utils.SetDefaultMockNetlinkProvider()
+			var err error
+			dev, err = netdevice.NewPciNetDevice(newPciDeviceFn("0000:00:00.1"), f, rc, 0)
+			Expect(err).NotTo(HaveOccurred())
+			rp = resources.NewResourcePool(rc, map[string]types.HostDevice{"0000:00:00.1": dev})
+			return tearDown
+		}
+		sysfs := func(path string) string {
+			return filepath.Join(probeFs.RootDir, "sys/bus/pci/devices", path)
+		}
+		It("should report no change for a healthy device", func() {
+			defer setup()()
+			Expect(rp.Probe()).To(BeFalse())
+			Expect(rp.GetDevices()["0000:00:00.1"].Health).To(Equal(pluginapi.Healthy))
+		})
+		It("should report the device unhealthy while the PF link is down", func() {
+			defer setup()()
+			Expect(os.WriteFile(sysfs("0000:01:00.0/net/enp2s0f0/operstate"), []byte("down"), 0600)).To(Succeed())
+			Expect(rp.Probe()).To(BeTrue())
+			Expect(rp.GetDevices()["0000:00:00.1"].Health).To(Equal(pluginapi.Unhealthy))
+			Expect(dev.GetAPIDevice().Health).To(Equal(pluginapi.Healthy))
+			Expect(rp.Probe()).To(BeFalse())
+
+			Expect(os.WriteFile(sysfs("0000:01:00.0/net/enp2s0f0/operstate"), []byte("up"), 0600)).To(Succeed())
+			Expect(rp.Probe()).To(BeTrue())
+			Expect(rp.GetDevices()["0000:00:00.1"].Health).To(Equal(pluginapi.Healthy))
+		})
+		It("should report the device unhealthy when its driver changed", func() {
+			defer setup()()
+			Expect(os.Remove(sysfs("0000:00:00.1/driver"))).To(Succeed())
+			Expect(os.Symlink("../../../../bus/pci/drivers/vfio-pci", sysfs("0000:00:00.1/driver"))).To(Succeed())
+			Expect(rp.Probe()).To(BeTrue())
+			Expect(rp.GetDevices()["0000:00:00.1"].Health).To(Equal(pluginapi.Unhealthy))
+		})
+		It("should report the device unhealthy when it disappeared from sysfs", func() {
+			defer setup()()
+			Expect(os.RemoveAll(sysfs("0000:00:00.1"))).To(Succeed())
+			Expect(rp.Probe()).To(BeTrue())
+			Expect(rp.GetDevices()["0000:00:00.1"].Health).To(Equal(pluginapi.Unhealthy))
+		})
+		It("should report a vfio-pci device unhealthy when its vfio group node is missing", func() {
+			probeFs.Dirs = append(probeFs.Dirs, "sys/kernel/iommu_groups/12345")
+			probeFs.Symlinks["sys/bus/pci/devices/0000:00:00.1/driver"] = "../../../../bus/pci/drivers/vfio-pci"
+			probeFs.Symlinks["sys/bus/pci/devices/0000:00:00.1/iommu_group"] = "../../../../kernel/iommu_groups/12345"
+			defer setup()()
+			Expect(rp.Probe()).To(BeTrue())
+			Expect(rp.GetDevices()["0000:00:00.1"].Health).To(Equal(pluginapi.Unhealthy))
+		})
+	})
 })
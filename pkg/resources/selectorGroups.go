@@ -0,0 +1,87 @@
+package resources
+
+import (
+	"reflect"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+)
+
+// SelectorFilter returns the devices matching a selector object, including its nested selector groups
+type SelectorFilter[S any] func(log klog.Logger, devices []types.HostDevice, selectors *S) []types.HostDevice
+
+// FilterBySelectorGroups returns the devices matching every allOf group, at least one anyOf group and not the not
+// group of a selector object. Groups are matched with filter, hence they can be nested.
+func FilterBySelectorGroups[S any](log klog.Logger, devices []types.HostDevice, not *S, anyOf, allOf []*S,
+	filter SelectorFilter[S]) []types.HostDevice {
+	for i, group := range allOf {
+		devices = filter(log.WithValues("allOf", i), devices, group)
+	}
+
+	if len(anyOf) > 0 {
+		matched := make(map[string]bool, len(devices))
+		for i, group := range anyOf {
+			for _, dev := range filter(log.WithValues("anyOf", i), devices, group) {
+				matched[dev.GetDeviceID()] = true
+			}
+		}
+		devices = keepDevices(log, "anyOf", devices, matched, true)
+	}
+
+	if not != nil {
+		// devices dropped by the not group are the ones kept, do not log them
+		excluded := make(map[string]bool, len(devices))
+		for _, dev := range filter(logr.Discard(), devices, not) {
+			excluded[dev.GetDeviceID()] = true
+		}
+		devices = keepDevices(log, "not", devices, excluded, false)
+	}
+	return devices
+}
+
+// SelectorGroups returns the nested selector groups of a selector object
+func SelectorGroups[S any](not *S, anyOf, allOf []*S) []*S {
+	groups := make([]*S, 0, len(anyOf)+len(allOf)+1)
+	if not != nil {
+		groups = append(groups, not)
+	}
+	groups = append(groups, anyOf...)
+	return append(groups, allOf...)
+}
+
+// EmptySelectorGroup tells whether a nested selector group is missing or sets no selector, like "not": {}
+func EmptySelectorGroup[S any](group *S) bool {
+	return group == nil || emptyValue(reflect.ValueOf(group).Elem())
+}
+
+// emptyValue tells whether v is the zero value, with empty slices and maps considered zero
+func emptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !emptyValue(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return v.IsZero()
+	}
+}
+
+// keepDevices returns the devices whose ID is set to want in ids, logging the others as dropped by selectorName
+func keepDevices(log klog.Logger, selectorName string, devices []types.HostDevice, ids map[string]bool,
+	want bool) []types.HostDevice {
+	kept := make([]types.HostDevice, 0, len(devices))
+	for _, dev := range devices {
+		if ids[dev.GetDeviceID()] == want {
+			kept = append(kept, dev)
+		}
+	}
+	LogDroppedDevices(log, selectorName, nil, devices, kept)
+	return kept
+}
@@ -0,0 +1,54 @@
+package resources
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DroppedDevice describes a device a selector did not keep
+type DroppedDevice struct {
+	DeviceID    string   `json:"deviceID"`
+	Selector    string   `json:"selector"`
+	Values      []string `json:"values"`
+	DeviceValue string   `json:"deviceValue,omitempty"`
+}
+
+// dropRecorder is called by LogDroppedDevices with every dropped device, see SetDropRecorder
+var dropRecorder atomic.Pointer[func(DroppedDevice)]
+
+// SetDropRecorder sets the function LogDroppedDevices reports every dropped device to, regardless of the log
+// verbosity. A nil recorder stops reporting dropped devices.
+func SetDropRecorder(recorder func(DroppedDevice)) {
+	if recorder == nil {
+		dropRecorder.Store(nil)
+		return
+	}
+	dropRecorder.Store(&recorder)
+}
+
+// SelectorTrace collects the devices dropped by selectors when its Record method is set as drop recorder
+type SelectorTrace struct {
+	mu      sync.Mutex
+	dropped []DroppedDevice
+}
+
+// NewSelectorTrace returns an empty SelectorTrace
+func NewSelectorTrace() *SelectorTrace {
+	return &SelectorTrace{}
+}
+
+// Record adds a dropped device to the trace
+func (t *SelectorTrace) Record(dev DroppedDevice) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dropped = append(t.dropped, dev)
+}
+
+// Take returns the devices dropped since the previous call
+func (t *SelectorTrace) Take() []DroppedDevice {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	dropped := t.dropped
+	t.dropped = nil
+	return dropped
+}
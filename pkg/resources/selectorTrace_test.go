@@ -0,0 +1,50 @@
+package resources_test
+
+import (
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/resources"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types/mocks"
+)
+
+var _ = Describe("SelectorTrace", func() {
+	var trace *resources.SelectorTrace
+	BeforeEach(func() {
+		trace = resources.NewSelectorTrace()
+		resources.SetDropRecorder(trace.Record)
+	})
+	AfterEach(func() {
+		resources.SetDropRecorder(nil)
+	})
+	It("should record the devices dropped by selectors regardless of the verbosity", func() {
+		dev0 := &mocks.PciNetDevice{}
+		dev0.On("GetDeviceID").Return("0000:01:10.0")
+		dev1 := &mocks.PciNetDevice{}
+		dev1.On("GetDeviceID").Return("0000:01:10.1").
+			On("GetDriver").Return("iavf")
+		in := []types.HostDevice{dev0, dev1}
+
+		resources.LogDroppedDevices(logr.Discard(), "drivers", []string{"vfio-pci"}, in, in[:1])
+
+		Expect(trace.Take()).To(Equal([]resources.DroppedDevice{{
+			DeviceID:    "0000:01:10.1",
+			Selector:    "drivers",
+			Values:      []string{"vfio-pci"},
+			DeviceValue: "iavf",
+		}}))
+		Expect(trace.Take()).To(BeEmpty())
+	})
+	It("should stop recording once the drop recorder is unset", func() {
+		dev0 := &mocks.PciNetDevice{}
+		dev0.On("GetDeviceID").Return("0000:01:10.0").
+			On("GetDriver").Return("iavf")
+
+		resources.SetDropRecorder(nil)
+		resources.LogDroppedDevices(logr.Discard(), "drivers", []string{"vfio-pci"}, []types.HostDevice{dev0}, nil)
+
+		Expect(trace.Take()).To(BeEmpty())
+	})
+})
@@ -20,16 +20,19 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/golang/glog"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
 
 	cdiPkg "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/metrics"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 )
 
@@ -37,17 +40,25 @@ type resourceServer struct {
 	pluginapi.UnimplementedDevicePluginServer
 	registerapi.UnimplementedRegistrationServer
 	resourcePool       types.ResourcePool
+	allocator          types.Allocator
+	checkpoint         types.AllocationCheckpoint
+	assignments        types.DeviceAssignmentLookup
 	pluginWatch        bool
 	endPoint           string // Socket file
 	sockPath           string // Socket file path
 	resourceNamePrefix string
+	qualifiedName      string // fully qualified resource name used as metrics label and checkpoint key
 	grpcServer         *grpc.Server
 	termSignal         chan bool
 	updateSignal       chan bool
 	stopWatcher        chan bool
-	checkIntervals     int // health check intervals in seconds
+	stopProbe          chan struct{}
+	probeDone          chan struct{} // closed when the health check loop exits
+	checkIntervals     int           // health check intervals in seconds
 	useCdi             bool
 	cdi                cdiPkg.CDI
+	lastHealth         map[string]string // device health seen by the last health check
+	log                klog.Logger
 }
 
 const (
@@ -55,21 +66,30 @@ const (
 	unix            = "unix"
 )
 
+// lastRequestID numbers the kubelet requests handled by all resource servers so that their log lines can be correlated
+var lastRequestID atomic.Uint64
+
 // NewResourceServer returns an instance of ResourceServer
-func NewResourceServer(prefix, suffix string, pluginWatch, useCdi bool, rp types.ResourcePool) types.ResourceServer {
+func NewResourceServer(prefix, suffix string, pluginWatch, useCdi bool, rp types.ResourcePool,
+	allocator types.Allocator, checkpoint types.AllocationCheckpoint, assignments types.DeviceAssignmentLookup) types.ResourceServer {
 	sockName := fmt.Sprintf("%s_%s.%s", prefix, rp.GetResourceName(), suffix)
 	sockPath := filepath.Join(types.SockDir, sockName)
 	if !pluginWatch {
 		sockPath = filepath.Join(types.DeprecatedSockDir, sockName)
 	}
 
+	qualifiedName := prefix + "/" + rp.GetResourceName()
 	//nolint:mnd
 	return &resourceServer{
 		resourcePool:       rp,
+		allocator:          allocator,
+		checkpoint:         checkpoint,
+		assignments:        assignments,
 		pluginWatch:        pluginWatch,
 		endPoint:           sockName,
 		sockPath:           sockPath,
 		resourceNamePrefix: prefix,
+		qualifiedName:      qualifiedName,
 		useCdi:             useCdi,
 		grpcServer:         grpc.NewServer(),
 		termSignal:         make(chan bool, 1),
@@ -77,14 +97,20 @@ func NewResourceServer(prefix, suffix string, pluginWatch, useCdi bool, rp types
 		stopWatcher:        make(chan bool),
 		checkIntervals:     20, // updates every 20 seconds
 		cdi:                cdiPkg.New(),
+		log:                klog.Background().WithName("server").WithValues("resourceName", qualifiedName),
 	}
 }
 
+// requestLogger returns the logger of a kubelet request, carrying a new request ID
+func (rs *resourceServer) requestLogger(method string) klog.Logger {
+	return rs.log.WithValues("method", method, "requestID", lastRequestID.Add(1))
+}
+
 func (rs *resourceServer) register() error {
 	kubeletEndpoint := unix + ":" + filepath.Join(types.DeprecatedSockDir, types.KubeEndPoint)
 	conn, err := grpc.NewClient(kubeletEndpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
-		glog.Errorf("%s device plugin unable connect to Kubelet : %v", rs.resourcePool.GetResourceName(), err)
+		rs.log.Error(err, "Device plugin unable to connect to Kubelet")
 		return err
 	}
 	defer conn.Close() //nolint:errcheck
@@ -97,10 +123,10 @@ func (rs *resourceServer) register() error {
 	}
 
 	if _, err = client.Register(context.Background(), request); err != nil {
-		glog.Errorf("%s device plugin unable to register with Kubelet : %v", rs.resourcePool.GetResourceName(), err)
+		rs.log.Error(err, "Device plugin unable to register with Kubelet")
 		return err
 	}
-	glog.Infof("%s device plugin registered with Kubelet", rs.resourcePool.GetResourceName())
+	rs.log.Info("Device plugin registered with Kubelet")
 	return nil
 }
 
@@ -117,31 +143,44 @@ func (rs *resourceServer) GetInfo(ctx context.Context, rqt *registerapi.InfoRequ
 func (rs *resourceServer) NotifyRegistrationStatus(ctx context.Context,
 	regstat *registerapi.RegistrationStatus) (*registerapi.RegistrationStatusResponse, error) {
 	if regstat.PluginRegistered {
-		glog.Infof("Plugin: %s gets registered successfully at Kubelet\n", rs.endPoint)
+		rs.log.Info("Plugin gets registered successfully at Kubelet", "endpoint", rs.endPoint)
 	} else {
-		glog.Infof("Plugin: %s failed to be registered at Kubelet: %v; restarting.\n", rs.endPoint, regstat.Error)
+		rs.log.Info("Plugin failed to be registered at Kubelet, restarting", "endpoint", rs.endPoint, "error", regstat.Error)
 		rs.grpcServer.Stop()
 	}
 	return &registerapi.RegistrationStatusResponse{}, nil
 }
 
 func (rs *resourceServer) Allocate(ctx context.Context, rqt *pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
-	glog.Infof("Allocate() called with %+v", rqt)
+	start := time.Now()
+	log := rs.requestLogger("Allocate")
+	resp, err := rs.allocate(log, rqt)
+	metrics.ObserveAllocate(rs.qualifiedName, start, err)
+	if err != nil {
+		log.Error(err, "Allocate failed")
+	}
+	return resp, err
+}
+
+func (rs *resourceServer) allocate(log klog.Logger, rqt *pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
 	resp := new(pluginapi.AllocateResponse)
 
-	for _, container := range rqt.ContainerRequests {
+	for i, container := range rqt.ContainerRequests {
+		log := log.WithValues("container", i)
+		log.Info("Allocating devices", "deviceIDs", container.DevicesIds)
 		containerResp := new(pluginapi.ContainerAllocateResponse)
 
 		envs, err := rs.getEnvs(container.DevicesIds)
 		if err != nil {
-			glog.Errorf("failed to get environment variables for device IDs %v: %v", container.DevicesIds, err)
-			return nil, err
+			return nil, fmt.Errorf("failed to get environment variables for device IDs %v: %v", container.DevicesIds, err)
 		}
 
 		if rs.useCdi {
+			// device nodes and mounts come with the container edits of the CDI devices
 			containerResp.Annotations, err = rs.cdi.CreateContainerAnnotations(
 				container.DevicesIds, rs.resourceNamePrefix, rs.resourcePool.GetCDIName())
 			if err != nil {
+				metrics.CDIError(rs.qualifiedName)
 				return nil, fmt.Errorf("can't create container annotation: %s", err)
 			}
 		} else {
@@ -151,20 +190,23 @@ func (rs *resourceServer) Allocate(ctx context.Context, rqt *pluginapi.AllocateR
 
 		err = rs.resourcePool.StoreDeviceInfoFile(rs.resourceNamePrefix, container.DevicesIds)
 		if err != nil {
-			glog.Errorf("failed to store device info file for device IDs %v: %v", container.DevicesIds, err)
-			return nil, err
+			metrics.DeviceInfoFileError(rs.qualifiedName)
+			return nil, fmt.Errorf("failed to store device info file for device IDs %v: %v", container.DevicesIds, err)
 		}
 
 		containerResp.Envs = envs
 		resp.ContainerResponses = append(resp.ContainerResponses, containerResp)
+		log.V(4).Info("Container allocate response", "envs", containerResp.Envs, "annotations", containerResp.Annotations,
+			"deviceSpecs", len(containerResp.Devices), "mounts", len(containerResp.Mounts))
 	}
-	glog.Infof("AllocateResponse send: %+v", resp)
+	rs.checkpointAllocatedDevices(log, rqt)
+	log.Info("Allocate response sent", "containers", len(resp.ContainerResponses))
 	return resp, nil
 }
 
 func (rs *resourceServer) ListAndWatch(empty *pluginapi.Empty, stream pluginapi.DevicePlugin_ListAndWatchServer) error {
-	methodID := fmt.Sprintf("ListAndWatch(%s)", rs.resourcePool.GetResourceName()) // for logging purpose
-	glog.Infof("%s invoked", methodID)
+	log := rs.requestLogger("ListAndWatch")
+	log.Info("ListAndWatch invoked")
 	// Send initial list of devices
 	devs := make([]*pluginapi.Device, 0)
 	resp := new(pluginapi.ListAndWatchResponse)
@@ -172,14 +214,15 @@ func (rs *resourceServer) ListAndWatch(empty *pluginapi.Empty, stream pluginapi.
 		devs = append(devs, dev)
 	}
 	resp.Devices = devs
+	rs.updateDeviceMetrics(devs)
 	err := rs.updateCDISpec()
 	if err != nil {
-		glog.Errorf("can't update CDI specs: %v", err)
+		log.Error(err, "Cannot update CDI specs")
 		return err
 	}
-	glog.Infof("%s: send devices %v\n", methodID, resp)
+	log.Info("Sending devices", "devices", deviceStates(devs))
 	if err := stream.Send(resp); err != nil {
-		glog.Errorf("%s: error: cannot update device states: %v\n", methodID, err)
+		log.Error(err, "Cannot update device states")
 		return err
 	}
 
@@ -188,24 +231,25 @@ func (rs *resourceServer) ListAndWatch(empty *pluginapi.Empty, stream pluginapi.
 		select {
 		case <-rs.termSignal:
 			// Terminate signal received; return from mehtod call
-			glog.Infof("%s: terminate signal received", methodID)
+			log.Info("Terminate signal received")
 			return nil
 		case <-rs.updateSignal:
 			// Device health changed; so send new device list
-			glog.Infof("%s: device health changed!\n", methodID)
+			log.Info("Devices changed")
 			newDevs := make([]*pluginapi.Device, 0)
 			for _, dev := range rs.resourcePool.GetDevices() {
 				newDevs = append(newDevs, dev)
 			}
 			resp.Devices = newDevs
+			rs.updateDeviceMetrics(newDevs)
 			if err := rs.updateCDISpec(); err != nil {
-				glog.Errorf("cannot update CDI specs: %v", err)
+				log.Error(err, "Cannot update CDI specs")
 				return err
 			}
-			glog.Infof("%s: send updated devices %v", methodID, resp)
+			log.Info("Sending updated devices", "devices", deviceStates(newDevs))
 
 			if err := stream.Send(resp); err != nil {
-				glog.Errorf("%s: error: cannot update device states: %v\n", methodID, err)
+				log.Error(err, "Cannot update device states")
 				return err
 			}
 		}
@@ -223,16 +267,27 @@ func (rs *resourceServer) updateCDISpec() error {
 	}
 	err := rs.cdi.CreateCDISpecForPool(prefix, rs.resourcePool)
 	if err != nil {
-		glog.Errorf("updateCDISpec(): error creating CDI spec: %v", err)
-		return err
+		metrics.CDIError(rs.qualifiedName)
+		return fmt.Errorf("error creating CDI spec: %v", err)
 	}
 	return nil
 }
 
-// TODO: (SchSeba) check if we want to use this function
 func (rs *resourceServer) GetPreferredAllocation(ctx context.Context,
 	request *pluginapi.PreferredAllocationRequest) (*pluginapi.PreferredAllocationResponse, error) {
-	return &pluginapi.PreferredAllocationResponse{}, nil
+	log := rs.requestLogger("GetPreferredAllocation")
+	resp := &pluginapi.PreferredAllocationResponse{}
+	for i, container := range request.ContainerRequests {
+		containerResp := &pluginapi.ContainerPreferredAllocationResponse{}
+		if rs.allocator != nil {
+			containerResp.DeviceIDs = rs.allocator.Allocate(container, rs.resourcePool)
+		}
+		log.Info("Preferred devices", "container", i, "size", container.AllocationSize,
+			"mustInclude", container.MustIncludeDeviceIDs, "deviceIDs", containerResp.DeviceIDs)
+		log.V(4).Info("Available devices", "container", i, "deviceIDs", container.AvailableDeviceIDs)
+		resp.ContainerResponses = append(resp.ContainerResponses, containerResp)
+	}
+	return resp, nil
 }
 
 func (rs *resourceServer) PreStartContainer(ctx context.Context,
@@ -243,7 +298,7 @@ func (rs *resourceServer) PreStartContainer(ctx context.Context,
 func (rs *resourceServer) GetDevicePluginOptions(ctx context.Context, empty *pluginapi.Empty) (*pluginapi.DevicePluginOptions, error) {
 	return &pluginapi.DevicePluginOptions{
 		PreStartRequired:                false,
-		GetPreferredAllocationAvailable: false,
+		GetPreferredAllocationAvailable: rs.allocator != nil,
 	}, nil
 }
 
@@ -253,13 +308,12 @@ func (rs *resourceServer) Init() error {
 
 // gRPC server related
 func (rs *resourceServer) Start() error {
-	resourceName := rs.resourcePool.GetResourceName()
 	_ = rs.cleanUp() // try tp clean up and continue
 
-	glog.Infof("starting %s device plugin endpoint at: %s\n", resourceName, rs.endPoint)
+	rs.log.Info("Starting device plugin endpoint", "endpoint", rs.endPoint)
 	lis, err := net.Listen(unix, rs.sockPath)
 	if err != nil {
-		glog.Errorf("error starting %s device plugin endpoint: %v", resourceName, err)
+		rs.log.Error(err, "Error starting device plugin endpoint", "endpoint", rs.endPoint)
 		return err
 	}
 
@@ -273,7 +327,7 @@ func (rs *resourceServer) Start() error {
 	go func() {
 		err := rs.grpcServer.Serve(lis)
 		if err != nil {
-			glog.Errorf("serving incoming requests failed: %s", err.Error())
+			rs.log.Error(err, "Serving incoming requests failed")
 		}
 	}()
 
@@ -285,7 +339,8 @@ func (rs *resourceServer) Start() error {
 		if err != nil {
 			// Stop server
 			rs.grpcServer.Stop()
-			glog.Fatal(err)
+			rs.log.Error(err, "Unable to register with Kubelet, exiting")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 			return err
 		}
 	}
@@ -294,13 +349,13 @@ func (rs *resourceServer) Start() error {
 }
 
 func (rs *resourceServer) restart() error {
-	resourceName := rs.resourcePool.GetResourceName()
-	glog.Infof("restarting %s device plugin server...", resourceName)
+	rs.log.Info("Restarting device plugin server")
 	if rs.grpcServer == nil {
-		return fmt.Errorf("grpc server instance not found for %s", resourceName)
+		return fmt.Errorf("grpc server instance not found for %s", rs.resourcePool.GetResourceName())
 	}
 	rs.grpcServer.Stop()
 	rs.grpcServer = nil
+	rs.stopProbeLoop()
 	// Send terminate signal to ListAndWatch()
 	rs.termSignal <- true
 
@@ -309,11 +364,11 @@ func (rs *resourceServer) restart() error {
 }
 
 func (rs *resourceServer) Stop() error {
-	resourceName := rs.resourcePool.GetResourceName()
-	glog.Infof("stopping %s device plugin server...", resourceName)
+	rs.log.Info("Stopping device plugin server")
 	if rs.grpcServer == nil {
 		return nil
 	}
+	rs.stopProbeLoop()
 	// Send terminate signal to ListAndWatch()
 	rs.termSignal <- true
 	if !rs.pluginWatch {
@@ -332,18 +387,19 @@ func (rs *resourceServer) Watch() {
 		select {
 		case stop := <-rs.stopWatcher:
 			if stop {
-				glog.Infof("kubelet watcher stopped for server %s", rs.resourcePool.GetResourceName())
+				rs.log.Info("Kubelet watcher stopped")
 				return
 			}
 		default:
 			_, err := os.Lstat(rs.sockPath)
 			if err != nil {
 				// Socket file not found; restart server
-				glog.Warningf("server endpoint not found %s", rs.endPoint)
-				glog.Warningf("most likely Kubelet restarted")
+				rs.log.Info("Server endpoint not found, most likely Kubelet restarted", "endpoint", rs.endPoint)
 				if err := rs.restart(); err != nil {
-					glog.Fatalf("unable to restart server %v", err)
+					rs.log.Error(err, "Unable to restart server, exiting")
+					klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 				}
+				metrics.KubeletReregistration(rs.qualifiedName)
 			}
 		}
 		// Sleep for some intervals; TODO: investigate on suggested interval
@@ -351,12 +407,28 @@ func (rs *resourceServer) Watch() {
 	}
 }
 
+// UpdateDevices replaces the devices of the resource pool and sends the new device list to the kubelet
+func (rs *resourceServer) UpdateDevices(devices []types.HostDevice) {
+	devicePool := make(map[string]types.HostDevice, len(devices))
+	for _, dev := range devices {
+		devicePool[dev.GetDeviceID()] = dev
+	}
+	rs.resourcePool.SetDevicePool(devicePool)
+
+	select {
+	case rs.updateSignal <- true:
+	case <-time.After(rsWatchInterval):
+		// ListAndWatch sends the current devices once the kubelet connects
+		rs.log.Info("ListAndWatch is not running, devices update is deferred")
+	}
+}
+
 func (rs *resourceServer) cleanUp() error {
 	errors := make([]string, 0)
 	if err := os.Remove(rs.sockPath); err != nil && !os.IsNotExist(err) {
 		errors = append(errors, err.Error())
 	}
-	if err := rs.resourcePool.CleanDeviceInfoFile(rs.resourceNamePrefix); err != nil {
+	if err := rs.resourcePool.CleanDeviceInfoFile(rs.resourceNamePrefix, rs.unallocatedDeviceIDs()); err != nil {
 		errors = append(errors, err.Error())
 	}
 	if len(errors) > 0 {
@@ -368,18 +440,123 @@ func (rs *resourceServer) cleanUp() error {
 func (rs *resourceServer) triggerUpdate() {
 	rp := rs.resourcePool
 	if rs.checkIntervals > 0 {
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		rs.stopProbe = stop
+		rs.probeDone = done
 		go func() {
+			defer close(done)
 			for {
 				changed := rp.Probe()
 				if changed {
-					rs.updateSignal <- true
+					rs.recordHealthTransitions()
+					select {
+					case rs.updateSignal <- true:
+					case <-stop:
+						return
+					}
+				}
+				select {
+				case <-time.After(time.Second * time.Duration(rs.checkIntervals)):
+				case <-stop:
+					return
 				}
-				time.Sleep(time.Second * time.Duration(rs.checkIntervals))
 			}
 		}()
 	}
 }
 
+// stopProbeLoop stops the health check loop started by triggerUpdate and waits for it to exit
+func (rs *resourceServer) stopProbeLoop() {
+	if rs.stopProbe != nil {
+		close(rs.stopProbe)
+		<-rs.probeDone
+		rs.stopProbe = nil
+		rs.probeDone = nil
+	}
+}
+
+// recordHealthTransitions records the devices whose health differs from the one seen by the last
+// health check, devices start as healthy
+func (rs *resourceServer) recordHealthTransitions() {
+	health := make(map[string]string)
+	for id, dev := range rs.resourcePool.GetDevices() {
+		previous, ok := rs.lastHealth[id]
+		if !ok {
+			previous = pluginapi.Healthy
+		}
+		if previous != dev.Health {
+			metrics.HealthTransition(rs.qualifiedName, dev.Health)
+			rs.logAssignedHealthTransition(id, dev.Health)
+		}
+		health[id] = dev.Health
+	}
+	rs.lastHealth = health
+}
+
+// logAssignedHealthTransition logs the container a device that changed its health is assigned to
+func (rs *resourceServer) logAssignedHealthTransition(deviceID, health string) {
+	if rs.assignments == nil {
+		return
+	}
+	if a, ok := rs.assignments.GetDeviceAssignment(rs.qualifiedName, deviceID); ok {
+		rs.log.Info("Device assigned to a container changed health", "deviceID", deviceID, "health", health,
+			"pod", a.Namespace+"/"+a.Pod, "container", a.Container)
+	}
+}
+
+// updateDeviceMetrics records the number of total and healthy devices of the resource pool
+func (rs *resourceServer) updateDeviceMetrics(devices []*pluginapi.Device) {
+	healthy := 0
+	for _, dev := range devices {
+		if dev.Health == pluginapi.Healthy {
+			healthy++
+		}
+	}
+	metrics.SetDevices(rs.qualifiedName, len(devices), healthy)
+}
+
+// checkpointAllocatedDevices records the devices handed out by an Allocate request in the checkpoint.
+// A failure to write the checkpoint does not fail the allocation.
+func (rs *resourceServer) checkpointAllocatedDevices(log klog.Logger, rqt *pluginapi.AllocateRequest) {
+	if rs.checkpoint == nil {
+		return
+	}
+	for _, container := range rqt.ContainerRequests {
+		if err := rs.checkpoint.AddDevices(rs.qualifiedName, container.DevicesIds); err != nil {
+			log.Error(err, "Failed to checkpoint allocated devices", "deviceIDs", container.DevicesIds)
+		}
+	}
+}
+
+// unallocatedDeviceIDs returns the devices of the pool that are not recorded as allocated in the
+// checkpoint, all devices of the pool without a checkpoint
+func (rs *resourceServer) unallocatedDeviceIDs() []string {
+	allocated := make(map[string]bool)
+	if rs.checkpoint != nil {
+		for _, id := range rs.checkpoint.GetDevices(rs.qualifiedName) {
+			allocated[id] = true
+		}
+	}
+	ids := make([]string, 0)
+	for id := range rs.resourcePool.GetDevicePool() {
+		if !allocated[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// deviceStates returns the health of every device keyed by device ID, for logging purpose
+func deviceStates(devices []*pluginapi.Device) map[string]string {
+	states := make(map[string]string, len(devices))
+	for _, dev := range devices {
+		states[dev.ID] = dev.Health
+	}
+	return states
+}
+
 func (rs *resourceServer) getEnvs(deviceIDs []string) (map[string]string, error) {
 	return rs.resourcePool.GetEnvs(rs.resourceNamePrefix, deviceIDs)
 }
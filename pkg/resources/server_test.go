@@ -9,6 +9,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	CDImocks "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/cdi/mocks"
@@ -29,7 +30,7 @@ var _ = Describe("Server", func() {
 			})
 			It("should have the properties correctly assigned when plugin watcher enabled", func() {
 				// Create ResourceServer with plugin watch mode enabled
-				obj := NewResourceServer("fakeprefix", "fakesuffix", true, false, &rp)
+				obj := NewResourceServer("fakeprefix", "fakesuffix", true, false, &rp, nil, nil, nil)
 				rs = obj.(*resourceServer)
 				Expect(rs.resourcePool.GetResourceName()).To(Equal("fakename"))
 				Expect(rs.resourceNamePrefix).To(Equal("fakeprefix"))
@@ -39,7 +40,7 @@ var _ = Describe("Server", func() {
 			})
 			It("should have the properties correctly assigned when plugin watcher disabled", func() {
 				// Create ResourceServer with plugin watch mode disabled
-				obj := NewResourceServer("fakeprefix", "fakesuffix", false, false, &rp)
+				obj := NewResourceServer("fakeprefix", "fakesuffix", false, false, &rp, nil, nil, nil)
 				rs = obj.(*resourceServer)
 				Expect(rs.resourcePool.GetResourceName()).To(Equal("fakename"))
 				Expect(rs.resourceNamePrefix).To(Equal("fakeprefix"))
@@ -59,13 +60,14 @@ var _ = Describe("Server", func() {
 			rp := mocks.ResourcePool{}
 			rp.On("Probe").Return(false)
 			rp.On("GetResourceName").Return("fakename")
-			rp.On("CleanDeviceInfoFile", "fakeprefix").Return(nil)
+			rp.On("CleanDeviceInfoFile", "fakeprefix", mock.Anything).Return(nil).
+				On("GetDevicePool").Return(map[string]types.HostDevice{})
 
 			// Use faked dir as socket dir
 			types.SockDir = fs.RootDir
 			types.DeprecatedSockDir = fs.RootDir
 
-			obj := NewResourceServer("fakeprefix", "fakesuffix", shouldEnablePluginWatch, false, &rp)
+			obj := NewResourceServer("fakeprefix", "fakesuffix", shouldEnablePluginWatch, false, &rp, nil, nil, nil)
 			rs := obj.(*resourceServer)
 
 			registrationServer := createFakeRegistrationServer(fs.RootDir,
@@ -74,7 +76,7 @@ var _ = Describe("Server", func() {
 			if shouldRunServer {
 				if shouldEnablePluginWatch {
 					_ = rs.Start()
-					rp.AssertCalled(t, "CleanDeviceInfoFile", "fakeprefix")
+					rp.AssertCalled(t, "CleanDeviceInfoFile", "fakeprefix", mock.Anything)
 				} else {
 					_ = os.MkdirAll(pluginapi.DevicePluginPath, 0755)
 					registrationServer.start()
@@ -112,7 +114,7 @@ var _ = Describe("Server", func() {
 				defer fs.Use()()
 				rp := mocks.ResourcePool{}
 				rp.On("GetResourceName").Return("fake.com")
-				rs := NewResourceServer("fakeprefix", "fakesuffix", true, false, &rp).(*resourceServer)
+				rs := NewResourceServer("fakeprefix", "fakesuffix", true, false, &rp, nil, nil, nil).(*resourceServer)
 				err = rs.Init()
 			})
 			It("should never fail", func() {
@@ -149,10 +151,11 @@ var _ = Describe("Server", func() {
 					On("DiscoverDevices").Return(nil).
 					On("GetDevices").Return(map[string]*pluginapi.Device{}).
 					On("Probe").Return(true).
-					On("CleanDeviceInfoFile", "fake").Return(nil)
+					On("CleanDeviceInfoFile", "fake", mock.Anything).Return(nil).
+					On("GetDevicePool").Return(map[string]types.HostDevice{})
 
 				// Create ResourceServer with plugin watch mode disabled
-				rs := NewResourceServer("fake", "fake", false, false, &rp).(*resourceServer)
+				rs := NewResourceServer("fake", "fake", false, false, &rp, nil, nil, nil).(*resourceServer)
 
 				registrationServer := createFakeRegistrationServer(fs.RootDir,
 					"fake_fake.com.fake", false, false)
@@ -169,10 +172,11 @@ var _ = Describe("Server", func() {
 				Eventually(rs.termSignal).WithTimeout(time.Second * 10).Should(Receive())
 
 				go func() {
-					rp.On("CleanDeviceInfoFile", "fake").Return(nil)
+					rp.On("CleanDeviceInfoFile", "fake", mock.Anything).Return(nil).
+						On("GetDevicePool").Return(map[string]types.HostDevice{})
 					err := rs.Stop()
 					Expect(err).NotTo(HaveOccurred())
-					rp.AssertCalled(t, "CleanDeviceInfoFile", "fake")
+					rp.AssertCalled(t, "CleanDeviceInfoFile", "fake", mock.Anything)
 				}()
 				Eventually(rs.termSignal).WithTimeout(time.Second * 10).Should(Receive())
 				Eventually(rs.stopWatcher).WithTimeout(time.Second * 10).Should(Receive())
@@ -189,9 +193,10 @@ var _ = Describe("Server", func() {
 					On("DiscoverDevices").Return(nil).
 					On("GetDevices").Return(map[string]*pluginapi.Device{}).
 					On("Probe").Return(true).
-					On("CleanDeviceInfoFile", "fake").Return(nil)
+					On("CleanDeviceInfoFile", "fake", mock.Anything).Return(nil).
+					On("GetDevicePool").Return(map[string]types.HostDevice{})
 				// Create ResourceServer with plugin watch mode enabled
-				rs := NewResourceServer("fake", "fake", true, false, &rp).(*resourceServer)
+				rs := NewResourceServer("fake", "fake", true, false, &rp, nil, nil, nil).(*resourceServer)
 
 				registrationServer := createFakeRegistrationServer(fs.RootDir,
 					"fake_fake.com.fake", false, true)
@@ -202,10 +207,11 @@ var _ = Describe("Server", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				go func() {
-					rp.On("CleanDeviceInfoFile", "fake").Return(nil)
+					rp.On("CleanDeviceInfoFile", "fake", mock.Anything).Return(nil).
+						On("GetDevicePool").Return(map[string]types.HostDevice{})
 					err := rs.Stop()
 					Expect(err).NotTo(HaveOccurred())
-					rp.AssertCalled(t, "CleanDeviceInfoFile", "fake")
+					rp.AssertCalled(t, "CleanDeviceInfoFile", "fake", mock.Anything)
 				}()
 				Eventually(rs.termSignal).WithTimeout(time.Second * 10).Should(Receive())
 			})
@@ -221,10 +227,11 @@ var _ = Describe("Server", func() {
 					On("DiscoverDevices").Return(nil).
 					On("GetDevices").Return(map[string]*pluginapi.Device{}).
 					On("Probe").Return(true).
-					On("CleanDeviceInfoFile", "fake").Return(nil)
+					On("CleanDeviceInfoFile", "fake", mock.Anything).Return(nil).
+					On("GetDevicePool").Return(map[string]types.HostDevice{})
 
 				// Create ResourceServer with plugin watch mode disabled
-				rs := NewResourceServer("fake", "fake", false, false, &rp).(*resourceServer)
+				rs := NewResourceServer("fake", "fake", false, false, &rp, nil, nil, nil).(*resourceServer)
 
 				registrationServer := createFakeRegistrationServer(fs.RootDir,
 					"fake_fake.com.fake", false, false)
@@ -265,7 +272,7 @@ var _ = Describe("Server", func() {
 				On("StoreDeviceInfoFile", "fake.com", []string{"00:00.01"}).
 				Return(nil)
 
-			rs := NewResourceServer("fake.com", "fake", true, false, &rp).(*resourceServer)
+			rs := NewResourceServer("fake.com", "fake", true, false, &rp, nil, nil, nil).(*resourceServer)
 
 			resp, err := rs.Allocate(context.TODO(), req)
 
@@ -311,7 +318,7 @@ var _ = Describe("Server", func() {
 				On("StoreDeviceInfoFile", "fake.com", []string{"00:00.01"}).
 				Return(nil)
 
-			rs := NewResourceServer("fake.com", "fake", true, true, &rp).(*resourceServer)
+			rs := NewResourceServer("fake.com", "fake", true, true, &rp, nil, nil, nil).(*resourceServer)
 
 			cdi := &CDImocks.CDI{}
 			cdi.On("CreateCDISpecForPool", "fake.com", &rp).Return(nil).Twice().
@@ -359,6 +366,88 @@ var _ = Describe("Server", func() {
 			resp, err := rs.GetDevicePluginOptions(context.TODO(), nil)
 			Expect(resp).NotTo(BeNil())
 			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.GetPreferredAllocationAvailable).To(BeFalse())
+		})
+		It("should advertise preferred allocation when an allocator is set", func() {
+			rs := &resourceServer{allocator: &mocks.Allocator{}}
+			resp, err := rs.GetDevicePluginOptions(context.TODO(), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.GetPreferredAllocationAvailable).To(BeTrue())
+		})
+	})
+	Describe("allocation checkpoint", func() {
+		var (
+			rp *mocks.ResourcePool
+			cp *mocks.AllocationCheckpoint
+			rs *resourceServer
+		)
+		BeforeEach(func() {
+			rp = &mocks.ResourcePool{}
+			rp.On("GetResourceName").Return("fake").
+				On("GetDevicePool").Return(map[string]types.HostDevice{
+				"00:00.01": &mocks.HostDevice{}, "00:00.02": &mocks.HostDevice{}, "00:00.03": &mocks.HostDevice{},
+			})
+			cp = &mocks.AllocationCheckpoint{}
+			rs = NewResourceServer("fake.com", "fake", true, false, rp, nil, cp, nil).(*resourceServer)
+		})
+		It("should record the allocated devices", func() {
+			rp.On("GetEnvs", "fake.com", []string{"00:00.01"}).Return(map[string]string{}, nil).
+				On("GetDeviceSpecs", []string{"00:00.01"}).Return([]*pluginapi.DeviceSpec{}).
+				On("GetMounts", []string{"00:00.01"}).Return([]*pluginapi.Mount{}).
+				On("StoreDeviceInfoFile", "fake.com", []string{"00:00.01"}).Return(nil)
+			cp.On("AddDevices", "fake.com/fake", []string{"00:00.01"}).Return(nil)
+
+			_, err := rs.Allocate(context.TODO(), &pluginapi.AllocateRequest{
+				ContainerRequests: []*pluginapi.ContainerAllocateRequest{{DevicesIds: []string{"00:00.01"}}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			cp.AssertExpectations(GinkgoT())
+		})
+		It("should only clean the device info files of devices that are not allocated", func() {
+			cp.On("GetDevices", "fake.com/fake").Return([]string{"00:00.02"})
+			rp.On("CleanDeviceInfoFile", "fake.com", []string{"00:00.01", "00:00.03"}).Return(nil)
+
+			Expect(rs.cleanUp()).To(Succeed())
+			rp.AssertExpectations(GinkgoT())
+		})
+	})
+	Describe("updating devices", func() {
+		It("should replace the devices of the pool and notify ListAndWatch", func() {
+			dev := &mocks.PciDevice{}
+			dev.On("GetDeviceID").Return("00:00.01")
+			rp := &mocks.ResourcePool{}
+			rp.On("SetDevicePool", map[string]types.HostDevice{"00:00.01": dev}).Return()
+			rs := &resourceServer{resourcePool: rp, updateSignal: make(chan bool)}
+			go rs.UpdateDevices([]types.HostDevice{dev})
+			Eventually(rs.updateSignal).WithTimeout(time.Second * 2).Should(Receive())
+			rp.AssertExpectations(GinkgoT())
+		})
+	})
+	Describe("running GetPreferredAllocation", func() {
+		rqt := &pluginapi.PreferredAllocationRequest{
+			ContainerRequests: []*pluginapi.ContainerPreferredAllocationRequest{
+				{AvailableDeviceIDs: []string{"00:00.01", "00:00.02"}, AllocationSize: 1},
+				{AvailableDeviceIDs: []string{"00:00.01", "00:00.02"}, AllocationSize: 2},
+			},
+		}
+		It("should return empty preferences when no allocator is set", func() {
+			rs := &resourceServer{}
+			resp, err := rs.GetPreferredAllocation(context.TODO(), rqt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.ContainerResponses).To(HaveLen(2))
+			Expect(resp.ContainerResponses[0].DeviceIDs).To(BeEmpty())
+		})
+		It("should return the allocator preferences for every container", func() {
+			rp := &mocks.ResourcePool{}
+			allocator := &mocks.Allocator{}
+			allocator.On("Allocate", rqt.ContainerRequests[0], rp).Return([]string{"00:00.02"}).
+				On("Allocate", rqt.ContainerRequests[1], rp).Return([]string{"00:00.01", "00:00.02"})
+			rs := &resourceServer{resourcePool: rp, allocator: allocator}
+			resp, err := rs.GetPreferredAllocation(context.TODO(), rqt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.ContainerResponses).To(HaveLen(2))
+			Expect(resp.ContainerResponses[0].DeviceIDs).To(Equal([]string{"00:00.02"}))
+			Expect(resp.ContainerResponses[1].DeviceIDs).To(Equal([]string{"00:00.01", "00:00.02"}))
 		})
 	})
 	Describe("ListAndWatch", func() {
@@ -370,7 +459,7 @@ var _ = Describe("Server", func() {
 				rp.On("GetResourceName").Return("fake.com").
 					On("GetDevices").Return(map[string]*pluginapi.Device{"00:00.01": {ID: "00:00.01", Health: "Healthy"}}).Once()
 
-				rs := NewResourceServer("fake.com", "fake", true, false, &rp).(*resourceServer)
+				rs := NewResourceServer("fake.com", "fake", true, false, &rp, nil, nil, nil).(*resourceServer)
 				rs.sockPath = fs.RootDir
 
 				lwSrv := &fakeListAndWatchServer{
@@ -391,7 +480,7 @@ var _ = Describe("Server", func() {
 					On("GetDevices").Return(map[string]*pluginapi.Device{"00:00.01": {ID: "00:00.01", Health: "Healthy"}}).Once().
 					On("GetDevices").Return(map[string]*pluginapi.Device{"00:00.02": {ID: "00:00.02", Health: "Healthy"}}).Once()
 
-				rs := NewResourceServer("fake.com", "fake", true, false, &rp).(*resourceServer)
+				rs := NewResourceServer("fake.com", "fake", true, false, &rp, nil, nil, nil).(*resourceServer)
 				rs.sockPath = fs.RootDir
 
 				lwSrv := &fakeListAndWatchServer{
@@ -425,7 +514,7 @@ var _ = Describe("Server", func() {
 					On("GetDevices").Return(map[string]*pluginapi.Device{"00:00.01": {ID: "00:00.01", Health: "Healthy"}}).Once().
 					On("GetDevices").Return(map[string]*pluginapi.Device{"00:00.02": {ID: "00:00.02", Health: "Healthy"}}).Once()
 
-				rs := NewResourceServer("fake.com", "fake", true, false, &rp).(*resourceServer)
+				rs := NewResourceServer("fake.com", "fake", true, false, &rp, nil, nil, nil).(*resourceServer)
 				rs.sockPath = fs.RootDir
 
 				lwSrv := &fakeListAndWatchServer{
@@ -461,7 +550,7 @@ var _ = Describe("Server", func() {
 					On("GetDevices").Return(map[string]*pluginapi.Device{"00:00.01": {ID: "00:00.01", Health: "Healthy"}}).Twice().
 					On("GetResourcePrefix").Return("fake.com").Twice()
 
-				rs := NewResourceServer("fake.com", "fake", true, true, &rp).(*resourceServer)
+				rs := NewResourceServer("fake.com", "fake", true, true, &rp, nil, nil, nil).(*resourceServer)
 				rs.sockPath = fs.RootDir
 
 				cdi := &CDImocks.CDI{}
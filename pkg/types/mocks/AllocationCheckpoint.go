@@ -0,0 +1,92 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// AllocationCheckpoint is an autogenerated mock type for the AllocationCheckpoint type
+type AllocationCheckpoint struct {
+	mock.Mock
+}
+
+// AddDevices provides a mock function with given fields: resourceName, deviceIDs
+func (_m *AllocationCheckpoint) AddDevices(resourceName string, deviceIDs []string) error {
+	ret := _m.Called(resourceName, deviceIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddDevices")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []string) error); ok {
+		r0 = rf(resourceName, deviceIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetDevices provides a mock function with given fields: resourceName
+func (_m *AllocationCheckpoint) GetDevices(resourceName string) []string {
+	ret := _m.Called(resourceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDevices")
+	}
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(resourceName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
+// Reconcile provides a mock function with given fields: inUse
+func (_m *AllocationCheckpoint) Reconcile(inUse map[string][]string) (map[string][]string, error) {
+	ret := _m.Called(inUse)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reconcile")
+	}
+
+	var r0 map[string][]string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(map[string][]string) (map[string][]string, error)); ok {
+		return rf(inUse)
+	}
+	if rf, ok := ret.Get(0).(func(map[string][]string) map[string][]string); ok {
+		r0 = rf(inUse)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(map[string][]string) error); ok {
+		r1 = rf(inUse)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewAllocationCheckpoint creates a new instance of AllocationCheckpoint. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAllocationCheckpoint(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AllocationCheckpoint {
+	mock := &AllocationCheckpoint{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
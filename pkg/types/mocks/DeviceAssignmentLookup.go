@@ -0,0 +1,55 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	types "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// DeviceAssignmentLookup is an autogenerated mock type for the DeviceAssignmentLookup type
+type DeviceAssignmentLookup struct {
+	mock.Mock
+}
+
+// GetDeviceAssignment provides a mock function with given fields: resourceName, deviceID
+func (_m *DeviceAssignmentLookup) GetDeviceAssignment(resourceName string, deviceID string) (types.DeviceAssignment, bool) {
+	ret := _m.Called(resourceName, deviceID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDeviceAssignment")
+	}
+
+	var r0 types.DeviceAssignment
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(string, string) (types.DeviceAssignment, bool)); ok {
+		return rf(resourceName, deviceID)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) types.DeviceAssignment); ok {
+		r0 = rf(resourceName, deviceID)
+	} else {
+		r0 = ret.Get(0).(types.DeviceAssignment)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) bool); ok {
+		r1 = rf(resourceName, deviceID)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// NewDeviceAssignmentLookup creates a new instance of DeviceAssignmentLookup. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDeviceAssignmentLookup(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *DeviceAssignmentLookup {
+	mock := &DeviceAssignmentLookup{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
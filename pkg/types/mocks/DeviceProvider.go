@@ -102,6 +102,11 @@ func (_m *DeviceProvider) GetFilteredDevices(_a0 []types.HostDevice, _a1 *types.
 	return r0, r1
 }
 
+// ResetDevices provides a mock function with no fields
+func (_m *DeviceProvider) ResetDevices() {
+	_m.Called()
+}
+
 // ValidConfig provides a mock function with given fields: _a0
 func (_m *DeviceProvider) ValidConfig(_a0 *types.ResourceConfig) bool {
 	ret := _m.Called(_a0)
@@ -163,6 +163,24 @@ func (_m *PciNetDevice) GetEnvVal() map[string]types.AdditionalInfo {
 	return r0
 }
 
+// GetEswitchMode provides a mock function with no fields
+func (_m *PciNetDevice) GetEswitchMode() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetEswitchMode")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // GetFuncID provides a mock function with no fields
 func (_m *PciNetDevice) GetFuncID() int {
 	ret := _m.Called()
@@ -237,6 +255,24 @@ func (_m *PciNetDevice) GetMounts() []*v1beta1.Mount {
 	return r0
 }
 
+// GetMtu provides a mock function with no fields
+func (_m *PciNetDevice) GetMtu() int {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMtu")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
 // GetNetName provides a mock function with no fields
 func (_m *PciNetDevice) GetNetName() string {
 	ret := _m.Called()
@@ -255,6 +291,24 @@ func (_m *PciNetDevice) GetNetName() string {
 	return r0
 }
 
+// GetNumaNode provides a mock function with no fields
+func (_m *PciNetDevice) GetNumaNode() int {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNumaNode")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
 // GetPKey provides a mock function with no fields
 func (_m *PciNetDevice) GetPKey() string {
 	ret := _m.Called()
@@ -309,6 +363,24 @@ func (_m *PciNetDevice) GetPfNetName() string {
 	return r0
 }
 
+// GetPfOperState provides a mock function with no fields
+func (_m *PciNetDevice) GetPfOperState() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPfOperState")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // GetPfPciAddr provides a mock function with no fields
 func (_m *PciNetDevice) GetPfPciAddr() string {
 	ret := _m.Called()
@@ -327,6 +399,42 @@ func (_m *PciNetDevice) GetPfPciAddr() string {
 	return r0
 }
 
+// GetRepresentor provides a mock function with no fields
+func (_m *PciNetDevice) GetRepresentor() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRepresentor")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// GetUplinkRepresentor provides a mock function with no fields
+func (_m *PciNetDevice) GetUplinkRepresentor() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUplinkRepresentor")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // GetVdpaDevice provides a mock function with no fields
 func (_m *PciNetDevice) GetVdpaDevice() types.VdpaDevice {
 	ret := _m.Called()
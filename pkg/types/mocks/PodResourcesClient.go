@@ -0,0 +1,89 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	types "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// PodResourcesClient is an autogenerated mock type for the PodResourcesClient type
+type PodResourcesClient struct {
+	mock.Mock
+}
+
+// GetClaimDevices provides a mock function with given fields: ctx, driverName
+func (_m *PodResourcesClient) GetClaimDevices(ctx context.Context, driverName string) ([]string, error) {
+	ret := _m.Called(ctx, driverName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetClaimDevices")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]string, error)); ok {
+		return rf(ctx, driverName)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []string); ok {
+		r0 = rf(ctx, driverName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, driverName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDeviceAssignments provides a mock function with given fields: ctx
+func (_m *PodResourcesClient) GetDeviceAssignments(ctx context.Context) (map[string]map[string]types.DeviceAssignment, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDeviceAssignments")
+	}
+
+	var r0 map[string]map[string]types.DeviceAssignment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (map[string]map[string]types.DeviceAssignment, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) map[string]map[string]types.DeviceAssignment); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]map[string]types.DeviceAssignment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewPodResourcesClient creates a new instance of PodResourcesClient. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPodResourcesClient(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *PodResourcesClient {
+	mock := &PodResourcesClient{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
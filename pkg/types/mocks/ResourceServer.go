@@ -2,7 +2,10 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	types "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // ResourceServer is an autogenerated mock type for the ResourceServer type
 type ResourceServer struct {
@@ -63,6 +66,11 @@ func (_m *ResourceServer) Stop() error {
 	return r0
 }
 
+// UpdateDevices provides a mock function with given fields: _a0
+func (_m *ResourceServer) UpdateDevices(_a0 []types.HostDevice) {
+	_m.Called(_a0)
+}
+
 // Watch provides a mock function with no fields
 func (_m *ResourceServer) Watch() {
 	_m.Called()
@@ -145,6 +145,24 @@ func (_m *MockAuxNetDevice) GetEnvVal() map[string]types.AdditionalInfo {
 	return r0
 }
 
+// GetEswitchMode provides a mock function with no fields
+func (_m *MockAuxNetDevice) GetEswitchMode() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetEswitchMode")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // GetFuncID provides a mock function with no fields
 func (_m *MockAuxNetDevice) GetFuncID() int {
 	ret := _m.Called()
@@ -219,6 +237,24 @@ func (_m *MockAuxNetDevice) GetMounts() []*v1beta1.Mount {
 	return r0
 }
 
+// GetMtu provides a mock function with no fields
+func (_m *MockAuxNetDevice) GetMtu() int {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMtu")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
 // GetNetName provides a mock function with no fields
 func (_m *MockAuxNetDevice) GetNetName() string {
 	ret := _m.Called()
@@ -237,6 +273,24 @@ func (_m *MockAuxNetDevice) GetNetName() string {
 	return r0
 }
 
+// GetNumaNode provides a mock function with no fields
+func (_m *MockAuxNetDevice) GetNumaNode() int {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNumaNode")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
 // GetPfNetName provides a mock function with no fields
 func (_m *MockAuxNetDevice) GetPfNetName() string {
 	ret := _m.Called()
@@ -255,6 +309,24 @@ func (_m *MockAuxNetDevice) GetPfNetName() string {
 	return r0
 }
 
+// GetPfOperState provides a mock function with no fields
+func (_m *MockAuxNetDevice) GetPfOperState() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPfOperState")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // GetPfPciAddr provides a mock function with no fields
 func (_m *MockAuxNetDevice) GetPfPciAddr() string {
 	ret := _m.Called()
@@ -273,6 +345,62 @@ func (_m *MockAuxNetDevice) GetPfPciAddr() string {
 	return r0
 }
 
+// GetRepresentor provides a mock function with no fields
+func (_m *MockAuxNetDevice) GetRepresentor() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRepresentor")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// GetUplinkRepresentor provides a mock function with no fields
+func (_m *MockAuxNetDevice) GetUplinkRepresentor() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUplinkRepresentor")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// GetVdpaDevice provides a mock function with no fields
+func (_m *MockAuxNetDevice) GetVdpaDevice() types.VdpaDevice {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVdpaDevice")
+	}
+
+	var r0 types.VdpaDevice
+	if rf, ok := ret.Get(0).(func() types.VdpaDevice); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.VdpaDevice)
+		}
+	}
+
+	return r0
+}
+
 // GetVendor provides a mock function with no fields
 func (_m *MockAuxNetDevice) GetVendor() string {
 	ret := _m.Called()
@@ -102,6 +102,11 @@ func (_m *MockDeviceProvider) GetFilteredDevices(_a0 []types.HostDevice, _a1 *ty
 	return r0, r1
 }
 
+// ResetDevices provides a mock function with no fields
+func (_m *MockDeviceProvider) ResetDevices() {
+	_m.Called()
+}
+
 // ValidConfig provides a mock function with given fields: _a0
 func (_m *MockDeviceProvider) ValidConfig(_a0 *types.ResourceConfig) bool {
 	ret := _m.Called(_a0)
@@ -3,9 +3,9 @@
 package mocks
 
 import (
-	mock "github.com/stretchr/testify/mock"
-
 	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	types "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	mock "github.com/stretchr/testify/mock"
 )
 
 // MockNadUtils is an autogenerated mock type for the NadUtils type
@@ -31,6 +31,24 @@ func (_m *MockNadUtils) CleanDeviceInfoFile(resourceName string, deviceID string
 	return r0
 }
 
+// SaveAuxDeviceInfoFile provides a mock function with given fields: resourceName, deviceID, auxDev
+func (_m *MockNadUtils) SaveAuxDeviceInfoFile(resourceName string, deviceID string, auxDev *types.AuxiliaryDevice) error {
+	ret := _m.Called(resourceName, deviceID, auxDev)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveAuxDeviceInfoFile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, *types.AuxiliaryDevice) error); ok {
+		r0 = rf(resourceName, deviceID, auxDev)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SaveDeviceInfoFile provides a mock function with given fields: resourceName, deviceID, devInfo
 func (_m *MockNadUtils) SaveDeviceInfoFile(resourceName string, deviceID string, devInfo *v1.DeviceInfo) error {
 	ret := _m.Called(resourceName, deviceID, devInfo)
@@ -49,6 +67,24 @@ func (_m *MockNadUtils) SaveDeviceInfoFile(resourceName string, deviceID string,
 	return r0
 }
 
+// SetDeviceInfoAssignment provides a mock function with given fields: resourceName, deviceID, assignment
+func (_m *MockNadUtils) SetDeviceInfoAssignment(resourceName string, deviceID string, assignment *types.DeviceAssignment) error {
+	ret := _m.Called(resourceName, deviceID, assignment)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDeviceInfoAssignment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, *types.DeviceAssignment) error); ok {
+		r0 = rf(resourceName, deviceID, assignment)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewMockNadUtils creates a new instance of MockNadUtils. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockNadUtils(t interface {
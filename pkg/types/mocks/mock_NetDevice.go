@@ -127,6 +127,24 @@ func (_m *MockNetDevice) GetEnvVal() map[string]types.AdditionalInfo {
 	return r0
 }
 
+// GetEswitchMode provides a mock function with no fields
+func (_m *MockNetDevice) GetEswitchMode() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetEswitchMode")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // GetFuncID provides a mock function with no fields
 func (_m *MockNetDevice) GetFuncID() int {
 	ret := _m.Called()
@@ -201,6 +219,24 @@ func (_m *MockNetDevice) GetMounts() []*v1beta1.Mount {
 	return r0
 }
 
+// GetMtu provides a mock function with no fields
+func (_m *MockNetDevice) GetMtu() int {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMtu")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
 // GetNetName provides a mock function with no fields
 func (_m *MockNetDevice) GetNetName() string {
 	ret := _m.Called()
@@ -219,6 +255,24 @@ func (_m *MockNetDevice) GetNetName() string {
 	return r0
 }
 
+// GetNumaNode provides a mock function with no fields
+func (_m *MockNetDevice) GetNumaNode() int {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNumaNode")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
 // GetPfNetName provides a mock function with no fields
 func (_m *MockNetDevice) GetPfNetName() string {
 	ret := _m.Called()
@@ -237,6 +291,24 @@ func (_m *MockNetDevice) GetPfNetName() string {
 	return r0
 }
 
+// GetPfOperState provides a mock function with no fields
+func (_m *MockNetDevice) GetPfOperState() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPfOperState")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // GetPfPciAddr provides a mock function with no fields
 func (_m *MockNetDevice) GetPfPciAddr() string {
 	ret := _m.Called()
@@ -255,6 +327,42 @@ func (_m *MockNetDevice) GetPfPciAddr() string {
 	return r0
 }
 
+// GetRepresentor provides a mock function with no fields
+func (_m *MockNetDevice) GetRepresentor() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRepresentor")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// GetUplinkRepresentor provides a mock function with no fields
+func (_m *MockNetDevice) GetUplinkRepresentor() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUplinkRepresentor")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // GetVendor provides a mock function with no fields
 func (_m *MockNetDevice) GetVendor() string {
 	ret := _m.Called()
@@ -3,8 +3,8 @@
 package mocks
 
 import (
+	types "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	mock "github.com/stretchr/testify/mock"
-
 	v1beta1 "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 )
 
@@ -51,6 +51,26 @@ func (_m *MockRdmaSpec) GetRdmaDeviceSpec() []*v1beta1.DeviceSpec {
 	return r0
 }
 
+// GetRdmaPortInfo provides a mock function with no fields
+func (_m *MockRdmaSpec) GetRdmaPortInfo() *types.RdmaPortInfo {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRdmaPortInfo")
+	}
+
+	var r0 *types.RdmaPortInfo
+	if rf, ok := ret.Get(0).(func() *types.RdmaPortInfo); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.RdmaPortInfo)
+		}
+	}
+
+	return r0
+}
+
 // IsRdma provides a mock function with no fields
 func (_m *MockRdmaSpec) IsRdma() bool {
 	ret := _m.Called()
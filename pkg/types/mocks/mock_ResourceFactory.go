@@ -3,8 +3,10 @@
 package mocks
 
 import (
-	types "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
+	logr "github.com/go-logr/logr"
 	mock "github.com/stretchr/testify/mock"
+
+	types "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 )
 
 // MockResourceFactory is an autogenerated mock type for the ResourceFactory type
@@ -12,17 +14,17 @@ type MockResourceFactory struct {
 	mock.Mock
 }
 
-// FilterBySelector provides a mock function with given fields: _a0, _a1, _a2
-func (_m *MockResourceFactory) FilterBySelector(_a0 string, _a1 []string, _a2 []types.HostDevice) []types.HostDevice {
-	ret := _m.Called(_a0, _a1, _a2)
+// FilterBySelector provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *MockResourceFactory) FilterBySelector(_a0 logr.Logger, _a1 string, _a2 []string, _a3 []types.HostDevice) []types.HostDevice {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
 
 	if len(ret) == 0 {
 		panic("no return value specified for FilterBySelector")
 	}
 
 	var r0 []types.HostDevice
-	if rf, ok := ret.Get(0).(func(string, []string, []types.HostDevice) []types.HostDevice); ok {
-		r0 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(0).(func(logr.Logger, string, []string, []types.HostDevice) []types.HostDevice); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]types.HostDevice)
@@ -32,17 +34,67 @@ func (_m *MockResourceFactory) FilterBySelector(_a0 string, _a1 []string, _a2 []
 	return r0
 }
 
-// GetDefaultInfoProvider provides a mock function with given fields: _a0, _a1
-func (_m *MockResourceFactory) GetDefaultInfoProvider(_a0 string, _a1 string) []types.DeviceInfoProvider {
-	ret := _m.Called(_a0, _a1)
+// GetAllocator provides a mock function with given fields: _a0
+func (_m *MockResourceFactory) GetAllocator(_a0 types.AllocationPolicy) (types.Allocator, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAllocator")
+	}
+
+	var r0 types.Allocator
+	var r1 error
+	if rf, ok := ret.Get(0).(func(types.AllocationPolicy) (types.Allocator, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(types.AllocationPolicy) types.Allocator); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.Allocator)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(types.AllocationPolicy) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAuxVdpaDevice provides a mock function with given fields: _a0
+func (_m *MockResourceFactory) GetAuxVdpaDevice(_a0 string) types.VdpaDevice {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAuxVdpaDevice")
+	}
+
+	var r0 types.VdpaDevice
+	if rf, ok := ret.Get(0).(func(string) types.VdpaDevice); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.VdpaDevice)
+		}
+	}
+
+	return r0
+}
+
+// GetDefaultInfoProvider provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockResourceFactory) GetDefaultInfoProvider(_a0 string, _a1 string, _a2 types.VfioMode) []types.DeviceInfoProvider {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetDefaultInfoProvider")
 	}
 
 	var r0 []types.DeviceInfoProvider
-	if rf, ok := ret.Get(0).(func(string, string) []types.DeviceInfoProvider); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(string, string, types.VfioMode) []types.DeviceInfoProvider); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]types.DeviceInfoProvider)
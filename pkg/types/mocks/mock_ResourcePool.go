@@ -3,8 +3,8 @@
 package mocks
 
 import (
+	types "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	mock "github.com/stretchr/testify/mock"
-
 	v1beta1 "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 )
 
@@ -13,17 +13,17 @@ type MockResourcePool struct {
 	mock.Mock
 }
 
-// CleanDeviceInfoFile provides a mock function with given fields: resourceNamePrefix
-func (_m *MockResourcePool) CleanDeviceInfoFile(resourceNamePrefix string) error {
-	ret := _m.Called(resourceNamePrefix)
+// CleanDeviceInfoFile provides a mock function with given fields: resourceNamePrefix, deviceIDs
+func (_m *MockResourcePool) CleanDeviceInfoFile(resourceNamePrefix string, deviceIDs []string) error {
+	ret := _m.Called(resourceNamePrefix, deviceIDs)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CleanDeviceInfoFile")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(resourceNamePrefix)
+	if rf, ok := ret.Get(0).(func(string, []string) error); ok {
+		r0 = rf(resourceNamePrefix, deviceIDs)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -49,6 +49,76 @@ func (_m *MockResourcePool) GetCDIName() string {
 	return r0
 }
 
+// GetConfig provides a mock function with no fields
+func (_m *MockResourcePool) GetConfig() *types.ResourceConfig {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetConfig")
+	}
+
+	var r0 *types.ResourceConfig
+	if rf, ok := ret.Get(0).(func() *types.ResourceConfig); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.ResourceConfig)
+		}
+	}
+
+	return r0
+}
+
+// GetDeviceEnvs provides a mock function with given fields: prefix, deviceID
+func (_m *MockResourcePool) GetDeviceEnvs(prefix string, deviceID string) (map[string]string, error) {
+	ret := _m.Called(prefix, deviceID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDeviceEnvs")
+	}
+
+	var r0 map[string]string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) (map[string]string, error)); ok {
+		return rf(prefix, deviceID)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) map[string]string); ok {
+		r0 = rf(prefix, deviceID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(prefix, deviceID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDevicePool provides a mock function with no fields
+func (_m *MockResourcePool) GetDevicePool() map[string]types.HostDevice {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDevicePool")
+	}
+
+	var r0 map[string]types.HostDevice
+	if rf, ok := ret.Get(0).(func() map[string]types.HostDevice); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]types.HostDevice)
+		}
+	}
+
+	return r0
+}
+
 // GetDeviceSpecs provides a mock function with given fields: deviceIDs
 func (_m *MockResourcePool) GetDeviceSpecs(deviceIDs []string) []*v1beta1.DeviceSpec {
 	ret := _m.Called(deviceIDs)
@@ -193,6 +263,11 @@ func (_m *MockResourcePool) Probe() bool {
 	return r0
 }
 
+// SetDevicePool provides a mock function with given fields: _a0
+func (_m *MockResourcePool) SetDevicePool(_a0 map[string]types.HostDevice) {
+	_m.Called(_a0)
+}
+
 // StoreDeviceInfoFile provides a mock function with given fields: resourceNamePrefix, deviceIDs
 func (_m *MockResourcePool) StoreDeviceInfoFile(resourceNamePrefix string, deviceIDs []string) error {
 	ret := _m.Called(resourceNamePrefix, deviceIDs)
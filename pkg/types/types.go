@@ -15,11 +15,13 @@
 package types
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/jaypipes/ghw"
 	"github.com/k8snetworkplumbingwg/govdpa/pkg/kvdpa"
 	nettypes "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 )
 
@@ -28,6 +30,12 @@ var (
 	SockDir = "/var/lib/kubelet/plugins_registry"
 	// DeprecatedSockDir is the deprecated Kubelet device plugin socket directory
 	DeprecatedSockDir = "/var/lib/kubelet/device-plugins"
+	// DRAPluginsDir is the Kubelet directory holding the sockets of DRA drivers
+	DRAPluginsDir = "/var/lib/kubelet/plugins"
+	// PodResourcesSocket is the Kubelet PodResources API socket
+	PodResourcesSocket = "/var/lib/kubelet/pod-resources/kubelet.sock"
+	// DefaultCheckpointFile is the default file persisting the allocated devices
+	DefaultCheckpointFile = "/var/lib/sriov-network-device-plugin/allocation_checkpoint.json"
 )
 
 const (
@@ -57,6 +65,45 @@ const (
 	VdpaInvalidType VdpaType = "invalid"
 )
 
+// AllocationPolicy is a type to define the supported preferred allocation policies
+type AllocationPolicy string
+
+const (
+	// PackedAllocationPolicy prefers devices of as few PFs as possible
+	PackedAllocationPolicy AllocationPolicy = "packed"
+	// SpreadAllocationPolicy prefers devices spread across as many PFs as possible
+	SpreadAllocationPolicy AllocationPolicy = "spread"
+	// NumaAllocationPolicy prefers devices of as few NUMA nodes as possible
+	NumaAllocationPolicy AllocationPolicy = "numa"
+	// BondAllocationPolicy prefers devices whose PFs are members of the same bond, spread across its members
+	BondAllocationPolicy AllocationPolicy = "bond"
+)
+
+const (
+	// RdmaNetnsModeShared is the RDMA subsystem network namespace mode sharing RDMA devices with all namespaces
+	RdmaNetnsModeShared = "shared"
+	// RdmaNetnsModeExclusive is the RDMA subsystem network namespace mode binding RDMA devices to one namespace
+	RdmaNetnsModeExclusive = "exclusive"
+)
+
+// VfioMode is a type to define how the VFIO devices of a pool are exposed
+type VfioMode string
+
+const (
+	// VfioModeGroup exposes the legacy VFIO container and IOMMU group devices, /dev/vfio/vfio and /dev/vfio/<group>
+	VfioModeGroup VfioMode = "group"
+	// VfioModeCdev exposes the VFIO device cdev, /dev/vfio/devices/vfioN, and the iommufd device, /dev/iommu
+	VfioModeCdev VfioMode = "cdev"
+	// VfioModeBoth exposes the devices of both the group and the cdev modes
+	VfioModeBoth VfioMode = "both"
+	// VfioModeAuto exposes the devices of both modes when the host supports VFIO cdevs and iommufd,
+	// and those of the group mode otherwise
+	VfioModeAuto VfioMode = "auto"
+)
+
+// RdmaOptionalCharDevices are the RDMA character devices that can be left out of the devices of a pool
+var RdmaOptionalCharDevices = []string{"rdma_cm", "umad", "issm"}
+
 // SupportedDevices is map of 'device identifier as string' to 'device class hexcode as int'
 /*
 Supported PCI Device Classes. ref: https://pci-ids.ucw.cz/read/PD
@@ -97,12 +144,37 @@ type ResourceConfig struct {
 	// optional resource prefix that will overwrite	global prefix specified in cli params
 	ResourcePrefix string `json:"resourcePrefix,omitempty"`
 	//nolint:lll
-	ResourceName    string                    `json:"resourceName"` // the resource name will be added with resource prefix in K8s api
-	DeviceType      DeviceType                `json:"deviceType,omitempty"`
-	ExcludeTopology bool                      `json:"excludeTopology,omitempty"`
-	Selectors       *json.RawMessage          `json:"selectors,omitempty"`
-	AdditionalInfo  map[string]AdditionalInfo `json:"additionalInfo,omitempty"`
-	SelectorObjs    []interface{}
+	ResourceName     string                    `json:"resourceName"` // the resource name will be added with resource prefix in K8s api
+	DeviceType       DeviceType                `json:"deviceType,omitempty"`
+	ExcludeTopology  bool                      `json:"excludeTopology,omitempty"`
+	Selectors        *json.RawMessage          `json:"selectors,omitempty"`
+	AdditionalInfo   map[string]AdditionalInfo `json:"additionalInfo,omitempty"`
+	AllocationPolicy AllocationPolicy          `json:"allocationPolicy,omitempty"`
+	CDIHooks         []CDIHook                 `json:"cdiHooks,omitempty"`
+	Rdma             *RdmaConfig               `json:"rdma,omitempty"`
+	VfioMode         VfioMode                  `json:"vfioMode,omitempty"`
+	SelectorObjs     []interface{}
+}
+
+// RdmaConfig configures how the RDMA devices of the devices selected with isRdma are exposed
+type RdmaConfig struct {
+	// NetnsMode is the RDMA subsystem network namespace mode the pool is meant for, "shared" or "exclusive".
+	// A mismatch with the mode of the host is logged, or rejects the config when Strict is set
+	NetnsMode string `json:"netnsMode,omitempty"`
+	Strict    bool   `json:"strict,omitempty"`
+	// CharDevices lists the optional RDMA character devices exposed along with the uverbs device: "rdma_cm",
+	// "umad" and "issm". All of them are exposed when CharDevices is omitted
+	CharDevices []string `json:"charDevices,omitempty"`
+}
+
+// CDIHook is an OCI hook added to the container edits of the CDI spec of a resource pool
+type CDIHook struct {
+	// HookName is the OCI hook the hook runs as, e.g. createContainer
+	HookName string   `json:"hookName"`
+	Path     string   `json:"path"`
+	Args     []string `json:"args,omitempty"`
+	Env      []string `json:"env,omitempty"`
+	Timeout  *int     `json:"timeout,omitempty"`
 }
 
 // DeviceSelectors contains common device selectors fields
@@ -118,6 +190,7 @@ type AdditionalInfo map[string]string
 // GenericPciDeviceSelectors contains common PCI device selectors fields
 type GenericPciDeviceSelectors struct {
 	PciAddresses []string `json:"pciAddresses,omitempty"`
+	BindDriver   string   `json:"bindDriver,omitempty"` // driver the selected devices are bound to before building the pool
 }
 
 // GenericNetDeviceSelectors contains common net device selectors fields
@@ -128,6 +201,7 @@ type GenericNetDeviceSelectors struct {
 	IsRdma       bool     // the resource support rdma
 	AcpiIndexes  []string `json:"acpiIndexes,omitempty"`
 	NeedVhostNet bool     `json:"needVhostNet,omitempty"` // share vhost-net along the selected resource
+	EswitchModes []string `json:"eswitchModes,omitempty"` // eswitch mode of the PF, "legacy" or "switchdev"
 }
 
 // NetDeviceSelectors contains network device related selectors fields
@@ -137,25 +211,81 @@ type NetDeviceSelectors struct {
 	GenericNetDeviceSelectors
 	DDPProfiles []string `json:"ddpProfiles,omitempty"`
 	VdpaType    VdpaType `json:"vdpaType,omitempty"`
-	PKeys       []string `json:"pKeys,omitempty"`
+	// CreateVdpa creates a vDPA device of VdpaType on the selected devices that lack one
+	CreateVdpa bool     `json:"createVdpa,omitempty"`
+	PKeys      []string `json:"pKeys,omitempty"`
+	// MinLinkSpeed and MaxLinkSpeed bound the link speed in Mb/s, unbounded when 0
+	MinLinkSpeed int      `json:"minLinkSpeed,omitempty"`
+	MaxLinkSpeed int      `json:"maxLinkSpeed,omitempty"`
+	Mtus         []int    `json:"mtus,omitempty"`
+	PfOperStates []string `json:"pfOperStates,omitempty"`
+	NumaNodes    []int    `json:"numaNodes,omitempty"`
+	// Not excludes the devices matching the nested selectors
+	Not *NetDeviceSelectors `json:"not,omitempty"`
+	// AnyOf keeps the devices matching at least one of the nested selectors
+	AnyOf []*NetDeviceSelectors `json:"anyOf,omitempty"`
+	// AllOf keeps the devices matching every one of the nested selectors
+	AllOf []*NetDeviceSelectors `json:"allOf,omitempty"`
 }
 
 // AccelDeviceSelectors contains accelerator(FPGA etc.) related selectors fields
 type AccelDeviceSelectors struct {
 	DeviceSelectors
 	GenericPciDeviceSelectors
+	Not   *AccelDeviceSelectors   `json:"not,omitempty"`
+	AnyOf []*AccelDeviceSelectors `json:"anyOf,omitempty"`
+	AllOf []*AccelDeviceSelectors `json:"allOf,omitempty"`
 }
 
 // AuxNetDeviceSelectors contains auxiliary device related selector fields
 type AuxNetDeviceSelectors struct {
 	DeviceSelectors
 	GenericNetDeviceSelectors
-	AuxTypes []string `json:"auxTypes,omitempty"`
+	AuxTypes []string                 `json:"auxTypes,omitempty"`
+	VdpaType VdpaType                 `json:"vdpaType,omitempty"`
+	Not      *AuxNetDeviceSelectors   `json:"not,omitempty"`
+	AnyOf    []*AuxNetDeviceSelectors `json:"anyOf,omitempty"`
+	AllOf    []*AuxNetDeviceSelectors `json:"allOf,omitempty"`
 }
 
 // ResourceConfList is list of ResourceConfig
 type ResourceConfList struct {
-	ResourceList []ResourceConfig `json:"resourceList"` // config file: "resourceList" :[{<ResourceConfig configs>},{},{},...]
+	ResourceList []ResourceConfig `json:"resourceList"`           // config file: "resourceList" :[{<ResourceConfig configs>},{},{},...]
+	SriovPfs     []PfConfig       `json:"sriovPfs,omitempty"`     // config file: "sriovPfs" :[{<PfConfig configs>},{},{},...]
+	Subfunctions []SfConfig       `json:"subfunctions,omitempty"` // config file: "subfunctions" :[{<SfConfig configs>},{},...]
+}
+
+// PfIdentifier identifies a PF by exactly one of its netdevice name, PCI address or ACPI index
+type PfIdentifier struct {
+	PfName     string `json:"pfName,omitempty"`
+	PciAddress string `json:"pciAddress,omitempty"`
+	AcpiIndex  string `json:"acpiIndex,omitempty"`
+}
+
+// PfConfig declares the number of VFs of an SR-IOV PF
+type PfConfig struct {
+	PfIdentifier
+	NumVfs int `json:"numVfs"`
+}
+
+// SfConfig declares the subfunctions of a PF. The SFs are numbered from SfNumStart on, MacAddresses optionally
+// holds the MAC address of each SF in that order and State is the state of their port function, "active" by default
+type SfConfig struct {
+	PfIdentifier
+	NumSfs       int      `json:"numSfs"`
+	SfNumStart   int      `json:"sfNumStart,omitempty"`
+	MacAddresses []string `json:"macAddresses,omitempty"`
+	State        string   `json:"state,omitempty"`
+}
+
+// DevlinkPortNumbers holds the numbers of a devlink port, which the devlink ports of the netlink library do not expose
+type DevlinkPortNumbers struct {
+	// PortNumber is the number of a physical port
+	PortNumber uint32
+	// PfNumber is the PF number of a PCI PF, VF or SF port
+	PfNumber uint16
+	// SfNumber is the SF number of a PCI SF port
+	SfNumber uint32
 }
 
 // ResourceServer is gRPC server implements K8s device plugin api
@@ -167,20 +297,24 @@ type ResourceServer interface {
 	Init() error
 	// Watch watches for socket file deletion and restart server if needed
 	Watch()
+	// UpdateDevices replaces the devices of the resourcePool and advertises them to the kubelet
+	UpdateDevices([]HostDevice)
 }
 
 // ResourceFactory is an interface to get instances of ResourcePool and ResourceServer
 type ResourceFactory interface {
 	GetResourceServer(ResourcePool) (ResourceServer, error)
-	GetDefaultInfoProvider(string, string) []DeviceInfoProvider
+	GetDefaultInfoProvider(string, string, VfioMode) []DeviceInfoProvider
 	GetSelector(string, []string) (DeviceSelector, error)
+	GetAllocator(AllocationPolicy) (Allocator, error)
 	GetResourcePool(rc *ResourceConfig, deviceList []HostDevice) (ResourcePool, error)
 	GetRdmaSpec(DeviceType, string) RdmaSpec
 	GetVdpaDevice(string) VdpaDevice
+	GetAuxVdpaDevice(string) VdpaDevice
 	GetDeviceProvider(DeviceType) DeviceProvider
 	GetDeviceFilter(*ResourceConfig) ([]interface{}, error)
 	GetNadUtils() NadUtils
-	FilterBySelector(string, []string, []HostDevice) []HostDevice
+	FilterBySelector(klog.Logger, string, []string, []HostDevice) []HostDevice
 }
 
 // ResourcePool represents a generic resource entity
@@ -188,13 +322,19 @@ type ResourcePool interface {
 	// extended API for internal use
 	GetResourceName() string
 	GetResourcePrefix() string
+	GetConfig() *ResourceConfig
 	GetDevices() map[string]*pluginapi.Device // for ListAndWatch
+	GetDevicePool() map[string]HostDevice
+	SetDevicePool(map[string]HostDevice)
 	Probe() bool
 	GetDeviceSpecs(deviceIDs []string) []*pluginapi.DeviceSpec
 	GetEnvs(prefix string, deviceIDs []string) (map[string]string, error)
+	// GetDeviceEnvs returns the environment variables of a single device, named after the device so that the
+	// variables of several devices of the pool do not override each other
+	GetDeviceEnvs(prefix string, deviceID string) (map[string]string, error)
 	GetMounts(deviceIDs []string) []*pluginapi.Mount
 	StoreDeviceInfoFile(resourceNamePrefix string, deviceIDs []string) error
-	CleanDeviceInfoFile(resourceNamePrefix string) error
+	CleanDeviceInfoFile(resourceNamePrefix string, deviceIDs []string) error
 	GetCDIName() string
 }
 
@@ -203,6 +343,8 @@ type DeviceProvider interface {
 	// AddTargetDevices adds a list of devices in a DeviceProvider that matches the 'device class hexcode as int'
 	AddTargetDevices([]*ghw.PCIDevice, int) error
 	GetDiscoveredDevices() []*ghw.PCIDevice
+	// ResetDevices clears the list of discovered devices ahead of a new discovery
+	ResetDevices()
 
 	// GetDevices runs through the Discovered Devices and returns a list of fully populated HostDevices according to the given ResourceConfig
 	GetDevices(*ResourceConfig, int) []HostDevice
@@ -266,6 +408,19 @@ type NetDevice interface {
 	GetLinkType() string
 	// GetLinkType returns link type of the devuce
 	GetLinkSpeed() string
+	// GetMtu returns the MTU of the network interface of the device, or of its PF when it has none
+	GetMtu() int
+	// GetPfOperState returns the operational state of the network interface of the parent PCI device
+	GetPfOperState() string
+	// GetNumaNode returns the NUMA node of the device, -1 if unknown
+	GetNumaNode() int
+	// GetEswitchMode returns the eswitch mode of the parent PCI device, empty if unknown
+	GetEswitchMode() string
+	// GetUplinkRepresentor returns the uplink representor netdevice of a device whose PF is in switchdev mode
+	GetUplinkRepresentor() string
+	// GetRepresentor returns the representor netdevice of a VF or SF whose PF is in switchdev mode
+	GetRepresentor() string
+
 	// GetFuncID returns ID > -1 if device is a PCI Virtual Function or Scalable Function
 	GetFuncID() int
 	// IsRdma returns true if device is RDMA capable
@@ -296,6 +451,8 @@ type AuxNetDevice interface {
 	NetDevice
 	// GetAuxType returns type of auxiliary device
 	GetAuxType() string
+	// GetVdpaDevice returns VDPA device
+	GetVdpaDevice() VdpaDevice
 }
 
 // DeviceInfoProvider is an interface to get Device Plugin API specific device information
@@ -311,6 +468,45 @@ type DeviceSelector interface {
 	Filter([]HostDevice) []HostDevice
 }
 
+// Allocator provides an interface to choose the preferred devices of a container allocation
+type Allocator interface {
+	// Allocate returns the preferred device IDs out of the available ones in the request
+	Allocate(*pluginapi.ContainerPreferredAllocationRequest, ResourcePool) []string
+}
+
+// AllocationCheckpoint persists the devices allocated to containers so that they survive plugin restarts.
+// Resources are identified by their fully qualified resource name.
+type AllocationCheckpoint interface {
+	// AddDevices records devices of a resource as allocated
+	AddDevices(resourceName string, deviceIDs []string) error
+	// GetDevices returns the devices of a resource recorded as allocated
+	GetDevices(resourceName string) []string
+	// Reconcile replaces the recorded allocations with the devices in use and returns the recorded
+	// devices that are no longer in use
+	Reconcile(inUse map[string][]string) (map[string][]string, error)
+}
+
+// DeviceAssignment identifies the container a device is assigned to
+type DeviceAssignment struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+}
+
+// PodResourcesClient provides an interface to the Kubelet PodResources API
+type PodResourcesClient interface {
+	// GetDeviceAssignments returns the containers devices are assigned to keyed by resource name and device ID
+	GetDeviceAssignments(ctx context.Context) (map[string]map[string]DeviceAssignment, error)
+	// GetClaimDevices returns the names of the devices of a DRA driver allocated to containers through resource claims
+	GetClaimDevices(ctx context.Context, driverName string) ([]string, error)
+}
+
+// DeviceAssignmentLookup provides an interface to find the container a device is assigned to
+type DeviceAssignmentLookup interface {
+	// GetDeviceAssignment returns the container a device of a resource is assigned to, if any
+	GetDeviceAssignment(resourceName, deviceID string) (DeviceAssignment, bool)
+}
+
 // LinkWatcher in interface to watch Network link status
 type LinkWatcher interface { // This is not fully defined yet!!
 	Subscribe()
@@ -321,12 +517,45 @@ type RdmaSpec interface {
 	IsRdma() bool
 	GetRdmaDeviceSpec() []*pluginapi.DeviceSpec
 	GetRdmaDeviceName() string
+	// GetRdmaPortInfo returns the attributes of the first port of the RDMA device, nil if unavailable
+	GetRdmaPortInfo() *RdmaPortInfo
+}
+
+// RdmaPortInfo describes a port of an RDMA device
+type RdmaPortInfo struct {
+	Port      string
+	LinkLayer string // "InfiniBand" or "Ethernet" for RoCE
+	PortGUID  string
+	Gids      []RdmaGid // used entries of the GID table ordered by index
+}
+
+// RdmaGid is an entry of the GID table of an RDMA port
+type RdmaGid struct {
+	Index int
+	Gid   string
+	Type  string // RoCE type of the entry, e.g. "RoCE v2", empty for InfiniBand ports
 }
 
 // NadUtils is an interface for Network-Attachment-Definition utilities
 type NadUtils interface {
 	SaveDeviceInfoFile(resourceName string, deviceID string, devInfo *nettypes.DeviceInfo) error
 	CleanDeviceInfoFile(resourceName string, deviceID string) error
+	// SaveAuxDeviceInfoFile saves a Device Info file of type DeviceInfoTypeAuxiliary
+	SaveAuxDeviceInfoFile(resourceName string, deviceID string, auxDev *AuxiliaryDevice) error
+	// SetDeviceInfoAssignment adds the container a device is assigned to to an existing Device Info file
+	SetDeviceInfoAssignment(resourceName string, deviceID string, assignment *DeviceAssignment) error
+}
+
+// DeviceInfoTypeAuxiliary is the Device Info type of auxiliary network devices, which the
+// network-attachment-definition client does not define
+const DeviceInfoTypeAuxiliary = "auxiliary"
+
+// AuxiliaryDevice is the Device Info of an auxiliary network device, stored under the "auxiliary" key
+type AuxiliaryDevice struct {
+	Name              string `json:"name,omitempty"`
+	RdmaDevice        string `json:"rdma-device,omitempty"`
+	PfPciAddress      string `json:"pf-pci-address,omitempty"`
+	RepresentorDevice string `json:"representor-device,omitempty"`
 }
 
 // VdpaDevice is an interface to access vDPA device information
@@ -153,6 +153,34 @@ func (_m *SriovnetProvider) GetSfIndexByAuxDev(auxDev string) (int, error) {
 	return r0, r1
 }
 
+// GetSfRepresentor provides a mock function with given fields: uplink, sfNum
+func (_m *SriovnetProvider) GetSfRepresentor(uplink string, sfNum int) (string, error) {
+	ret := _m.Called(uplink, sfNum)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSfRepresentor")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) (string, error)); ok {
+		return rf(uplink, sfNum)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) string); ok {
+		r0 = rf(uplink, sfNum)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(uplink, sfNum)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUplinkRepresentor provides a mock function with given fields: vfPciAddress
 func (_m *SriovnetProvider) GetUplinkRepresentor(vfPciAddress string) (string, error) {
 	ret := _m.Called(vfPciAddress)
@@ -209,6 +237,34 @@ func (_m *SriovnetProvider) GetUplinkRepresentorFromAux(auxDev string) (string,
 	return r0, r1
 }
 
+// GetVfRepresentor provides a mock function with given fields: uplink, vfIndex
+func (_m *SriovnetProvider) GetVfRepresentor(uplink string, vfIndex int) (string, error) {
+	ret := _m.Called(uplink, vfIndex)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVfRepresentor")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) (string, error)); ok {
+		return rf(uplink, vfIndex)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) string); ok {
+		r0 = rf(uplink, vfIndex)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(uplink, vfIndex)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewSriovnetProvider creates a new instance of SriovnetProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewSriovnetProvider(t interface {
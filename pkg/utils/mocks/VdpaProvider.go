@@ -12,6 +12,72 @@ type VdpaProvider struct {
 	mock.Mock
 }
 
+// AddVdpaDevice provides a mock function with given fields: pciAddr, name
+func (_m *VdpaProvider) AddVdpaDevice(pciAddr string, name string) error {
+	ret := _m.Called(pciAddr, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddVdpaDevice")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(pciAddr, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteVdpaDevice provides a mock function with given fields: name
+func (_m *VdpaProvider) DeleteVdpaDevice(name string) error {
+	ret := _m.Called(name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteVdpaDevice")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetVdpaDeviceByAux provides a mock function with given fields: auxDev
+func (_m *VdpaProvider) GetVdpaDeviceByAux(auxDev string) (kvdpa.VdpaDevice, error) {
+	ret := _m.Called(auxDev)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVdpaDeviceByAux")
+	}
+
+	var r0 kvdpa.VdpaDevice
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (kvdpa.VdpaDevice, error)); ok {
+		return rf(auxDev)
+	}
+	if rf, ok := ret.Get(0).(func(string) kvdpa.VdpaDevice); ok {
+		r0 = rf(auxDev)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(kvdpa.VdpaDevice)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(auxDev)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetVdpaDeviceByPci provides a mock function with given fields: pciAddr
 func (_m *VdpaProvider) GetVdpaDeviceByPci(pciAddr string) (kvdpa.VdpaDevice, error) {
 	ret := _m.Called(pciAddr)
@@ -42,6 +108,36 @@ func (_m *VdpaProvider) GetVdpaDeviceByPci(pciAddr string) (kvdpa.VdpaDevice, er
 	return r0, r1
 }
 
+// ListVdpaDeviceNames provides a mock function with no fields
+func (_m *VdpaProvider) ListVdpaDeviceNames() ([]string, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListVdpaDeviceNames")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewVdpaProvider creates a new instance of VdpaProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewVdpaProvider(t interface {
@@ -5,6 +5,8 @@ package mocks
 import (
 	mock "github.com/stretchr/testify/mock"
 	netlink "github.com/vishvananda/netlink"
+
+	types "github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 )
 
 // MockNetlinkProvider is an autogenerated mock type for the NetlinkProvider type
@@ -12,6 +14,36 @@ type MockNetlinkProvider struct {
 	mock.Mock
 }
 
+// AddDevlinkSfPort provides a mock function with given fields: pfAddr, pfNum, sfNum
+func (_m *MockNetlinkProvider) AddDevlinkSfPort(pfAddr string, pfNum uint16, sfNum uint32) (*netlink.DevlinkPort, error) {
+	ret := _m.Called(pfAddr, pfNum, sfNum)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddDevlinkSfPort")
+	}
+
+	var r0 *netlink.DevlinkPort
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, uint16, uint32) (*netlink.DevlinkPort, error)); ok {
+		return rf(pfAddr, pfNum, sfNum)
+	}
+	if rf, ok := ret.Get(0).(func(string, uint16, uint32) *netlink.DevlinkPort); ok {
+		r0 = rf(pfAddr, pfNum, sfNum)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*netlink.DevlinkPort)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, uint16, uint32) error); ok {
+		r1 = rf(pfAddr, pfNum, sfNum)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetDevLinkDeviceEswitchAttrs provides a mock function with given fields: ifName
 func (_m *MockNetlinkProvider) GetDevLinkDeviceEswitchAttrs(ifName string) (*netlink.DevlinkDevEswitchAttr, error) {
 	ret := _m.Called(ifName)
@@ -72,6 +104,66 @@ func (_m *MockNetlinkProvider) GetDevlinkGetDeviceInfoByNameAsMap(bus string, de
 	return r0, r1
 }
 
+// GetDevlinkPortNumbers provides a mock function with given fields: bus, device
+func (_m *MockNetlinkProvider) GetDevlinkPortNumbers(bus string, device string) (map[uint32]types.DevlinkPortNumbers, error) {
+	ret := _m.Called(bus, device)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDevlinkPortNumbers")
+	}
+
+	var r0 map[uint32]types.DevlinkPortNumbers
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) (map[uint32]types.DevlinkPortNumbers, error)); ok {
+		return rf(bus, device)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) map[uint32]types.DevlinkPortNumbers); ok {
+		r0 = rf(bus, device)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[uint32]types.DevlinkPortNumbers)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(bus, device)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDevlinkPorts provides a mock function with given fields: bus, device
+func (_m *MockNetlinkProvider) GetDevlinkPorts(bus string, device string) ([]*netlink.DevlinkPort, error) {
+	ret := _m.Called(bus, device)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDevlinkPorts")
+	}
+
+	var r0 []*netlink.DevlinkPort
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) ([]*netlink.DevlinkPort, error)); ok {
+		return rf(bus, device)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) []*netlink.DevlinkPort); ok {
+		r0 = rf(bus, device)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*netlink.DevlinkPort)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(bus, device)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetIPv4RouteList provides a mock function with given fields: ifName
 func (_m *MockNetlinkProvider) GetIPv4RouteList(ifName string) ([]netlink.Route, error) {
 	ret := _m.Called(ifName)
@@ -132,6 +224,62 @@ func (_m *MockNetlinkProvider) GetLinkAttrs(ifName string) (*netlink.LinkAttrs,
 	return r0, r1
 }
 
+// GetLinkTypeByIndex provides a mock function with given fields: index
+func (_m *MockNetlinkProvider) GetLinkTypeByIndex(index int) (string, error) {
+	ret := _m.Called(index)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLinkTypeByIndex")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int) (string, error)); ok {
+		return rf(index)
+	}
+	if rf, ok := ret.Get(0).(func(int) string); ok {
+		r0 = rf(index)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		r1 = rf(index)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRdmaNetnsMode provides a mock function with no fields
+func (_m *MockNetlinkProvider) GetRdmaNetnsMode() (string, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRdmaNetnsMode")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // HasRdmaParam provides a mock function with given fields: bus, pciAddr
 func (_m *MockNetlinkProvider) HasRdmaParam(bus string, pciAddr string) (bool, error) {
 	ret := _m.Called(bus, pciAddr)
@@ -160,6 +308,106 @@ func (_m *MockNetlinkProvider) HasRdmaParam(bus string, pciAddr string) (bool, e
 	return r0, r1
 }
 
+// MoveLinkToHostNetns provides a mock function with given fields: ifIndex, ifName, netnsPath
+func (_m *MockNetlinkProvider) MoveLinkToHostNetns(ifIndex int, ifName string, netnsPath string) error {
+	ret := _m.Called(ifIndex, ifName, netnsPath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MoveLinkToHostNetns")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int, string, string) error); ok {
+		r0 = rf(ifIndex, ifName, netnsPath)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MoveLinkToNetns provides a mock function with given fields: ifName, netnsPath
+func (_m *MockNetlinkProvider) MoveLinkToNetns(ifName string, netnsPath string) (int, error) {
+	ret := _m.Called(ifName, netnsPath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MoveLinkToNetns")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) (int, error)); ok {
+		return rf(ifName, netnsPath)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) int); ok {
+		r0 = rf(ifName, netnsPath)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(ifName, netnsPath)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MoveRdmaDeviceToHostNetns provides a mock function with given fields: rdmaDevice, netnsPath
+func (_m *MockNetlinkProvider) MoveRdmaDeviceToHostNetns(rdmaDevice string, netnsPath string) error {
+	ret := _m.Called(rdmaDevice, netnsPath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MoveRdmaDeviceToHostNetns")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(rdmaDevice, netnsPath)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MoveRdmaDeviceToNetns provides a mock function with given fields: rdmaDevice, netnsPath
+func (_m *MockNetlinkProvider) MoveRdmaDeviceToNetns(rdmaDevice string, netnsPath string) error {
+	ret := _m.Called(rdmaDevice, netnsPath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MoveRdmaDeviceToNetns")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(rdmaDevice, netnsPath)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetDevlinkPortFunction provides a mock function with given fields: bus, device, portIndex, attrs
+func (_m *MockNetlinkProvider) SetDevlinkPortFunction(bus string, device string, portIndex uint32, attrs netlink.DevlinkPortFnSetAttrs) error {
+	ret := _m.Called(bus, device, portIndex, attrs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDevlinkPortFunction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, uint32, netlink.DevlinkPortFnSetAttrs) error); ok {
+		r0 = rf(bus, device, portIndex, attrs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewMockNetlinkProvider creates a new instance of MockNetlinkProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockNetlinkProvider(t interface {
@@ -15,16 +15,26 @@
 package utils
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"net"
 
 	nl "github.com/vishvananda/netlink"
+	nlapi "github.com/vishvananda/netlink/nl"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 )
 
 // NetlinkProvider is a wrapper type over netlink library
 type NetlinkProvider interface {
 	// GetLinkAttrs returns a net device's link attributes.
 	GetLinkAttrs(ifName string) (*nl.LinkAttrs, error)
+	// GetLinkTypeByIndex returns the type, e.g. "bond", of the net device with the given interface index
+	GetLinkTypeByIndex(index int) (string, error)
 	// GetDevLinkDeviceEswitchAttrs returns a devlink device's attributes
 	GetDevLinkDeviceEswitchAttrs(ifName string) (*nl.DevlinkDevEswitchAttr, error)
 	// GetIPv4RouteList returns a list of IPv4 routes for specified interface
@@ -33,6 +43,27 @@ type NetlinkProvider interface {
 	GetDevlinkGetDeviceInfoByNameAsMap(bus, device string) (map[string]string, error)
 	// HasRdmaParam returns true if device has "enable_rdma" param
 	HasRdmaParam(bus, pciAddr string) (bool, error)
+	// GetDevlinkPorts returns the devlink ports of a devlink device
+	GetDevlinkPorts(bus, device string) ([]*nl.DevlinkPort, error)
+	// GetDevlinkPortNumbers returns the numbers of the devlink ports of a devlink device keyed by port index
+	GetDevlinkPortNumbers(bus, device string) (map[uint32]types.DevlinkPortNumbers, error)
+	// AddDevlinkSfPort adds a subfunction port with the given SF number to a PF
+	AddDevlinkSfPort(pfAddr string, pfNum uint16, sfNum uint32) (*nl.DevlinkPort, error)
+	// SetDevlinkPortFunction sets the port function attributes of a devlink port
+	SetDevlinkPortFunction(bus, device string, portIndex uint32, attrs nl.DevlinkPortFnSetAttrs) error
+	// MoveLinkToNetns moves a net device of the host network namespace into the network namespace at netnsPath
+	// and returns its interface index in that network namespace
+	MoveLinkToNetns(ifName, netnsPath string) (int, error)
+	// MoveLinkToHostNetns moves the net device with the given interface index in the network namespace at netnsPath
+	// back into the host network namespace under the name ifName
+	MoveLinkToHostNetns(ifIndex int, ifName, netnsPath string) error
+	// GetRdmaNetnsMode returns the RDMA subsystem network namespace mode, "shared" or "exclusive"
+	GetRdmaNetnsMode() (string, error)
+	// MoveRdmaDeviceToNetns moves an RDMA device of the host network namespace into the network namespace at netnsPath
+	MoveRdmaDeviceToNetns(rdmaDevice, netnsPath string) error
+	// MoveRdmaDeviceToHostNetns moves an RDMA device of the network namespace at netnsPath back into the host
+	// network namespace
+	MoveRdmaDeviceToHostNetns(rdmaDevice, netnsPath string) error
 }
 
 type defaultNetlinkProvider struct {
@@ -40,6 +71,9 @@ type defaultNetlinkProvider struct {
 
 var netlinkProvider NetlinkProvider = &defaultNetlinkProvider{}
 
+// hostNetnsPath is the network namespace of the plugin, which runs in the host network namespace
+var hostNetnsPath = "/proc/self/ns/net"
+
 // Implement the DevlinkGetDeviceInfoByNameAsMap method
 func (defaultNetlinkProvider) GetDevlinkGetDeviceInfoByNameAsMap(bus, device string) (map[string]string, error) {
 	return nl.DevlinkGetDeviceInfoByNameAsMap(bus, device)
@@ -79,6 +113,15 @@ func (defaultNetlinkProvider) GetLinkAttrs(ifName string) (*nl.LinkAttrs, error)
 	return link.Attrs(), nil
 }
 
+// GetLinkTypeByIndex returns the type of the net device with the given interface index
+func (defaultNetlinkProvider) GetLinkTypeByIndex(index int) (string, error) {
+	link, err := nl.LinkByIndex(index)
+	if err != nil {
+		return "", fmt.Errorf("error getting net device with index %d %v", index, err)
+	}
+	return link.Type(), nil
+}
+
 // GetDevLinkDeviceEswitchAttrs returns a devlink device's attributes
 func (defaultNetlinkProvider) GetDevLinkDeviceEswitchAttrs(pfAddr string) (*nl.DevlinkDevEswitchAttr, error) {
 	dev, err := nl.DevLinkGetDeviceByName("pci", pfAddr)
@@ -88,6 +131,87 @@ func (defaultNetlinkProvider) GetDevLinkDeviceEswitchAttrs(pfAddr string) (*nl.D
 	return &(dev.Attrs.Eswitch), nil
 }
 
+// GetDevlinkPorts returns the devlink ports of a devlink device
+// equivalent to "devlink port show" filtered by the devlink device, e.g. pci/0000:08:00.0
+func (defaultNetlinkProvider) GetDevlinkPorts(bus, device string) ([]*nl.DevlinkPort, error) {
+	ports, err := nl.DevLinkGetAllPortList()
+	if err != nil {
+		return nil, fmt.Errorf("error getting devlink ports %v", err)
+	}
+	devPorts := make([]*nl.DevlinkPort, 0)
+	for _, port := range ports {
+		if port.BusName == bus && port.DeviceName == device {
+			devPorts = append(devPorts, port)
+		}
+	}
+	return devPorts, nil
+}
+
+// GetDevlinkPortNumbers returns the numbers of the devlink ports of a devlink device keyed by port index
+// equivalent to the "port", "pfnum" and "sfnum" attributes of "devlink port show"
+func (defaultNetlinkProvider) GetDevlinkPortNumbers(bus, device string) (map[uint32]types.DevlinkPortNumbers, error) {
+	family, err := nl.GenlFamilyGet(nlapi.GENL_DEVLINK_NAME)
+	if err != nil {
+		return nil, fmt.Errorf("error getting devlink family %v", err)
+	}
+	req := nlapi.NewNetlinkRequest(int(family.ID), unix.NLM_F_REQUEST|unix.NLM_F_ACK|unix.NLM_F_DUMP)
+	req.AddData(&nlapi.Genlmsg{Command: nlapi.DEVLINK_CMD_PORT_GET, Version: nlapi.GENL_DEVLINK_VERSION})
+	msgs, err := req.Execute(unix.NETLINK_GENERIC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error getting devlink ports %v", err)
+	}
+	numbers := make(map[uint32]types.DevlinkPortNumbers)
+	for _, m := range msgs {
+		attrs, err := nlapi.ParseRouteAttr(m[nlapi.SizeofGenlmsg:])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing devlink port %v", err)
+		}
+		var busName, devName string
+		var portIndex uint32
+		var portNumbers types.DevlinkPortNumbers
+		for _, a := range attrs {
+			switch a.Attr.Type {
+			case nlapi.DEVLINK_ATTR_BUS_NAME:
+				busName = string(bytes.TrimRight(a.Value, "\x00"))
+			case nlapi.DEVLINK_ATTR_DEV_NAME:
+				devName = string(bytes.TrimRight(a.Value, "\x00"))
+			case nlapi.DEVLINK_ATTR_PORT_INDEX:
+				portIndex = nlapi.NativeEndian().Uint32(a.Value)
+			case unix.DEVLINK_ATTR_PORT_NUMBER:
+				portNumbers.PortNumber = nlapi.NativeEndian().Uint32(a.Value)
+			case nlapi.DEVLINK_ATTR_PORT_PCI_PF_NUMBER:
+				portNumbers.PfNumber = nlapi.NativeEndian().Uint16(a.Value)
+			case nlapi.DEVLINK_ATTR_PORT_PCI_SF_NUMBER:
+				portNumbers.SfNumber = nlapi.NativeEndian().Uint32(a.Value)
+			}
+		}
+		if busName == bus && devName == device {
+			numbers[portIndex] = portNumbers
+		}
+	}
+	return numbers, nil
+}
+
+// AddDevlinkSfPort adds a subfunction port with the given SF number to a PF
+// equivalent to "devlink port add pci/0000:08:00.0 flavour pcisf pfnum 0 sfnum 88"
+func (defaultNetlinkProvider) AddDevlinkSfPort(pfAddr string, pfNum uint16, sfNum uint32) (*nl.DevlinkPort, error) {
+	port, err := nl.DevLinkPortAdd(pciBus, pfAddr, nlapi.DEVLINK_PORT_FLAVOUR_PCI_SF,
+		nl.DevLinkPortAddAttrs{PfNumber: pfNum, SfNumber: sfNum, SfNumberValid: true})
+	if err != nil {
+		return nil, fmt.Errorf("error adding subfunction %d to PF %s %v", sfNum, pfAddr, err)
+	}
+	return port, nil
+}
+
+// SetDevlinkPortFunction sets the port function attributes of a devlink port
+// equivalent to "devlink port function set pci/0000:08:00.0/32768 hw_addr 00:00:00:00:88:88 state active"
+func (defaultNetlinkProvider) SetDevlinkPortFunction(bus, device string, portIndex uint32, attrs nl.DevlinkPortFnSetAttrs) error {
+	if err := nl.DevlinkPortFnSet(bus, device, portIndex, attrs); err != nil {
+		return fmt.Errorf("error setting port function of devlink port %s/%s/%d %v", bus, device, portIndex, err)
+	}
+	return nil
+}
+
 // GetIPv4RouteList returns a list of IPv4 routes for specified interface
 func (defaultNetlinkProvider) GetIPv4RouteList(ifName string) ([]nl.Route, error) {
 	link, err := nl.LinkByName(ifName)
@@ -97,6 +221,137 @@ func (defaultNetlinkProvider) GetIPv4RouteList(ifName string) ([]nl.Route, error
 	return nl.RouteList(link, nl.FAMILY_V4)
 }
 
+// MoveLinkToNetns moves a net device of the host network namespace into the network namespace at netnsPath
+// and returns its interface index in that network namespace, which may differ from the one on the host. It returns
+// ErrLinkNotFound when the host network namespace has no net device named ifName.
+// equivalent to "ip link set dev <ifName> netns <netns>"
+func (defaultNetlinkProvider) MoveLinkToNetns(ifName, netnsPath string) (int, error) {
+	link, err := nl.LinkByName(ifName)
+	if err != nil {
+		var notFound nl.LinkNotFoundError
+		if errors.As(err, &notFound) {
+			return 0, fmt.Errorf("%w: %s", ErrLinkNotFound, ifName)
+		}
+		return 0, fmt.Errorf("error getting net device %s %v", ifName, err)
+	}
+	ns, err := netns.GetFromPath(netnsPath)
+	if err != nil {
+		return 0, fmt.Errorf("error opening network namespace %s %v", netnsPath, err)
+	}
+	defer ns.Close() //nolint:errcheck
+	handle, err := nl.NewHandleAt(ns)
+	if err != nil {
+		return 0, fmt.Errorf("error getting netlink handle of network namespace %s %v", netnsPath, err)
+	}
+	defer handle.Close()
+
+	if err := nl.LinkSetNsFd(link, int(ns)); err != nil {
+		return 0, fmt.Errorf("error moving net device %s to network namespace %s %v", ifName, netnsPath, err)
+	}
+	moved, err := handle.LinkByName(ifName)
+	if err != nil {
+		return 0, fmt.Errorf("error getting net device %s in network namespace %s %v", ifName, netnsPath, err)
+	}
+	return moved.Attrs().Index, nil
+}
+
+// MoveLinkToHostNetns moves the net device with the given interface index in the network namespace at netnsPath
+// back into the host network namespace under the name ifName, renaming it when it was renamed in the meantime
+func (defaultNetlinkProvider) MoveLinkToHostNetns(ifIndex int, ifName, netnsPath string) error {
+	ns, err := netns.GetFromPath(netnsPath)
+	if err != nil {
+		return fmt.Errorf("error opening network namespace %s %v", netnsPath, err)
+	}
+	defer ns.Close() //nolint:errcheck
+	hostNs, err := netns.GetFromPath(hostNetnsPath)
+	if err != nil {
+		return fmt.Errorf("error opening host network namespace %v", err)
+	}
+	defer hostNs.Close() //nolint:errcheck
+	handle, err := nl.NewHandleAt(ns)
+	if err != nil {
+		return fmt.Errorf("error getting netlink handle of network namespace %s %v", netnsPath, err)
+	}
+	defer handle.Close()
+
+	link, err := handle.LinkByIndex(ifIndex)
+	if err != nil {
+		return fmt.Errorf("error getting net device %s with index %d in network namespace %s %v", ifName, ifIndex,
+			netnsPath, err)
+	}
+	if link.Attrs().Name != ifName {
+		// a net device can only be renamed while it is down
+		if err := handle.LinkSetDown(link); err != nil {
+			return fmt.Errorf("error setting net device %s down %v", link.Attrs().Name, err)
+		}
+		if err := handle.LinkSetName(link, ifName); err != nil {
+			return fmt.Errorf("error renaming net device %s back to %s %v", link.Attrs().Name, ifName, err)
+		}
+	}
+	if err := handle.LinkSetNsFd(link, int(hostNs)); err != nil {
+		return fmt.Errorf("error moving net device %s to the host network namespace %v", ifName, err)
+	}
+	return nil
+}
+
+// GetRdmaNetnsMode returns the RDMA subsystem network namespace mode, "shared" or "exclusive"
+// equivalent to "rdma system show netns"
+func (defaultNetlinkProvider) GetRdmaNetnsMode() (string, error) {
+	mode, err := nl.RdmaSystemGetNetnsMode()
+	if err != nil {
+		return "", fmt.Errorf("error getting RDMA network namespace mode %v", err)
+	}
+	return mode, nil
+}
+
+// MoveRdmaDeviceToNetns moves an RDMA device of the host network namespace into the network namespace at netnsPath
+// equivalent to "rdma dev set <rdmaDevice> netns <netns>"
+func (defaultNetlinkProvider) MoveRdmaDeviceToNetns(rdmaDevice, netnsPath string) error {
+	link, err := nl.RdmaLinkByName(rdmaDevice)
+	if err != nil {
+		return fmt.Errorf("error getting RDMA device %s %v", rdmaDevice, err)
+	}
+	ns, err := netns.GetFromPath(netnsPath)
+	if err != nil {
+		return fmt.Errorf("error opening network namespace %s %v", netnsPath, err)
+	}
+	defer ns.Close() //nolint:errcheck
+
+	if err := nl.RdmaLinkSetNsFd(link, uint32(ns)); err != nil { //nolint:gosec
+		return fmt.Errorf("error moving RDMA device %s to network namespace %s %v", rdmaDevice, netnsPath, err)
+	}
+	return nil
+}
+
+// MoveRdmaDeviceToHostNetns moves an RDMA device of the network namespace at netnsPath back into the host
+// network namespace
+func (defaultNetlinkProvider) MoveRdmaDeviceToHostNetns(rdmaDevice, netnsPath string) error {
+	ns, err := netns.GetFromPath(netnsPath)
+	if err != nil {
+		return fmt.Errorf("error opening network namespace %s %v", netnsPath, err)
+	}
+	defer ns.Close() //nolint:errcheck
+	hostNs, err := netns.GetFromPath(hostNetnsPath)
+	if err != nil {
+		return fmt.Errorf("error opening host network namespace %v", err)
+	}
+	defer hostNs.Close() //nolint:errcheck
+	handle, err := nl.NewHandleAt(ns, unix.NETLINK_RDMA)
+	if err != nil {
+		return fmt.Errorf("error getting netlink handle of network namespace %s %v", netnsPath, err)
+	}
+	defer handle.Close()
+
+	link, err := handle.RdmaLinkByName(rdmaDevice)
+	if err != nil {
+		return fmt.Errorf("error getting RDMA device %s in network namespace %s %v", rdmaDevice, netnsPath, err)
+	}
+	if err := handle.RdmaLinkSetNsFd(link, uint32(hostNs)); err != nil { //nolint:gosec
+		return fmt.Errorf("error moving RDMA device %s to the host network namespace %v", rdmaDevice, err)
+	}
+	return nil
+}
+
 // SetNetlinkProviderInst sets a passed instance of NetlinkProvider to be used by unit test in other packages
 func SetNetlinkProviderInst(inst NetlinkProvider) {
 	netlinkProvider = inst
@@ -156,7 +411,102 @@ func devlinkGetDeviceInfoByNameAndKey(device, key string) (string, error) {
 // ErrKeyNotFound error when key is not found in the parsed response
 var ErrKeyNotFound = errors.New("key could not be found")
 
+// ErrLinkNotFound error when a net device is not found in a network namespace
+var ErrLinkNotFound = errors.New("net device could not be found")
+
 // KeyNotFoundError returns ErrKeyNotFound
 func keyNotFoundError(function, key string) error {
 	return fmt.Errorf("%s - %w: %s", function, ErrKeyNotFound, key)
 }
+
+// GetSfPorts returns the subfunction devlink ports of a PF keyed by their SF number, including the ports of
+// inactive subfunctions, which have no representor netdevice
+func GetSfPorts(pfAddr string) (map[int]*nl.DevlinkPort, error) {
+	ports, err := netlinkProvider.GetDevlinkPorts(pciBus, pfAddr)
+	if err != nil {
+		return nil, err
+	}
+	numbers, err := netlinkProvider.GetDevlinkPortNumbers(pciBus, pfAddr)
+	if err != nil {
+		return nil, err
+	}
+	sfPorts := make(map[int]*nl.DevlinkPort)
+	for _, port := range ports {
+		if port.PortFlavour != nlapi.DEVLINK_PORT_FLAVOUR_PCI_SF {
+			continue
+		}
+		portNumbers, ok := numbers[port.PortIndex]
+		if !ok {
+			klog.InfoS("Skipping SF port with unknown SF number", "pciAddress", pfAddr, "portIndex", port.PortIndex)
+			continue
+		}
+		sfPorts[int(portNumbers.SfNumber)] = port
+	}
+	return sfPorts, nil
+}
+
+// AddSf adds a subfunction with the given SF number to a PF and returns its devlink port. The PF number of the
+// subfunction is the number of the physical port of the PF.
+func AddSf(pfAddr string, sfNum int) (*nl.DevlinkPort, error) {
+	ports, err := netlinkProvider.GetDevlinkPorts(pciBus, pfAddr)
+	if err != nil {
+		return nil, err
+	}
+	numbers, err := netlinkProvider.GetDevlinkPortNumbers(pciBus, pfAddr)
+	if err != nil {
+		return nil, err
+	}
+	for _, port := range ports {
+		if port.PortFlavour != nlapi.DEVLINK_PORT_FLAVOUR_PHYSICAL {
+			continue
+		}
+		portNumbers, ok := numbers[port.PortIndex]
+		if !ok {
+			break
+		}
+		return netlinkProvider.AddDevlinkSfPort(pfAddr, uint16(portNumbers.PortNumber), uint32(sfNum)) //nolint:gosec
+	}
+	return nil, fmt.Errorf("error getting PF number of PF %s: no physical devlink port", pfAddr)
+}
+
+// IsSfFunctionSet tells whether the port function of a subfunction has the given MAC address, unless hwAddr is
+// nil, and state
+func IsSfFunctionSet(port *nl.DevlinkPort, hwAddr net.HardwareAddr, active bool) bool {
+	attrs := sfFunctionChanges(port, hwAddr, active)
+	return !attrs.HwAddrValid && !attrs.StateValid
+}
+
+// SetSfFunction sets the MAC address, unless hwAddr is nil, and the state of the port function of a subfunction.
+// Only attributes that differ from the current ones are set; it returns true when any was.
+func SetSfFunction(pfAddr string, port *nl.DevlinkPort, hwAddr net.HardwareAddr, active bool) (bool, error) {
+	attrs := sfFunctionChanges(port, hwAddr, active)
+	if !attrs.HwAddrValid && !attrs.StateValid {
+		return false, nil
+	}
+	if err := netlinkProvider.SetDevlinkPortFunction(pciBus, pfAddr, port.PortIndex, attrs); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sfFunctionChanges returns the port function attributes of a subfunction that differ from the given ones
+func sfFunctionChanges(port *nl.DevlinkPort, hwAddr net.HardwareAddr, active bool) nl.DevlinkPortFnSetAttrs {
+	current := port.Fn
+	if current == nil {
+		current = &nl.DevlinkPortFn{}
+	}
+	var state uint8 = nlapi.DEVLINK_PORT_FN_STATE_INACTIVE
+	if active {
+		state = nlapi.DEVLINK_PORT_FN_STATE_ACTIVE
+	}
+	attrs := nl.DevlinkPortFnSetAttrs{}
+	if hwAddr != nil && !bytes.Equal(current.HwAddr, hwAddr) {
+		attrs.FnAttrs.HwAddr = hwAddr
+		attrs.HwAddrValid = true
+	}
+	if current.State != state || port.Fn == nil {
+		attrs.FnAttrs.State = state
+		attrs.StateValid = true
+	}
+	return attrs
+}
@@ -2,11 +2,15 @@ package utils
 
 import (
 	"errors"
+	"net"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/mock"
+	nl "github.com/vishvananda/netlink"
+	nlapi "github.com/vishvananda/netlink/nl"
 
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils/mocks"
 )
 
@@ -99,6 +103,88 @@ var _ = Describe("NetlinkProvider Functions", func() {
 			Expect(info).To(BeNil())
 		})
 	})
+
+	Describe("GetSfPorts", func() {
+		It("should return the SF ports of a PF keyed by SF number", func() {
+			uplink := &nl.DevlinkPort{PortIndex: 65535, PortFlavour: nlapi.DEVLINK_PORT_FLAVOUR_PHYSICAL, NetdeviceName: "ens3f0"}
+			sf88 := &nl.DevlinkPort{PortIndex: 32768, PortFlavour: nlapi.DEVLINK_PORT_FLAVOUR_PCI_SF, NetdeviceName: "en3f0pf0sf88"}
+			inactive := &nl.DevlinkPort{PortIndex: 32769, PortFlavour: nlapi.DEVLINK_PORT_FLAVOUR_PCI_SF}
+			unknown := &nl.DevlinkPort{PortIndex: 32770, PortFlavour: nlapi.DEVLINK_PORT_FLAVOUR_PCI_SF}
+			mockProvider.On("GetDevlinkPorts", pciBus, "0000:03:00.0").
+				Return([]*nl.DevlinkPort{uplink, sf88, inactive, unknown}, nil)
+			mockProvider.On("GetDevlinkPortNumbers", pciBus, "0000:03:00.0").Return(map[uint32]types.DevlinkPortNumbers{
+				65535: {PortNumber: 0}, 32768: {SfNumber: 88}, 32769: {SfNumber: 1}}, nil)
+
+			ports, err := GetSfPorts("0000:03:00.0")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ports).To(Equal(map[int]*nl.DevlinkPort{88: sf88, 1: inactive}))
+		})
+
+		It("should return an error when the port numbers cannot be read", func() {
+			mockProvider.On("GetDevlinkPorts", pciBus, "0000:03:00.0").Return([]*nl.DevlinkPort{}, nil)
+			mockProvider.On("GetDevlinkPortNumbers", pciBus, "0000:03:00.0").Return(nil, errFakeNetlink)
+
+			_, err := GetSfPorts("0000:03:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("AddSf", func() {
+		It("should add an SF port using the number of the physical port of the PF as PF number", func() {
+			port := &nl.DevlinkPort{PortIndex: 32768}
+			mockProvider.On("GetDevlinkPorts", pciBus, "0000:03:00.0").Return([]*nl.DevlinkPort{
+				{PortIndex: 131071, PortFlavour: nlapi.DEVLINK_PORT_FLAVOUR_PHYSICAL}}, nil)
+			mockProvider.On("GetDevlinkPortNumbers", pciBus, "0000:03:00.0").Return(map[uint32]types.DevlinkPortNumbers{
+				131071: {PortNumber: 1}}, nil)
+			mockProvider.On("AddDevlinkSfPort", "0000:03:00.0", uint16(1), uint32(88)).Return(port, nil)
+
+			Expect(AddSf("0000:03:00.0", 88)).To(Equal(port))
+		})
+
+		It("should return an error when the PF has no physical port", func() {
+			mockProvider.On("GetDevlinkPorts", pciBus, "0000:03:00.0").Return([]*nl.DevlinkPort{}, nil)
+			mockProvider.On("GetDevlinkPortNumbers", pciBus, "0000:03:00.0").Return(map[uint32]types.DevlinkPortNumbers{}, nil)
+
+			_, err := AddSf("0000:03:00.0", 88)
+			Expect(err).To(HaveOccurred())
+			mockProvider.AssertNotCalled(GinkgoT(), "AddDevlinkSfPort", mock.Anything, mock.Anything, mock.Anything)
+		})
+	})
+
+	Describe("SetSfFunction", func() {
+		mac, _ := net.ParseMAC("00:00:00:00:88:88")
+
+		It("should not set anything when the port function is already as requested", func() {
+			port := &nl.DevlinkPort{PortIndex: 32768, Fn: &nl.DevlinkPortFn{HwAddr: mac, State: nlapi.DEVLINK_PORT_FN_STATE_ACTIVE}}
+
+			Expect(SetSfFunction("0000:03:00.0", port, mac, true)).To(BeFalse())
+			mockProvider.AssertNotCalled(GinkgoT(), "SetDevlinkPortFunction")
+		})
+
+		It("should only set the attributes that differ", func() {
+			port := &nl.DevlinkPort{PortIndex: 32768, Fn: &nl.DevlinkPortFn{State: nlapi.DEVLINK_PORT_FN_STATE_ACTIVE}}
+			expected := nl.DevlinkPortFnSetAttrs{FnAttrs: nl.DevlinkPortFn{HwAddr: mac}, HwAddrValid: true}
+			mockProvider.On("SetDevlinkPortFunction", pciBus, "0000:03:00.0", uint32(32768), expected).Return(nil)
+
+			Expect(SetSfFunction("0000:03:00.0", port, mac, true)).To(BeTrue())
+		})
+
+		It("should activate a new SF", func() {
+			port := &nl.DevlinkPort{PortIndex: 32768, Fn: &nl.DevlinkPortFn{}}
+			expected := nl.DevlinkPortFnSetAttrs{FnAttrs: nl.DevlinkPortFn{State: nlapi.DEVLINK_PORT_FN_STATE_ACTIVE}, StateValid: true}
+			mockProvider.On("SetDevlinkPortFunction", pciBus, "0000:03:00.0", uint32(32768), expected).Return(nil)
+
+			Expect(SetSfFunction("0000:03:00.0", port, nil, true)).To(BeTrue())
+		})
+
+		It("should return an error when setting the port function fails", func() {
+			port := &nl.DevlinkPort{PortIndex: 32768, Fn: &nl.DevlinkPortFn{}}
+			mockProvider.On("SetDevlinkPortFunction", pciBus, "0000:03:00.0", uint32(32768), mock.Anything).Return(errFakeNetlink)
+
+			_, err := SetSfFunction("0000:03:00.0", port, nil, true)
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
 
 func init() {
@@ -8,6 +8,8 @@ import (
 type SriovnetProvider interface {
 	GetUplinkRepresentor(vfPciAddress string) (string, error)
 	GetUplinkRepresentorFromAux(auxDev string) (string, error)
+	GetVfRepresentor(uplink string, vfIndex int) (string, error)
+	GetSfRepresentor(uplink string, sfNum int) (string, error)
 	GetPfPciFromAux(auxDev string) (string, error)
 	GetSfIndexByAuxDev(auxDev string) (int, error)
 	GetNetDevicesFromAux(auxDev string) ([]string, error)
@@ -38,6 +40,14 @@ func (defaultSriovnetProvider) GetUplinkRepresentorFromAux(auxDev string) (strin
 	return sriovnet.GetUplinkRepresentorFromAux(auxDev)
 }
 
+func (defaultSriovnetProvider) GetVfRepresentor(uplink string, vfIndex int) (string, error) {
+	return sriovnet.GetVfRepresentor(uplink, vfIndex)
+}
+
+func (defaultSriovnetProvider) GetSfRepresentor(uplink string, sfNum int) (string, error) {
+	return sriovnet.GetSfRepresentor(uplink, sfNum)
+}
+
 func (defaultSriovnetProvider) GetPfPciFromAux(auxDev string) (string, error) {
 	return sriovnet.GetPfPciFromAux(auxDev)
 }
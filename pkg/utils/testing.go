@@ -65,6 +65,11 @@ func (fs *FakeFilesystem) Use() func() {
 
 	sysBusPci = path.Join(fs.RootDir, "/sys/bus/pci/devices")
 	sysBusAux = path.Join(fs.RootDir, "/sys/bus/auxiliary/devices")
+	sysBusPciDrivers = path.Join(fs.RootDir, "/sys/bus/pci/drivers")
+	sysBusVdpa = path.Join(fs.RootDir, "/sys/bus/vdpa/devices")
+	sysBusVdpaDrivers = path.Join(fs.RootDir, "/sys/bus/vdpa/drivers")
+	sysClassNet = path.Join(fs.RootDir, "/sys/class/net")
+	sysClassRdma = path.Join(fs.RootDir, "/sys/class/infiniband")
 
 	return func() {
 		// remove temporary fake fs
@@ -115,5 +120,8 @@ func SetDefaultMockNetlinkProvider() {
 	mockProvider.
 		On("GetDevlinkGetDeviceInfoByNameAsMap", mock.AnythingOfType("string")).
 		Return(map[string]string{"someKey": "someValue"}, nil)
+	mockProvider.
+		On("GetRdmaNetnsMode").
+		Return("shared", nil)
 	SetNetlinkProviderInst(mockProvider)
 }
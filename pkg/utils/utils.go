@@ -23,28 +23,36 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
 )
 
 var (
-	sysBusPci = "/sys/bus/pci/devices"
-	// golangci-lint doesn't see it is used in the testing.go
-	//nolint: unused
-	sysBusAux = "/sys/bus/auxiliary/devices"
-	devDir    = "/dev"
+	sysBusPci         = "/sys/bus/pci/devices"
+	sysBusAux         = "/sys/bus/auxiliary/devices"
+	sysBusPciDrivers  = "/sys/bus/pci/drivers"
+	sysBusVdpa        = "/sys/bus/vdpa/devices"
+	sysBusVdpaDrivers = "/sys/bus/vdpa/drivers"
+	sysClassNet       = "/sys/class/net"
+	sysClassRdma      = "/sys/class/infiniband"
+	devDir            = "/dev"
 )
 
 const (
-	totalVfFile          = "sriov_totalvfs"
-	configuredVfFile     = "sriov_numvfs"
-	eswitchModeSwitchdev = "switchdev"
-	classIDBaseInt       = 16
-	classIDBitSize       = 64
-	maxVendorName        = 20
-	maxProductName       = 40
-	ellipsis             = "..."
+	totalVfFile      = "sriov_totalvfs"
+	configuredVfFile = "sriov_numvfs"
+	classIDBaseInt   = 16
+	classIDBitSize   = 64
+	maxVendorName    = 20
+	maxProductName   = 40
+	ellipsis         = "..."
 )
 
+// zeroGid is the value of unused entries of an RDMA port GID table
+const zeroGid = "0000:0000:0000:0000:0000:0000:0000:0000"
+
+// EswitchModeSwitchdev is the eswitch mode of PFs whose VFs and SFs have representor netdevices
+const EswitchModeSwitchdev = "switchdev"
+
 // DetectPluginWatchMode returns true if plugins registry directory exist
 func DetectPluginWatchMode(sockDir string) bool {
 	if _, err := os.Stat(sockDir); err != nil {
@@ -80,11 +88,11 @@ func GetPfName(pciAddr string) (string, error) {
 		// If device doesn't support eswitch mode query or doesn't have sriov enabled,
 		// fall back to the default implementation
 		if err == nil || strings.Contains(strings.ToLower(err.Error()), "error getting devlink device attributes for net device") {
-			glog.Infof("Devlink query for eswitch mode is not supported for device %s. %v", pciAddr, err)
+			klog.V(2).InfoS("Devlink query for eswitch mode is not supported", "pciAddress", pciAddr, "err", err)
 		} else {
 			return "", err
 		}
-	} else if pfEswitchMode == eswitchModeSwitchdev {
+	} else if pfEswitchMode == EswitchModeSwitchdev {
 		name, err := GetSriovnetProvider().GetUplinkRepresentor(pciAddr)
 		if err != nil {
 			return "", err
@@ -164,6 +172,57 @@ func GetVFconfigured(pf string) int {
 	return numConfiguredVFs
 }
 
+// SetVFconfigured sets the number of VFs of a PF. The kernel only accepts a new number of VFs when none are
+// configured, hence existing VFs are removed first when the number changes.
+func SetVFconfigured(pf string, numVfs int) error {
+	if !IsSriovPF(pf) {
+		return fmt.Errorf("device %s is not an SR-IOV PF", pf)
+	}
+	if capacity := GetSriovVFcapacity(pf); numVfs > capacity {
+		return fmt.Errorf("device %s supports at most %d VFs, %d requested", pf, capacity, numVfs)
+	}
+	current := GetVFconfigured(pf)
+	if current == numVfs {
+		return nil
+	}
+	numVfsFile := filepath.Join(sysBusPci, pf, configuredVfFile)
+	if current > 0 {
+		if err := writeSysfsFile(numVfsFile, "0"); err != nil {
+			return fmt.Errorf("error removing the %d VFs of device %s: %v", current, pf, err)
+		}
+	}
+	if numVfs > 0 {
+		if err := writeSysfsFile(numVfsFile, strconv.Itoa(numVfs)); err != nil {
+			return fmt.Errorf("error creating %d VFs on device %s: %v", numVfs, pf, err)
+		}
+	}
+	return nil
+}
+
+// GetPciAddrFromNetName returns the PCI address of the device of a network interface
+func GetPciAddrFromNetName(ifName string) (string, error) {
+	devLink := filepath.Join(sysClassNet, ifName, "device")
+	target, err := os.Readlink(devLink)
+	if err != nil {
+		return "", fmt.Errorf("error getting PCI device of network interface %s %v", ifName, err)
+	}
+	return filepath.Base(target), nil
+}
+
+// GetPciAddrFromAcpiIndex returns the PCI address of the device with the given ACPI index
+func GetPciAddrFromAcpiIndex(acpiIndex string) (string, error) {
+	devDirs, err := os.ReadDir(sysBusPci)
+	if err != nil {
+		return "", fmt.Errorf("error reading PCI devices %v", err)
+	}
+	for _, dir := range devDirs {
+		if index, err := GetAcpiIndex(dir.Name()); err == nil && index == acpiIndex {
+			return dir.Name(), nil
+		}
+	}
+	return "", fmt.Errorf("no PCI device with ACPI index %s found", acpiIndex)
+}
+
 // GetVFList returns a List containing PCI addr for all VF discovered in a given PF
 func GetVFList(pf string) (vfList []string, err error) {
 	vfList = make([]string, 0)
@@ -249,6 +308,21 @@ func GetDevNode(pciAddr string) int {
 	return numNode
 }
 
+// GetLinkSpeed returns the link speed of a network interface in Mb/s, an error when it is unknown, e.g. because
+// the link is down
+func GetLinkSpeed(ifName string) (int, error) {
+	speedFile := filepath.Join(sysClassNet, ifName, "speed")
+	data, err := os.ReadFile(speedFile)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read link speed of %s: %v", ifName, err)
+	}
+	speed, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || speed <= 0 {
+		return 0, fmt.Errorf("unknown link speed of %s: %q", ifName, strings.TrimSpace(string(data)))
+	}
+	return speed, nil
+}
+
 // IsNetlinkStatusUp returns 'false' if 'operstate' is not "up" for a Linux network device.
 // This function will only return 'false' if the 'operstate' file of the device is readable
 // and holds value anything other than "up". Or else we assume link is up.
@@ -282,6 +356,36 @@ func ValidPciAddr(addr string) (string, error) {
 	return "", fmt.Errorf("invalid pci address %s", addr)
 }
 
+// IsPciDevicePresent returns true if the PCI device is present in sysfs
+func IsPciDevicePresent(pciAddr string) bool {
+	return deviceExist(pciAddr) == nil
+}
+
+// IsAuxDevicePresent returns true if the auxiliary device is present in sysfs
+func IsAuxDevicePresent(auxDev string) bool {
+	_, err := os.Lstat(filepath.Join(sysBusAux, auxDev))
+	return err == nil
+}
+
+// ListHostDevices returns the names of all PCI and auxiliary devices present in sysfs
+func ListHostDevices() ([]string, error) {
+	devices := make([]string, 0)
+	for _, bus := range []string{sysBusPci, sysBusAux} {
+		entries, err := os.ReadDir(bus)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// the auxiliary bus is missing on hosts without auxiliary device support
+				continue
+			}
+			return nil, fmt.Errorf("error listing devices in %s: %v", bus, err)
+		}
+		for _, entry := range entries {
+			devices = append(devices, entry.Name())
+		}
+	}
+	return devices, nil
+}
+
 func deviceExist(addr string) error {
 	devPath := filepath.Join(sysBusPci, addr)
 	_, err := os.Lstat(devPath)
@@ -356,6 +460,23 @@ func GetVFIODeviceFile(dev string) (devFileHost, devFileContainer string, err er
 	return devFileHost, devFileContainer, err
 }
 
+// GetVFIOCdevFile returns the VFIO device cdev, e.g. /dev/vfio/devices/vfio0, of a vfio-pci bound PCI device.
+// Kernels without VFIO cdev support do not expose it.
+func GetVFIOCdevFile(dev string) (string, error) {
+	vfioDevDir := filepath.Join(sysBusPci, dev, "vfio-dev")
+	entries, err := os.ReadDir(vfioDevDir)
+	if err != nil {
+		return "", fmt.Errorf("GetVFIOCdevFile(): unable to read vfio-dev directory of device %s: %v", dev, err)
+	}
+	for _, entry := range entries {
+		// the cdev is named noiommu-vfioN when the device is used without IOMMU
+		if strings.HasPrefix(entry.Name(), "vfio") || strings.HasPrefix(entry.Name(), "noiommu-vfio") {
+			return filepath.Join(devDir, "vfio", "devices", entry.Name()), nil
+		}
+	}
+	return "", fmt.Errorf("GetVFIOCdevFile(): no VFIO cdev found for device %s", dev)
+}
+
 // GetUIODeviceFile returns a vfio device files for vfio-pci bound PCI device's PCI address
 func GetUIODeviceFile(dev string) (devFile string, err error) {
 	vfDir := filepath.Join(sysBusPci, dev, "uio")
@@ -408,6 +529,72 @@ func GetDriverName(pciAddr string) (string, error) {
 	return filepath.Base(driverInfo), nil
 }
 
+// BindDriver binds a PCI device to the given driver. The driver is set as driver_override of the device, which
+// keeps the device from being probed by any other driver, before the device is unbound from its current driver
+// and probed again.
+func BindDriver(pciAddr, driver string) error {
+	if _, err := os.Stat(filepath.Join(sysBusPciDrivers, driver)); err != nil {
+		return fmt.Errorf("driver %s is not loaded: %v", driver, err)
+	}
+	overrideFile := filepath.Join(sysBusPci, pciAddr, "driver_override")
+	if err := writeSysfsFile(overrideFile, driver); err != nil {
+		return fmt.Errorf("error setting driver_override of device %s: %v", pciAddr, err)
+	}
+	unbound := false
+	if current, err := GetDriverName(pciAddr); err == nil && current != driver {
+		unbindFile := filepath.Join(sysBusPci, pciAddr, "driver", "unbind")
+		if err := writeSysfsFile(unbindFile, pciAddr); err != nil {
+			return restoreDriver(pciAddr, false,
+				fmt.Errorf("error unbinding device %s from driver %s: %v", pciAddr, current, err))
+		}
+		unbound = true
+	}
+	probeFile := filepath.Join(filepath.Dir(sysBusPciDrivers), "drivers_probe")
+	if err := writeSysfsFile(probeFile, pciAddr); err != nil {
+		return restoreDriver(pciAddr, unbound, fmt.Errorf("error probing driver of device %s: %v", pciAddr, err))
+	}
+
+	current, err := GetDriverName(pciAddr)
+	if err != nil {
+		return restoreDriver(pciAddr, unbound, err)
+	}
+	if current != driver {
+		return restoreDriver(pciAddr, unbound,
+			fmt.Errorf("device %s is bound to driver %s instead of %s", pciAddr, current, driver))
+	}
+	return nil
+}
+
+// restoreDriver clears the driver_override of a device that failed to bind and, when the device got unbound
+// from its driver, probes it again so that it gets back to its original driver. It returns the bind error.
+func restoreDriver(pciAddr string, unbound bool, bindErr error) error {
+	overrideFile := filepath.Join(sysBusPci, pciAddr, "driver_override")
+	if err := writeSysfsFile(overrideFile, "\n"); err != nil {
+		return fmt.Errorf("%v, error clearing driver_override: %v", bindErr, err)
+	}
+	if !unbound {
+		return bindErr
+	}
+	probeFile := filepath.Join(filepath.Dir(sysBusPciDrivers), "drivers_probe")
+	if err := writeSysfsFile(probeFile, pciAddr); err != nil {
+		return fmt.Errorf("%v, error probing the original driver: %v", bindErr, err)
+	}
+	return bindErr
+}
+
+// writeSysfsFile writes a value to an existing sysfs attribute
+func writeSysfsFile(path, value string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(value); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // GetAcpiIndex returns the ACPI index attached to a pci device from its pci address
 func GetAcpiIndex(pciAddr string) (string, error) {
 	acpiIndexLink := filepath.Join(sysBusPci, pciAddr, "acpi_index")
@@ -468,6 +655,9 @@ func GetPfEswitchMode(pciAddr string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("error getting PF PCI address for device %s %v", pciAddr, err)
 	}
+	if pfAddr == "" {
+		pfAddr = pciAddr
+	}
 	devLinkDeviceAttrs, err := GetNetlinkProvider().GetDevLinkDeviceEswitchAttrs(pfAddr)
 	if err != nil {
 		return "", err
@@ -498,12 +688,12 @@ func HasDefaultRoute(pciAddr string) (bool, error) {
 		for _, ifName := range ifNames {
 			routes, err := GetNetlinkProvider().GetIPv4RouteList(ifName) // IPv6 routes: all interface has at least one link local route entry
 			if err != nil {
-				glog.Errorf("failed to get routes for interface: %s, %q", ifName, err)
+				klog.ErrorS(err, "Failed to get routes for interface", "interface", ifName)
 				continue
 			}
 			for _, r := range routes {
 				if r.Dst == nil {
-					glog.Infof("excluding interface %s:  default route found: %+v", ifName, r)
+					klog.InfoS("Excluding interface, default route found", "interface", ifName, "route", r.String())
 					return true, nil
 				}
 			}
@@ -573,3 +763,65 @@ func GetPKey(pciAddr string) (string, error) {
 
 	return pKey, nil
 }
+
+// GetRdmaFirstPort returns the lowest port number of an RDMA device
+func GetRdmaFirstPort(rdmaDevice string) (string, error) {
+	entries, err := os.ReadDir(filepath.Join(sysClassRdma, rdmaDevice, "ports"))
+	if err != nil {
+		return "", fmt.Errorf("error reading ports of RDMA device %s: %v", rdmaDevice, err)
+	}
+	first := -1
+	for _, e := range entries {
+		if n, err := strconv.Atoi(e.Name()); err == nil && (first < 0 || n < first) {
+			first = n
+		}
+	}
+	if first < 0 {
+		return "", fmt.Errorf("no ports found for RDMA device %s", rdmaDevice)
+	}
+	return strconv.Itoa(first), nil
+}
+
+// GetRdmaLinkLayer returns the link layer of a port of an RDMA device, "InfiniBand" or "Ethernet"
+func GetRdmaLinkLayer(rdmaDevice, port string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(sysClassRdma, rdmaDevice, "ports", port, "link_layer"))
+	if err != nil {
+		return "", fmt.Errorf("error reading link layer of RDMA device %s port %s: %v", rdmaDevice, port, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// GetRdmaGids returns the used entries of the GID table of a port of an RDMA device keyed by GID index
+func GetRdmaGids(rdmaDevice, port string) (map[int]string, error) {
+	gidsDir := filepath.Join(sysClassRdma, rdmaDevice, "ports", port, "gids")
+	entries, err := os.ReadDir(gidsDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading GID table of RDMA device %s port %s: %v", rdmaDevice, port, err)
+	}
+	gids := make(map[int]string)
+	for _, e := range entries {
+		index, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(gidsDir, e.Name()))
+		if err != nil {
+			// reading unused entries fails on some drivers
+			continue
+		}
+		if gid := strings.TrimSpace(string(data)); gid != "" && gid != zeroGid {
+			gids[index] = gid
+		}
+	}
+	return gids, nil
+}
+
+// GetRdmaGidType returns the RoCE type of a GID table entry of a port of an RDMA device, e.g. "RoCE v2",
+// or an empty string if unknown, e.g. for InfiniBand ports
+func GetRdmaGidType(rdmaDevice, port string, index int) string {
+	data, err := os.ReadFile(filepath.Join(sysClassRdma, rdmaDevice, "ports", port, "gid_attrs", "types", strconv.Itoa(index)))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -179,6 +181,34 @@ var _ = Describe("In the utils package", func() {
 		),
 	)
 
+	DescribeTable("getting link speed of interface",
+		func(fs *FakeFilesystem, ifName string, expected int, shouldFail bool) {
+			defer fs.Use()()
+			speed, err := GetLinkSpeed(ifName)
+			if shouldFail {
+				Expect(err).To(HaveOccurred())
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(speed).To(Equal(expected))
+		},
+		Entry("speed file doesn't exist", &FakeFilesystem{}, "eth0", 0, true),
+		Entry("link is down",
+			&FakeFilesystem{
+				Dirs:  []string{"sys/class/net/eth0"},
+				Files: map[string][]byte{"sys/class/net/eth0/speed": []byte("-1\n")},
+			},
+			"eth0", 0, true,
+		),
+		Entry("link speed is known",
+			&FakeFilesystem{
+				Dirs:  []string{"sys/class/net/eth0"},
+				Files: map[string][]byte{"sys/class/net/eth0/speed": []byte("25000\n")},
+			},
+			"eth0", 25000, false,
+		),
+	)
+
 	DescribeTable("getting NUMA node of device",
 		func(fs *FakeFilesystem, pciAddr string, expected int) {
 			defer fs.Use()()
@@ -277,6 +307,50 @@ var _ = Describe("In the utils package", func() {
 		),
 	)
 
+	DescribeTable("checking whether PCI device is present",
+		func(fs *FakeFilesystem, addr string, expected bool) {
+			defer fs.Use()()
+			Expect(IsPciDevicePresent(addr)).To(Equal(expected))
+		},
+		Entry("device directory exists",
+			&FakeFilesystem{Dirs: []string{"sys/bus/pci/devices/0000:00:00.0"}}, "0000:00:00.0", true,
+		),
+		Entry("device directory doesn't exist",
+			&FakeFilesystem{Dirs: []string{"sys/bus/pci/devices/0000:00:00.0"}}, "0000:00:00.1", false,
+		),
+	)
+
+	DescribeTable("checking whether auxiliary device is present",
+		func(fs *FakeFilesystem, auxDev string, expected bool) {
+			defer fs.Use()()
+			Expect(IsAuxDevicePresent(auxDev)).To(Equal(expected))
+		},
+		Entry("device directory exists",
+			&FakeFilesystem{Dirs: []string{"sys/bus/auxiliary/devices/mlx5_core.sf.4"}}, "mlx5_core.sf.4", true,
+		),
+		Entry("device directory doesn't exist",
+			&FakeFilesystem{Dirs: []string{"sys/bus/auxiliary/devices/mlx5_core.sf.4"}}, "mlx5_core.sf.5", false,
+		),
+	)
+
+	DescribeTable("listing host devices",
+		func(fs *FakeFilesystem, expected []string) {
+			defer fs.Use()()
+			devices, err := ListHostDevices()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devices).To(Equal(expected))
+		},
+		Entry("no devices", &FakeFilesystem{Dirs: []string{"sys/bus/pci/devices"}}, []string{}),
+		Entry("PCI devices only",
+			&FakeFilesystem{Dirs: []string{"sys/bus/pci/devices/0000:01:00.1", "sys/bus/pci/devices/0000:01:00.0"}},
+			[]string{"0000:01:00.0", "0000:01:00.1"},
+		),
+		Entry("PCI and auxiliary devices",
+			&FakeFilesystem{Dirs: []string{"sys/bus/pci/devices/0000:01:00.0", "sys/bus/auxiliary/devices/mlx5_core.sf.4"}},
+			[]string{"0000:01:00.0", "mlx5_core.sf.4"},
+		),
+	)
+
 	DescribeTable("checking whether SR-IOV is configured",
 		func(fs *FakeFilesystem, addr string, expected bool) {
 			defer fs.Use()()
@@ -329,6 +403,31 @@ var _ = Describe("In the utils package", func() {
 		),
 	)
 
+	DescribeTable("getting VFIO cdev",
+		func(fs *FakeFilesystem, device, expected string, shouldFail bool) {
+			defer fs.Use()()
+			actual, err := GetVFIOCdevFile(device)
+			Expect(actual).To(Equal(expected))
+			assertShouldFail(err, shouldFail)
+		},
+		Entry("kernel without VFIO cdev support",
+			&FakeFilesystem{Dirs: []string{"sys/bus/pci/devices/0000:01:10.0"}},
+			"0000:01:10.0", "", true,
+		),
+		Entry("empty vfio-dev directory",
+			&FakeFilesystem{Dirs: []string{"sys/bus/pci/devices/0000:01:10.0/vfio-dev"}},
+			"0000:01:10.0", "", true,
+		),
+		Entry("VFIO cdev path is returned",
+			&FakeFilesystem{Dirs: []string{"sys/bus/pci/devices/0000:01:10.0/vfio-dev/vfio3"}},
+			"0000:01:10.0", "/dev/vfio/devices/vfio3", false,
+		),
+		Entry("noiommu VFIO cdev path is returned",
+			&FakeFilesystem{Dirs: []string{"sys/bus/pci/devices/0000:01:10.0/vfio-dev/noiommu-vfio0"}},
+			"0000:01:10.0", "/dev/vfio/devices/noiommu-vfio0", false,
+		),
+	)
+
 	DescribeTable("getting UIO device file",
 		func(fs *FakeFilesystem, device, expected string, shouldFail bool) {
 			defer fs.Use()()
@@ -375,6 +474,115 @@ var _ = Describe("In the utils package", func() {
 		),
 	)
 
+	Describe("setting the number of VFs of a PF", func() {
+		newFs := func(numVfs string) *FakeFilesystem {
+			return &FakeFilesystem{
+				Dirs: []string{"sys/bus/pci/devices/0000:01:00.0"},
+				Files: map[string][]byte{
+					"sys/bus/pci/devices/0000:01:00.0/sriov_totalvfs": []byte("8"),
+					"sys/bus/pci/devices/0000:01:00.0/sriov_numvfs":   []byte(numVfs),
+				},
+			}
+		}
+		It("should fail for a device that is not an SR-IOV PF", func() {
+			defer (&FakeFilesystem{Dirs: []string{"sys/bus/pci/devices/0000:01:00.0"}}).Use()()
+			Expect(SetVFconfigured("0000:01:00.0", 2)).To(MatchError("device 0000:01:00.0 is not an SR-IOV PF"))
+		})
+		It("should fail when more VFs than supported are requested", func() {
+			defer newFs("0").Use()()
+			Expect(SetVFconfigured("0000:01:00.0", 16)).To(MatchError("device 0000:01:00.0 supports at most 8 VFs, 16 requested"))
+		})
+		It("should remove the existing VFs before creating the requested ones", func() {
+			defer newFs("2").Use()()
+			Expect(SetVFconfigured("0000:01:00.0", 4)).To(Succeed())
+			Expect(GetVFconfigured("0000:01:00.0")).To(Equal(4))
+		})
+		It("should remove all VFs", func() {
+			defer newFs("2").Use()()
+			Expect(SetVFconfigured("0000:01:00.0", 0)).To(Succeed())
+			Expect(GetVFconfigured("0000:01:00.0")).To(Equal(0))
+		})
+	})
+
+	Describe("getting the PCI address of a PF", func() {
+		It("should resolve the device of a network interface", func() {
+			fs := &FakeFilesystem{
+				Dirs:     []string{"sys/bus/pci/devices/0000:01:00.0", "sys/class/net/ens1f0"},
+				Symlinks: map[string]string{"sys/class/net/ens1f0/device": "../../../bus/pci/devices/0000:01:00.0"},
+			}
+			defer fs.Use()()
+			Expect(GetPciAddrFromNetName("ens1f0")).To(Equal("0000:01:00.0"))
+			_, err := GetPciAddrFromNetName("ens1f1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should find the device with an ACPI index", func() {
+			fs := &FakeFilesystem{
+				Dirs: []string{"sys/bus/pci/devices/0000:01:00.0", "sys/bus/pci/devices/0000:02:00.0"},
+				Files: map[string][]byte{
+					"sys/bus/pci/devices/0000:01:00.0/acpi_index": []byte("101\n"),
+					"sys/bus/pci/devices/0000:02:00.0/acpi_index": []byte("102\n"),
+				},
+			}
+			defer fs.Use()()
+			Expect(GetPciAddrFromAcpiIndex("102")).To(Equal("0000:02:00.0"))
+			_, err := GetPciAddrFromAcpiIndex("103")
+			Expect(err).To(MatchError("no PCI device with ACPI index 103 found"))
+		})
+	})
+
+	Describe("binding a device to a driver", func() {
+		It("should fail when the driver is not loaded", func() {
+			defer (&FakeFilesystem{Dirs: []string{"sys/bus/pci/devices/0000:01:10.0"}}).Use()()
+			Expect(BindDriver("0000:01:10.0", "vfio-pci")).To(MatchError(ContainSubstring("driver vfio-pci is not loaded")))
+		})
+		It("should unbind the current driver and probe the device with the driver override", func() {
+			fs := &FakeFilesystem{
+				Dirs: []string{"sys/bus/pci/devices/0000:01:10.0", "sys/bus/pci/drivers/iavf", "sys/bus/pci/drivers/vfio-pci"},
+				Files: map[string][]byte{
+					"sys/bus/pci/devices/0000:01:10.0/driver_override": nil,
+					"sys/bus/pci/drivers/iavf/unbind":                  nil,
+					"sys/bus/pci/drivers_probe":                        nil,
+				},
+				Symlinks: map[string]string{"sys/bus/pci/devices/0000:01:10.0/driver": "../../../../bus/pci/drivers/iavf"},
+			}
+			defer fs.Use()()
+
+			// the fake filesystem does not rebind the device, hence it is still bound to iavf after probing
+			err := BindDriver("0000:01:10.0", "vfio-pci")
+			Expect(err).To(MatchError("device 0000:01:10.0 is bound to driver iavf instead of vfio-pci"))
+
+			// the driver_override is cleared again and the device probed for its original driver
+			Expect(os.ReadFile(filepath.Join(fs.RootDir, "sys/bus/pci/devices/0000:01:10.0/driver_override"))).To(BeEquivalentTo("\n"))
+			Expect(os.ReadFile(filepath.Join(fs.RootDir, "sys/bus/pci/drivers/iavf/unbind"))).To(BeEquivalentTo("0000:01:10.0"))
+			Expect(os.ReadFile(filepath.Join(fs.RootDir, "sys/bus/pci/drivers_probe"))).To(BeEquivalentTo("0000:01:10.0"))
+		})
+		It("should clear the driver_override when probing the device fails", func() {
+			fs := &FakeFilesystem{
+				Dirs: []string{"sys/bus/pci/devices/0000:01:10.0", "sys/bus/pci/drivers/vfio-pci"},
+				Files: map[string][]byte{
+					"sys/bus/pci/devices/0000:01:10.0/driver_override": nil,
+				},
+			}
+			defer fs.Use()()
+
+			Expect(BindDriver("0000:01:10.0", "vfio-pci")).To(MatchError(ContainSubstring("error probing driver of device 0000:01:10.0")))
+			Expect(os.ReadFile(filepath.Join(fs.RootDir, "sys/bus/pci/devices/0000:01:10.0/driver_override"))).To(BeEquivalentTo("\n"))
+		})
+		It("should succeed when the device ends up bound to the driver", func() {
+			fs := &FakeFilesystem{
+				Dirs: []string{"sys/bus/pci/devices/0000:01:10.0", "sys/bus/pci/drivers/vfio-pci"},
+				Files: map[string][]byte{
+					"sys/bus/pci/devices/0000:01:10.0/driver_override": nil,
+					"sys/bus/pci/drivers_probe":                        nil,
+				},
+				Symlinks: map[string]string{"sys/bus/pci/devices/0000:01:10.0/driver": "../../../../bus/pci/drivers/vfio-pci"},
+			}
+			defer fs.Use()()
+
+			Expect(BindDriver("0000:01:10.0", "vfio-pci")).To(Succeed())
+		})
+	})
+
 	DescribeTable("getting interface names",
 		func(fs *FakeFilesystem, device string, expected []string, shouldFail bool) {
 			defer fs.Use()()
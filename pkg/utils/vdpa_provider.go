@@ -2,14 +2,24 @@ package utils
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
-	"github.com/golang/glog"
 	vdpa "github.com/k8snetworkplumbingwg/govdpa/pkg/kvdpa"
+	"k8s.io/klog/v2"
 )
 
 // VdpaProvider is a wrapper type over go-vdpa library
 type VdpaProvider interface {
 	GetVdpaDeviceByPci(pciAddr string) (vdpa.VdpaDevice, error)
+	// GetVdpaDeviceByAux returns the vdpa device of an auxiliary device, e.g. mlx5_core.sf.4
+	GetVdpaDeviceByAux(auxDev string) (vdpa.VdpaDevice, error)
+	// AddVdpaDevice creates a vdpa device with the given name on the management device of a PCI device
+	AddVdpaDevice(pciAddr, name string) error
+	// DeleteVdpaDevice deletes the vdpa device with the given name
+	DeleteVdpaDevice(name string) error
+	// ListVdpaDeviceNames returns the names of all the vdpa devices
+	ListVdpaDeviceNames() ([]string, error)
 }
 
 type defaultVdpaProvider struct {
@@ -39,7 +49,105 @@ func (defaultVdpaProvider) GetVdpaDeviceByPci(pciAddr string) (vdpa.VdpaDevice,
 		return nil, fmt.Errorf("no vdpa device associated to pciAddress %s", pciAddr)
 	}
 	if numVdpaDevices > 1 {
-		glog.Infof("More than one vDPA device found for pciAddress %s, returning the first one", pciAddr)
+		klog.InfoS("More than one vDPA device found, returning the first one", "pciAddress", pciAddr)
 	}
 	return vdpaDevices[0], nil
 }
+
+// GetVdpaDeviceByAux returns the vdpa device of an auxiliary device, whose vdpa management device is
+// auxiliary/<auxDev>
+func (defaultVdpaProvider) GetVdpaDeviceByAux(auxDev string) (vdpa.VdpaDevice, error) {
+	vdpaDevices, err := vdpa.GetVdpaDevicesByMgmtDev("auxiliary", auxDev)
+	if err != nil {
+		return nil, err
+	}
+	if len(vdpaDevices) == 0 {
+		return nil, fmt.Errorf("no vdpa device associated to auxiliary device %s", auxDev)
+	}
+	if len(vdpaDevices) > 1 {
+		klog.InfoS("More than one vDPA device found, returning the first one", "deviceID", auxDev)
+	}
+	return vdpaDevices[0], nil
+}
+
+// AddVdpaDevice creates a vdpa device with the given name on the management device of a PCI device
+// equivalent to "vdpa dev add name <name> mgmtdev pci/<pciAddr>"
+func (defaultVdpaProvider) AddVdpaDevice(pciAddr, name string) error {
+	if err := vdpa.AddVdpaDevice("pci/"+pciAddr, name); err != nil {
+		return fmt.Errorf("error adding vdpa device %s to %s %v", name, pciAddr, err)
+	}
+	return nil
+}
+
+// DeleteVdpaDevice deletes the vdpa device with the given name
+// equivalent to "vdpa dev del <name>"
+func (defaultVdpaProvider) DeleteVdpaDevice(name string) error {
+	if err := vdpa.DeleteVdpaDevice(name); err != nil {
+		return fmt.Errorf("error deleting vdpa device %s %v", name, err)
+	}
+	return nil
+}
+
+// ListVdpaDeviceNames returns the names of all the vdpa devices
+// equivalent to "vdpa dev show"
+func (defaultVdpaProvider) ListVdpaDeviceNames() ([]string, error) {
+	vdpaDevices, err := vdpa.ListVdpaDevices()
+	if err != nil {
+		return nil, fmt.Errorf("error listing vdpa devices %v", err)
+	}
+	names := make([]string, 0, len(vdpaDevices))
+	for _, vdpaDev := range vdpaDevices {
+		names = append(names, vdpaDev.Name())
+	}
+	return names, nil
+}
+
+// CreateVdpaDevice creates a vdpa device with the given name on a PCI device and binds it to the given vdpa bus
+// driver, e.g. vhost_vdpa, in case the kernel probed it with another one. The device is deleted again when it
+// cannot be bound to the driver.
+func CreateVdpaDevice(pciAddr, name, driver string) error {
+	driverDir := filepath.Join(sysBusVdpaDrivers, driver)
+	if _, err := os.Stat(driverDir); err != nil {
+		return fmt.Errorf("vdpa driver %s is not loaded %v", driver, err)
+	}
+	if err := vdpaProvider.AddVdpaDevice(pciAddr, name); err != nil {
+		return err
+	}
+	if err := bindVdpaDriver(name, driver); err != nil {
+		if delErr := vdpaProvider.DeleteVdpaDevice(name); delErr != nil {
+			klog.ErrorS(delErr, "Unable to delete vdpa device", "vdpaDevice", name)
+		}
+		return err
+	}
+	return nil
+}
+
+// bindVdpaDriver binds a vdpa device to the given vdpa bus driver unless it is bound to it already
+func bindVdpaDriver(name, driver string) error {
+	current := ""
+	if target, err := os.Readlink(filepath.Join(sysBusVdpa, name, "driver")); err == nil {
+		current = filepath.Base(target)
+	}
+	if current == driver {
+		return nil
+	}
+	if current != "" {
+		if err := writeSysfsFile(filepath.Join(sysBusVdpaDrivers, current, "unbind"), name); err != nil {
+			return fmt.Errorf("error unbinding vdpa device %s from driver %s: %v", name, current, err)
+		}
+	}
+	if err := writeSysfsFile(filepath.Join(sysBusVdpaDrivers, driver, "bind"), name); err != nil {
+		return fmt.Errorf("error binding vdpa device %s to driver %s: %v", name, driver, err)
+	}
+	return nil
+}
+
+// DeleteVdpaDevice deletes the vdpa device with the given name
+func DeleteVdpaDevice(name string) error {
+	return vdpaProvider.DeleteVdpaDevice(name)
+}
+
+// ListVdpaDeviceNames returns the names of all the vdpa devices
+func ListVdpaDeviceNames() ([]string, error) {
+	return vdpaProvider.ListVdpaDeviceNames()
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils/mocks"
+)
+
+var _ = Describe("VdpaProvider Functions", func() {
+	var (
+		mockProvider *mocks.VdpaProvider
+		origProvider VdpaProvider
+	)
+
+	BeforeEach(func() {
+		mockProvider = &mocks.VdpaProvider{}
+		origProvider = GetVdpaProvider()
+		SetVdpaProviderInst(mockProvider)
+	})
+	AfterEach(func() {
+		SetVdpaProviderInst(origProvider)
+	})
+
+	Describe("CreateVdpaDevice", func() {
+		It("should fail without adding the device when the driver is not loaded", func() {
+			defer (&FakeFilesystem{}).Use()()
+
+			err := CreateVdpaDevice("0000:01:10.0", "sriovdp-0000:01:10.0", "vhost_vdpa")
+			Expect(err).To(MatchError(ContainSubstring("vdpa driver vhost_vdpa is not loaded")))
+			mockProvider.AssertNotCalled(GinkgoT(), "AddVdpaDevice", "0000:01:10.0", "sriovdp-0000:01:10.0")
+		})
+
+		It("should leave a device probed by the requested driver alone", func() {
+			fs := &FakeFilesystem{
+				Dirs: []string{"sys/bus/vdpa/devices/sriovdp-0000:01:10.0", "sys/bus/vdpa/drivers/vhost_vdpa"},
+				Symlinks: map[string]string{
+					"sys/bus/vdpa/devices/sriovdp-0000:01:10.0/driver": "../../drivers/vhost_vdpa",
+				},
+			}
+			defer fs.Use()()
+			mockProvider.On("AddVdpaDevice", "0000:01:10.0", "sriovdp-0000:01:10.0").Return(nil)
+
+			Expect(CreateVdpaDevice("0000:01:10.0", "sriovdp-0000:01:10.0", "vhost_vdpa")).To(Succeed())
+		})
+
+		It("should rebind a device probed by another driver", func() {
+			fs := &FakeFilesystem{
+				Dirs: []string{"sys/bus/vdpa/devices/sriovdp-0000:01:10.0", "sys/bus/vdpa/drivers/vhost_vdpa",
+					"sys/bus/vdpa/drivers/virtio_vdpa"},
+				Files: map[string][]byte{
+					"sys/bus/vdpa/drivers/virtio_vdpa/unbind": nil,
+					"sys/bus/vdpa/drivers/vhost_vdpa/bind":    nil,
+				},
+				Symlinks: map[string]string{
+					"sys/bus/vdpa/devices/sriovdp-0000:01:10.0/driver": "../../drivers/virtio_vdpa",
+				},
+			}
+			defer fs.Use()()
+			mockProvider.On("AddVdpaDevice", "0000:01:10.0", "sriovdp-0000:01:10.0").Return(nil)
+
+			Expect(CreateVdpaDevice("0000:01:10.0", "sriovdp-0000:01:10.0", "vhost_vdpa")).To(Succeed())
+			Expect(os.ReadFile(filepath.Join(fs.RootDir, "sys/bus/vdpa/drivers/virtio_vdpa/unbind"))).
+				To(BeEquivalentTo("sriovdp-0000:01:10.0"))
+			Expect(os.ReadFile(filepath.Join(fs.RootDir, "sys/bus/vdpa/drivers/vhost_vdpa/bind"))).
+				To(BeEquivalentTo("sriovdp-0000:01:10.0"))
+		})
+
+		It("should delete the device again when it cannot be bound to the driver", func() {
+			fs := &FakeFilesystem{
+				Dirs: []string{"sys/bus/vdpa/devices/sriovdp-0000:01:10.0", "sys/bus/vdpa/drivers/vhost_vdpa"},
+			}
+			defer fs.Use()()
+			mockProvider.On("AddVdpaDevice", "0000:01:10.0", "sriovdp-0000:01:10.0").Return(nil)
+			mockProvider.On("DeleteVdpaDevice", "sriovdp-0000:01:10.0").Return(nil)
+
+			Expect(CreateVdpaDevice("0000:01:10.0", "sriovdp-0000:01:10.0", "vhost_vdpa")).ToNot(Succeed())
+			mockProvider.AssertCalled(GinkgoT(), "DeleteVdpaDevice", "sriovdp-0000:01:10.0")
+		})
+	})
+})